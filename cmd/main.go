@@ -3,9 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
-	"github.com/Kucoin/kucoin-go-sdk"
 	"log"
 	"os"
+	"vsC1Y2025V01/internal/connectors"
 )
 
 func main() {
@@ -19,34 +19,27 @@ func main() {
 	}
 
 	// Initialize KuCoin API client
-	//apiService := connectors.NewKucoinConnector(apiKey, apiSecret, apiPassphrase)
-
-	client := kucoin.NewApiService(
-		kucoin.ApiKeyOption(apiKey),
-		kucoin.ApiSecretOption(apiSecret),
-		kucoin.ApiPassPhraseOption(apiPassphrase),
-	)
+	connector := connectors.NewKucoinConnector(apiKey, apiSecret, apiPassphrase)
 
 	// Test connection to the KuCoin API
-	serverTime, err := client.ServerTime(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to fetch server time: %v", err)
+	ctx := context.Background()
+	if err := connector.TestConnection(ctx); err != nil {
+		log.Fatalf("Failed to connect to KuCoin: %v", err)
 	}
-	fmt.Printf("Connected to KuCoin. Server time: %d\n", serverTime)
-	//
-	//// Fetch account balances
-	//fetchAccountBalances(client)
+	fmt.Println("Connected to KuCoin.")
+
+	// Fetch account balances
+	fetchAccountBalances(ctx, connector)
 }
 
-func fetchAccountBalances(client *kucoin.ApiService) {
-	// Request account balances
-	//resp, err := client.Accounts()
-	//if err != nil {
-	//	log.Fatalf("Failed to fetch account balances: %v", err)
-	//}
-	//
-	//// Print account balances
-	//for _, account := range resp.Data {
-	//	fmt.Printf("Currency: %s, Available: %s, Balance: %s\n", account.Currency, account.Available, account.Balance)
-	//}
+func fetchAccountBalances(ctx context.Context, connector connectors.ExchangeConnector) {
+	balances, err := connector.GetBalances(ctx)
+	if err != nil {
+		log.Fatalf("Failed to fetch account balances: %v", err)
+	}
+
+	// Print account balances
+	for _, balance := range balances {
+		fmt.Printf("Currency: %s, Available: %f, Balance: %f\n", balance.Asset, balance.Free, balance.Total())
+	}
 }
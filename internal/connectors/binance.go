@@ -2,39 +2,570 @@ package connectors
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	bnspotmd "github.com/linstohu/nexapi/binance/spot/marketdata"
 	bnspottypes "github.com/linstohu/nexapi/binance/spot/marketdata/types"
+	bnspotaccount "github.com/linstohu/nexapi/binance/spot/spotaccount"
+	bnaccounttypes "github.com/linstohu/nexapi/binance/spot/spotaccount/types"
 	bnspotutils "github.com/linstohu/nexapi/binance/spot/utils"
+	bnwallet "github.com/linstohu/nexapi/binance/spot/wallet"
+	bnutils "github.com/linstohu/nexapi/binance/utils"
+	nexutils "github.com/linstohu/nexapi/utils"
 )
 
-func main() {
-	cli, err := bnspotmd.NewSpotMarketDataClient(&bnspotutils.SpotClientCfg{
-		Debug:   true,
-		BaseURL: bnspotutils.BaseURL,
+const (
+	// Binance only lists trades in windows of at most 24 hours.
+	binanceTradesWindow   = 24 * time.Hour
+	binanceTradesPageSize = 1000
+	binanceQuoteAsset     = "USDT"
+
+	binanceWeightHeader = "X-Mbx-Used-Weight-1m"
+	// binanceWeightBudget leaves headroom below the 6000/minute spot limit
+	// for the calls other instances make with the same key.
+	binanceWeightBudget = 4800
+)
+
+var (
+	_ ExchangeConnector   = (*BinanceConnector)(nil)
+	_ PermissionsReporter = (*BinanceConnector)(nil)
+	_ PriceProvider       = (*BinanceConnector)(nil)
+)
+
+// BinanceConnector talks to Binance spot through the nexapi clients. It keeps
+// track of the request weight Binance reports and waits for the next minute
+// before going over budget, since exceeding it gets the IP banned.
+type BinanceConnector struct {
+	marketDataClient *bnspotmd.SpotMarketDataClient
+	accountClient    *bnspotaccount.SpotAccountClient
+	walletClient     *bnwallet.SpotWalletClient
+
+	usedWeight   atomic.Int64
+	weightMinute atomic.Int64
+}
+
+func NewBinanceConnector(apiKey, apiSecret string) (*BinanceConnector, error) {
+	return newBinanceConnector(bnspotutils.BaseURL, apiKey, apiSecret)
+}
+
+func newBinanceConnector(baseURL, apiKey, apiSecret string) (*BinanceConnector, error) {
+	marketDataClient, err := bnspotmd.NewSpotMarketDataClient(&bnspotutils.SpotClientCfg{
+		BaseURL: baseURL,
 	})
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("initialize Binance market data client: %w", err)
 	}
 
-	orderbook, err := cli.GetOrderbook(context.TODO(), bnspottypes.GetOrderbookParams{
-		Symbol: "BTCUSDT",
-		Limit:  5,
+	connector := &BinanceConnector{marketDataClient: marketDataClient}
+	if apiKey == "" || apiSecret == "" {
+		return connector, nil
+	}
+
+	accountClient, err := bnspotaccount.NewSpotAccountClient(&bnspotaccount.SpotAccountClientCfg{
+		BaseURL:    baseURL,
+		Key:        apiKey,
+		Secret:     apiSecret,
+		RecvWindow: 5000,
 	})
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("initialize Binance account client: %w", err)
+	}
+
+	walletClient, err := bnwallet.NewSpotWalletClient(&bnwallet.SpotWalletClientCfg{
+		BaseURL:    baseURL,
+		Key:        apiKey,
+		Secret:     apiSecret,
+		RecvWindow: 5000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initialize Binance wallet client: %w", err)
+	}
+
+	connector.accountClient = accountClient
+	connector.walletClient = walletClient
+	return connector, nil
+}
+
+// UsedWeight is the request weight Binance last reported for the current
+// minute.
+func (bc *BinanceConnector) UsedWeight() int {
+	if bc.weightMinute.Load() != time.Now().Unix()/60 {
+		return 0
+	}
+	return int(bc.usedWeight.Load())
+}
+
+func (bc *BinanceConnector) TestConnection(ctx context.Context) error {
+	if err := bc.waitForWeight(ctx); err != nil {
+		return err
+	}
+	if err := bc.marketDataClient.Ping(ctx); err != nil {
+		return binanceError(err)
+	}
+	if bc.accountClient == nil {
+		return nil
+	}
+
+	_, err := bc.getAccountInfo(ctx)
+	return err
+}
+
+func (bc *BinanceConnector) GetPrice(ctx context.Context, symbol string) (float64, error) {
+	if err := bc.waitForWeight(ctx); err != nil {
+		return 0, err
+	}
+
+	resp, err := bc.marketDataClient.GetTickerPriceForSymbol(ctx, bnspottypes.GetTickerPriceForSymbolParam{Symbol: marketSymbol(symbol)})
+	if err != nil {
+		return 0, binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+
+	price := parseFloat(resp.Body.Price)
+	if price <= 0 {
+		return 0, fmt.Errorf("Binance returned no price for %s", symbol)
+	}
+	return price, nil
+}
+
+func (bc *BinanceConnector) GetBalances(ctx context.Context) ([]Balance, error) {
+	info, err := bc.getAccountInfo(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	limit := orderbook.Http.ApiRes.Header.Get("X-Mbx-Used-Weight-1m")
+	var balances []Balance
+	for _, b := range info.Balances {
+		balance := Balance{
+			Asset:  b.Asset,
+			Free:   parseFloat(b.Free),
+			Locked: parseFloat(b.Locked),
+		}
+		// Binance lists every asset it supports; only report holdings.
+		if balance.Total() > 0 {
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
+
+func (bc *BinanceConnector) getAccountInfo(ctx context.Context) (*bnaccounttypes.AccountInfo, error) {
+	if bc.accountClient == nil {
+		return nil, errBinanceNoCredentials
+	}
+	if err := bc.waitForWeight(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := bc.accountClient.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+	return resp.Body, nil
+}
+
+// GetKeyPermissions reads the restrictions Binance keeps per API key.
+func (bc *BinanceConnector) GetKeyPermissions(ctx context.Context) (*KeyPermissions, error) {
+	if bc.walletClient == nil {
+		return nil, errBinanceNoCredentials
+	}
+	if err := bc.waitForWeight(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := bc.walletClient.GetApiRestrictions(ctx)
+	if err != nil {
+		return nil, binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+
+	return &KeyPermissions{
+		Read:     resp.Body.EnableReading,
+		Trade:    resp.Body.EnableSpotAndMarginTrading,
+		Withdraw: resp.Body.EnableWithdrawals,
+		Futures:  resp.Body.EnableFutures,
+	}, nil
+}
+
+func (bc *BinanceConnector) GetOpenOrders(ctx context.Context, req OpenOrdersRequest) ([]Order, error) {
+	if bc.accountClient == nil {
+		return nil, errBinanceNoCredentials
+	}
+	if err := bc.waitForWeight(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := bc.accountClient.GetOpenOrders(ctx, bnaccounttypes.GetOpenOrdersParam{Symbol: req.Symbol})
+	if err != nil {
+		return nil, binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+
+	orders := make([]Order, 0, len(resp.Body))
+	for _, o := range resp.Body {
+		order := binanceOrder(o.OrderInfo)
+		order.CreatedAt = time.UnixMilli(o.Time).UTC()
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
 
-	fmt.Printf("Current used request weight: %v\n", limit)
+// binanceOrderParams mirrors nexapi's NewOrderParams with decimal strings:
+// nexapi encodes float64 quantities with %v, which turns small sizes such as
+// 0.00001 BTC into 1e-05 and gets the order rejected.
+type binanceOrderParams struct {
+	Symbol           string `url:"symbol"`
+	Side             string `url:"side"`
+	Type             string `url:"type"`
+	TimeInForce      string `url:"timeInForce,omitempty"`
+	Quantity         string `url:"quantity"`
+	Price            string `url:"price,omitempty"`
+	NewClientOrderID string `url:"newClientOrderId,omitempty"`
+	NewOrderRespType string `url:"newOrderRespType"`
+	bnutils.DefaultParam
+}
 
-	bytes, err := json.MarshalIndent(orderbook.Body, "", "\t")
+func (bc *BinanceConnector) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if bc.accountClient == nil {
+		return nil, errBinanceNoCredentials
+	}
+	if err := bc.waitForWeight(ctx); err != nil {
+		return nil, err
+	}
+
+	headers, err := bc.accountClient.GenHeaders(bnspotutils.TRADE)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	body := binanceOrderParams{
+		Symbol:           req.Symbol,
+		Side:             strings.ToUpper(req.Side),
+		Type:             strings.ToUpper(req.Type),
+		Quantity:         formatFloat(req.Quantity),
+		NewClientOrderID: req.ClientOrderID,
+		NewOrderRespType: string(bnaccounttypes.RESULT),
+		DefaultParam: bnutils.DefaultParam{
+			RecvWindow: bc.accountClient.GetRecvWindow(),
+			Timestamp:  time.Now().UnixMilli(),
+		},
+	}
+	if req.Type == OrderTypeLimit {
+		body.TimeInForce = string(bnaccounttypes.GTC)
+		body.Price = formatFloat(req.Price)
 	}
 
-	fmt.Println(string(bytes))
+	signString, err := bnutils.NormalizeRequestContent(nil, body)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, []byte(bc.accountClient.GetSecret()))
+	h.Write([]byte(signString))
+	body.Signature = hex.EncodeToString(h.Sum(nil))
+
+	resp, err := bc.accountClient.SendHTTPRequest(ctx, nexutils.HTTPRequest{
+		BaseURL: bc.accountClient.GetBaseURL(),
+		Path:    "/api/v3/order",
+		Method:  http.MethodPost,
+		Headers: headers,
+		Body:    body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bc.trackWeight(resp)
+
+	var placed bnaccounttypes.NewOrderAPIResp
+	if err := resp.ReadJsonBody(&placed); err != nil {
+		return nil, binanceError(err)
+	}
+
+	order := binanceOrder(placed.OrderInfo)
+	order.CreatedAt = time.UnixMilli(placed.TransactTime).UTC()
+	return &order, nil
+}
+
+func (bc *BinanceConnector) CancelOrder(ctx context.Context, req CancelOrderRequest) error {
+	if req.Symbol == "" {
+		return fmt.Errorf("%w: symbol is required", ErrInvalidOrder)
+	}
+
+	param := bnaccounttypes.CancelOrderParam{Symbol: req.Symbol}
+	switch {
+	case req.OrderID != "":
+		orderID, err := strconv.ParseInt(req.OrderID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid order id %q", ErrInvalidOrder, req.OrderID)
+		}
+		param.OrderID = orderID
+	case req.ClientOrderID != "":
+		param.OrigClientOrderId = req.ClientOrderID
+	default:
+		return fmt.Errorf("%w: order id or client order id is required", ErrInvalidOrder)
+	}
+
+	if bc.accountClient == nil {
+		return errBinanceNoCredentials
+	}
+	if err := bc.waitForWeight(ctx); err != nil {
+		return err
+	}
+
+	resp, err := bc.accountClient.CancelOrder(ctx, param)
+	if err != nil {
+		return binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+	return nil
+}
+
+// GetPositions is not supported: this connector only covers Binance spot.
+func (bc *BinanceConnector) GetPositions(ctx context.Context) ([]Position, error) {
+	return nil, ErrNotSupported
+}
+
+func (bc *BinanceConnector) GetSymbols(ctx context.Context) ([]SymbolInfo, error) {
+	if err := bc.waitForWeight(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := bc.marketDataClient.GetExchangeInfo(ctx, bnspottypes.GetExchangeInfoParam{})
+	if err != nil {
+		return nil, binanceError(err)
+	}
+	bc.trackWeight(resp.Http)
+
+	symbols := make([]SymbolInfo, 0, len(resp.Body.Symbols))
+	for _, s := range resp.Body.Symbols {
+		symbol := SymbolInfo{
+			Symbol:     s.Symbol,
+			BaseAsset:  s.BaseAsset,
+			QuoteAsset: s.QuoteAsset,
+			Trading:    s.Status == "TRADING" && s.IsSpotTradingAllowed,
+		}
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				symbol.TickSize = parseFloat(f.TickSize)
+			case "LOT_SIZE":
+				symbol.StepSize = parseFloat(f.StepSize)
+				symbol.MinQuantity = parseFloat(f.MinQty)
+			case "NOTIONAL", "MIN_NOTIONAL":
+				symbol.MinNotional = parseFloat(f.MinNotional)
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// GetFills walks /api/v3/myTrades one symbol and one 24-hour window at a
+// time. Binance only lists trades per symbol, so without req.Symbols the
+// markets are the assets currently held, quoted in USDT, plus
+// req.KnownSymbols.
+func (bc *BinanceConnector) GetFills(ctx context.Context, req HistoryRequest) ([]Fill, error) {
+	if bc.accountClient == nil {
+		return nil, errBinanceNoCredentials
+	}
+
+	symbols := req.Symbols
+	derived := len(symbols) == 0
+	if derived {
+		balances, err := bc.GetBalances(ctx)
+		if err != nil {
+			return nil, err
+		}
+		symbols = historySymbols(balances, binanceQuoteAsset, req.KnownSymbols)
+	}
+
+	since := req.Since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultHistoryLookback)
+	}
+	now := time.Now()
+
+	var fills []Fill
+symbols:
+	for _, symbol := range symbols {
+		for start := since; start.Before(now); start = start.Add(binanceTradesWindow) {
+			end := start.Add(binanceTradesWindow)
+			if end.After(now) {
+				end = now
+			}
+
+			for cursor := start; ; {
+				if err := bc.waitForWeight(ctx); err != nil {
+					return nil, err
+				}
+
+				resp, err := bc.accountClient.GetTradeList(ctx, bnaccounttypes.GetTradesParam{
+					Symbol:    symbol,
+					StartTime: cursor.UnixMilli(),
+					EndTime:   end.UnixMilli(),
+					Limit:     binanceTradesPageSize,
+				})
+				if err != nil {
+					err = binanceError(err)
+					// A journal symbol may not be a Binance spot market.
+					if derived && errors.Is(err, ErrUnknownSymbol) {
+						continue symbols
+					}
+					return nil, err
+				}
+				bc.trackWeight(resp.Http)
+
+				for _, trade := range resp.Body {
+					fills = append(fills, binanceFill(trade))
+					if req.Limit > 0 && len(fills) >= req.Limit {
+						return fills, nil
+					}
+				}
+
+				if len(resp.Body) < binanceTradesPageSize {
+					break
+				}
+				// Resume just after the last trade of the full page.
+				cursor = time.UnixMilli(resp.Body[len(resp.Body)-1].Time + 1)
+			}
+		}
+	}
+
+	return fills, nil
+}
+
+func binanceOrder(o bnaccounttypes.OrderInfo) Order {
+	return Order{
+		ID:             strconv.FormatInt(o.OrderID, 10),
+		ClientOrderID:  o.ClientOrderID,
+		Symbol:         o.Symbol,
+		Side:           strings.ToLower(o.Side),
+		Type:           strings.ToLower(o.Type),
+		Status:         normalizeOrderStatus(o.Status),
+		Price:          parseFloat(o.Price),
+		Quantity:       parseFloat(o.OrigQty),
+		FilledQuantity: parseFloat(o.ExecutedQty),
+	}
+}
+
+func binanceFill(t *bnaccounttypes.Trade) Fill {
+	side := SideSell
+	if t.IsBuyer {
+		side = SideBuy
+	}
+
+	return Fill{
+		TradeID:      strconv.FormatInt(t.ID, 10),
+		OrderID:      strconv.FormatInt(t.OrderID, 10),
+		Symbol:       t.Symbol,
+		Side:         side,
+		ContractType: "spot",
+		Price:        parseFloat(t.Price),
+		Quantity:     parseFloat(t.Qty),
+		Fee:          parseFloat(t.Commission),
+		FeeAsset:     t.CommissionAsset,
+		IsMaker:      t.IsMaker,
+		Time:         time.UnixMilli(t.Time).UTC(),
+	}
+}
+
+// trackWeight records the weight Binance reports on every response.
+func (bc *BinanceConnector) trackWeight(resp *nexutils.ApiResponse) {
+	if resp == nil || resp.ApiRes == nil {
+		return
+	}
+	weight, err := strconv.ParseInt(resp.ApiRes.Header.Get(binanceWeightHeader), 10, 64)
+	if err != nil {
+		return
+	}
+	bc.weightMinute.Store(time.Now().Unix() / 60)
+	bc.usedWeight.Store(weight)
+}
+
+// waitForWeight blocks until the next minute when the weight used in the
+// current one is over budget.
+func (bc *BinanceConnector) waitForWeight(ctx context.Context) error {
+	if bc.UsedWeight() < binanceWeightBudget {
+		return nil
+	}
+
+	now := time.Now()
+	timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var errBinanceNoCredentials = &ExchangeError{Exchange: "binance", Message: "API credentials are required", Kind: ErrAuth}
+
+// nexapi reports Binance failures as formatted errors only; the status code
+// and the JSON error body are recovered from the message.
+var binanceHTTPError = regexp.MustCompile(`(?s)respond code=(\d+) body=(.*)$`)
+
+// binanceError maps an error returned by nexapi onto the normalized errors.
+func binanceError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	m := binanceHTTPError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+
+	status, _ := strconv.Atoi(m[1])
+	var body struct {
+		Code flexString `json:"code"`
+		Msg  string     `json:"msg"`
+	}
+	_ = json.Unmarshal([]byte(m[2]), &body)
+
+	kind := binanceErrorKind(string(body.Code), body.Msg)
+	if kind == nil {
+		kind = errorKindForStatus(status)
+	}
+
+	return &ExchangeError{
+		Exchange:   "binance",
+		StatusCode: status,
+		Code:       string(body.Code),
+		Message:    body.Msg,
+		Kind:       kind,
+	}
+}
+
+func binanceErrorKind(code, msg string) error {
+	switch code {
+	case "-1022", "-2014", "-2015":
+		return ErrAuth
+	case "-1003", "-1015":
+		return ErrRateLimited
+	case "-1121":
+		return ErrUnknownSymbol
+	case "-2010", "-2018", "-2019":
+		// -2010 is any rejected order; only the message tells why.
+		if code != "-2010" || strings.Contains(strings.ToLower(msg), "insufficient balance") {
+			return ErrInsufficientFunds
+		}
+	}
+	return nil
 }
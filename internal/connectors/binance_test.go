@@ -0,0 +1,208 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBinanceTestServer(t *testing.T, handler http.HandlerFunc) *BinanceConnector {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	connector, err := newBinanceConnector(server.URL, "test-key", "test-secret")
+	assert.NoError(t, err)
+	return connector
+}
+
+func TestNewBinanceConnector(t *testing.T) {
+	connector, err := NewBinanceConnector("test-key", "test-secret")
+	assert.NoError(t, err)
+	assert.NotNil(t, connector.marketDataClient)
+	assert.NotNil(t, connector.accountClient)
+
+	connector, err = NewBinanceConnector("", "")
+	assert.NoError(t, err)
+	_, err = connector.GetBalances(context.Background())
+	assert.ErrorIs(t, err, ErrAuth)
+}
+
+func TestBinanceConnector_GetBalances(t *testing.T) {
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/account", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("X-MBX-APIKEY"))
+		assert.NotEmpty(t, r.URL.Query().Get("timestamp"))
+		assert.Len(t, r.URL.Query().Get("signature"), 64)
+
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "42")
+		w.Write([]byte(`{"canTrade":true,"balances":[
+			{"asset":"BTC","free":"0.5","locked":"0.1"},
+			{"asset":"LTC","free":"0.00000000","locked":"0.00000000"}
+		]}`))
+	})
+
+	balances, err := connector.GetBalances(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Balance{{Asset: "BTC", Free: 0.5, Locked: 0.1}}, balances)
+	assert.Equal(t, 42, connector.UsedWeight())
+}
+
+func TestBinanceConnector_GetPrice(t *testing.T) {
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/ticker/price", r.URL.Path)
+		assert.Equal(t, "ETHUSDT", r.URL.Query().Get("symbol"))
+
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "2")
+		w.Write([]byte(`{"symbol":"ETHUSDT","price":"3120.45000000"}`))
+	})
+
+	price, err := connector.GetPrice(context.Background(), "ETH-USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 3120.45, price)
+	assert.Equal(t, 2, connector.UsedWeight())
+}
+
+func TestBinanceConnector_GetKeyPermissions(t *testing.T) {
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sapi/v1/account/apiRestrictions", r.URL.Path)
+
+		w.Write([]byte(`{"ipRestrict":false,"enableReading":true,"enableWithdrawals":false,
+			"enableSpotAndMarginTrading":true,"enableFutures":false,"createTime":1698645219000}`))
+	})
+
+	permissions, err := connector.GetKeyPermissions(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &KeyPermissions{Read: true, Trade: true}, permissions)
+}
+
+func TestBinanceConnector_PlaceOrder(t *testing.T) {
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v3/order", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "BTCUSDT", r.PostForm.Get("symbol"))
+		assert.Equal(t, "BUY", r.PostForm.Get("side"))
+		assert.Equal(t, "LIMIT", r.PostForm.Get("type"))
+		assert.Equal(t, "GTC", r.PostForm.Get("timeInForce"))
+		assert.Equal(t, "0.00001", r.PostForm.Get("quantity"))
+		assert.Equal(t, "60000.5", r.PostForm.Get("price"))
+		assert.Len(t, r.PostForm.Get("signature"), 64)
+
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "7")
+		w.Write([]byte(`{"symbol":"BTCUSDT","orderId":28,"clientOrderId":"abc","transactTime":1720732560000,
+			"price":"60000.50","origQty":"0.00001","executedQty":"0.00000","status":"NEW","type":"LIMIT","side":"BUY"}`))
+	})
+
+	order, err := connector.PlaceOrder(context.Background(), PlaceOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     SideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 0.00001,
+		Price:    60000.5,
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, order) {
+		assert.Equal(t, "28", order.ID)
+		assert.Equal(t, "abc", order.ClientOrderID)
+		assert.Equal(t, OrderStatusNew, order.Status)
+		assert.Equal(t, OrderTypeLimit, order.Type)
+		assert.Equal(t, int64(1720732560000), order.CreatedAt.UnixMilli())
+	}
+	assert.Equal(t, 7, connector.UsedWeight())
+}
+
+func TestBinanceConnector_GetFills(t *testing.T) {
+	calls := 0
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v3/myTrades", r.URL.Path)
+		assert.Equal(t, "ETHUSDT", r.URL.Query().Get("symbol"))
+		assert.NotEmpty(t, r.URL.Query().Get("endTime"))
+
+		w.Write([]byte(`[{"symbol":"ETHUSDT","id":28457,"orderId":100234,"price":"3100.10","qty":"0.5","quoteQty":"1550.05",
+			"commission":"0.0005","commissionAsset":"ETH","time":1720732560000,"isBuyer":true,"isMaker":false}]`))
+	})
+
+	fills, err := connector.GetFills(context.Background(), HistoryRequest{
+		Since:   time.Now().Add(-time.Hour),
+		Symbols: []string{"ETHUSDT"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	if assert.Len(t, fills, 1) {
+		assert.Equal(t, "28457", fills[0].TradeID)
+		assert.Equal(t, "100234", fills[0].OrderID)
+		assert.Equal(t, SideBuy, fills[0].Side)
+		assert.Equal(t, 3100.10, fills[0].Price)
+		assert.Equal(t, "ETH", fills[0].FeeAsset)
+	}
+}
+
+func TestBinanceConnector_GetFillsWithoutBalance(t *testing.T) {
+	var queried []string
+	connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/account":
+			w.Write([]byte(`{"canTrade":true,"balances":[{"asset":"ETH","free":"0.00000000","locked":"0.00000000"}]}`))
+		case "/api/v3/myTrades":
+			symbol := r.URL.Query().Get("symbol")
+			queried = append(queried, symbol)
+			if symbol != "ETHUSDT" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+				return
+			}
+			w.Write([]byte(`[{"symbol":"ETHUSDT","id":28457,"orderId":100234,"price":"3100.10","qty":"0.5","quoteQty":"1550.05",
+				"commission":"1.55","commissionAsset":"USDT","time":1720732560000,"isBuyer":false,"isMaker":false}]`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	// The ETH was bought and sold again, so only the journal knows the market.
+	fills, err := connector.GetFills(context.Background(), HistoryRequest{
+		Since:        time.Now().Add(-time.Hour),
+		KnownSymbols: []string{"ETH/USDT", "ETHUSDTM"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ETHUSDT", "ETHUSDTM"}, queried)
+	if assert.Len(t, fills, 1) {
+		assert.Equal(t, "28457", fills[0].TradeID)
+		assert.Equal(t, SideSell, fills[0].Side)
+	}
+}
+
+func TestBinanceConnector_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"auth", http.StatusUnauthorized, `{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`, ErrAuth},
+		{"rate limited", http.StatusTooManyRequests, `{"code":-1003,"msg":"Too many requests."}`, ErrRateLimited},
+		{"insufficient balance", http.StatusBadRequest, `{"code":-2010,"msg":"Account has insufficient balance for requested action."}`, ErrInsufficientFunds},
+		{"unknown symbol", http.StatusBadRequest, `{"code":-1121,"msg":"Invalid symbol."}`, ErrUnknownSymbol},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			connector := newBinanceTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+
+			_, err := connector.PlaceOrder(context.Background(), PlaceOrderRequest{Symbol: "BTCUSDT", Side: SideSell, Type: OrderTypeMarket, Quantity: 1})
+			assert.ErrorIs(t, err, tc.want)
+
+			var exchangeErr *ExchangeError
+			if assert.ErrorAs(t, err, &exchangeErr) {
+				assert.Equal(t, tc.status, exchangeErr.StatusCode)
+			}
+		})
+	}
+}
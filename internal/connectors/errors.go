@@ -0,0 +1,56 @@
+package connectors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Normalized connector errors. Every connector maps the exchange's own error
+// codes onto these so callers can react without knowing which exchange they
+// are talking to; test with errors.Is.
+var (
+	ErrAuth              = errors.New("exchange rejected the API credentials")
+	ErrRateLimited       = errors.New("exchange rate limit exceeded")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrUnknownSymbol     = errors.New("unknown symbol")
+	ErrInvalidOrder      = errors.New("invalid order request")
+	ErrNotSupported      = errors.New("operation not supported by this exchange")
+)
+
+// ExchangeError is an error reported by an exchange API. It keeps the
+// exchange's own code and message for logs and unwraps to the normalized
+// error it maps to, if any.
+type ExchangeError struct {
+	Exchange   string
+	StatusCode int
+	Code       string
+	Message    string
+	Kind       error
+}
+
+func (e *ExchangeError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Kind != nil {
+		msg = e.Kind.Error()
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %s (code %s)", e.Exchange, msg, e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Exchange, msg)
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Kind
+}
+
+// errorKindForStatus maps the HTTP statuses that mean the same thing on every
+// exchange.
+func errorKindForStatus(status int) error {
+	switch status {
+	case 401, 403:
+		return ErrAuth
+	case 418, 429:
+		return ErrRateLimited
+	}
+	return nil
+}
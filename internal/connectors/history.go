@@ -0,0 +1,74 @@
+package connectors
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Fill is a single execution reported by an exchange.
+type Fill struct {
+	TradeID      string
+	OrderID      string
+	Symbol       string
+	Side         string // SideBuy or SideSell
+	OrderType    string
+	ContractType string // "spot" or "futures"
+	Price        float64
+	Quantity     float64
+	Fee          float64
+	FeeAsset     string
+	IsMaker      bool
+	Time         time.Time
+}
+
+// ClosedPosition is a derivatives position that has been fully closed.
+type ClosedPosition struct {
+	PositionID  string
+	Symbol      string
+	IsLong      bool
+	MarginMode  string
+	Leverage    float64
+	Quantity    float64
+	EntryPrice  float64
+	ExitPrice   float64
+	RealizedPnL float64
+	Fee         float64
+	OpenedAt    time.Time
+	ClosedAt    time.Time
+}
+
+// defaultHistoryLookback is how far back a first sync reaches when the caller
+// has no cursor yet.
+const defaultHistoryLookback = 90 * 24 * time.Hour
+
+// HistoryRequest asks for everything that happened at or after Since.
+// Connectors page through the exchange API themselves; Limit caps the total
+// number of records returned (0 means the connector default). Symbols
+// narrows the request; exchanges that can only list history per symbol fall
+// back to the markets of the assets currently held, plus KnownSymbols, when
+// it is empty.
+type HistoryRequest struct {
+	Since   time.Time
+	Limit   int
+	Symbols []string
+	// KnownSymbols are markets the account is known to have traded, such as
+	// the symbols already in the user's journal. They catch round trips that
+	// left no balance behind. Symbols the exchange does not list are skipped.
+	KnownSymbols []string
+}
+
+// TradeHistoryProvider is implemented by connectors that can list fills.
+type TradeHistoryProvider interface {
+	GetFills(ctx context.Context, req HistoryRequest) ([]Fill, error)
+}
+
+// PositionHistoryProvider is implemented by connectors for exchanges with
+// derivatives accounts that report closed positions.
+type PositionHistoryProvider interface {
+	GetClosedPositions(ctx context.Context, req HistoryRequest) ([]ClosedPosition, error)
+}
@@ -1,8 +1,37 @@
 package connectors
 
-// ExchangeConnector defines the interface for exchange connectors
+import "context"
+
+// ExchangeConnector defines the interface for exchange connectors. Every call
+// honours ctx and returns errors that wrap one of the normalized errors in
+// errors.go whenever the exchange told us why it failed. Calls a connector
+// cannot serve (e.g. positions on a spot-only exchange) return
+// ErrNotSupported.
 type ExchangeConnector interface {
-	TestConnection() error
-	GetAccountBalances() (map[string]float64, error)
-	ExecuteOrder(orderType string, symbol string, quantity float64, price float64) (string, error)
+	TradeHistoryProvider
+
+	// TestConnection checks that the exchange is reachable and, when the
+	// connector holds credentials, that they are accepted.
+	TestConnection(ctx context.Context) error
+	GetBalances(ctx context.Context) ([]Balance, error)
+	GetOpenOrders(ctx context.Context, req OpenOrdersRequest) ([]Order, error)
+	PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*Order, error)
+	CancelOrder(ctx context.Context, req CancelOrderRequest) error
+	GetPositions(ctx context.Context) ([]Position, error)
+	GetSymbols(ctx context.Context) ([]SymbolInfo, error)
+}
+
+// KeyPermissions is what the API key in use may do, as reported by the
+// exchange.
+type KeyPermissions struct {
+	Read     bool
+	Trade    bool
+	Withdraw bool
+	Futures  bool
+}
+
+// PermissionsReporter is implemented by connectors for exchanges that report
+// the permissions of an API key.
+type PermissionsReporter interface {
+	GetKeyPermissions(ctx context.Context) (*KeyPermissions, error)
 }
@@ -2,49 +2,371 @@ package connectors
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	kucoin "github.com/Kucoin/kucoin-go-sdk"
 )
 
+const (
+	// KuCoin only serves fills in windows of at most seven days.
+	kucoinFillsWindow   = 7 * 24 * time.Hour
+	kucoinFillsPageSize = 500
+	kucoinOrdersPage    = 500
+)
+
+var (
+	_ ExchangeConnector   = (*KucoinConnector)(nil)
+	_ PermissionsReporter = (*KucoinConnector)(nil)
+)
+
 type KucoinConnector struct {
-	apiService *kucoin.ApiService
+	apiService    *kucoin.ApiService
+	authenticated bool
 }
 
-func NewKucoinConnector(apiKey, apiSecret, apiPassphrase string) *KucoinConnector {
-	apiService := kucoin.NewApiService(
+// NewKucoinConnector builds a spot connector. Keys are expected to be V2 API
+// keys (the passphrase is signed, as KuCoin requires for every key created
+// since 2021); opts can override that or point at another base URI.
+func NewKucoinConnector(apiKey, apiSecret, apiPassphrase string, opts ...kucoin.ApiServiceOption) *KucoinConnector {
+	apiService := kucoin.NewApiService(append([]kucoin.ApiServiceOption{
 		kucoin.ApiKeyOption(apiKey),
 		kucoin.ApiSecretOption(apiSecret),
 		kucoin.ApiPassPhraseOption(apiPassphrase),
+		kucoin.ApiKeyVersionOption(kucoin.ApiKeyVersionV2),
+	}, opts...)...)
+
+	return &KucoinConnector{apiService: apiService, authenticated: apiKey != ""}
+}
+
+func (kc *KucoinConnector) TestConnection(ctx context.Context) error {
+	var serverTime int64
+	rsp, err := kc.apiService.ServerTime(ctx)
+	if err := kucoinData(rsp, err, &serverTime); err != nil {
+		return err
+	}
+	if !kc.authenticated {
+		return nil
+	}
+
+	var accounts kucoin.AccountsModel
+	rsp, err = kc.apiService.Accounts(ctx, "", "trade")
+	return kucoinData(rsp, err, &accounts)
+}
+
+// kucoinAPIKeyInfo is the part of GET /api/v1/user/api-key we use; the SDK
+// does not wrap that endpoint.
+type kucoinAPIKeyInfo struct {
+	Permission string `json:"permission"` // e.g. "General,Spot,Futures"
+}
+
+// GetKeyPermissions reads the permission list KuCoin keeps per API key.
+func (kc *KucoinConnector) GetKeyPermissions(ctx context.Context) (*KeyPermissions, error) {
+	var info kucoinAPIKeyInfo
+	rsp, err := kc.apiService.Call(ctx, kucoin.NewRequest(http.MethodGet, "/api/v1/user/api-key", nil))
+	if err := kucoinData(rsp, err, &info); err != nil {
+		return nil, err
+	}
+
+	permissions := &KeyPermissions{}
+	for _, p := range strings.Split(info.Permission, ",") {
+		switch strings.TrimSpace(p) {
+		case "General":
+			permissions.Read = true
+		case "Spot", "Margin":
+			permissions.Trade = true
+		case "Futures":
+			permissions.Futures = true
+		case "Withdrawal":
+			permissions.Withdraw = true
+		}
+	}
+	return permissions, nil
+}
+
+// GetBalances returns the holdings of the trade (spot) account.
+func (kc *KucoinConnector) GetBalances(ctx context.Context) ([]Balance, error) {
+	var accounts kucoin.AccountsModel
+	rsp, err := kc.apiService.Accounts(ctx, "", "trade")
+	if err := kucoinData(rsp, err, &accounts); err != nil {
+		return nil, err
+	}
+
+	balances := make([]Balance, 0, len(accounts))
+	for _, account := range accounts {
+		balances = append(balances, Balance{
+			Asset:  account.Currency,
+			Free:   parseFloat(account.Available),
+			Locked: parseFloat(account.Holds),
+		})
+	}
+	return balances, nil
+}
+
+func (kc *KucoinConnector) GetOpenOrders(ctx context.Context, req OpenOrdersRequest) ([]Order, error) {
+	params := map[string]string{"status": "active", "tradeType": "TRADE"}
+	if req.Symbol != "" {
+		params["symbol"] = req.Symbol
+	}
+
+	var orders []Order
+	for page := int64(1); ; page++ {
+		var items kucoin.OrdersModel
+		rsp, err := kc.apiService.Orders(ctx, params, &kucoin.PaginationParam{CurrentPage: page, PageSize: kucoinOrdersPage})
+		pagination, err := kucoinPage(rsp, err, &items)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			orders = append(orders, kucoinOrder(item))
+		}
+
+		if page >= pagination.TotalPage {
+			return orders, nil
+		}
+	}
+}
+
+func (kc *KucoinConnector) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	clientOrderID := req.ClientOrderID
+	if clientOrderID == "" {
+		generated, err := newClientOrderID()
+		if err != nil {
+			return nil, err
+		}
+		clientOrderID = generated
+	}
+
+	order := &kucoin.CreateOrderModel{
+		ClientOid: clientOrderID,
+		Side:      req.Side,
+		Symbol:    req.Symbol,
+		Type:      req.Type,
+		TradeType: "TRADE",
+		Size:      formatFloat(req.Quantity),
+	}
+	if req.Type == OrderTypeLimit {
+		order.Price = formatFloat(req.Price)
+	}
+
+	var result kucoin.CreateOrderResultModel
+	rsp, err := kc.apiService.CreateOrder(ctx, order)
+	if err := kucoinData(rsp, err, &result); err != nil {
+		return nil, err
+	}
+
+	return &Order{
+		ID:            result.OrderId,
+		ClientOrderID: clientOrderID,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		Status:        OrderStatusNew,
+		Price:         req.Price,
+		Quantity:      req.Quantity,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+func (kc *KucoinConnector) CancelOrder(ctx context.Context, req CancelOrderRequest) error {
+	var (
+		rsp *kucoin.ApiResponse
+		err error
 	)
+	switch {
+	case req.OrderID != "":
+		rsp, err = kc.apiService.CancelOrder(ctx, req.OrderID)
+	case req.ClientOrderID != "":
+		rsp, err = kc.apiService.CancelOrderByClient(ctx, req.ClientOrderID)
+	default:
+		return fmt.Errorf("%w: order id or client order id is required", ErrInvalidOrder)
+	}
+
+	var result map[string]interface{}
+	return kucoinData(rsp, err, &result)
+}
+
+// GetPositions is not supported: this connector only covers KuCoin spot.
+func (kc *KucoinConnector) GetPositions(ctx context.Context) ([]Position, error) {
+	return nil, ErrNotSupported
+}
+
+func (kc *KucoinConnector) GetSymbols(ctx context.Context) ([]SymbolInfo, error) {
+	var items kucoin.SymbolsModelV2
+	rsp, err := kc.apiService.SymbolsV2(ctx, "")
+	if err := kucoinData(rsp, err, &items); err != nil {
+		return nil, err
+	}
+
+	symbols := make([]SymbolInfo, 0, len(items))
+	for _, item := range items {
+		symbols = append(symbols, SymbolInfo{
+			Symbol:      item.Symbol,
+			BaseAsset:   item.BaseCurrency,
+			QuoteAsset:  item.QuoteCurrency,
+			TickSize:    parseFloat(item.PriceIncrement),
+			StepSize:    parseFloat(item.BaseIncrement),
+			MinQuantity: parseFloat(item.BaseMinSize),
+			MinNotional: parseFloat(item.MinFunds),
+			Trading:     item.EnableTrading,
+		})
+	}
+	return symbols, nil
+}
+
+// GetFills walks the spot fills history from req.Since until now, one
+// seven-day window and one page at a time.
+func (kc *KucoinConnector) GetFills(ctx context.Context, req HistoryRequest) ([]Fill, error) {
+	since := req.Since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultHistoryLookback)
+	}
+	now := time.Now()
+
+	// An empty symbol asks KuCoin for fills across all markets.
+	symbols := req.Symbols
+	if len(symbols) == 0 {
+		symbols = []string{""}
+	}
+
+	var fills []Fill
+	for _, symbol := range symbols {
+		for start := since; start.Before(now); start = start.Add(kucoinFillsWindow) {
+			end := start.Add(kucoinFillsWindow)
+			if end.After(now) {
+				end = now
+			}
+
+			for page := int64(1); ; page++ {
+				params := map[string]string{
+					"startAt":   strconv.FormatInt(start.UnixMilli(), 10),
+					"endAt":     strconv.FormatInt(end.UnixMilli(), 10),
+					"tradeType": "TRADE",
+				}
+				if symbol != "" {
+					params["symbol"] = symbol
+				}
+
+				var items kucoin.FillsModel
+				rsp, err := kc.apiService.Fills(ctx, params, &kucoin.PaginationParam{CurrentPage: page, PageSize: kucoinFillsPageSize})
+				pagination, err := kucoinPage(rsp, err, &items)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, item := range items {
+					fills = append(fills, kucoinFill(item))
+					if req.Limit > 0 && len(fills) >= req.Limit {
+						return fills, nil
+					}
+				}
+
+				if page >= pagination.TotalPage {
+					break
+				}
+			}
+		}
+	}
+
+	return fills, nil
+}
+
+func kucoinFill(f *kucoin.FillModel) Fill {
+	return Fill{
+		TradeID:      f.TradeId,
+		OrderID:      f.OrderId,
+		Symbol:       f.Symbol,
+		Side:         f.Side,
+		OrderType:    f.Type,
+		ContractType: "spot",
+		Price:        parseFloat(f.Price),
+		Quantity:     parseFloat(f.Size),
+		Fee:          parseFloat(f.Fee),
+		FeeAsset:     f.FeeCurrency,
+		IsMaker:      f.Liquidity == "maker",
+		Time:         time.UnixMilli(f.CreatedAt).UTC(),
+	}
+}
+
+func kucoinOrder(o *kucoin.OrderModel) Order {
+	quantity := parseFloat(o.Size)
+	filled := parseFloat(o.DealSize)
+
+	status := OrderStatusFilled
+	switch {
+	case o.IsActive && filled > 0:
+		status = OrderStatusPartiallyFilled
+	case o.IsActive:
+		status = OrderStatusNew
+	case o.CancelExist:
+		status = OrderStatusCanceled
+	}
+
+	return Order{
+		ID:             o.Id,
+		ClientOrderID:  o.ClientOid,
+		Symbol:         o.Symbol,
+		Side:           o.Side,
+		Type:           o.Type,
+		Status:         status,
+		Price:          parseFloat(o.Price),
+		Quantity:       quantity,
+		FilledQuantity: filled,
+		CreatedAt:      time.UnixMilli(o.CreatedAt).UTC(),
+	}
+}
+
+// kucoinData checks a KuCoin response and reads its data into v, mapping
+// API failures onto the normalized errors.
+func kucoinData(rsp *kucoin.ApiResponse, err error, v interface{}) error {
+	if err := kucoinCheck(rsp, err); err != nil {
+		return err
+	}
+	return rsp.ReadData(v)
+}
+
+// kucoinPage is kucoinData for paginated endpoints.
+func kucoinPage(rsp *kucoin.ApiResponse, err error, v interface{}) (*kucoin.PaginationModel, error) {
+	if err := kucoinCheck(rsp, err); err != nil {
+		return nil, err
+	}
+	return rsp.ReadPaginationData(v)
+}
+
+func kucoinCheck(rsp *kucoin.ApiResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if rsp.ApiSuccessful() {
+		return nil
+	}
+
+	return &ExchangeError{
+		Exchange: "kucoin",
+		Code:     rsp.Code,
+		Message:  rsp.Message,
+		Kind:     kucoinErrorKind(rsp.Code),
+	}
+}
 
-	return &KucoinConnector{apiService: apiService}
-}
-
-func (kc *KucoinConnector) TestConnection() error {
-	ctx := context.Background()
-	_, err := kc.apiService.ServerTime(ctx)
-	return err
-}
-
-//func (kc *KucoinConnector) GetOrderBook(symbol string, limit int) (*kucoin.OrderBookModel, error) {
-//	ctx := context.Background()
-//	params := map[string]string{
-//		"symbol": symbol,
-//		"limit":  fmt.Sprintf("%d", limit),
-//	}
-//	rsp, err := kc.apiService.MarketOrderBook(ctx, params)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	var orderBook kucoin.OrderBookModel
-//	if err := rsp.ReadData(&orderBook); err != nil {
-//		return nil, err
-//	}
-//
-//	return &orderBook, nil
-//}
-
-func (kc *KucoinConnector) ExecuteOrder(orderType, symbol string, quantity, price float64) (string, error) {
-	// Implementação para execução de ordens
-	return "", nil
+func kucoinErrorKind(code string) error {
+	switch code {
+	case "400001", "400002", "400003", "400004", "400005", "400006", "400007", "411100":
+		return ErrAuth
+	case "429000":
+		return ErrRateLimited
+	case "200004", "400200":
+		return ErrInsufficientFunds
+	case "900001", "400350":
+		return ErrUnknownSymbol
+	}
+	return nil
 }
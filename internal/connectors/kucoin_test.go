@@ -1 +1,151 @@
 package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kucoin "github.com/Kucoin/kucoin-go-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKucoinConnector_GetFills(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v1/fills", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("KC-API-KEY"))
+		assert.Equal(t, kucoin.ApiKeyVersionV2, r.Header.Get("KC-API-KEY-VERSION"))
+		assert.NotEmpty(t, r.URL.Query().Get("startAt"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"200000","data":{"currentPage":1,"pageSize":500,"totalNum":1,"totalPage":1,"items":[
+			{"symbol":"BTC-USDT","tradeId":"5c35c02709e4f67d5266954e","orderId":"5c35c02703aa673ceec2a168",
+			 "side":"buy","liquidity":"taker","price":"64000.5","size":"0.01","funds":"640.005",
+			 "fee":"0.64","feeCurrency":"USDT","type":"limit","createdAt":1720732560000}
+		]}}`))
+	}))
+	defer server.Close()
+
+	connector := NewKucoinConnector("test-key", "test-secret", "test-passphrase", kucoin.ApiBaseURIOption(server.URL))
+
+	fills, err := connector.GetFills(context.Background(), HistoryRequest{Since: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	if assert.Len(t, fills, 1) {
+		assert.Equal(t, "5c35c02709e4f67d5266954e", fills[0].TradeID)
+		assert.Equal(t, SideBuy, fills[0].Side)
+		assert.Equal(t, 64000.5, fills[0].Price)
+		assert.Equal(t, 0.01, fills[0].Quantity)
+		assert.Equal(t, 0.64, fills[0].Fee)
+		assert.Equal(t, int64(1720732560000), fills[0].Time.UnixMilli())
+		assert.False(t, fills[0].IsMaker)
+	}
+}
+
+func TestKucoinConnector_GetBalances(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/accounts", r.URL.Path)
+		assert.Equal(t, "trade", r.URL.Query().Get("type"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"200000","data":[
+			{"id":"1","currency":"USDT","type":"trade","balance":"150.5","available":"100.5","holds":"50"}
+		]}`))
+	}))
+	defer server.Close()
+
+	connector := NewKucoinConnector("test-key", "test-secret", "test-passphrase", kucoin.ApiBaseURIOption(server.URL))
+
+	balances, err := connector.GetBalances(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Balance{{Asset: "USDT", Free: 100.5, Locked: 50}}, balances)
+}
+
+func TestKucoinConnector_GetKeyPermissions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/user/api-key", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"200000","data":{"remark":"journal","apiKey":"test-key","apiVersion":3,"permission":"General,Spot","createdAt":1720732560000}}`))
+	}))
+	defer server.Close()
+
+	connector := NewKucoinConnector("test-key", "test-secret", "test-passphrase", kucoin.ApiBaseURIOption(server.URL))
+
+	permissions, err := connector.GetKeyPermissions(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &KeyPermissions{Read: true, Trade: true}, permissions)
+}
+
+func TestKucoinConnector_PlaceOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/orders", r.URL.Path)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "BTC-USDT", body["symbol"])
+		assert.Equal(t, "buy", body["side"])
+		assert.Equal(t, "limit", body["type"])
+		assert.Equal(t, "0.01", body["size"])
+		assert.Equal(t, "60000", body["price"])
+		assert.NotEmpty(t, body["clientOid"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"200000","data":{"orderId":"5bd6e9286d99522a52e458de"}}`))
+	}))
+	defer server.Close()
+
+	connector := NewKucoinConnector("test-key", "test-secret", "test-passphrase", kucoin.ApiBaseURIOption(server.URL))
+
+	order, err := connector.PlaceOrder(context.Background(), PlaceOrderRequest{
+		Symbol:   "BTC-USDT",
+		Side:     SideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 0.01,
+		Price:    60000,
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, order) {
+		assert.Equal(t, "5bd6e9286d99522a52e458de", order.ID)
+		assert.NotEmpty(t, order.ClientOrderID)
+		assert.Equal(t, OrderStatusNew, order.Status)
+	}
+}
+
+func TestKucoinConnector_Errors(t *testing.T) {
+	cases := []struct {
+		code string
+		want error
+	}{
+		{"400005", ErrAuth},
+		{"429000", ErrRateLimited},
+		{"200004", ErrInsufficientFunds},
+		{"900001", ErrUnknownSymbol},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.code, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"code":"` + tc.code + `","msg":"rejected"}`))
+			}))
+			defer server.Close()
+
+			connector := NewKucoinConnector("test-key", "test-secret", "test-passphrase", kucoin.ApiBaseURIOption(server.URL))
+
+			_, err := connector.PlaceOrder(context.Background(), PlaceOrderRequest{Symbol: "BTC-USDT", Side: SideSell, Type: OrderTypeMarket, Quantity: 1})
+			assert.ErrorIs(t, err, tc.want)
+
+			var exchangeErr *ExchangeError
+			if assert.ErrorAs(t, err, &exchangeErr) {
+				assert.Equal(t, tc.code, exchangeErr.Code)
+				assert.Equal(t, "rejected", exchangeErr.Message)
+			}
+		})
+	}
+}
@@ -2,27 +2,65 @@ package connectors
 
 import (
 	"context"
-	"log"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	mexcMarketData "github.com/linstohu/nexapi/mexc/spot/marketdata"
 	mexcTypes "github.com/linstohu/nexapi/mexc/spot/marketdata/types"
+	mexcAccount "github.com/linstohu/nexapi/mexc/spot/spotaccount"
+	mexcAccountTypes "github.com/linstohu/nexapi/mexc/spot/spotaccount/types"
 	mexcUtils "github.com/linstohu/nexapi/mexc/spot/utils"
+	mexcAuth "github.com/linstohu/nexapi/mexc/utils"
+)
+
+const (
+	mexcTradesPageSize = 100
+	mexcQuoteAsset     = "USDT"
 )
 
 // MarketDataClient é uma interface para o cliente de dados de mercado
 type MarketDataClient interface {
 	Ping(ctx context.Context) error
 	GetOrderbook(ctx context.Context, params mexcTypes.GetOrderbookParams) (*mexcTypes.Orderbook, error)
+	GetExchangeInfo(ctx context.Context, params mexcTypes.GetExchangeInfoParam) (*mexcTypes.ExchangeInfo, error)
+	GetTickerPriceForSymbol(ctx context.Context, params mexcTypes.GetTickerPriceForSymbolParam) (*mexcTypes.TickerPrice, error)
 }
 
+// AccountClient is the signed account endpoint of MEXC spot.
+type AccountClient interface {
+	GetAccountInfo(ctx context.Context) (*mexcAccountTypes.AccountInfo, error)
+}
+
+var (
+	_ ExchangeConnector   = (*MexcConnector)(nil)
+	_ PermissionsReporter = (*MexcConnector)(nil)
+	_ PriceProvider       = (*MexcConnector)(nil)
+)
+
 type MexcConnector struct {
 	marketDataClient MarketDataClient
+	accountClient    AccountClient
+	// tradeClient signs the order and trade endpoints nexapi does not wrap.
+	tradeClient *mexcUtils.SpotClient
+}
+
+func NewMexcConnector(apiKey, apiSecret string) (*MexcConnector, error) {
+	return newMexcConnector(mexcUtils.BaseURL, apiKey, apiSecret)
 }
 
-func NewMexcConnector(apiKey, apiSecret string) *MexcConnector {
+func newMexcConnector(baseURL, apiKey, apiSecret string) (*MexcConnector, error) {
 	cfg := &mexcUtils.SpotClientCfg{
-		Debug:      true,
-		BaseURL:    mexcUtils.BaseURL,
+		BaseURL:    baseURL,
 		Key:        apiKey,
 		Secret:     apiSecret,
 		RecvWindow: 5000,
@@ -30,26 +68,472 @@ func NewMexcConnector(apiKey, apiSecret string) *MexcConnector {
 
 	marketDataClient, err := mexcMarketData.NewSpotMarketDataClient(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize MEXC market data client: %v", err)
+		return nil, fmt.Errorf("initialize MEXC market data client: %w", err)
+	}
+
+	connector := &MexcConnector{marketDataClient: marketDataClient}
+	if apiKey == "" || apiSecret == "" {
+		return connector, nil
+	}
+
+	accountClient, err := mexcAccount.NewSpotAccountClient(&mexcAccount.SpotAccountClientCfg{
+		BaseURL:    baseURL,
+		Key:        apiKey,
+		Secret:     apiSecret,
+		RecvWindow: cfg.RecvWindow,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initialize MEXC account client: %w", err)
 	}
 
-	return &MexcConnector{marketDataClient: marketDataClient}
+	connector.accountClient = accountClient
+	connector.tradeClient = accountClient.SpotClient
+	return connector, nil
 }
 
-func (mc *MexcConnector) TestConnection() error {
-	return mc.marketDataClient.Ping(context.Background())
+func (mc *MexcConnector) TestConnection(ctx context.Context) error {
+	if err := mc.marketDataClient.Ping(ctx); err != nil {
+		return mexcError(err)
+	}
+	if mc.accountClient == nil {
+		return nil
+	}
+
+	_, err := mc.accountClient.GetAccountInfo(ctx)
+	return mexcError(err)
 }
 
-func (mc *MexcConnector) GetOrderBook(symbol string, limit int) (*mexcTypes.Orderbook, error) {
+func (mc *MexcConnector) GetOrderBook(ctx context.Context, symbol string, limit int) (*mexcTypes.Orderbook, error) {
 	params := mexcTypes.GetOrderbookParams{
 		Symbol: symbol,
 		Limit:  limit,
 	}
 
-	return mc.marketDataClient.GetOrderbook(context.Background(), params)
+	orderbook, err := mc.marketDataClient.GetOrderbook(ctx, params)
+	return orderbook, mexcError(err)
+}
+
+func (mc *MexcConnector) GetPrice(ctx context.Context, symbol string) (float64, error) {
+	ticker, err := mc.marketDataClient.GetTickerPriceForSymbol(ctx, mexcTypes.GetTickerPriceForSymbolParam{Symbol: marketSymbol(symbol)})
+	if err != nil {
+		return 0, mexcError(err)
+	}
+
+	price := parseFloat(ticker.Price)
+	if price <= 0 {
+		return 0, fmt.Errorf("MEXC returned no price for %s", symbol)
+	}
+	return price, nil
+}
+
+func (mc *MexcConnector) GetBalances(ctx context.Context) ([]Balance, error) {
+	if mc.accountClient == nil {
+		return nil, errMexcNoCredentials
+	}
+
+	info, err := mc.accountClient.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, mexcError(err)
+	}
+
+	balances := make([]Balance, 0, len(info.Balances))
+	for _, b := range info.Balances {
+		balances = append(balances, Balance{
+			Asset:  b.Asset,
+			Free:   parseFloat(b.Free),
+			Locked: parseFloat(b.Locked),
+		})
+	}
+	return balances, nil
+}
+
+// GetKeyPermissions reports what MEXC allows on the account the key belongs
+// to; MEXC does not expose per-key restrictions.
+func (mc *MexcConnector) GetKeyPermissions(ctx context.Context) (*KeyPermissions, error) {
+	if mc.accountClient == nil {
+		return nil, errMexcNoCredentials
+	}
+
+	info, err := mc.accountClient.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, mexcError(err)
+	}
+
+	return &KeyPermissions{
+		Read:     true,
+		Trade:    info.CanTrade,
+		Withdraw: info.CanWithdraw,
+	}, nil
+}
+
+// GetOpenOrders needs req.Symbol: MEXC only lists open orders per market.
+func (mc *MexcConnector) GetOpenOrders(ctx context.Context, req OpenOrdersRequest) ([]Order, error) {
+	if req.Symbol == "" {
+		return nil, errMexcOpenOrdersNeedSymbol
+	}
+
+	var items []mexcOrder
+	params := &mexcSymbolParams{Symbol: req.Symbol}
+	if err := mc.signedRequest(ctx, http.MethodGet, "/api/v3/openOrders", params, &items); err != nil {
+		return nil, err
+	}
+
+	orders := make([]Order, 0, len(items))
+	for _, item := range items {
+		orders = append(orders, item.order())
+	}
+	return orders, nil
 }
 
-func (mc *MexcConnector) ExecuteOrder(orderType, symbol string, quantity, price float64) (string, error) {
-	// Placeholder para implementação da execução de ordens
-	return "", nil
+func (mc *MexcConnector) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := &mexcPlaceOrderParams{
+		Symbol:           req.Symbol,
+		Side:             strings.ToUpper(req.Side),
+		Type:             strings.ToUpper(req.Type),
+		Quantity:         formatFloat(req.Quantity),
+		NewClientOrderID: req.ClientOrderID,
+	}
+	if req.Type == OrderTypeLimit {
+		params.Price = formatFloat(req.Price)
+	}
+
+	var placed mexcOrder
+	if err := mc.signedRequest(ctx, http.MethodPost, "/api/v3/order", params, &placed); err != nil {
+		return nil, err
+	}
+
+	order := placed.order()
+	order.Status = OrderStatusNew
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = req.ClientOrderID
+	}
+	return &order, nil
+}
+
+func (mc *MexcConnector) CancelOrder(ctx context.Context, req CancelOrderRequest) error {
+	if req.Symbol == "" {
+		return fmt.Errorf("%w: symbol is required", ErrInvalidOrder)
+	}
+	if req.OrderID == "" && req.ClientOrderID == "" {
+		return fmt.Errorf("%w: order id or client order id is required", ErrInvalidOrder)
+	}
+
+	params := &mexcCancelOrderParams{Symbol: req.Symbol, OrderID: req.OrderID}
+	if req.OrderID == "" {
+		params.OrigClientOrderID = req.ClientOrderID
+	}
+
+	var canceled mexcOrder
+	return mc.signedRequest(ctx, http.MethodDelete, "/api/v3/order", params, &canceled)
+}
+
+// GetPositions is not supported: this connector only covers MEXC spot.
+func (mc *MexcConnector) GetPositions(ctx context.Context) ([]Position, error) {
+	return nil, ErrNotSupported
+}
+
+func (mc *MexcConnector) GetSymbols(ctx context.Context) ([]SymbolInfo, error) {
+	info, err := mc.marketDataClient.GetExchangeInfo(ctx, mexcTypes.GetExchangeInfoParam{})
+	if err != nil {
+		return nil, mexcError(err)
+	}
+
+	symbols := make([]SymbolInfo, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		symbol := SymbolInfo{
+			Symbol:      s.Symbol,
+			BaseAsset:   s.BaseAsset,
+			QuoteAsset:  s.QuoteAsset,
+			TickSize:    math.Pow10(-s.QuotePrecision),
+			StepSize:    parseFloat(s.BaseSizePrecision),
+			MinNotional: parseFloat(s.QuoteAmountPrecision),
+			Trading:     s.IsSpotTradingAllowed && (s.Status == "1" || s.Status == "ENABLED"),
+		}
+		for _, f := range s.Filters {
+			if f.FilterType == "LOT_SIZE" {
+				symbol.MinQuantity = parseFloat(f.MinQty)
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// GetFills pages through /api/v3/myTrades. MEXC only lists trades per
+// symbol, so without req.Symbols the markets are the assets currently held,
+// quoted in USDT, plus req.KnownSymbols.
+func (mc *MexcConnector) GetFills(ctx context.Context, req HistoryRequest) ([]Fill, error) {
+	symbols := req.Symbols
+	derived := len(symbols) == 0
+	if derived {
+		balances, err := mc.GetBalances(ctx)
+		if err != nil {
+			return nil, err
+		}
+		symbols = historySymbols(balances, mexcQuoteAsset, req.KnownSymbols)
+	}
+
+	since := req.Since
+	if since.IsZero() {
+		since = time.Now().Add(-defaultHistoryLookback)
+	}
+
+	var fills []Fill
+symbols:
+	for _, symbol := range symbols {
+		seen := make(map[string]bool)
+		for cursor := since; ; {
+			var items []mexcTrade
+			params := &mexcTradesParams{
+				Symbol:    symbol,
+				StartTime: cursor.UnixMilli(),
+				Limit:     mexcTradesPageSize,
+			}
+			if err := mc.signedRequest(ctx, http.MethodGet, "/api/v3/myTrades", params, &items); err != nil {
+				// A journal symbol may not be a MEXC spot market.
+				if derived && errors.Is(err, ErrUnknownSymbol) {
+					continue symbols
+				}
+				return nil, err
+			}
+
+			next := cursor
+			for _, item := range items {
+				fill := item.fill()
+				if seen[fill.TradeID] {
+					continue
+				}
+				seen[fill.TradeID] = true
+				fills = append(fills, fill)
+				if req.Limit > 0 && len(fills) >= req.Limit {
+					return fills, nil
+				}
+				if fill.Time.After(next) {
+					next = fill.Time
+				}
+			}
+
+			if len(items) < mexcTradesPageSize {
+				break
+			}
+			// A full page sharing a single millisecond would otherwise be
+			// requested again forever.
+			if !next.After(cursor) {
+				next = cursor.Add(time.Millisecond)
+			}
+			cursor = next
+		}
+	}
+
+	return fills, nil
+}
+
+var (
+	errMexcNoCredentials        = &ExchangeError{Exchange: "mexc", Message: "API credentials are required", Kind: ErrAuth}
+	errMexcOpenOrdersNeedSymbol = &ExchangeError{Exchange: "mexc", Message: "open orders can only be listed per symbol", Kind: ErrNotSupported}
+)
+
+// mexcSignable is implemented by the request parameters of signed endpoints.
+type mexcSignable interface {
+	defaults() *mexcAuth.DefaultParam
+}
+
+type mexcSigned struct {
+	mexcAuth.DefaultParam
+}
+
+func (p *mexcSigned) defaults() *mexcAuth.DefaultParam {
+	return &p.DefaultParam
+}
+
+type mexcSymbolParams struct {
+	Symbol string `url:"symbol"`
+	mexcSigned
+}
+
+type mexcPlaceOrderParams struct {
+	Symbol           string `url:"symbol"`
+	Side             string `url:"side"`
+	Type             string `url:"type"`
+	Quantity         string `url:"quantity,omitempty"`
+	Price            string `url:"price,omitempty"`
+	NewClientOrderID string `url:"newClientOrderId,omitempty"`
+	mexcSigned
+}
+
+type mexcCancelOrderParams struct {
+	Symbol            string `url:"symbol"`
+	OrderID           string `url:"orderId,omitempty"`
+	OrigClientOrderID string `url:"origClientOrderId,omitempty"`
+	mexcSigned
+}
+
+type mexcTradesParams struct {
+	Symbol    string `url:"symbol"`
+	StartTime int64  `url:"startTime,omitempty"`
+	Limit     int    `url:"limit,omitempty"`
+	mexcSigned
+}
+
+// signedRequest signs params the same way nexapi signs its own account
+// calls and decodes the JSON response into out.
+func (mc *MexcConnector) signedRequest(ctx context.Context, method, path string, params mexcSignable, out interface{}) error {
+	if mc.tradeClient == nil {
+		return errMexcNoCredentials
+	}
+
+	req := mexcUtils.HTTPRequest{
+		BaseURL: mc.tradeClient.GetBaseURL(),
+		Path:    path,
+		Method:  method,
+	}
+
+	headers, err := mc.tradeClient.GenAuthHeaders(req)
+	if err != nil {
+		return err
+	}
+	req.Headers = headers
+
+	defaults := params.defaults()
+	defaults.RecvWindow = mc.tradeClient.GetRecvWindow()
+	defaults.Timestamp = time.Now().UnixMilli()
+	defaults.Signature = ""
+
+	signString, err := mexcAuth.NormalizeRequestContent(params, nil)
+	if err != nil {
+		return err
+	}
+	h := hmac.New(sha256.New, []byte(mc.tradeClient.GetSecret()))
+	h.Write([]byte(signString))
+	defaults.Signature = hex.EncodeToString(h.Sum(nil))
+	req.Query = params
+
+	resp, err := mc.tradeClient.SendHTTPRequest(ctx, req)
+	if err != nil {
+		return mexcError(err)
+	}
+	return json.Unmarshal(resp, out)
+}
+
+// mexcOrder covers the order shapes returned by the order endpoints.
+type mexcOrder struct {
+	Symbol        string     `json:"symbol"`
+	OrderID       flexString `json:"orderId"`
+	ClientOrderID string     `json:"clientOrderId"`
+	Price         string     `json:"price"`
+	OrigQty       string     `json:"origQty"`
+	ExecutedQty   string     `json:"executedQty"`
+	Status        string     `json:"status"`
+	Type          string     `json:"type"`
+	Side          string     `json:"side"`
+	Time          int64      `json:"time"`
+	TransactTime  int64      `json:"transactTime"`
+}
+
+func (o mexcOrder) order() Order {
+	createdAt := o.Time
+	if createdAt == 0 {
+		createdAt = o.TransactTime
+	}
+
+	return Order{
+		ID:             string(o.OrderID),
+		ClientOrderID:  o.ClientOrderID,
+		Symbol:         o.Symbol,
+		Side:           strings.ToLower(o.Side),
+		Type:           strings.ToLower(o.Type),
+		Status:         normalizeOrderStatus(o.Status),
+		Price:          parseFloat(o.Price),
+		Quantity:       parseFloat(o.OrigQty),
+		FilledQuantity: parseFloat(o.ExecutedQty),
+		CreatedAt:      time.UnixMilli(createdAt).UTC(),
+	}
+}
+
+type mexcTrade struct {
+	Symbol          string     `json:"symbol"`
+	ID              flexString `json:"id"`
+	OrderID         flexString `json:"orderId"`
+	Price           string     `json:"price"`
+	Qty             string     `json:"qty"`
+	Commission      string     `json:"commission"`
+	CommissionAsset string     `json:"commissionAsset"`
+	Time            int64      `json:"time"`
+	IsBuyer         bool       `json:"isBuyer"`
+	IsMaker         bool       `json:"isMaker"`
+}
+
+func (t mexcTrade) fill() Fill {
+	side := SideSell
+	if t.IsBuyer {
+		side = SideBuy
+	}
+
+	return Fill{
+		TradeID:      string(t.ID),
+		OrderID:      string(t.OrderID),
+		Symbol:       t.Symbol,
+		Side:         side,
+		ContractType: "spot",
+		Price:        parseFloat(t.Price),
+		Quantity:     parseFloat(t.Qty),
+		Fee:          parseFloat(t.Commission),
+		FeeAsset:     t.CommissionAsset,
+		IsMaker:      t.IsMaker,
+		Time:         time.UnixMilli(t.Time).UTC(),
+	}
+}
+
+// nexapi reports MEXC failures as formatted errors only; the status code and
+// the JSON error body are recovered from the message.
+var mexcHTTPError = regexp.MustCompile(`(?s)^API returned a non-200 status code: \[(\d+)\] - \[(.*)\]$`)
+
+// mexcError maps an error returned by nexapi onto the normalized errors.
+func mexcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	m := mexcHTTPError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+
+	status, _ := strconv.Atoi(m[1])
+	var body struct {
+		Code flexString `json:"code"`
+		Msg  string     `json:"msg"`
+	}
+	_ = json.Unmarshal([]byte(m[2]), &body)
+
+	kind := mexcErrorKind(string(body.Code))
+	if kind == nil {
+		kind = errorKindForStatus(status)
+	}
+
+	return &ExchangeError{
+		Exchange:   "mexc",
+		StatusCode: status,
+		Code:       string(body.Code),
+		Message:    body.Msg,
+		Kind:       kind,
+	}
+}
+
+func mexcErrorKind(code string) error {
+	switch code {
+	case "10072", "700001", "700002", "700003", "700006", "700007":
+		return ErrAuth
+	case "429", "510":
+		return ErrRateLimited
+	case "10101", "30004", "30005":
+		return ErrInsufficientFunds
+	case "-1121", "10007", "30014":
+		return ErrUnknownSymbol
+	}
+	return nil
 }
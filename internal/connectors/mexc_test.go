@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	mexcTypes "github.com/linstohu/nexapi/mexc/spot/marketdata/types"
+	mexcAccountTypes "github.com/linstohu/nexapi/mexc/spot/spotaccount/types"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -28,24 +32,58 @@ func (m *MockSpotMarketDataClient) GetOrderbook(ctx context.Context, params mexc
 	return nil, args.Error(1)
 }
 
+func (m *MockSpotMarketDataClient) GetExchangeInfo(ctx context.Context, params mexcTypes.GetExchangeInfoParam) (*mexcTypes.ExchangeInfo, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) != nil {
+		return args.Get(0).(*mexcTypes.ExchangeInfo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockSpotMarketDataClient) GetTickerPriceForSymbol(ctx context.Context, params mexcTypes.GetTickerPriceForSymbolParam) (*mexcTypes.TickerPrice, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) != nil {
+		return args.Get(0).(*mexcTypes.TickerPrice), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// MockSpotAccountClient é um mock para SpotAccountClient
+type MockSpotAccountClient struct {
+	mock.Mock
+}
+
+func (m *MockSpotAccountClient) GetAccountInfo(ctx context.Context) (*mexcAccountTypes.AccountInfo, error) {
+	args := m.Called(ctx)
+	if args.Get(0) != nil {
+		return args.Get(0).(*mexcAccountTypes.AccountInfo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestNewMexcConnector(t *testing.T) {
 	apiKey := "test-key"
 	apiSecret := "test-secret"
 
-	connector := NewMexcConnector(apiKey, apiSecret)
+	connector, err := NewMexcConnector(apiKey, apiSecret)
+	assert.NoError(t, err)
 	assert.NotNil(t, connector)
 	assert.NotNil(t, connector.marketDataClient)
+	assert.NotNil(t, connector.accountClient)
 }
 
 func TestMexcConnector_TestConnection(t *testing.T) {
 	mockClient := new(MockSpotMarketDataClient)
 	mockClient.On("Ping", mock.Anything).Return(nil)
+	mockAccount := new(MockSpotAccountClient)
+	mockAccount.On("GetAccountInfo", mock.Anything).Return(&mexcAccountTypes.AccountInfo{CanTrade: true}, nil)
 
-	connector := &MexcConnector{marketDataClient: mockClient}
+	connector := &MexcConnector{marketDataClient: mockClient, accountClient: mockAccount}
 
-	err := connector.TestConnection()
+	err := connector.TestConnection(context.Background())
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
+	mockAccount.AssertExpectations(t)
 }
 
 func TestMexcConnector_TestConnection_Fail(t *testing.T) {
@@ -54,12 +92,44 @@ func TestMexcConnector_TestConnection_Fail(t *testing.T) {
 
 	connector := &MexcConnector{marketDataClient: mockClient}
 
-	err := connector.TestConnection()
+	err := connector.TestConnection(context.Background())
 	assert.Error(t, err)
 	assert.Equal(t, "connection error", err.Error())
 	mockClient.AssertExpectations(t)
 }
 
+func TestMexcConnector_TestConnection_BadCredentials(t *testing.T) {
+	mockClient := new(MockSpotMarketDataClient)
+	mockClient.On("Ping", mock.Anything).Return(nil)
+	mockAccount := new(MockSpotAccountClient)
+	mockAccount.On("GetAccountInfo", mock.Anything).
+		Return(nil, errors.New(`API returned a non-200 status code: [400] - [{"code":700002,"msg":"Signature for this request is not valid."}]`))
+
+	connector := &MexcConnector{marketDataClient: mockClient, accountClient: mockAccount}
+
+	err := connector.TestConnection(context.Background())
+	assert.ErrorIs(t, err, ErrAuth)
+
+	var exchangeErr *ExchangeError
+	if assert.ErrorAs(t, err, &exchangeErr) {
+		assert.Equal(t, "700002", exchangeErr.Code)
+		assert.Equal(t, 400, exchangeErr.StatusCode)
+	}
+}
+
+func TestMexcConnector_GetPrice(t *testing.T) {
+	mockClient := new(MockSpotMarketDataClient)
+	mockClient.On("GetTickerPriceForSymbol", mock.Anything, mexcTypes.GetTickerPriceForSymbolParam{Symbol: "BTCUSDT"}).
+		Return(&mexcTypes.TickerPrice{Symbol: "BTCUSDT", Price: "64250.5"}, nil)
+
+	connector := &MexcConnector{marketDataClient: mockClient}
+
+	price, err := connector.GetPrice(context.Background(), "btc/usdt")
+	assert.NoError(t, err)
+	assert.Equal(t, 64250.5, price)
+	mockClient.AssertExpectations(t)
+}
+
 func TestMexcConnector_GetOrderBook(t *testing.T) {
 	mockClient := new(MockSpotMarketDataClient)
 	orderbook := &mexcTypes.Orderbook{
@@ -69,7 +139,7 @@ func TestMexcConnector_GetOrderBook(t *testing.T) {
 
 	connector := &MexcConnector{marketDataClient: mockClient}
 
-	result, err := connector.GetOrderBook("BTCUSDT", 5)
+	result, err := connector.GetOrderBook(context.Background(), "BTCUSDT", 5)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, int64(12345), result.LastUpdateID)
@@ -82,16 +152,125 @@ func TestMexcConnector_GetOrderBook_Fail(t *testing.T) {
 
 	connector := &MexcConnector{marketDataClient: mockClient}
 
-	result, err := connector.GetOrderBook("BTCUSDT", 5)
+	result, err := connector.GetOrderBook(context.Background(), "BTCUSDT", 5)
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, "orderbook error", err.Error())
 	mockClient.AssertExpectations(t)
 }
 
-func TestMexcConnector_ExecuteOrder(t *testing.T) {
-	connector := &MexcConnector{}
-	orderID, err := connector.ExecuteOrder("LIMIT", "BTCUSDT", 1.0, 50000.0)
+func TestMexcConnector_GetBalances(t *testing.T) {
+	info := &mexcAccountTypes.AccountInfo{}
+	info.Balances = append(info.Balances, struct {
+		Asset  string `json:"asset"`
+		Free   string `json:"free"`
+		Locked string `json:"locked"`
+	}{Asset: "BTC", Free: "0.5", Locked: "0.25"})
+
+	mockAccount := new(MockSpotAccountClient)
+	mockAccount.On("GetAccountInfo", mock.Anything).Return(info, nil)
+
+	connector := &MexcConnector{accountClient: mockAccount}
+
+	balances, err := connector.GetBalances(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Balance{{Asset: "BTC", Free: 0.5, Locked: 0.25}}, balances)
+	assert.Equal(t, 0.75, balances[0].Total())
+}
+
+func TestMexcConnector_PlaceOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v3/order", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("X-MEXC-APIKEY"))
+
+		query := r.URL.Query()
+		assert.Equal(t, "BTCUSDT", query.Get("symbol"))
+		assert.Equal(t, "BUY", query.Get("side"))
+		assert.Equal(t, "LIMIT", query.Get("type"))
+		assert.Equal(t, "1", query.Get("quantity"))
+		assert.Equal(t, "50000", query.Get("price"))
+		assert.NotEmpty(t, query.Get("timestamp"))
+		assert.Len(t, query.Get("signature"), 64)
+
+		w.Write([]byte(`{"symbol":"BTCUSDT","orderId":"C02__4431","orderListId":-1,"price":"50000","origQty":"1","type":"LIMIT","side":"BUY","transactTime":1720732560000}`))
+	}))
+	defer server.Close()
+
+	connector, err := newMexcConnector(server.URL, "test-key", "test-secret")
+	assert.NoError(t, err)
+
+	order, err := connector.PlaceOrder(context.Background(), PlaceOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     SideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 1.0,
+		Price:    50000.0,
+	})
 	assert.NoError(t, err)
-	assert.Equal(t, "", orderID) // Placeholder response
+	if assert.NotNil(t, order) {
+		assert.Equal(t, "C02__4431", order.ID)
+		assert.Equal(t, SideBuy, order.Side)
+		assert.Equal(t, OrderTypeLimit, order.Type)
+		assert.Equal(t, OrderStatusNew, order.Status)
+		assert.Equal(t, int64(1720732560000), order.CreatedAt.UnixMilli())
+	}
+}
+
+func TestMexcConnector_PlaceOrder_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":30004,"msg":"Insufficient position"}`))
+	}))
+	defer server.Close()
+
+	connector, err := newMexcConnector(server.URL, "test-key", "test-secret")
+	assert.NoError(t, err)
+
+	_, err = connector.PlaceOrder(context.Background(), PlaceOrderRequest{Symbol: "BTCUSDT", Side: SideSell, Type: OrderTypeMarket, Quantity: 1})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = connector.PlaceOrder(context.Background(), PlaceOrderRequest{Symbol: "BTCUSDT", Side: SideBuy, Type: OrderTypeLimit, Quantity: 1})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+}
+
+func TestMexcConnector_GetFills(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v3/myTrades", r.URL.Path)
+		assert.Equal(t, "ETHUSDT", r.URL.Query().Get("symbol"))
+
+		w.Write([]byte(`[{"symbol":"ETHUSDT","id":"fb56d1","orderId":"C02__77","price":"3000.5","qty":"0.2","quoteQty":"600.1",
+			"commission":"0.6","commissionAsset":"USDT","time":1720732560000,"isBuyer":false,"isMaker":true}]`))
+	}))
+	defer server.Close()
+
+	connector, err := newMexcConnector(server.URL, "test-key", "test-secret")
+	assert.NoError(t, err)
+
+	fills, err := connector.GetFills(context.Background(), HistoryRequest{Since: time.Now().Add(-time.Hour), Symbols: []string{"ETHUSDT"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	if assert.Len(t, fills, 1) {
+		assert.Equal(t, "fb56d1", fills[0].TradeID)
+		assert.Equal(t, SideSell, fills[0].Side)
+		assert.Equal(t, 3000.5, fills[0].Price)
+		assert.Equal(t, 0.6, fills[0].Fee)
+		assert.True(t, fills[0].IsMaker)
+	}
+}
+
+func TestMexcConnector_WithoutCredentials(t *testing.T) {
+	connector, err := NewMexcConnector("", "")
+	assert.NoError(t, err)
+
+	_, err = connector.GetBalances(context.Background())
+	assert.ErrorIs(t, err, ErrAuth)
+
+	_, err = connector.GetPositions(context.Background())
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = connector.GetOpenOrders(context.Background(), OpenOrdersRequest{})
+	assert.ErrorIs(t, err, ErrNotSupported)
 }
@@ -0,0 +1,18 @@
+package connectors
+
+import (
+	"context"
+	"strings"
+)
+
+// PriceProvider is implemented by connectors that can quote the last traded
+// price of a symbol from public market data, without credentials.
+type PriceProvider interface {
+	GetPrice(ctx context.Context, symbol string) (float64, error)
+}
+
+// marketSymbol turns the way symbols are written in a journal ("btc/usdt",
+// "BTC-USDT") into the concatenated form Binance style APIs expect.
+func marketSymbol(symbol string) string {
+	return strings.ToUpper(strings.NewReplacer("/", "", "-", "", "_", "", " ", "").Replace(symbol))
+}
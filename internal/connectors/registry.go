@@ -0,0 +1,95 @@
+package connectors
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+)
+
+var ErrUnsupportedExchange = errors.New("no connector is registered for this exchange")
+
+// Factory builds a connector from decrypted credentials.
+type Factory func(creds credentials.ExchangeCredentials) (ExchangeConnector, error)
+
+type registration struct {
+	factory      Factory
+	capabilities model.ExchangeCapabilities
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registration{}
+)
+
+func init() {
+	Register("binance", model.ExchangeCapabilities{Spot: true, OrderPlacement: true, HistorySync: true},
+		func(creds credentials.ExchangeCredentials) (ExchangeConnector, error) {
+			return NewBinanceConnector(creds.APIKey, creds.APISecret)
+		})
+	Register("kucoin", model.ExchangeCapabilities{Spot: true, OrderPlacement: true, HistorySync: true},
+		func(creds credentials.ExchangeCredentials) (ExchangeConnector, error) {
+			return NewKucoinConnector(creds.APIKey, creds.APISecret, creds.APIPassphrase), nil
+		})
+	Register("mexc", model.ExchangeCapabilities{Spot: true, OrderPlacement: true, HistorySync: true},
+		func(creds credentials.ExchangeCredentials) (ExchangeConnector, error) {
+			return NewMexcConnector(creds.APIKey, creds.APISecret)
+		})
+}
+
+// Register adds or replaces the connector for an exchange identifier (see
+// Identifier). Tests use it to swap in fakes.
+func Register(id string, capabilities model.ExchangeCapabilities, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[Identifier(id)] = registration{factory: factory, capabilities: capabilities}
+}
+
+// Unregister removes the connector for an exchange identifier.
+func Unregister(id string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registry, Identifier(id))
+}
+
+// Identifier maps an exchange name onto its registry key: lower case with
+// everything but letters and digits dropped, so "KuCoin", "kucoin" and
+// "Ku Coin" all resolve to the same connector.
+func Identifier(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func lookup(exchange model.Exchange) (registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	reg, ok := registry[Identifier(exchange.Name)]
+	return reg, ok
+}
+
+// CapabilitiesFor reports what the connector for exchange supports; ok is
+// false when no connector is registered for it.
+func CapabilitiesFor(exchange model.Exchange) (model.ExchangeCapabilities, bool) {
+	reg, ok := lookup(exchange)
+	return reg.capabilities, ok
+}
+
+// New builds the connector for exchange from decrypted credentials.
+func New(exchange model.Exchange, creds credentials.ExchangeCredentials) (ExchangeConnector, error) {
+	reg, ok := lookup(exchange)
+	if !ok {
+		return nil, ErrUnsupportedExchange
+	}
+	return reg.factory(creds)
+}
@@ -0,0 +1,55 @@
+package connectors
+
+import (
+	"testing"
+
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifier(t *testing.T) {
+	assert.Equal(t, "kucoin", Identifier("KuCoin"))
+	assert.Equal(t, "kucoin", Identifier(" Ku Coin "))
+	assert.Equal(t, "gateio", Identifier("Gate.io"))
+}
+
+func TestRegistry_BuiltIns(t *testing.T) {
+	for _, name := range []string{"Binance", "KuCoin", "MEXC"} {
+		capabilities, ok := CapabilitiesFor(model.Exchange{Name: name})
+		assert.True(t, ok, name)
+		assert.True(t, capabilities.Spot, name)
+		assert.True(t, capabilities.HistorySync, name)
+		assert.False(t, capabilities.Futures, name)
+
+		connector, err := New(model.Exchange{Name: name}, credentials.ExchangeCredentials{APIKey: "key", APISecret: "secret", APIPassphrase: "pass"})
+		assert.NoError(t, err, name)
+		assert.NotNil(t, connector, name)
+	}
+
+	_, ok := CapabilitiesFor(model.Exchange{Name: "Bitstamp"})
+	assert.False(t, ok)
+
+	_, err := New(model.Exchange{Name: "Bitstamp"}, credentials.ExchangeCredentials{})
+	assert.ErrorIs(t, err, ErrUnsupportedExchange)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	var received credentials.ExchangeCredentials
+	Register("Test Exchange", model.ExchangeCapabilities{Futures: true}, func(creds credentials.ExchangeCredentials) (ExchangeConnector, error) {
+		received = creds
+		return &KucoinConnector{}, nil
+	})
+	t.Cleanup(func() {
+		Unregister("testexchange")
+	})
+
+	capabilities, ok := CapabilitiesFor(model.Exchange{Name: "TEST-EXCHANGE"})
+	assert.True(t, ok)
+	assert.Equal(t, model.ExchangeCapabilities{Futures: true}, capabilities)
+
+	_, err := New(model.Exchange{Name: "test exchange"}, credentials.ExchangeCredentials{APIKey: "k"})
+	assert.NoError(t, err)
+	assert.Equal(t, "k", received.APIKey)
+}
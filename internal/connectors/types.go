@@ -0,0 +1,212 @@
+package connectors
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OrderTypeLimit  = "limit"
+	OrderTypeMarket = "market"
+)
+
+const (
+	OrderStatusNew             = "new"
+	OrderStatusPartiallyFilled = "partially_filled"
+	OrderStatusFilled          = "filled"
+	OrderStatusCanceled        = "canceled"
+	OrderStatusRejected        = "rejected"
+)
+
+// Balance is the holding of one asset. Total is Free plus Locked.
+type Balance struct {
+	Asset  string
+	Free   float64
+	Locked float64
+}
+
+func (b Balance) Total() float64 {
+	return b.Free + b.Locked
+}
+
+// Order is an order as reported by the exchange. Symbols are always in the
+// exchange's own notation (BTCUSDT, BTC-USDT, ...).
+type Order struct {
+	ID             string
+	ClientOrderID  string
+	Symbol         string
+	Side           string // SideBuy or SideSell
+	Type           string // OrderTypeLimit or OrderTypeMarket
+	Status         string
+	Price          float64
+	Quantity       float64
+	FilledQuantity float64
+	CreatedAt      time.Time
+}
+
+// OpenOrdersRequest filters open orders by symbol. Some exchanges require it.
+type OpenOrdersRequest struct {
+	Symbol string
+}
+
+// PlaceOrderRequest describes a new spot order. Price is ignored for market
+// orders. ClientOrderID is optional; connectors generate one when the
+// exchange requires it.
+type PlaceOrderRequest struct {
+	Symbol        string
+	Side          string
+	Type          string
+	Quantity      float64
+	Price         float64
+	ClientOrderID string
+}
+
+// Validate checks the request before it is sent, so obviously broken orders
+// never reach the exchange.
+func (r PlaceOrderRequest) Validate() error {
+	if r.Symbol == "" {
+		return fmt.Errorf("%w: symbol is required", ErrInvalidOrder)
+	}
+	if r.Side != SideBuy && r.Side != SideSell {
+		return fmt.Errorf("%w: side must be %q or %q", ErrInvalidOrder, SideBuy, SideSell)
+	}
+	if r.Quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalidOrder)
+	}
+	switch r.Type {
+	case OrderTypeLimit:
+		if r.Price <= 0 {
+			return fmt.Errorf("%w: limit orders need a positive price", ErrInvalidOrder)
+		}
+	case OrderTypeMarket:
+	default:
+		return fmt.Errorf("%w: unsupported order type %q", ErrInvalidOrder, r.Type)
+	}
+	return nil
+}
+
+// CancelOrderRequest identifies the order to cancel by exchange ID or, if
+// OrderID is empty, by client order ID.
+type CancelOrderRequest struct {
+	Symbol        string
+	OrderID       string
+	ClientOrderID string
+}
+
+// Position is an open derivatives position.
+type Position struct {
+	Symbol        string
+	IsLong        bool
+	MarginMode    string
+	Leverage      float64
+	Quantity      float64
+	EntryPrice    float64
+	MarkPrice     float64
+	UnrealizedPnL float64
+}
+
+// SymbolInfo is the trading metadata of one market.
+type SymbolInfo struct {
+	Symbol      string
+	BaseAsset   string
+	QuoteAsset  string
+	TickSize    float64
+	StepSize    float64
+	MinQuantity float64
+	MinNotional float64
+	Trading     bool
+}
+
+// newClientOrderID returns a random client order ID that fits the length
+// limits of every supported exchange.
+func newClientOrderID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// parseFloat reads the decimal strings exchanges use for amounts. Empty or
+// malformed values read as zero.
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// normalizeOrderStatus maps the upper-case order statuses shared by Binance
+// style APIs (NEW, PARTIALLY_FILLED, ...) onto ours.
+func normalizeOrderStatus(status string) string {
+	switch strings.ToUpper(status) {
+	case "NEW":
+		return OrderStatusNew
+	case "PARTIALLY_FILLED":
+		return OrderStatusPartiallyFilled
+	case "FILLED":
+		return OrderStatusFilled
+	case "CANCELED", "PARTIALLY_CANCELED", "EXPIRED":
+		return OrderStatusCanceled
+	case "REJECTED":
+		return OrderStatusRejected
+	}
+	return strings.ToLower(status)
+}
+
+// flexString decodes identifiers that an exchange sends as either JSON
+// strings or numbers.
+type flexString string
+
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = flexString(str)
+		return nil
+	}
+	if string(data) == "null" {
+		*s = ""
+		return nil
+	}
+	*s = flexString(data)
+	return nil
+}
+
+// historySymbols lists the markets to query on exchanges that only report
+// history per symbol: the quote markets of the assets in balances, then the
+// known symbols in the exchange's BASEQUOTE form, without duplicates.
+func historySymbols(balances []Balance, quote string, known []string) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	add := func(symbol string) {
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	for _, b := range balances {
+		if b.Asset != quote && b.Total() > 0 {
+			add(b.Asset + quote)
+		}
+	}
+	for _, symbol := range known {
+		add(strings.Map(func(r rune) rune {
+			if r == '-' || r == '/' || r == '_' || r == ' ' {
+				return -1
+			}
+			return r
+		}, strings.ToUpper(strings.TrimSpace(symbol))))
+	}
+	return symbols
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 	"vsC1Y2025V01/src/db"
 	"vsC1Y2025V01/src/server"
+	"vsC1Y2025V01/src/userexchanges"
 )
 
 var (
@@ -35,6 +36,9 @@ func init() {
 func main() {
 	initLog()
 	db.InitDB(log) // ✅ MUST be here before any DB access
+	if err := userexchanges.MigrateCredentials(log); err != nil {
+		log.WithError(err).Error("Failed to migrate user exchange credentials")
+	}
 	defer handlePanic()
 
 	server.StartServer(PORT, log)
@@ -0,0 +1,77 @@
+// Package blobstore keeps opaque files under string keys, either on the
+// local filesystem or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store saves, reads and removes blobs. Keys use "/" as separator and must
+// not contain "." or ".." segments.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the blob content; the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// LoadFromEnv builds the store selected by:
+//
+//	BLOB_STORE             "local" (default) or "s3"
+//	BLOB_STORE_DIR         root directory of the local store, defaults to ./data/blobs
+//	BLOB_S3_ENDPOINT       base URL such as https://s3.eu-west-1.amazonaws.com or http://minio:9000
+//	BLOB_S3_REGION         signing region, defaults to us-east-1
+//	BLOB_S3_BUCKET         bucket name
+//	BLOB_S3_ACCESS_KEY     access key ID
+//	BLOB_S3_SECRET_KEY     secret access key
+//	BLOB_S3_PATH_STYLE     "true" to address the bucket as a path instead of a subdomain
+func LoadFromEnv() (Store, error) {
+	switch kind := strings.ToLower(strings.TrimSpace(os.Getenv("BLOB_STORE"))); kind {
+	case "", "local":
+		dir := strings.TrimSpace(os.Getenv("BLOB_STORE_DIR"))
+		if dir == "" {
+			dir = "./data/blobs"
+		}
+		return NewFilesystem(dir)
+	case "s3":
+		pathStyle := false
+		if v := strings.TrimSpace(os.Getenv("BLOB_S3_PATH_STYLE")); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid BLOB_S3_PATH_STYLE: %w", err)
+			}
+			pathStyle = parsed
+		}
+		return NewS3(S3Config{
+			Endpoint:  strings.TrimSpace(os.Getenv("BLOB_S3_ENDPOINT")),
+			Region:    strings.TrimSpace(os.Getenv("BLOB_S3_REGION")),
+			Bucket:    strings.TrimSpace(os.Getenv("BLOB_S3_BUCKET")),
+			AccessKey: strings.TrimSpace(os.Getenv("BLOB_S3_ACCESS_KEY")),
+			SecretKey: strings.TrimSpace(os.Getenv("BLOB_S3_SECRET_KEY")),
+			PathStyle: pathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", kind)
+	}
+}
+
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsRune(segment, '\\') {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
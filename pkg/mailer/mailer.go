@@ -0,0 +1,142 @@
+// Package mailer sends plain-text emails. SMTP is used in production, and
+// Memory keeps the messages for tests.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("mailer: SMTP_HOST is not configured")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LoadFromEnv returns an SMTP mailer configured by:
+//
+//	SMTP_HOST      server host name, required
+//	SMTP_PORT      defaults to 587
+//	SMTP_USERNAME  optional; PLAIN auth is used when set
+//	SMTP_PASSWORD
+//	MAIL_FROM      sender address, defaults to SMTP_USERNAME
+func LoadFromEnv() (Mailer, error) {
+	host := strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	if host == "" {
+		return nil, ErrNotConfigured
+	}
+
+	port := strings.TrimSpace(os.Getenv("SMTP_PORT"))
+	if port == "" {
+		port = "587"
+	}
+
+	username := os.Getenv("SMTP_USERNAME")
+	from := strings.TrimSpace(os.Getenv("MAIL_FROM"))
+	if from == "" {
+		from = username
+	}
+	if from == "" {
+		return nil, errors.New("mailer: MAIL_FROM is not configured")
+	}
+
+	return &SMTP{
+		Addr:     net.JoinHostPort(host, port),
+		Host:     host,
+		Username: username,
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}, nil
+}
+
+// SMTP delivers through one SMTP server. net/smtp upgrades to TLS with
+// STARTTLS when the server offers it, and refuses to send credentials over a
+// plain connection to anything but localhost.
+type SMTP struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	data, err := format(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, data)
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errors.New("mailer: header values must not contain line breaks")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	for _, line := range strings.Split(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n") {
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// Memory records messages instead of sending them.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
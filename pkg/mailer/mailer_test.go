@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one message and returns what was sent after DATA.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 OK")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), received
+}
+
+func TestSMTPSend(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	m := &SMTP{Addr: addr, Host: "127.0.0.1", From: "journal@example.com"}
+
+	err := m.Send(context.Background(), Message{To: "alice@example.com", Subject: "Réinitialiser", Body: "line one\n.line two"})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	data := <-received
+	for _, want := range []string{"From: journal@example.com\r\n", "To: alice@example.com\r\n", "Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n", "\r\n\r\nline one\r\n..line two\r\n"} {
+		if !strings.Contains(data, want) {
+			t.Fatalf("expected %q in message, got %q", want, data)
+		}
+	}
+
+	if err := m.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "x"}); err == nil {
+		t.Fatal("expected header injection to be rejected")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	m.Send(context.Background(), Message{To: "a@example.com", Subject: "hi"})
+	if got := m.Messages(); len(got) != 1 || got[0].To != "a@example.com" {
+		t.Fatalf("unexpected messages %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// "HELLO WORLD" as a 1-M symbol, from the worked example at thonky.com.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := rsRemainder(data, rsDivisor(10)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected error correction codewords: got %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	for mask, want := range []int{
+		0b101010000010010,
+		0b101000100100101,
+		0b101111001111100,
+		0b101101101001011,
+		0b100010111111001,
+		0b100000011001110,
+		0b100111110010111,
+		0b100101010100000,
+	} {
+		if got := formatBits(mask); got != want {
+			t.Fatalf("mask %d: got format bits %015b, want %015b", mask, got, want)
+		}
+	}
+
+	if got := versionBits(7); got != 0x07C94 {
+		t.Fatalf("got version 7 bits %018b", got)
+	}
+	if got := alignmentPositions(10); !reflect.DeepEqual(got, []int{6, 28, 50}) {
+		t.Fatalf("unexpected alignment positions for version 10: %v", got)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	uri := "otpauth://totp/Trading%20Journal:alice?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Trading%20Journal&digits=6&period=30"
+	c, err := Encode([]byte(uri))
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if c.Version != 7 || c.Size != 45 {
+		t.Fatalf("expected version 7 for %d bytes, got version %d", len(uri), c.Version)
+	}
+
+	// Finder pattern corners and the module that is always dark.
+	for _, p := range [][2]int{{0, 0}, {6, 6}, {c.Size - 1, 0}, {0, c.Size - 1}, {3, 3}, {8, c.Size - 8}} {
+		if !c.Dark(p[0], p[1]) {
+			t.Fatalf("expected module %v to be dark", p)
+		}
+	}
+	if c.Dark(1, 1) || c.Dark(7, 7) {
+		t.Fatal("expected the finder pattern separator to be light")
+	}
+
+	// Read the first copy of the format information back.
+	var format int
+	read := func(x, y, i int) {
+		if c.Dark(x, y) {
+			format |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		read(8, i, i)
+	}
+	read(8, 7, 6)
+	read(8, 8, 7)
+	read(7, 8, 8)
+	for i := 9; i < 15; i++ {
+		read(14-i, 8, i)
+	}
+	if format != formatBits(c.Mask) {
+		t.Fatalf("format information %015b does not match mask %d", format, c.Mask)
+	}
+
+	img, err := c.PNG(4)
+	if err != nil {
+		t.Fatalf("png failed: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(img))
+	if err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+	if side := (c.Size + 8) * 4; decoded.Bounds().Dx() != side || decoded.Bounds().Dy() != side {
+		t.Fatalf("unexpected image size %v", decoded.Bounds())
+	}
+
+	if _, err := Encode([]byte(strings.Repeat("x", capacity(maxVersion)+1))); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("expected ErrTooLong, got %v", err)
+	}
+}
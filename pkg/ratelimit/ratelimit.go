@@ -0,0 +1,176 @@
+// Package ratelimit counts events per key in fixed time windows, and locks
+// keys out after repeated failures.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows up to limit events per key in each window. Counters live in
+// memory, so every process keeps its own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// When it is not, the duration says how long until the window resets.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		b = &bucket{start: now}
+		l.buckets[key] = b
+	}
+
+	if b.count >= l.limit {
+		return false, b.start.Add(l.window).Sub(now)
+	}
+	b.count++
+	return true, 0
+}
+
+// Reset forgets the events of key.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.buckets, key)
+}
+
+// prune drops finished windows, at most once per window.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.window {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.start) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// lockoutMemory is how long a key's failures are remembered after the last
+// one.
+const lockoutMemory = 24 * time.Hour
+
+// Lockout locks a key out after threshold failures in a row. The first lock
+// lasts base, and every further failure doubles it up to max. Failures are
+// forgotten on Reset or a day after the last one.
+type Lockout struct {
+	threshold int
+	base      time.Duration
+	max       time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*lockoutEntry
+	lastPrune time.Time
+}
+
+type lockoutEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func NewLockout(threshold int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		threshold: threshold,
+		base:      base,
+		max:       max,
+		now:       time.Now,
+		entries:   make(map[string]*lockoutEntry),
+	}
+}
+
+// Locked returns how long key stays locked out, or 0.
+func (l *Lockout) Locked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+	return max(e.lockedUntil.Sub(l.now()), 0)
+}
+
+// Fail records a failure for key and returns how long key is now locked
+// out, or 0.
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	e, ok := l.entries[key]
+	if !ok || now.Sub(e.lastFailure) >= lockoutMemory {
+		e = &lockoutEntry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	if e.failures < l.threshold {
+		return 0
+	}
+
+	d := l.base
+	for i := l.threshold; i < e.failures && d < l.max; i++ {
+		d *= 2
+	}
+	d = min(d, l.max)
+	e.lockedUntil = now.Add(d)
+	return d
+}
+
+// Reset forgets the failures of key.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// prune drops forgotten keys, at most once an hour.
+func (l *Lockout) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Hour {
+		return
+	}
+	l.lastPrune = now
+
+	for key, e := range l.entries {
+		if now.Sub(e.lastFailure) >= lockoutMemory {
+			delete(l.entries, key)
+		}
+	}
+}
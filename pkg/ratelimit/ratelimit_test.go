@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected event %d to be allowed", i+1)
+		}
+	}
+
+	now = now.Add(20 * time.Second)
+	ok, retryAfter := l.Allow("a")
+	if ok || retryAfter != 40*time.Second {
+		t.Fatalf("expected a refusal for 40s, got %v %v", ok, retryAfter)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("expected other keys to be counted separately")
+	}
+
+	now = now.Add(40 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("expected a new window to allow events again")
+	}
+
+	l.Allow("a")
+	l.Reset("a")
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("expected Reset to clear the key")
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Fatalf("expected finished windows to be pruned, got %d buckets", len(l.buckets))
+	}
+}
+
+func TestLockout(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLockout(3, time.Minute, 5*time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if d := l.Fail("alice"); d != 0 {
+			t.Fatalf("expected no lock after %d failures, got %v", i+1, d)
+		}
+	}
+	if d := l.Fail("alice"); d != time.Minute {
+		t.Fatalf("expected a one minute lock, got %v", d)
+	}
+	if d := l.Locked("alice"); d != time.Minute {
+		t.Fatalf("expected alice to be locked for a minute, got %v", d)
+	}
+	if d := l.Locked("bob"); d != 0 {
+		t.Fatalf("expected bob not to be locked, got %v", d)
+	}
+
+	now = now.Add(time.Minute)
+	if d := l.Locked("alice"); d != 0 {
+		t.Fatalf("expected the lock to expire, got %v", d)
+	}
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for _, w := range want {
+		if d := l.Fail("alice"); d != w {
+			t.Fatalf("expected a %v lock, got %v", w, d)
+		}
+	}
+
+	l.Reset("alice")
+	if d := l.Fail("alice"); d != 0 {
+		t.Fatalf("expected Reset to forget failures, got %v", d)
+	}
+
+	now = now.Add(lockoutMemory)
+	l.Fail("alice")
+	l.Fail("alice")
+	if d := l.Locked("alice"); d != 0 {
+		t.Fatalf("expected old failures to be forgotten, got %v", d)
+	}
+}
@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// The SHA1 vectors of RFC 6238 appendix B, cut to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	} {
+		got, err := Code(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("code failed: %v", err)
+		}
+		if got != want {
+			t.Fatalf("at %d: got %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	now := time.Unix(1_750_000_000, 0)
+
+	previous, _ := Code(secret, now.Add(-Period))
+	if counter, ok := Validate(secret, previous, now); !ok || counter != Counter(now)-1 {
+		t.Fatalf("expected the previous code to be accepted for its own step, got %d %v", counter, ok)
+	}
+
+	stale, _ := Code(secret, now.Add(-3*Period))
+	if _, ok := Validate(secret, stale, now); ok {
+		t.Fatal("expected a code three periods old to be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Fatal("expected a short code to be rejected")
+	}
+	if _, ok := Validate("not base32!", "123456", now); ok {
+		t.Fatal("expected an invalid secret to be rejected")
+	}
+
+	uri := URI("Trading Journal", "alice", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Trading%20Journal:alice?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
@@ -0,0 +1,179 @@
+// Package xlsx writes single-sheet Excel workbooks row by row, without
+// holding the sheet in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	sheetHeaderXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetFooterXML = `</sheetData></worksheet>`
+)
+
+var ErrClosed = errors.New("xlsx writer is closed")
+
+// Writer streams one worksheet. Call Close to finish the file.
+type Writer struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	rows   int
+	closed bool
+}
+
+// NewWriter starts a workbook with a single sheet called sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	var escapedName string
+	if name, err := escape(sheetName); err == nil {
+		escapedName = name
+	}
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escapedName)},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The sheet is the last part, so it can be written as rows arrive.
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(sheetHeaderXML); err != nil {
+		return nil, err
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Cells may be strings, numbers, bools, time.Time
+// (written as RFC3339 text) or nil for an empty cell.
+func (w *Writer) WriteRow(cells []any) error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	w.rows++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows); err != nil {
+		return err
+	}
+	for _, cell := range cells {
+		if err := w.writeCell(cell); err != nil {
+			return err
+		}
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (w *Writer) writeCell(cell any) error {
+	var number string
+	switch v := cell.(type) {
+	case nil:
+		_, err := w.sheet.WriteString(`<c/>`)
+		return err
+	case float64:
+		number = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		number = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int:
+		number = strconv.Itoa(v)
+	case int64:
+		number = strconv.FormatInt(v, 10)
+	case uint:
+		number = strconv.FormatUint(uint64(v), 10)
+	case bool:
+		value := "0"
+		if v {
+			value = "1"
+		}
+		_, err := fmt.Fprintf(w.sheet, `<c t="b"><v>%s</v></c>`, value)
+		return err
+	case time.Time:
+		return w.writeString(v.Format(time.RFC3339))
+	case string:
+		return w.writeString(v)
+	default:
+		return w.writeString(fmt.Sprint(v))
+	}
+
+	_, err := fmt.Fprintf(w.sheet, `<c><v>%s</v></c>`, number)
+	return err
+}
+
+// writeString writes s as an inline string. Spreadsheets show inline strings
+// as text, so a value such as "=HYPERLINK(...)" is never run as a formula.
+func (w *Writer) writeString(s string) error {
+	escaped, err := escape(s)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.sheet, `<c t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, escaped)
+	return err
+}
+
+func escape(s string) (string, error) {
+	var b strings.Builder
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Close finishes the sheet and the zip archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if _, err := w.sheet.WriteString(sheetFooterXML); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
@@ -0,0 +1,56 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Trades")
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if err := w.WriteRow([]any{"symbol", "net_pnl"}); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	if err := w.WriteRow([]any{"BTC<USDT>", 12.5, nil, true, time.Date(2025, 7, 11, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("failed to write row: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `name="Trades"`) {
+		t.Fatalf("expected sheet name in workbook, got %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<row r="2">`, `BTC&lt;USDT&gt;`, `<c><v>12.5</v></c>`, `<c t="b"><v>1</v></c>`, `2025-07-11T00:00:00Z`, `</sheetData></worksheet>`} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("expected %q in sheet, got %s", want, sheet)
+		}
+	}
+	if _, ok := files["[Content_Types].xml"]; !ok {
+		t.Fatalf("expected content types part")
+	}
+}
@@ -1,11 +1,162 @@
 package alerts
 
 import (
-	"github.com/sirupsen/logrus"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
 )
 
+// TradingView caps alert messages well below this; anything larger is not a
+// webhook we want to store.
+const maxAlertBodyBytes = 64 << 10
+
+// AlertHandler ingests TradingView webhooks. TradingView cannot send custom
+// headers, so the caller's webhook token is read from the {token} path
+// segment or, failing that, from the payload itself. TradingView gives up
+// after a few seconds and retries, so the handler only parses and inserts the
+// alert before answering.
 func AlertHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAlertBodyBytes))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "alert payload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			logger.WithError(err).Warn("failed to read alert payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		alert, payloadToken, err := ParseAlert(body)
+		if err != nil {
+			logger.WithError(err).Warn("invalid alert payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		plaintext := chi.URLParam(r, "token")
+		if plaintext == "" {
+			plaintext = payloadToken
+		}
+		if plaintext == "" {
+			logger.Warn("alert received without webhook token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		token, err := getAlertStore().FindActiveWebhookTokenByHash(hashWebhookToken(plaintext))
+		if err != nil {
+			if errors.Is(err, ErrWebhookTokenNotFound) {
+				logger.Warn("alert received with unknown or revoked webhook token")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			logger.WithError(err).Error("failed to resolve webhook token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		alert.ReceivedAt = &now
+		alert.UserID = &token.UserID
+
+		if err := getAlertStore().CreateAlert(alert); err != nil {
+			logger.WithError(err).Error("failed to store alert")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := getAlertStore().TouchWebhookToken(token.ID, now); err != nil {
+			logger.WithError(err).Warn("failed to update webhook token last use")
+		}
+
+		logger.WithFields(logrus.Fields{
+			"alert_id": alert.ID,
+			"user_id":  token.UserID,
+			"symbol":   derefString(alert.Symbol),
+		}).Info("alert received")
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": alert.ID}); err != nil {
+			logger.WithError(err).Error("failed to encode alert response")
+		}
+	}
+}
+
+// alertSchema lists what GET /alerts can filter and sort on.
+var alertSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":            {Column: "id", Kind: query.Number},
+		"alert_name":    {Column: "alert_name", Kind: query.String},
+		"event":         {Column: "event", Kind: query.String},
+		"description":   {Column: "description", Kind: query.String},
+		"symbol":        {Column: "symbol", Kind: query.String},
+		"exchange":      {Column: "exchange", Kind: query.String},
+		"interval":      {Column: "interval", Kind: query.String},
+		"action":        {Column: "action", Kind: query.String},
+		"currency":      {Column: "currency", Kind: query.String},
+		"base_currency": {Column: "base_currency", Kind: query.String},
+		"open":          {Column: "open", Kind: query.Number},
+		"close":         {Column: "close", Kind: query.Number},
+		"high":          {Column: "high", Kind: query.Number},
+		"low":           {Column: "low", Kind: query.Number},
+		"volume":        {Column: "volume", Kind: query.Number},
+		"received_at":   {Column: "received_at", Kind: query.Time},
+		"alert_time":    {Column: "alert_time", Kind: query.Time},
+		"created_at":    {Column: "created_at", Kind: query.Time},
+	},
+	DefaultSort:  query.Sort{Field: "received_at", Desc: true},
+	DefaultLimit: 10,
+}
+
+// ListAlertsHandler returns the caller's own alert feed, newest first
+// unless the react-admin sort param says otherwise.
+func ListAlertsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing alerts")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		params, err := query.Parse(r, alertSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		alerts, total, err := getAlertStore().ListAlerts(user.ID, params)
+		if err != nil {
+			logger.WithError(err).Error("failed to list alerts")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		w.Header().Set("X-Total-Count", fmt.Sprintf("%d", total))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(alerts); err != nil {
+			logger.WithError(err).Error("failed to encode alert list response")
+		}
+	}
+}
 
-	return nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
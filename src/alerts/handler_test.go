@@ -0,0 +1,405 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type inMemoryAlertStore struct {
+	mu          sync.Mutex
+	nextID      uint
+	nextTokenID uint
+	alerts      []model.Alert
+	tokens      map[uint]*model.WebhookToken
+}
+
+func newInMemoryAlertStore() *inMemoryAlertStore {
+	return &inMemoryAlertStore{tokens: make(map[uint]*model.WebhookToken)}
+}
+
+func (s *inMemoryAlertStore) CreateAlert(alert *model.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	alert.ID = s.nextID
+	s.alerts = append(s.alerts, *alert)
+	return nil
+}
+
+func (s *inMemoryAlertStore) ListAlerts(userID uint, params query.Params) ([]model.Alert, int64, error) {
+	offset, limit := params.Offset, params.Limit
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned []model.Alert
+	for _, alert := range s.alerts {
+		if alert.UserID != nil && *alert.UserID == userID {
+			owned = append(owned, alert)
+		}
+	}
+
+	total := int64(len(owned))
+	if offset >= len(owned) {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if limit < 0 || end > len(owned) {
+		end = len(owned)
+	}
+	return owned[offset:end], total, nil
+}
+
+func (s *inMemoryAlertStore) CreateWebhookToken(token *model.WebhookToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextTokenID++
+	token.ID = s.nextTokenID
+	token.CreatedAt = time.Now()
+	clone := *token
+	s.tokens[token.ID] = &clone
+	return nil
+}
+
+func (s *inMemoryAlertStore) SaveWebhookToken(token *model.WebhookToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := *token
+	s.tokens[token.ID] = &clone
+	return nil
+}
+
+func (s *inMemoryAlertStore) ListWebhookTokens(userID uint) ([]model.WebhookToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []model.WebhookToken
+	for id := uint(1); id <= s.nextTokenID; id++ {
+		if token, ok := s.tokens[id]; ok && token.UserID == userID {
+			result = append(result, *token)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryAlertStore) FindWebhookToken(userID, id uint) (*model.WebhookToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || token.UserID != userID {
+		return nil, ErrWebhookTokenNotFound
+	}
+	clone := *token
+	return &clone, nil
+}
+
+func (s *inMemoryAlertStore) FindActiveWebhookTokenByHash(hash string) (*model.WebhookToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == hash && token.RevokedAt == nil {
+			clone := *token
+			return &clone, nil
+		}
+	}
+	return nil, ErrWebhookTokenNotFound
+}
+
+func (s *inMemoryAlertStore) TouchWebhookToken(id uint, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, ok := s.tokens[id]; ok {
+		token.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func newAlertsRouter(logger *logrus.Entry, user *model.User) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/alerts", AlertHandler(logger))
+	router.Post("/alerts/{token}", AlertHandler(logger))
+	router.Group(func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := context.WithValue(r.Context(), auth.UserKey, user)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+		r.Get("/alerts", ListAlertsHandler(logger))
+		r.Get("/webhook-tokens", ListWebhookTokensHandler(logger))
+		r.Post("/webhook-tokens", CreateWebhookTokenHandler(logger))
+		r.Post("/webhook-tokens/{id}/rotate", RotateWebhookTokenHandler(logger))
+		r.Delete("/webhook-tokens/{id}", RevokeWebhookTokenHandler(logger))
+	})
+	return router
+}
+
+func createWebhookToken(t *testing.T, router http.Handler) model.WebhookTokenResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook-tokens", strings.NewReader(`{"name":"tradingview"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected create token 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created model.WebhookTokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode token response: %v", err)
+	}
+	if created.Token == "" || created.WebhookPath != "/alerts/"+created.Token {
+		t.Fatalf("expected plaintext token and webhook path, got %+v", created)
+	}
+	return created
+}
+
+func postAlert(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAlertHandlerStoresJSONPayload(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	alertStore := newInMemoryAlertStore()
+	SetAlertStore(alertStore)
+	t.Cleanup(func() {
+		SetAlertStore(nil)
+	})
+
+	user := &model.User{ID: 7, Username: "alice"}
+	router := newAlertsRouter(logger, user)
+	token := createWebhookToken(t, router)
+
+	payload := `{
+		"alert_name": "BTC breakout",
+		"ticker": "BTCUSDT",
+		"exchange": "BINANCE",
+		"interval": "15",
+		"open": "64000.5",
+		"close": 64210.25,
+		"high": "64300",
+		"low": "63950",
+		"volume": "{{volume}}",
+		"plot_0": "1",
+		"time": "2025-07-11T21:15:00Z",
+		"timenow": "2025-07-11T21:16:05Z",
+		"strategy": {"order": {"action": "buy"}}
+	}`
+
+	rec := postAlert(router, token.WebhookPath, payload)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(alertStore.alerts) != 1 {
+		t.Fatalf("expected 1 stored alert, got %d", len(alertStore.alerts))
+	}
+
+	stored := alertStore.alerts[0]
+	if stored.UserID == nil || *stored.UserID != user.ID {
+		t.Fatalf("expected alert to be stored under user %d, got %v", user.ID, stored.UserID)
+	}
+	if stored.Symbol == nil || *stored.Symbol != "BTCUSDT" {
+		t.Fatalf("expected symbol BTCUSDT, got %v", stored.Symbol)
+	}
+	if stored.AlertName == nil || *stored.AlertName != "BTC breakout" {
+		t.Fatalf("expected alert name to be mapped, got %v", stored.AlertName)
+	}
+	if stored.Close == nil || *stored.Close != 64210.25 {
+		t.Fatalf("expected close 64210.25, got %v", stored.Close)
+	}
+	if stored.Open == nil || *stored.Open != 64000.5 {
+		t.Fatalf("expected open 64000.5, got %v", stored.Open)
+	}
+	if stored.Volume != nil {
+		t.Fatalf("expected unsubstituted volume placeholder to be ignored, got %v", *stored.Volume)
+	}
+	if stored.Plot == nil || *stored.Plot != "1" {
+		t.Fatalf("expected plot 1, got %v", stored.Plot)
+	}
+	if stored.Action == nil || *stored.Action != "buy" {
+		t.Fatalf("expected nested strategy action to be mapped, got %v", stored.Action)
+	}
+	if stored.AlertTime == nil || stored.AlertTime.Format("15:04") != "21:15" {
+		t.Fatalf("expected alert time 21:15, got %v", stored.AlertTime)
+	}
+	if stored.ServerTime == nil {
+		t.Fatalf("expected server time to be parsed")
+	}
+	if stored.ReceivedAt == nil {
+		t.Fatalf("expected received_at to be stamped")
+	}
+	if stored.Body == nil || !json.Valid([]byte(*stored.Body)) {
+		t.Fatalf("expected raw body to be kept as JSON, got %v", stored.Body)
+	}
+
+	var resp map[string]uint
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["id"] != stored.ID {
+		t.Fatalf("expected response id %d, got %d", stored.ID, resp["id"])
+	}
+}
+
+func TestWebhookTokenLifecycle(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	alertStore := newInMemoryAlertStore()
+	SetAlertStore(alertStore)
+	t.Cleanup(func() {
+		SetAlertStore(nil)
+	})
+
+	alice := &model.User{ID: 1, Username: "alice"}
+	bob := &model.User{ID: 2, Username: "bob"}
+	aliceRouter := newAlertsRouter(logger, alice)
+	bobRouter := newAlertsRouter(logger, bob)
+
+	token := createWebhookToken(t, aliceRouter)
+
+	if rec := postAlert(aliceRouter, "/alerts", `{"ticker":"BTCUSDT"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected alert without token to be rejected, got %d", rec.Code)
+	}
+	if rec := postAlert(aliceRouter, "/alerts/whk_bogus", `{"ticker":"BTCUSDT"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected alert with unknown token to be rejected, got %d", rec.Code)
+	}
+
+	body := `{"ticker":"SOLUSDT","token":"` + token.Token + `"}`
+	if rec := postAlert(aliceRouter, "/alerts", body); rec.Code != http.StatusOK {
+		t.Fatalf("expected alert with payload token to be accepted, got %d", rec.Code)
+	}
+	if strings.Contains(*alertStore.alerts[0].Body, token.Token) {
+		t.Fatalf("expected token to be stripped from stored body")
+	}
+
+	listReq := httptest.NewRequest(http.MethodGet, "/alerts", nil)
+	listRec := httptest.NewRecorder()
+	bobRouter.ServeHTTP(listRec, listReq)
+	if listRec.Header().Get("X-Total-Count") != "0" {
+		t.Fatalf("expected bob to see no alerts, got %s", listRec.Header().Get("X-Total-Count"))
+	}
+
+	listRec = httptest.NewRecorder()
+	aliceRouter.ServeHTTP(listRec, listReq)
+	if listRec.Header().Get("X-Total-Count") != "1" {
+		t.Fatalf("expected alice to see 1 alert, got %s", listRec.Header().Get("X-Total-Count"))
+	}
+
+	tokenPath := "/webhook-tokens/" + strconv.Itoa(int(token.ID))
+
+	rotateRec := httptest.NewRecorder()
+	bobRouter.ServeHTTP(rotateRec, httptest.NewRequest(http.MethodPost, tokenPath+"/rotate", nil))
+	if rotateRec.Code != http.StatusNotFound {
+		t.Fatalf("expected bob to get 404 rotating alice's token, got %d", rotateRec.Code)
+	}
+
+	rotateRec = httptest.NewRecorder()
+	aliceRouter.ServeHTTP(rotateRec, httptest.NewRequest(http.MethodPost, tokenPath+"/rotate", nil))
+	if rotateRec.Code != http.StatusOK {
+		t.Fatalf("expected rotate 200, got %d", rotateRec.Code)
+	}
+	var rotated model.WebhookTokenResponse
+	if err := json.Unmarshal(rotateRec.Body.Bytes(), &rotated); err != nil {
+		t.Fatalf("failed to decode rotate response: %v", err)
+	}
+	if rotated.ID != token.ID || rotated.Token == token.Token {
+		t.Fatalf("expected same token id with a new secret, got %+v", rotated)
+	}
+
+	if rec := postAlert(aliceRouter, token.WebhookPath, `{"ticker":"BTCUSDT"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected rotated-out token to be rejected, got %d", rec.Code)
+	}
+	if rec := postAlert(aliceRouter, rotated.WebhookPath, `{"ticker":"BTCUSDT"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected rotated token to be accepted, got %d", rec.Code)
+	}
+
+	revokeRec := httptest.NewRecorder()
+	aliceRouter.ServeHTTP(revokeRec, httptest.NewRequest(http.MethodDelete, tokenPath, nil))
+	if revokeRec.Code != http.StatusNoContent {
+		t.Fatalf("expected revoke 204, got %d", revokeRec.Code)
+	}
+	if rec := postAlert(aliceRouter, rotated.WebhookPath, `{"ticker":"BTCUSDT"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected, got %d", rec.Code)
+	}
+
+	tokensRec := httptest.NewRecorder()
+	aliceRouter.ServeHTTP(tokensRec, httptest.NewRequest(http.MethodGet, "/webhook-tokens", nil))
+	var listed []model.WebhookTokenResponse
+	if err := json.Unmarshal(tokensRec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode token list: %v", err)
+	}
+	if len(listed) != 1 || !listed[0].Revoked || listed[0].Token != "" {
+		t.Fatalf("expected one revoked token without plaintext, got %+v", listed)
+	}
+}
+
+func TestParseAlertPlainText(t *testing.T) {
+	alert, token, err := ParseAlert([]byte("ticker=ETHUSDT\nexchange: BYBIT\nclose=3120.4\ntime=1720732560000\ntoken=whk_abc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "whk_abc" {
+		t.Fatalf("expected token whk_abc, got %q", token)
+	}
+
+	if alert.Symbol == nil || *alert.Symbol != "ETHUSDT" {
+		t.Fatalf("expected symbol ETHUSDT, got %v", alert.Symbol)
+	}
+	if alert.Exchange == nil || *alert.Exchange != "BYBIT" {
+		t.Fatalf("expected exchange BYBIT, got %v", alert.Exchange)
+	}
+	if alert.Close == nil || *alert.Close != 3120.4 {
+		t.Fatalf("expected close 3120.4, got %v", alert.Close)
+	}
+	if alert.AlertTime == nil || alert.AlertTime.UnixMilli() != 1720732560000 {
+		t.Fatalf("expected millisecond timestamp to be parsed, got %v", alert.AlertTime)
+	}
+	if alert.Description != nil {
+		t.Fatalf("expected no description for structured text, got %q", *alert.Description)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal([]byte(*alert.Body), &body); err != nil {
+		t.Fatalf("expected text body to be wrapped as JSON: %v", err)
+	}
+	if !strings.HasPrefix(body["text"], "ticker=ETHUSDT") {
+		t.Fatalf("expected raw text to be preserved, got %q", body["text"])
+	}
+}
+
+func TestParseAlertFreeText(t *testing.T) {
+	alert, _, err := ParseAlert([]byte("BTCUSDT crossing 65000"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alert.Description == nil || *alert.Description != "BTCUSDT crossing 65000" {
+		t.Fatalf("expected free text to become the description, got %v", alert.Description)
+	}
+
+	if _, _, err := ParseAlert([]byte("   ")); err != ErrEmptyAlert {
+		t.Fatalf("expected ErrEmptyAlert, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"vsC1Y2025V01/src/model"
+)
+
+var ErrEmptyAlert = errors.New("alert body is empty")
+
+// alertFields maps normalized payload keys (lowercase, no separators) onto the
+// model.Alert column they fill. TradingView placeholders such as {{ticker}},
+// {{plot_0}} or {{strategy.order.action}} are covered by the aliases.
+var alertFields = map[string]func(a *model.Alert, v string){
+	"alertname":           func(a *model.Alert, v string) { a.AlertName = &v },
+	"name":                func(a *model.Alert, v string) { a.AlertName = &v },
+	"event":               func(a *model.Alert, v string) { a.Event = &v },
+	"description":         func(a *model.Alert, v string) { a.Description = &v },
+	"message":             func(a *model.Alert, v string) { a.Description = &v },
+	"symbol":              func(a *model.Alert, v string) { a.Symbol = &v },
+	"ticker":              func(a *model.Alert, v string) { a.Symbol = &v },
+	"exchange":            func(a *model.Alert, v string) { a.Exchange = &v },
+	"interval":            func(a *model.Alert, v string) { a.Interval = &v },
+	"timeframe":           func(a *model.Alert, v string) { a.Interval = &v },
+	"open":                func(a *model.Alert, v string) { a.Open = parseAlertNumber(v) },
+	"close":               func(a *model.Alert, v string) { a.Close = parseAlertNumber(v) },
+	"high":                func(a *model.Alert, v string) { a.High = parseAlertNumber(v) },
+	"low":                 func(a *model.Alert, v string) { a.Low = parseAlertNumber(v) },
+	"volume":              func(a *model.Alert, v string) { a.Volume = parseAlertNumber(v) },
+	"currency":            func(a *model.Alert, v string) { a.Currency = &v },
+	"syminfocurrency":     func(a *model.Alert, v string) { a.Currency = &v },
+	"basecurrency":        func(a *model.Alert, v string) { a.BaseCurrency = &v },
+	"syminfobasecurrency": func(a *model.Alert, v string) { a.BaseCurrency = &v },
+	"plot":                func(a *model.Alert, v string) { a.Plot = &v },
+	"plot0":               func(a *model.Alert, v string) { a.Plot = &v },
+	"time":                func(a *model.Alert, v string) { a.AlertTime = parseAlertTime(v) },
+	"alerttime":           func(a *model.Alert, v string) { a.AlertTime = parseAlertTime(v) },
+	"timenow":             func(a *model.Alert, v string) { a.ServerTime = parseAlertTime(v) },
+	"servertime":          func(a *model.Alert, v string) { a.ServerTime = parseAlertTime(v) },
+	"action":              func(a *model.Alert, v string) { a.Action = &v },
+	"side":                func(a *model.Alert, v string) { a.Action = &v },
+	"strategyorderaction": func(a *model.Alert, v string) { a.Action = &v },
+}
+
+// ParseAlert maps a TradingView webhook body onto a model.Alert. JSON objects
+// are matched key by key (nested objects are flattened, so
+// {"strategy":{"order":{"action":"buy"}}} fills Action). Anything else is read
+// as plain text with one "key=value" or "key: value" pair per line; text that
+// carries no known keys is kept as the alert description.
+//
+// A webhook token embedded in the payload (a top-level "token" key, or a
+// "token=..." line) is returned separately and stripped from the stored body.
+// The raw payload always ends up in Body, wrapped as {"text": ...} when it is
+// not JSON so that it fits the jsonb column.
+func ParseAlert(body []byte) (*model.Alert, string, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, "", ErrEmptyAlert
+	}
+
+	alert := &model.Alert{}
+
+	var object map[string]interface{}
+	if trimmed[0] == '{' && json.Unmarshal(trimmed, &object) == nil {
+		token, _ := object[alertTokenKey].(string)
+		delete(object, alertTokenKey)
+
+		fields := make(map[string]string)
+		flattenAlertJSON("", object, fields)
+		applyAlertFields(alert, fields)
+
+		raw, err := json.Marshal(object)
+		if err != nil {
+			return nil, "", err
+		}
+		rawStr := string(raw)
+		alert.Body = &rawStr
+		return alert, strings.TrimSpace(token), nil
+	}
+
+	text, token := extractTextToken(string(trimmed))
+	if !applyAlertFields(alert, parseAlertText(text)) && text != "" {
+		alert.Description = &text
+	}
+
+	raw, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, "", err
+	}
+	rawStr := string(raw)
+	alert.Body = &rawStr
+
+	return alert, token, nil
+}
+
+const alertTokenKey = "token"
+
+func extractTextToken(text string) (string, string) {
+	var token string
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		idx := strings.IndexAny(line, "=:")
+		if idx > 0 && normalizeAlertKey(line[:idx]) == alertTokenKey {
+			token = strings.TrimSpace(line[idx+1:])
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), token
+}
+
+func applyAlertFields(alert *model.Alert, fields map[string]string) bool {
+	matched := false
+	for key, value := range fields {
+		setter, ok := alertFields[key]
+		if !ok || value == "" {
+			continue
+		}
+		setter(alert, value)
+		matched = true
+	}
+	return matched
+}
+
+func flattenAlertJSON(prefix string, object map[string]interface{}, out map[string]string) {
+	for key, value := range object {
+		name := prefix + normalizeAlertKey(key)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenAlertJSON(name, v, out)
+		case string:
+			out[name] = strings.TrimSpace(v)
+		case float64:
+			out[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			out[name] = strconv.FormatBool(v)
+		}
+	}
+}
+
+func parseAlertText(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		idx := strings.IndexAny(line, "=:")
+		if idx <= 0 {
+			continue
+		}
+		key := normalizeAlertKey(line[:idx])
+		if _, ok := alertFields[key]; !ok {
+			continue
+		}
+		fields[key] = strings.TrimSpace(line[idx+1:])
+	}
+	return fields
+}
+
+func normalizeAlertKey(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseAlertNumber returns nil for values TradingView failed to substitute
+// (e.g. a literal "{{close}}") or that postgres numeric cannot hold sensibly.
+func parseAlertNumber(v string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+// parseAlertTime accepts the RFC3339 strings produced by {{time}} and
+// {{timenow}} as well as unix timestamps in seconds or milliseconds.
+func parseAlertTime(v string) *time.Time {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return &t
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		var t time.Time
+		if n > 1e12 {
+			t = time.UnixMilli(n).UTC()
+		} else {
+			t = time.Unix(n, 0).UTC()
+		}
+		return &t
+	}
+	return nil
+}
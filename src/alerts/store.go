@@ -0,0 +1,168 @@
+package alerts
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"gorm.io/gorm"
+)
+
+var ErrWebhookTokenNotFound = errors.New("webhook token not found")
+
+type AlertStore interface {
+	CreateAlert(alert *model.Alert) error
+	// ListAlerts returns one page of the user's alerts matching params and
+	// the number of matches across all pages.
+	ListAlerts(userID uint, params query.Params) ([]model.Alert, int64, error)
+
+	CreateWebhookToken(token *model.WebhookToken) error
+	SaveWebhookToken(token *model.WebhookToken) error
+	ListWebhookTokens(userID uint) ([]model.WebhookToken, error)
+	FindWebhookToken(userID, id uint) (*model.WebhookToken, error)
+	// FindActiveWebhookTokenByHash returns ErrWebhookTokenNotFound for unknown
+	// and revoked tokens alike.
+	FindActiveWebhookTokenByHash(hash string) (*model.WebhookToken, error)
+	TouchWebhookToken(id uint, usedAt time.Time) error
+}
+
+var (
+	storeMu sync.RWMutex
+	store   AlertStore = &gormAlertStore{}
+)
+
+func SetAlertStore(s AlertStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormAlertStore{}
+		return
+	}
+
+	store = s
+}
+
+func getAlertStore() AlertStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormAlertStore{}
+	}
+
+	return store
+}
+
+type gormAlertStore struct{}
+
+func (s *gormAlertStore) CreateAlert(alert *model.Alert) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(alert).Error
+}
+
+func (s *gormAlertStore) ListAlerts(userID uint, params query.Params) ([]model.Alert, int64, error) {
+	if db.DB == nil {
+		return nil, 0, errors.New("database connection is not initialized")
+	}
+
+	matching := params.Where(db.DB.Model(&model.Alert{}).Where("user_id = ?", userID)).Session(&gorm.Session{})
+
+	var total int64
+	if err := matching.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []model.Alert
+	if err := params.Page(params.Order(matching)).Find(&alerts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return alerts, total, nil
+}
+
+func (s *gormAlertStore) CreateWebhookToken(token *model.WebhookToken) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(token).Error
+}
+
+func (s *gormAlertStore) SaveWebhookToken(token *model.WebhookToken) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Save(token).Error
+}
+
+func (s *gormAlertStore) ListWebhookTokens(userID uint) ([]model.WebhookToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var tokens []model.WebhookToken
+	if err := db.DB.Where("user_id = ?", userID).Order("id ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *gormAlertStore) FindWebhookToken(userID, id uint) (*model.WebhookToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.WebhookToken
+	if err := db.DB.Where("user_id = ? AND id = ?", userID, id).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookTokenNotFound
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormAlertStore) FindActiveWebhookTokenByHash(hash string) (*model.WebhookToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.WebhookToken
+	if err := db.DB.Where("token_hash = ? AND revoked_at IS NULL", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookTokenNotFound
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormAlertStore) TouchWebhookToken(id uint, usedAt time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Model(&model.WebhookToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -0,0 +1,215 @@
+package alerts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookTokenPrefix = "whk_"
+	webhookTokenBytes  = 24
+)
+
+// newWebhookToken returns a fresh plaintext token and the hash that is stored.
+func newWebhookToken() (string, string, error) {
+	buf := make([]byte, webhookTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := webhookTokenPrefix + hex.EncodeToString(buf)
+	return token, hashWebhookToken(token), nil
+}
+
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func webhookTokenDisplayPrefix(token string) string {
+	return token[:len(webhookTokenPrefix)+6]
+}
+
+func newWebhookTokenSecretResponse(t *model.WebhookToken, plaintext string) model.WebhookTokenResponse {
+	resp := model.NewWebhookTokenResponse(t)
+	resp.Token = plaintext
+	resp.WebhookPath = "/alerts/" + plaintext
+	return resp
+}
+
+func CreateWebhookTokenHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating webhook token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.CreateWebhookTokenPayload
+		if r.ContentLength != 0 {
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&payload); err != nil {
+				logger.WithError(err).Warn("invalid webhook token payload")
+				http.Error(w, "Invalid payload", http.StatusBadRequest)
+				return
+			}
+		}
+
+		plaintext, hash, err := newWebhookToken()
+		if err != nil {
+			logger.WithError(err).Error("failed to generate webhook token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		token := &model.WebhookToken{
+			UserID:      user.ID,
+			Name:        strings.TrimSpace(payload.Name),
+			TokenHash:   hash,
+			TokenPrefix: webhookTokenDisplayPrefix(plaintext),
+		}
+
+		if err := getAlertStore().CreateWebhookToken(token); err != nil {
+			logger.WithError(err).Error("failed to store webhook token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(newWebhookTokenSecretResponse(token, plaintext)); err != nil {
+			logger.WithError(err).Error("failed to encode webhook token response")
+		}
+	}
+}
+
+func ListWebhookTokensHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing webhook tokens")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tokens, err := getAlertStore().ListWebhookTokens(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to list webhook tokens")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.WebhookTokenResponse, 0, len(tokens))
+		for i := range tokens {
+			responses = append(responses, model.NewWebhookTokenResponse(&tokens[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode webhook token list response")
+		}
+	}
+}
+
+// RotateWebhookTokenHandler swaps the secret of an active token while keeping
+// its id and name, so the old webhook URL stops working immediately.
+func RotateWebhookTokenHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := loadUserWebhookToken(w, r, logger)
+		if !ok {
+			return
+		}
+
+		if token.RevokedAt != nil {
+			http.Error(w, "webhook token is revoked", http.StatusConflict)
+			return
+		}
+
+		plaintext, hash, err := newWebhookToken()
+		if err != nil {
+			logger.WithError(err).Error("failed to generate webhook token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		token.TokenHash = hash
+		token.TokenPrefix = webhookTokenDisplayPrefix(plaintext)
+		token.LastUsedAt = nil
+
+		if err := getAlertStore().SaveWebhookToken(token); err != nil {
+			logger.WithError(err).Error("failed to rotate webhook token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(newWebhookTokenSecretResponse(token, plaintext)); err != nil {
+			logger.WithError(err).Error("failed to encode webhook token response")
+		}
+	}
+}
+
+func RevokeWebhookTokenHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := loadUserWebhookToken(w, r, logger)
+		if !ok {
+			return
+		}
+
+		if token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			if err := getAlertStore().SaveWebhookToken(token); err != nil {
+				logger.WithError(err).Error("failed to revoke webhook token")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func loadUserWebhookToken(w http.ResponseWriter, r *http.Request, logger *logrus.Entry) (*model.WebhookToken, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		logger.Warn("user not found in context while loading webhook token")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid token id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	token, err := getAlertStore().FindWebhookToken(user.ID, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrWebhookTokenNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return nil, false
+		}
+
+		logger.WithError(err).Error("failed to load webhook token")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return token, true
+}
@@ -0,0 +1,318 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"vsC1Y2025V01/pkg/blobstore"
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type inMemoryAttachmentStore struct {
+	trades      map[uint]uint // trade ID -> owner
+	attachments []model.TradeAttachment
+	nextID      uint
+}
+
+func (s *inMemoryAttachmentStore) TradeOwned(userID, tradeID uint) (bool, error) {
+	owner, ok := s.trades[tradeID]
+	return ok && owner == userID, nil
+}
+
+func (s *inMemoryAttachmentStore) ListAttachments(userID, tradeID uint) ([]model.TradeAttachment, error) {
+	var result []model.TradeAttachment
+	for _, a := range s.attachments {
+		if a.UserID == userID && a.TradeID == tradeID {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryAttachmentStore) GetAttachment(userID, tradeID, id uint) (*model.TradeAttachment, error) {
+	for _, a := range s.attachments {
+		if a.ID == id && a.UserID == userID && a.TradeID == tradeID {
+			return &a, nil
+		}
+	}
+	return nil, ErrAttachmentNotFound
+}
+
+func (s *inMemoryAttachmentStore) UsedBytes(userID uint) (int64, error) {
+	var used int64
+	for _, a := range s.attachments {
+		if a.UserID == userID {
+			used += a.Size
+		}
+	}
+	return used, nil
+}
+
+func (s *inMemoryAttachmentStore) CreateAttachment(a *model.TradeAttachment) error {
+	s.nextID++
+	a.ID = s.nextID
+	s.attachments = append(s.attachments, *a)
+	return nil
+}
+
+func (s *inMemoryAttachmentStore) DeleteAttachment(userID, id uint) error {
+	kept := s.attachments[:0]
+	for _, a := range s.attachments {
+		if a.ID != id || a.UserID != userID {
+			kept = append(kept, a)
+		}
+	}
+	s.attachments = kept
+	return nil
+}
+
+func (s *inMemoryAttachmentStore) ListForTrades(tradeIDs []uint) ([]model.TradeAttachment, error) {
+	var result []model.TradeAttachment
+	for _, a := range s.attachments {
+		for _, id := range tradeIDs {
+			if a.TradeID == id {
+				result = append(result, a)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryAttachmentStore) DeleteForTrades(tradeIDs []uint) error {
+	kept := s.attachments[:0]
+	for _, a := range s.attachments {
+		deleted := false
+		for _, id := range tradeIDs {
+			deleted = deleted || a.TradeID == id
+		}
+		if !deleted {
+			kept = append(kept, a)
+		}
+	}
+	s.attachments = kept
+	return nil
+}
+
+type memoryBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memoryBlobStore) Put(_ context.Context, key string, data []byte, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryBlobStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, blobstore.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryBlobStore) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
+
+func newTestRouter(t *testing.T) (*chi.Mux, *inMemoryAttachmentStore, *memoryBlobStore) {
+	store := &inMemoryAttachmentStore{trades: map[uint]uint{10: 1, 20: 2}}
+	blobs := &memoryBlobStore{blobs: make(map[string][]byte)}
+	SetAttachmentStore(store)
+	SetBlobStore(blobs)
+	t.Cleanup(func() {
+		SetAttachmentStore(nil)
+		SetBlobStore(nil)
+	})
+
+	logger := logrus.NewEntry(logrus.New())
+	router := chi.NewRouter()
+	router.Route("/trades/{id}/attachments", func(r chi.Router) {
+		r.Get("/", ListAttachmentsHandler(logger))
+		r.Post("/", UploadAttachmentHandler(logger))
+		r.Get("/{attachmentID}", GetAttachmentHandler(logger))
+		r.Get("/{attachmentID}/thumbnail", GetThumbnailHandler(logger))
+		r.Delete("/{attachmentID}", DeleteAttachmentHandler(logger))
+	})
+	return router, store, blobs
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func do(t *testing.T, router http.Handler, userID uint, method, target string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: userID}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func postFile(t *testing.T, router http.Handler, userID uint, target, kind, name string, data []byte) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	if kind != "" {
+		form.WriteField("kind", kind)
+	}
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+	return do(t, router, userID, http.MethodPost, target, &buf, form.FormDataContentType())
+}
+
+func TestAttachmentLifecycle(t *testing.T) {
+	router, store, blobs := newTestRouter(t)
+
+	rec := postFile(t, router, 1, "/trades/10/attachments", "before", "entry.png", pngImage(t, 800, 400))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.TradeAttachmentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.Kind != "before" || created.ContentType != "image/png" || created.FileName != "entry.png" || created.ThumbnailURL == nil {
+		t.Fatalf("unexpected attachment: %+v", created)
+	}
+
+	rec = do(t, router, 1, http.MethodGet, *created.ThumbnailURL, nil, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected a JPEG thumbnail, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	thumb, err := jpeg.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 320 || b.Dy() != 160 {
+		t.Fatalf("expected a 320x160 thumbnail, got %v", b)
+	}
+
+	rec = do(t, router, 1, http.MethodGet, created.URL, nil, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected the original image, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	if rec = do(t, router, 2, http.MethodGet, created.URL, nil, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's attachment, got %d", rec.Code)
+	}
+	if rec = postFile(t, router, 1, "/trades/20/attachments", "", "x.png", pngImage(t, 4, 4)); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when uploading to another user's trade, got %d", rec.Code)
+	}
+
+	rec = do(t, router, 1, http.MethodGet, "/trades/10/attachments", nil, "")
+	var listed []model.TradeAttachmentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&listed); err != nil || len(listed) != 1 {
+		t.Fatalf("expected one attachment, got %v (%v)", listed, err)
+	}
+
+	if rec = do(t, router, 1, http.MethodDelete, created.URL, nil, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if len(store.attachments) != 0 || len(blobs.blobs) != 0 {
+		t.Fatalf("expected the attachment and its files to be gone, got %v and %d blobs", store.attachments, len(blobs.blobs))
+	}
+}
+
+func TestUploadValidation(t *testing.T) {
+	router, _, blobs := newTestRouter(t)
+
+	if rec := postFile(t, router, 1, "/trades/10/attachments", "", "notes.txt", []byte("just some text")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for a text file, got %d", rec.Code)
+	}
+	if rec := postFile(t, router, 1, "/trades/10/attachments", "during", "a.png", pngImage(t, 4, 4)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown kind, got %d", rec.Code)
+	}
+
+	broken := append(pngImage(t, 4, 4)[:20], 0, 0, 0)
+	if rec := postFile(t, router, 1, "/trades/10/attachments", "", "a.png", broken); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a truncated PNG, got %d", rec.Code)
+	}
+
+	webp := []byte("RIFF\x1a\x00\x00\x00WEBPVP8 \x0e\x00\x00\x00placeholder...")
+	rec := postFile(t, router, 1, "/trades/10/attachments", "after", "a.webp", webp)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected WebP to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "thumbnailUrl") {
+		t.Fatalf("expected no thumbnail for WebP, got %s", rec.Body.String())
+	}
+
+	t.Setenv("ATTACHMENT_MAX_BYTES", "100")
+	if rec := postFile(t, router, 1, "/trades/10/attachments", "", "big.png", pngImage(t, 64, 64)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for a file over the limit, got %d", rec.Code)
+	}
+
+	t.Setenv("ATTACHMENT_MAX_BYTES", "")
+	t.Setenv("ATTACHMENT_QUOTA_BYTES", "64")
+	if rec := postFile(t, router, 1, "/trades/10/attachments", "", "small.png", pngImage(t, 16, 16)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 once the quota is used up, got %d", rec.Code)
+	}
+	if len(blobs.blobs) != 1 {
+		t.Fatalf("expected only the WebP file to be stored, got %d blobs", len(blobs.blobs))
+	}
+}
+
+func TestDeleteTradeAttachments(t *testing.T) {
+	router, store, blobs := newTestRouter(t)
+
+	for i := 0; i < 2; i++ {
+		if rec := postFile(t, router, 1, "/trades/10/attachments", "", "a.png", pngImage(t, 8, 8)); rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+	}
+	store.trades[11] = 1
+	if rec := postFile(t, router, 1, "/trades/11/attachments", "", "b.png", pngImage(t, 8, 8)); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+
+	if err := DeleteTradeAttachments(context.Background(), logrus.NewEntry(logrus.New()), []uint{10}); err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	if len(store.attachments) != 1 || store.attachments[0].TradeID != 11 {
+		t.Fatalf("expected only trade 11's attachment to remain, got %+v", store.attachments)
+	}
+	// One original and one thumbnail left.
+	if len(blobs.blobs) != 2 {
+		t.Fatalf("expected 2 blobs left, got %d", len(blobs.blobs))
+	}
+}
@@ -0,0 +1,124 @@
+package attachments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"vsC1Y2025V01/pkg/blobstore"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxFileBytes  = 10 << 20
+	defaultQuotaBytes    = 200 << 20
+	multipartOverheadCap = 1 << 20
+)
+
+var (
+	blobStoreMu sync.RWMutex
+	blobStore   blobstore.Store
+)
+
+// SetBlobStore overrides the store configured through the environment (see
+// blobstore.LoadFromEnv). Passing nil makes the next use reload it.
+func SetBlobStore(s blobstore.Store) {
+	blobStoreMu.Lock()
+	defer blobStoreMu.Unlock()
+
+	blobStore = s
+}
+
+func getBlobStore() (blobstore.Store, error) {
+	blobStoreMu.RLock()
+	current := blobStore
+	blobStoreMu.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	blobStoreMu.Lock()
+	defer blobStoreMu.Unlock()
+
+	if blobStore == nil {
+		loaded, err := blobstore.LoadFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		blobStore = loaded
+	}
+
+	return blobStore, nil
+}
+
+// limits reads ATTACHMENT_MAX_BYTES, the largest accepted file, and
+// ATTACHMENT_QUOTA_BYTES, the total a user may store. Unset or invalid
+// values fall back to 10 MiB and 200 MiB.
+func limits() (maxFile, quota int64) {
+	return bytesFromEnv("ATTACHMENT_MAX_BYTES", defaultMaxFileBytes), bytesFromEnv("ATTACHMENT_QUOTA_BYTES", defaultQuotaBytes)
+}
+
+func bytesFromEnv(name string, fallback int64) int64 {
+	value, err := strconv.ParseInt(strings.TrimSpace(os.Getenv(name)), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func newStorageKey(userID, tradeID uint) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("attachments/%d/%d/%s", userID, tradeID, hex.EncodeToString(random)), nil
+}
+
+// deleteBlobs removes the files of attachments. Failures are only logged:
+// the rows are gone by then and a leftover file does no harm.
+func deleteBlobs(ctx context.Context, logger *logrus.Entry, blobs blobstore.Store, attachments []model.TradeAttachment) {
+	for _, a := range attachments {
+		keys := []string{a.StorageKey}
+		if a.ThumbnailKey != nil {
+			keys = append(keys, *a.ThumbnailKey)
+		}
+		for _, key := range keys {
+			if err := blobs.Delete(ctx, key); err != nil {
+				logger.WithError(err).WithField("key", key).Warn("failed to delete attachment file")
+			}
+		}
+	}
+}
+
+// DeleteTradeAttachments removes the attachments of deleted trades, files
+// included. Trades call it after deleting their own rows.
+func DeleteTradeAttachments(ctx context.Context, logger *logrus.Entry, tradeIDs []uint) error {
+	if len(tradeIDs) == 0 {
+		return nil
+	}
+
+	attachmentStore := getAttachmentStore()
+	attachments, err := attachmentStore.ListForTrades(tradeIDs)
+	if err != nil || len(attachments) == 0 {
+		return err
+	}
+
+	blobs, err := getBlobStore()
+	if err != nil {
+		return err
+	}
+
+	if err := attachmentStore.DeleteForTrades(tradeIDs); err != nil {
+		return err
+	}
+	deleteBlobs(ctx, logger, blobs, attachments)
+	return nil
+}
@@ -0,0 +1,400 @@
+package attachments
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"vsC1Y2025V01/pkg/blobstore"
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var validKinds = map[string]bool{
+	model.AttachmentBefore: true,
+	model.AttachmentAfter:  true,
+	model.AttachmentOther:  true,
+}
+
+func parseURLID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, name), 10, 64)
+	return uint(id), err
+}
+
+// ownedTrade writes the error response and returns false unless the trade
+// in the URL belongs to the user.
+func ownedTrade(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, userID uint) (uint, bool) {
+	tradeID, err := parseURLID(r, "id")
+	if err != nil {
+		http.Error(w, "invalid trade id", http.StatusBadRequest)
+		return 0, false
+	}
+
+	owned, err := getAttachmentStore().TradeOwned(userID, tradeID)
+	if err != nil {
+		logger.WithError(err).Error("failed to load trade")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return 0, false
+	}
+	if !owned {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return 0, false
+	}
+
+	return tradeID, true
+}
+
+func loadAttachment(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, userID uint) (*model.TradeAttachment, bool) {
+	tradeID, err := parseURLID(r, "id")
+	if err != nil {
+		http.Error(w, "invalid trade id", http.StatusBadRequest)
+		return nil, false
+	}
+	id, err := parseURLID(r, "attachmentID")
+	if err != nil {
+		http.Error(w, "invalid attachment id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	attachment, err := getAttachmentStore().GetAttachment(userID, tradeID, id)
+	if err != nil {
+		if errors.Is(err, ErrAttachmentNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return nil, false
+		}
+
+		logger.WithError(err).Error("failed to load attachment")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return attachment, true
+}
+
+// upload is the content of the multipart form.
+type upload struct {
+	kind     string
+	fileName string
+	data     []byte
+}
+
+var errFileTooLarge = errors.New("file is too large")
+
+// readUpload reads a form with one "file" part and an optional "kind"
+// field, holding at most maxFile bytes of the file in memory.
+func readUpload(r *http.Request, maxFile int64) (*upload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("expected a multipart/form-data body")
+	}
+
+	u := &upload{kind: model.AttachmentOther}
+	seenFile := false
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("malformed multipart body")
+		}
+
+		switch part.FormName() {
+		case "file":
+			if seenFile {
+				return nil, errors.New("only one file can be uploaded per request")
+			}
+			seenFile = true
+
+			data, err := io.ReadAll(io.LimitReader(part, maxFile+1))
+			if err != nil {
+				return nil, errors.New("malformed multipart body")
+			}
+			if int64(len(data)) > maxFile {
+				return nil, errFileTooLarge
+			}
+			u.data = data
+			u.fileName = filepath.Base(strings.ReplaceAll(part.FileName(), "\\", "/"))
+		case "kind":
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			if err != nil {
+				return nil, errors.New("malformed multipart body")
+			}
+			u.kind = strings.ToLower(strings.TrimSpace(string(value)))
+		}
+		part.Close()
+	}
+
+	if !seenFile || len(u.data) == 0 {
+		return nil, errors.New("file is required")
+	}
+	if !validKinds[u.kind] {
+		return nil, errors.New("kind must be before, after or other")
+	}
+	if u.fileName == "" || u.fileName == "." || u.fileName == "/" {
+		u.fileName = "attachment"
+	}
+	if len(u.fileName) > 255 {
+		u.fileName = u.fileName[:255]
+	}
+
+	return u, nil
+}
+
+func ListAttachmentsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing attachments")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tradeID, ok := ownedTrade(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		attachments, err := getAttachmentStore().ListAttachments(user.ID, tradeID)
+		if err != nil {
+			logger.WithError(err).Error("failed to list attachments")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.TradeAttachmentResponse, 0, len(attachments))
+		for i := range attachments {
+			responses = append(responses, model.NewTradeAttachmentResponse(&attachments[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode attachment list response")
+		}
+	}
+}
+
+// UploadAttachmentHandler stores one image sent as multipart/form-data with
+// a "file" part and an optional "kind" field (before, after or other).
+// The type is sniffed from the content, not taken from the client.
+func UploadAttachmentHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while uploading attachment")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tradeID, ok := ownedTrade(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		maxFile, quota := limits()
+		r.Body = http.MaxBytesReader(w, r.Body, maxFile+multipartOverheadCap)
+
+		u, err := readUpload(r, maxFile)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.Is(err, errFileTooLarge) || errors.As(err, &tooLarge) {
+				http.Error(w, "file is larger than "+strconv.FormatInt(maxFile, 10)+" bytes", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contentType := http.DetectContentType(u.data)
+		if !allowedTypes[contentType] {
+			http.Error(w, "only PNG, JPEG, GIF and WebP images are accepted", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var thumbnail []byte
+		if decodable[contentType] {
+			thumbnail, err = makeThumbnail(u.data)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		attachmentStore := getAttachmentStore()
+		used, err := attachmentStore.UsedBytes(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to compute attachment usage")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if used+int64(len(u.data)) > quota {
+			http.Error(w, "attachment storage quota exceeded", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		blobs, err := getBlobStore()
+		if err != nil {
+			logger.WithError(err).Error("failed to open blob store")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		key, err := newStorageKey(user.ID, tradeID)
+		if err != nil {
+			logger.WithError(err).Error("failed to generate attachment key")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		attachment := &model.TradeAttachment{
+			UserID:      user.ID,
+			TradeID:     tradeID,
+			Kind:        u.kind,
+			FileName:    u.fileName,
+			ContentType: contentType,
+			Size:        int64(len(u.data)),
+			StorageKey:  key,
+		}
+
+		if err := blobs.Put(r.Context(), key, u.data, contentType); err != nil {
+			logger.WithError(err).Error("failed to store attachment")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if thumbnail != nil {
+			thumbnailKey := key + "-thumb"
+			attachment.ThumbnailKey = &thumbnailKey
+			if err := blobs.Put(r.Context(), thumbnailKey, thumbnail, "image/jpeg"); err != nil {
+				logger.WithError(err).Error("failed to store attachment thumbnail")
+				deleteBlobs(r.Context(), logger, blobs, []model.TradeAttachment{{StorageKey: key}})
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := attachmentStore.CreateAttachment(attachment); err != nil {
+			logger.WithError(err).Error("failed to create attachment")
+			deleteBlobs(r.Context(), logger, blobs, []model.TradeAttachment{*attachment})
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(model.NewTradeAttachmentResponse(attachment)); err != nil {
+			logger.WithError(err).Error("failed to encode attachment response")
+		}
+	}
+}
+
+func serveBlob(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, key, contentType, fileName string) {
+	blobs, err := getBlobStore()
+	if err != nil {
+		logger.WithError(err).Error("failed to open blob store")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	content, err := blobs.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			logger.WithField("key", key).Warn("attachment file is missing")
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		logger.WithError(err).Error("failed to read attachment")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if _, err := io.Copy(w, content); err != nil {
+		logger.WithError(err).Warn("failed to send attachment")
+	}
+}
+
+func GetAttachmentHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while fetching attachment")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attachment, ok := loadAttachment(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		serveBlob(w, r, logger, attachment.StorageKey, attachment.ContentType, attachment.FileName)
+	}
+}
+
+func GetThumbnailHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while fetching attachment thumbnail")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attachment, ok := loadAttachment(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+		if attachment.ThumbnailKey == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		name := strings.TrimSuffix(attachment.FileName, filepath.Ext(attachment.FileName)) + "-thumb.jpg"
+		serveBlob(w, r, logger, *attachment.ThumbnailKey, "image/jpeg", name)
+	}
+}
+
+func DeleteAttachmentHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting attachment")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attachment, ok := loadAttachment(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		blobs, err := getBlobStore()
+		if err != nil {
+			logger.WithError(err).Error("failed to open blob store")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := getAttachmentStore().DeleteAttachment(user.ID, attachment.ID); err != nil {
+			logger.WithError(err).Error("failed to delete attachment")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		deleteBlobs(r.Context(), logger, blobs, []model.TradeAttachment{*attachment})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,159 @@
+package attachments
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+type AttachmentStore interface {
+	// TradeOwned reports whether the trade exists and belongs to the user.
+	TradeOwned(userID, tradeID uint) (bool, error)
+	ListAttachments(userID, tradeID uint) ([]model.TradeAttachment, error)
+	GetAttachment(userID, tradeID, id uint) (*model.TradeAttachment, error)
+	// UsedBytes is the total size of the user's attachments.
+	UsedBytes(userID uint) (int64, error)
+	CreateAttachment(a *model.TradeAttachment) error
+	DeleteAttachment(userID, id uint) error
+	// ListForTrades returns the attachments of any of the trades, whoever
+	// owns them.
+	ListForTrades(tradeIDs []uint) ([]model.TradeAttachment, error)
+	DeleteForTrades(tradeIDs []uint) error
+}
+
+var (
+	storeMu sync.RWMutex
+	store   AttachmentStore = &gormAttachmentStore{}
+)
+
+func SetAttachmentStore(s AttachmentStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormAttachmentStore{}
+		return
+	}
+
+	store = s
+}
+
+func getAttachmentStore() AttachmentStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormAttachmentStore{}
+	}
+
+	return store
+}
+
+type gormAttachmentStore struct{}
+
+func (s *gormAttachmentStore) TradeOwned(userID, tradeID uint) (bool, error) {
+	if db.DB == nil {
+		return false, errors.New("database connection is not initialized")
+	}
+
+	var count int64
+	if err := db.DB.Model(&model.Trade{}).Where("id = ? AND user_id = ?", tradeID, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *gormAttachmentStore) ListAttachments(userID, tradeID uint) ([]model.TradeAttachment, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var attachments []model.TradeAttachment
+	if err := db.DB.Where("user_id = ? AND trade_id = ?", userID, tradeID).Order("created_at ASC, id ASC").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *gormAttachmentStore) GetAttachment(userID, tradeID, id uint) (*model.TradeAttachment, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var attachment model.TradeAttachment
+	if err := db.DB.Where("user_id = ? AND trade_id = ?", userID, tradeID).First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (s *gormAttachmentStore) UsedBytes(userID uint) (int64, error) {
+	if db.DB == nil {
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	var used int64
+	if err := db.DB.Model(&model.TradeAttachment{}).Where("user_id = ?", userID).Select("COALESCE(SUM(size), 0)").Scan(&used).Error; err != nil {
+		return 0, err
+	}
+
+	return used, nil
+}
+
+func (s *gormAttachmentStore) CreateAttachment(a *model.TradeAttachment) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Omit("User").Create(a).Error
+}
+
+func (s *gormAttachmentStore) DeleteAttachment(userID, id uint) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Where("user_id = ?", userID).Delete(&model.TradeAttachment{}, id).Error
+}
+
+func (s *gormAttachmentStore) ListForTrades(tradeIDs []uint) ([]model.TradeAttachment, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var attachments []model.TradeAttachment
+	if err := db.DB.Where("trade_id IN ?", tradeIDs).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *gormAttachmentStore) DeleteForTrades(tradeIDs []uint) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Where("trade_id IN ?", tradeIDs).Delete(&model.TradeAttachment{}).Error
+}
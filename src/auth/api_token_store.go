@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+type APITokenStore interface {
+	CreateAPIToken(token *model.APIToken) error
+	ListAPITokens(userID uint) ([]model.APIToken, error)
+	// FindAPITokenByHash returns ErrAPITokenNotFound for unknown hashes.
+	// Revoked and expired tokens are returned; callers check Active.
+	FindAPITokenByHash(hash string) (*model.APIToken, error)
+	RevokeAPIToken(userID, id uint, at time.Time) error
+	TouchAPIToken(id uint, usedAt time.Time) error
+}
+
+var (
+	apiTokenStoreMu sync.RWMutex
+	apiTokenStore   APITokenStore = &gormAPITokenStore{}
+)
+
+func SetAPITokenStore(s APITokenStore) {
+	apiTokenStoreMu.Lock()
+	defer apiTokenStoreMu.Unlock()
+
+	if s == nil {
+		apiTokenStore = &gormAPITokenStore{}
+		return
+	}
+
+	apiTokenStore = s
+}
+
+func getAPITokenStore() APITokenStore {
+	apiTokenStoreMu.RLock()
+	current := apiTokenStore
+	apiTokenStoreMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	apiTokenStoreMu.Lock()
+	defer apiTokenStoreMu.Unlock()
+
+	if apiTokenStore == nil {
+		apiTokenStore = &gormAPITokenStore{}
+	}
+
+	return apiTokenStore
+}
+
+type gormAPITokenStore struct{}
+
+func (s *gormAPITokenStore) CreateAPIToken(token *model.APIToken) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(token).Error
+}
+
+func (s *gormAPITokenStore) ListAPITokens(userID uint) ([]model.APIToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var tokens []model.APIToken
+	if err := db.DB.Where("user_id = ?", userID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *gormAPITokenStore) FindAPITokenByHash(hash string) (*model.APIToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.APIToken
+	if err := db.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPITokenNotFound
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormAPITokenStore) RevokeAPIToken(userID, id uint, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	var token model.APIToken
+	if err := db.DB.Where("user_id = ?", userID).First(&token, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPITokenNotFound
+		}
+
+		return err
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+
+	return db.DB.Model(&token).Update("revoked_at", at).Error
+}
+
+func (s *gormAPITokenStore) TouchAPIToken(id uint, usedAt time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
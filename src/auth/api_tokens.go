@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiTokenPrefix = "pat_"
+	apiTokenBytes  = 24
+
+	// apiTokenTouchInterval limits how often a request updates the token's
+	// last use.
+	apiTokenTouchInterval = time.Minute
+)
+
+var errAPITokenInactive = errors.New("api token is revoked or expired")
+
+// newAPIToken returns a fresh plaintext token and the hash that is stored.
+func newAPIToken() (string, string, error) {
+	buf := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := apiTokenPrefix + hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func apiTokenDisplayPrefix(token string) string {
+	return token[:len(apiTokenPrefix)+6]
+}
+
+// authenticateAPIToken looks up an active personal API token by its
+// plaintext and records its use.
+func authenticateAPIToken(plaintext string) (*model.APIToken, error) {
+	store := getAPITokenStore()
+	token, err := store.FindAPITokenByHash(hashToken(plaintext))
+	if err != nil {
+		if errors.Is(err, ErrAPITokenNotFound) {
+			return nil, errAPITokenInactive
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !token.Active(now) {
+		return nil, errAPITokenInactive
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := store.TouchAPIToken(token.ID, now); err == nil {
+			token.LastUsedAt = &now
+		}
+	}
+
+	return token, nil
+}
+
+// RequireScope authenticates a request with either a Bearer header or the
+// session cookie. Personal API tokens must carry scope, and get 403 when they
+// do not. Sessions may use every scope. Routes behind RequireAuthMiddleware
+// alone do not accept API tokens at all.
+func RequireScope(logger *logrus.Entry, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		checked := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := GetAPITokenFromContext(r.Context()); ok && !token.HasScope(scope) {
+				logger.WithFields(logrus.Fields{"token_id": token.ID, "scope": scope}).Warn("API token is missing a scope")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+
+		bearer := AuthMiddleware(logger)(checked)
+		cookie := RequireAuthMiddleware(logger)(checked)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				bearer.ServeHTTP(w, r)
+				return
+			}
+			cookie.ServeHTTP(w, r)
+		})
+	}
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(model.APITokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(model.APITokenScopes, ", "))
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// CreateAPITokenHandler issues a personal API token. The plaintext is only
+// part of this response.
+func CreateAPITokenHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating api token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.CreateAPITokenPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid api token payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		name := strings.TrimSpace(payload.Name)
+		if name == "" || len(name) > 100 {
+			http.Error(w, "name is required and must be at most 100 characters", http.StatusBadRequest)
+			return
+		}
+		scopes, err := normalizeScopes(payload.Scopes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+			http.Error(w, "expiresAt must be in the future", http.StatusBadRequest)
+			return
+		}
+
+		plaintext, hash, err := newAPIToken()
+		if err != nil {
+			logger.WithError(err).Error("failed to generate api token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		token := &model.APIToken{
+			UserID:      user.ID,
+			Name:        name,
+			TokenHash:   hash,
+			TokenPrefix: apiTokenDisplayPrefix(plaintext),
+			Scopes:      strings.Join(scopes, " "),
+			ExpiresAt:   payload.ExpiresAt,
+		}
+
+		if err := getAPITokenStore().CreateAPIToken(token); err != nil {
+			logger.WithError(err).Error("failed to store api token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		resp := model.NewAPITokenResponse(token)
+		resp.Token = plaintext
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Error("failed to encode api token response")
+		}
+	}
+}
+
+func ListAPITokensHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing api tokens")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tokens, err := getAPITokenStore().ListAPITokens(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to list api tokens")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.APITokenResponse, 0, len(tokens))
+		for i := range tokens {
+			responses = append(responses, model.NewAPITokenResponse(&tokens[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode api token list response")
+		}
+	}
+}
+
+func RevokeAPITokenHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while revoking api token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid token id", http.StatusBadRequest)
+			return
+		}
+
+		if err := getAPITokenStore().RevokeAPIToken(user.ID, uint(id), time.Now()); err != nil {
+			if errors.Is(err, ErrAPITokenNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+			logger.WithError(err).Error("failed to revoke api token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -1,128 +1,901 @@
-package auth
+package auth_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"vsC1Y2025V01/pkg/mailer"
+	"vsC1Y2025V01/pkg/totp"
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/auth/authtest"
+	"vsC1Y2025V01/src/credentials"
 	"vsC1Y2025V01/src/model"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 )
 
-type inMemoryUserRepository struct {
+func TestRegisterAndLogin(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	authtest.UseInMemoryStores(t)
+
+	// Setup test user payload
+	payload := map[string]string{
+		"username": "testuser",
+		"password": "password123",
+	}
+	body, _ := json.Marshal(payload)
+
+	// Test registration
+	req := httptest.NewRequest("POST", "/auth/register", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler := auth.RegisterHandler(logger)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+
+	// Test login
+	req = httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	auth.LoginHandler(logger).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func sessionCookies(t *testing.T, rec *httptest.ResponseRecorder) (access, refresh *http.Cookie) {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		switch cookie.Name {
+		case auth.AccessCookieName:
+			access = cookie
+		case auth.RefreshCookieName:
+			refresh = cookie
+		}
+	}
+	if access == nil || refresh == nil {
+		t.Fatalf("expected access and refresh cookies, got %v", rec.Result().Cookies())
+	}
+	return access, refresh
+}
+
+func newSessionTestRouter(t *testing.T) (http.Handler, func() *httptest.ResponseRecorder) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	authtest.UseInMemoryStores(t)
+
+	router := chi.NewRouter()
+	router.Post("/auth/register", auth.RegisterHandler(logger))
+	router.Post("/auth/login", auth.LoginHandler(logger))
+	router.Post("/auth/refresh", auth.RefreshHandler(logger))
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuthMiddleware(logger))
+		r.Get("/me", auth.MeHandler(logger))
+		r.Get("/logout", auth.LogoutHandler(logger))
+		r.Get("/me/sessions", auth.ListSessionsHandler(logger))
+		r.Delete("/me/sessions", auth.RevokeAllSessionsHandler(logger))
+		r.Delete("/me/sessions/{id}", auth.RevokeSessionHandler(logger))
+	})
+
+	body := `{"username":"alice","password":"password123"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+
+	login := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected login 200, got %d", rec.Code)
+		}
+		return rec
+	}
+	return router, login
+}
+
+func send(router http.Handler, method, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRefreshTokenRotationAndReuse(t *testing.T) {
+	router, login := newSessionTestRouter(t)
+
+	access, refresh := sessionCookies(t, login())
+	if rec := send(router, http.MethodGet, "/me", access); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the access token, got %d", rec.Code)
+	}
+
+	rec := send(router, http.MethodPost, "/auth/refresh", refresh)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected refresh 200, got %d", rec.Code)
+	}
+	newAccess, newRefresh := sessionCookies(t, rec)
+	if newRefresh.Value == refresh.Value {
+		t.Fatal("expected the refresh token to rotate")
+	}
+
+	rec = send(router, http.MethodGet, "/me/sessions", newAccess)
+	var sessions []model.SessionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&sessions); err != nil {
+		t.Fatalf("decode sessions: %v", err)
+	}
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("expected the current session, got %+v", sessions)
+	}
+
+	// Replaying the first refresh token revokes the session it belongs to.
+	if rec := send(router, http.MethodPost, "/auth/refresh", refresh); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a reused refresh token, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodGet, "/me", newAccess); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the access token of a revoked session to be rejected, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodPost, "/auth/refresh", newRefresh); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the latest refresh token of a revoked session to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	router, login := newSessionTestRouter(t)
+
+	phone, _ := sessionCookies(t, login())
+	laptop, _ := sessionCookies(t, login())
+	tablet, _ := sessionCookies(t, login())
+
+	// Session IDs follow login order in the in-memory store: 1, 3, 5.
+	if rec := send(router, http.MethodDelete, "/me/sessions/1", laptop); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodGet, "/me", phone); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session to be rejected, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodDelete, "/me/sessions/1", laptop); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an already revoked session, got %d", rec.Code)
+	}
+
+	if rec := send(router, http.MethodGet, "/logout", tablet); rec.Code != http.StatusOK {
+		t.Fatalf("expected logout 200, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodGet, "/me", tablet); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected logout to revoke the session, got %d", rec.Code)
+	}
+
+	if rec := send(router, http.MethodDelete, "/me/sessions", laptop); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := send(router, http.MethodGet, "/me", laptop); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected every session to be revoked, got %d", rec.Code)
+	}
+}
+
+type inMemoryAPITokenStore struct {
 	mu     sync.Mutex
-	nextID uint
-	users  map[uint]*model.User
+	tokens []*model.APIToken
+}
+
+func (s *inMemoryAPITokenStore) CreateAPIToken(token *model.APIToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token.ID = uint(len(s.tokens) + 1)
+	token.CreatedAt = time.Now()
+	clone := *token
+	s.tokens = append(s.tokens, &clone)
+	return nil
+}
+
+func (s *inMemoryAPITokenStore) ListAPITokens(userID uint) ([]model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []model.APIToken
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			result = append(result, *token)
+		}
+	}
+	return result, nil
 }
 
-func newInMemoryUserRepository() *inMemoryUserRepository {
-	return &inMemoryUserRepository{users: make(map[uint]*model.User)}
+func (s *inMemoryAPITokenStore) FindAPITokenByHash(hash string) (*model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == hash {
+			clone := *token
+			return &clone, nil
+		}
+	}
+	return nil, auth.ErrAPITokenNotFound
 }
 
-func (r *inMemoryUserRepository) Create(user *model.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (s *inMemoryAPITokenStore) RevokeAPIToken(userID, id uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.ID == id && token.UserID == userID {
+			if token.RevokedAt == nil {
+				token.RevokedAt = &at
+			}
+			return nil
+		}
+	}
+	return auth.ErrAPITokenNotFound
+}
+
+func (s *inMemoryAPITokenStore) TouchAPIToken(id uint, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.ID == id {
+			token.LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	users := authtest.NewUserRepository()
+	auth.SetUserRepository(users)
+	tokens := &inMemoryAPITokenStore{}
+	auth.SetAPITokenStore(tokens)
+	t.Cleanup(func() {
+		auth.SetUserRepository(nil)
+		auth.SetAPITokenStore(nil)
+	})
+
+	user := &model.User{Username: "bot-owner"}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router := chi.NewRouter()
+	router.With(auth.RequireScope(logger, model.ScopeTradesRead)).Get("/trades", ok)
+	router.With(auth.RequireScope(logger, model.ScopeTradesWrite)).Post("/trades", ok)
+	router.With(auth.RequireAuthMiddleware(logger)).Get("/me/tokens", ok)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/me/tokens", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, user))
+		rec := httptest.NewRecorder()
+		auth.CreateAPITokenHandler(logger).ServeHTTP(rec, req)
+		return rec
+	}
+	bearer := func(method, target, token string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, body := range []string{
+		`{"name":"bot","scopes":[]}`,
+		`{"name":"bot","scopes":["admin"]}`,
+		`{"name":"","scopes":["trades:read"]}`,
+		`{"name":"bot","scopes":["trades:read"],"expiresAt":"2001-01-01T00:00:00Z"}`,
+	} {
+		if rec := create(body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := create(`{"name":"notebook","scopes":["trades:read","TRADES:READ"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.APITokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	if !strings.HasPrefix(created.Token, auth.APITokenPrefix) || len(created.Scopes) != 1 {
+		t.Fatalf("unexpected token response: %+v", created)
+	}
+	if tokens.tokens[0].TokenHash == created.Token {
+		t.Fatal("the plaintext token was stored")
+	}
+
+	if code := bearer(http.MethodGet, "/trades", created.Token); code != http.StatusOK {
+		t.Fatalf("expected 200 for a granted scope, got %d", code)
+	}
+	if tokens.tokens[0].LastUsedAt == nil {
+		t.Fatal("expected the token's last use to be recorded")
+	}
+	if code := bearer(http.MethodPost, "/trades", created.Token); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a missing scope, got %d", code)
+	}
+	if code := bearer(http.MethodGet, "/me/tokens", created.Token); code != http.StatusUnauthorized {
+		t.Fatalf("expected session-only routes to reject API tokens, got %d", code)
+	}
+	if code := bearer(http.MethodGet, "/trades", created.Token+"x"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown token, got %d", code)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	tokens.tokens[0].ExpiresAt = &expired
+	if code := bearer(http.MethodGet, "/trades", created.Token); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an expired token, got %d", code)
+	}
+	tokens.tokens[0].ExpiresAt = nil
+
+	if err := tokens.RevokeAPIToken(user.ID, created.ID, time.Now()); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if code := bearer(http.MethodGet, "/trades", created.Token); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a revoked token, got %d", code)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	k, err := credentials.NewKeyring(1, map[int][]byte{1: bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	auth.SetKeyring(k)
+	users := authtest.NewUserRepository()
+	auth.SetUserRepository(users)
+	auth.SetSessionStore(authtest.NewSessionStore())
+	twoFactor := authtest.NewTwoFactorStore()
+	auth.SetTwoFactorStore(twoFactor)
+	t.Cleanup(func() {
+		auth.SetKeyring(nil)
+		auth.SetUserRepository(nil)
+		auth.SetSessionStore(nil)
+		auth.SetTwoFactorStore(nil)
+	})
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &model.User{Username: "alice", Password: string(hash)}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	call := func(handler http.HandlerFunc, body any) *httptest.ResponseRecorder {
+		encoded, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, user))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(auth.EnrollTwoFactorHandler(logger), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected enroll 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var enrollment model.TwoFactorEnrollmentResponse
+	json.NewDecoder(rec.Body).Decode(&enrollment)
+	if !strings.HasPrefix(enrollment.OTPAuthURI, "otpauth://totp/") || !strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,") {
+		t.Fatalf("unexpected enrollment: %+v", enrollment)
+	}
+	if stored, err := twoFactor.FindTwoFactor(user.ID); err != nil || stored.Secret == enrollment.Secret || stored.Secret == "" {
+		t.Fatal("expected the secret to be stored sealed")
+	}
+
+	// Password-only login still works until the first code is verified.
+	credentialsBody := map[string]string{"username": "alice", "password": "password123"}
+	if rec := call(auth.LoginHandler(logger), credentialsBody); len(rec.Result().Cookies()) == 0 {
+		t.Fatalf("expected a session before verification, got %s", rec.Body.String())
+	}
 
-	r.nextID++
 	now := time.Now()
-	clone := *user
-	clone.ID = r.nextID
-	clone.CreatedAt = now
-	clone.UpdatedAt = now
-	r.users[clone.ID] = &clone
-
-	user.ID = clone.ID
-	user.CreatedAt = clone.CreatedAt
-	user.UpdatedAt = clone.UpdatedAt
+	code, _ := totp.Code(enrollment.Secret, now)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if rec := call(auth.VerifyTwoFactorHandler(logger), map[string]string{"code": wrong}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong code, got %d", rec.Code)
+	}
+	rec = call(auth.VerifyTwoFactorHandler(logger), map[string]string{"code": code})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected verify 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recovery model.RecoveryCodesResponse
+	json.NewDecoder(rec.Body).Decode(&recovery)
+	if len(recovery.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %v", auth.RecoveryCodeCount, recovery.RecoveryCodes)
+	}
+
+	rec = call(auth.LoginHandler(logger), credentialsBody)
+	var challenge struct {
+		Status    string `json:"status"`
+		Challenge string `json:"challenge"`
+	}
+	json.NewDecoder(rec.Body).Decode(&challenge)
+	if rec.Code != http.StatusOK || challenge.Status != "2fa_required" || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected a challenge and no cookies, got %d %+v", rec.Code, challenge)
+	}
+	if _, _, err := auth.ParseToken(challenge.Challenge); err == nil {
+		t.Fatal("the challenge must not work as an access token")
+	}
+
+	if rec := call(auth.LoginTwoFactorHandler(logger), map[string]string{"challenge": challenge.Challenge, "code": code}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a used code to be refused, got %d", rec.Code)
+	}
+	next, _ := totp.Code(enrollment.Secret, now.Add(totp.Period))
+	rec = call(auth.LoginTwoFactorHandler(logger), map[string]string{"challenge": challenge.Challenge, "code": next})
+	if rec.Code != http.StatusOK || len(rec.Result().Cookies()) == 0 {
+		t.Fatalf("expected a session after the second factor, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = call(auth.LoginTwoFactorHandler(logger), map[string]string{"challenge": challenge.Challenge, "recoveryCode": strings.ToUpper(recovery.RecoveryCodes[0])})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a recovery code to work, got %d", rec.Code)
+	}
+	if rec := call(auth.LoginTwoFactorHandler(logger), map[string]string{"challenge": challenge.Challenge, "recoveryCode": recovery.RecoveryCodes[0]}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a recovery code to work once, got %d", rec.Code)
+	}
+
+	disable := auth.DisableTwoFactorHandler(logger)
+	if rec := call(disable, map[string]string{"password": "wrong", "recoveryCode": recovery.RecoveryCodes[1]}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without the password, got %d", rec.Code)
+	}
+	if rec := call(disable, map[string]string{"password": "password123"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without a second factor, got %d", rec.Code)
+	}
+	if rec := call(disable, map[string]string{"password": "password123", "recoveryCode": recovery.RecoveryCodes[1]}); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if _, err := twoFactor.FindTwoFactor(user.ID); !errors.Is(err, auth.ErrTwoFactorNotFound) {
+		t.Fatal("expected two-factor authentication to be removed")
+	}
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	k, _ := credentials.NewKeyring(1, map[int][]byte{1: bytes.Repeat([]byte{7}, 32)})
+	auth.SetKeyring(k)
+	store := authtest.NewTwoFactorStore()
+	auth.SetTwoFactorStore(store)
+	t.Cleanup(func() {
+		auth.SetKeyring(nil)
+		auth.SetTwoFactorStore(nil)
+	})
+
+	secret, _ := totp.GenerateSecret()
+	tf := &model.TwoFactor{UserID: 1}
+	if err := auth.SealTOTPSecret(tf, secret); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	store.SaveTwoFactor(tf)
+
+	for i := 0; i < auth.MaxTwoFactorAttempts; i++ {
+		if err := auth.VerifySecondFactor(tf, "", "nope"); !errors.Is(err, auth.ErrInvalidSecondFactor) {
+			t.Fatalf("attempt %d: expected an invalid code, got %v", i, err)
+		}
+	}
+
+	code, _ := totp.Code(secret, time.Now())
+	if err := auth.VerifySecondFactor(tf, code, ""); !errors.Is(err, auth.ErrTwoFactorLocked) {
+		t.Fatalf("expected the second factor to be locked, got %v", err)
+	}
+	if stored, err := store.FindTwoFactor(1); err != nil || stored.LockedUntil == nil {
+		t.Fatal("expected the lock to be stored")
+	}
+}
+
+type inMemoryUserTokenStore struct {
+	mu     sync.Mutex
+	nextID uint
+	tokens []*model.UserToken
+}
+
+func (s *inMemoryUserTokenStore) CreateUserToken(token *model.UserToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	token.ID = s.nextID
+	token.CreatedAt = time.Now()
+	clone := *token
+	s.tokens = append(s.tokens, &clone)
 	return nil
 }
 
-func (r *inMemoryUserRepository) FindByUsername(username string) (*model.User, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (s *inMemoryUserTokenStore) FindUserToken(purpose, hash string, at time.Time) (*model.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, user := range r.users {
-		if user.Username == username {
-			clone := *user
+	for _, token := range s.tokens {
+		if token.Purpose == purpose && token.TokenHash == hash && token.UsedAt == nil && token.ExpiresAt.After(at) {
+			clone := *token
 			return &clone, nil
 		}
 	}
+	return nil, auth.ErrUserTokenInvalid
+}
+
+func (s *inMemoryUserTokenStore) ConsumeUserToken(purpose, hash string, at time.Time) (*model.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	return nil, ErrUserNotFound
+	for _, token := range s.tokens {
+		if token.Purpose == purpose && token.TokenHash == hash && token.UsedAt == nil && token.ExpiresAt.After(at) {
+			token.UsedAt = &at
+			clone := *token
+			return &clone, nil
+		}
+	}
+	return nil, auth.ErrUserTokenInvalid
 }
 
-func (r *inMemoryUserRepository) FindByID(id uint) (*model.User, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func (s *inMemoryUserTokenStore) DeleteUserTokens(userID uint, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.tokens[:0]
+	for _, token := range s.tokens {
+		if token.UserID != userID || token.Purpose != purpose || token.UsedAt != nil {
+			kept = append(kept, token)
+		}
+	}
+	s.tokens = kept
+	return nil
+}
 
-	user, ok := r.users[id]
+// mailedToken waits for the nth mail and returns the token in its link.
+func mailedToken(t *testing.T, m *mailer.Memory, n int) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(m.Messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d mails, got %d", n, len(m.Messages()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	body := m.Messages()[n-1].Body
+	_, rest, ok := strings.Cut(body, "?token=")
 	if !ok {
-		return nil, ErrUserNotFound
+		t.Fatalf("no token in mail %q", body)
 	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return token
+}
+
+func newAccountMailTestRouter(t *testing.T) (http.Handler, *authtest.UserRepository, *mailer.Memory) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	users := authtest.NewUserRepository()
+	auth.SetUserRepository(users)
+	auth.SetSessionStore(authtest.NewSessionStore())
+	auth.SetTwoFactorStore(authtest.NewTwoFactorStore())
+	auth.SetUserTokenStore(&inMemoryUserTokenStore{})
+	outbox := mailer.NewMemory()
+	auth.SetMailer(outbox)
+	auth.ResetLimits()
+	t.Cleanup(func() {
+		auth.SetUserRepository(nil)
+		auth.SetSessionStore(nil)
+		auth.SetTwoFactorStore(nil)
+		auth.SetUserTokenStore(nil)
+		auth.SetMailer(nil)
+	})
 
-	clone := *user
-	return &clone, nil
+	router := chi.NewRouter()
+	router.Post("/auth/register", auth.RegisterHandler(logger))
+	router.Post("/auth/login", auth.LoginHandler(logger))
+	router.Post("/auth/verify-email", auth.VerifyEmailHandler(logger))
+	router.Post("/auth/forgot-password", auth.ForgotPasswordHandler(logger))
+	router.Post("/auth/reset-password", auth.ResetPasswordHandler(logger))
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuthMiddleware(logger))
+		r.Get("/me", auth.MeHandler(logger))
+		r.Post("/me/email/verification", auth.SendVerificationEmailHandler(logger))
+	})
+	return router, users, outbox
 }
 
-func (r *inMemoryUserRepository) Update(user *model.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+func post(router http.Handler, target, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
 
-	stored, ok := r.users[user.ID]
-	if !ok {
-		return ErrUserNotFound
+func TestEmailVerification(t *testing.T) {
+	router, users, outbox := newAccountMailTestRouter(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &model.User{Username: "alice", Password: string(hash), Email: "alice@example.com"}
+	users.Create(user)
+
+	rec := post(router, "/auth/login", `{"username":"alice","password":"password123"}`, nil)
+	access, _ := sessionCookies(t, rec)
+
+	if rec := post(router, "/me/email/verification", "", access); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	first := mailedToken(t, outbox, 1)
+	if got := outbox.Messages()[0].To; got != "alice@example.com" {
+		t.Fatalf("expected mail to alice@example.com, got %s", got)
 	}
 
-	if user.UpdatedAt.IsZero() {
-		user.UpdatedAt = time.Now()
+	// Asking again replaces the earlier link.
+	post(router, "/me/email/verification", "", access)
+	second := mailedToken(t, outbox, 2)
+	if rec := post(router, "/auth/verify-email", `{"token":"`+first+`"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected the replaced token to be refused, got %d", rec.Code)
 	}
 
-	stored.Username = user.Username
-	stored.Password = user.Password
-	stored.CreatedAt = user.CreatedAt
-	stored.UpdatedAt = user.UpdatedAt
-	stored.LastLogin = user.LastLogin
-	stored.LastSeen = user.LastSeen
-	return nil
+	if rec := post(router, "/auth/verify-email", `{"token":"`+second+`"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(router, "/auth/verify-email", `{"token":"`+second+`"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a used token to be refused, got %d", rec.Code)
+	}
+
+	var me model.UserResponse
+	json.NewDecoder(send(router, http.MethodGet, "/me", access).Body).Decode(&me)
+	if !me.EmailVerified {
+		t.Fatal("expected the email to be verified")
+	}
+	if rec := post(router, "/me/email/verification", "", access); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a verified address, got %d", rec.Code)
+	}
+
+	// A link for an address the user has since changed is refused.
+	bob := &model.User{Username: "bob", Password: string(hash), Email: "bob@example.com"}
+	users.Create(bob)
+	token, err := auth.IssueUserToken(bob, model.UserTokenVerifyEmail, time.Hour)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	bob.Email = "bob@other.example.com"
+	users.Update(bob)
+	if rec := post(router, "/auth/verify-email", `{"token":"`+token+`"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a token for an old address to be refused, got %d", rec.Code)
+	}
 }
 
-func TestRegisterAndLogin(t *testing.T) {
+func TestPasswordReset(t *testing.T) {
+	router, users, outbox := newAccountMailTestRouter(t)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	verifiedAt := time.Now()
+	user := &model.User{Username: "alice", Password: string(hash), Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+	users.Create(user)
+	users.Create(&model.User{Username: "mallory", Password: string(hash), Email: "mallory@example.com"})
+
+	access, _ := sessionCookies(t, post(router, "/auth/login", `{"username":"alice","password":"password123"}`, nil))
+
+	// Unknown and unverified addresses get the same answer and no mail.
+	for _, email := range []string{"nobody@example.com", "mallory@example.com"} {
+		if rec := post(router, "/auth/forgot-password", `{"email":"`+email+`"}`, nil); rec.Code != http.StatusAccepted {
+			t.Fatalf("expected 202 for %s, got %d", email, rec.Code)
+		}
+	}
+	if rec := post(router, "/auth/forgot-password", `{"email":"Alice@Example.com"}`, nil); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	token := mailedToken(t, outbox, 1)
+	time.Sleep(20 * time.Millisecond)
+	if n := len(outbox.Messages()); n != 1 {
+		t.Fatalf("expected a single mail, got %d", n)
+	}
+
+	if rec := post(router, "/auth/reset-password", `{"token":"`+token+`","password":"short"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a short password to be refused, got %d", rec.Code)
+	}
+	if rec := post(router, "/auth/reset-password", `{"token":"`+token+`","password":"my-alice-password"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a password containing the username to be refused, got %d", rec.Code)
+	}
+	if rec := post(router, "/auth/reset-password", `{"token":"`+token+`","password":"new-password-456"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(router, "/auth/reset-password", `{"token":"`+token+`","password":"another-password"}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected the token to work once, got %d", rec.Code)
+	}
+
+	if rec := send(router, http.MethodGet, "/me", access); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the old session to be revoked, got %d", rec.Code)
+	}
+	if rec := post(router, "/auth/login", `{"username":"alice","password":"password123"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the old password to fail, got %d", rec.Code)
+	}
+	if rec := post(router, "/auth/login", `{"username":"alice","password":"new-password-456"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new password to work, got %d", rec.Code)
+	}
+
+	// Three mails per address and hour.
+	for i := 0; i < 2; i++ {
+		post(router, "/auth/forgot-password", `{"email":"alice@example.com"}`, nil)
+	}
+	rec := post(router, "/auth/forgot-password", `{"email":"alice@example.com"}`, nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", rec.Code)
+	}
+}
+
+func TestRegistrationPolicy(t *testing.T) {
 	logger := logrus.NewEntry(logrus.StandardLogger())
+	auth.SetUserRepository(authtest.NewUserRepository())
+	auth.ResetLimits()
+	t.Cleanup(func() {
+		auth.SetUserRepository(nil)
+	})
+
+	register := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		auth.RegisterHandler(logger).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+		return rec
+	}
 
-	repo := newInMemoryUserRepository()
-	SetUserRepository(repo)
+	cases := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"empty", `{"username":"","password":""}`, http.StatusBadRequest},
+		{"short username", `{"username":"al","password":"password123"}`, http.StatusBadRequest},
+		{"invalid characters", `{"username":"al ice","password":"password123"}`, http.StatusBadRequest},
+		{"short password", `{"username":"alice","password":"pass"}`, http.StatusBadRequest},
+		{"password contains username", `{"username":"alice","password":"Alice2025!"}`, http.StatusBadRequest},
+		{"valid", `{"username":" alice ","password":"password123"}`, http.StatusCreated},
+		{"taken", `{"username":"ALICE","password":"password123"}`, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		if rec := register(tc.body); rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	t.Setenv("REGISTRATION_INVITE_CODES", "team-code-1, team-code-2")
+	if rec := register(`{"username":"bob","password":"password123"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without an invite code, got %d", rec.Code)
+	}
+	if rec := register(`{"username":"bob","password":"password123","inviteCode":"wrong"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a wrong invite code, got %d", rec.Code)
+	}
+	if rec := register(`{"username":"bob","password":"password123","inviteCode":"team-code-2"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with an invite code, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoginLockoutAndAudit(t *testing.T) {
+	router, login := newSessionTestRouter(t)
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	router.(*chi.Mux).With(auth.RequireAuthMiddleware(logger)).Get("/me/login-attempts", auth.ListLoginAttemptsHandler(logger))
+
+	audit := &authtest.LoginAttemptStore{}
+	auth.SetLoginAttemptStore(audit)
+	auth.ResetLimits()
 	t.Cleanup(func() {
-		SetUserRepository(nil)
+		auth.SetLoginAttemptStore(nil)
+		auth.ResetLimits()
 	})
 
-	// Setup test user payload
-	payload := map[string]string{
-		"username": "testuser",
-		"password": "password123",
+	wrong := `{"username":"alice","password":"wrong-password"}`
+	for i := 0; i < 5; i++ {
+		if rec := post(router, "/auth/login", wrong, nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
 	}
-	body, _ := json.Marshal(payload)
 
-	// Test registration
-	req := httptest.NewRequest("POST", "/auth/register", bytes.NewReader(body))
-	rec := httptest.NewRecorder()
-	handler := RegisterHandler(logger)
-	handler.ServeHTTP(rec, req)
+	// Locked out now, even with the right password.
+	rec := post(router, "/auth/login", `{"username":"alice","password":"password123"}`, nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After 60, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
 
-	if rec.Code != http.StatusCreated {
-		t.Fatalf("expected 201, got %d", rec.Code)
+	// Other usernames from the same IP are not locked yet.
+	if rec := post(router, "/auth/login", `{"username":"nobody","password":"password123"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for another username, got %d", rec.Code)
 	}
 
-	// Test login
-	req = httptest.NewRequest("POST", "/auth/login", bytes.NewReader(body))
-	rec = httptest.NewRecorder()
-	LoginHandler(logger).ServeHTTP(rec, req)
+	auth.ResetUsernameLockout("alice")
+	access, _ := sessionCookies(t, login())
 
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d", rec.Code)
+	rec = send(router, http.MethodGet, "/me/login-attempts", access)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != "7" {
+		t.Fatalf("expected 7 attempts, got %d with total %q", rec.Code, rec.Header().Get("X-Total-Count"))
+	}
+	var attempts []model.LoginAttemptResponse
+	json.NewDecoder(rec.Body).Decode(&attempts)
+	if !attempts[0].Success || attempts[1].FailureReason != model.LoginFailureLockedOut || attempts[2].FailureReason != model.LoginFailureInvalidPassword {
+		t.Fatalf("unexpected attempts %+v", attempts[:3])
+	}
+
+	rec = send(router, http.MethodGet, "/me/login-attempts?range=[0,1]", access)
+	json.NewDecoder(rec.Body).Decode(&attempts)
+	if len(attempts) != 2 {
+		t.Fatalf("expected a page of 2, got %d", len(attempts))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	request := func(remoteAddr string, forwarded ...string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for _, f := range forwarded {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		return req
+	}
+
+	// Without trusted proxies the header is ignored.
+	if got := auth.ClientIP(request("203.0.113.7:4000", "198.51.100.1")); got != "203.0.113.7" {
+		t.Fatalf("expected the remote address, got %s", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.10")
+	cases := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{"untrusted peer", request("203.0.113.7:4000", "198.51.100.1"), "203.0.113.7"},
+		{"trusted proxy", request("10.1.2.3:4000", "198.51.100.1"), "198.51.100.1"},
+		{"spoofed left entries", request("10.1.2.3:4000", "1.1.1.1, 198.51.100.1"), "198.51.100.1"},
+		{"proxy chain", request("10.1.2.3:4000", "198.51.100.1, 192.0.2.10", "10.9.9.9"), "198.51.100.1"},
+		{"no header", request("10.1.2.3:4000"), "10.1.2.3"},
+	}
+	for _, tc := range cases {
+		if got := auth.ClientIP(tc.req); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestLoginIPLockoutIgnoresForwardedFor(t *testing.T) {
+	router, _ := newSessionTestRouter(t)
+	auth.SetLoginAttemptStore(&authtest.LoginAttemptStore{})
+	auth.ResetLimits()
+	t.Cleanup(func() {
+		auth.SetLoginAttemptStore(nil)
+		auth.ResetLimits()
+	})
+
+	attempt := func(i int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":"user%d","password":"wrong-password"}`, i)
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Different usernames stay below the username lockout, and the rotating
+	// header must not spread the failures over several IPs.
+	for i := 0; i < 20; i++ {
+		if rec := attempt(i); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := attempt(20); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the IP to be locked out, got %d", rec.Code)
 	}
 }
@@ -0,0 +1,436 @@
+// Package authtest provides in-memory auth stores and a login helper for
+// tests of handlers behind auth.RequireAuthMiddleware. Only tests import it.
+package authtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+)
+
+// Stores are the in-memory stores installed by UseInMemoryStores.
+type Stores struct {
+	Users         *UserRepository
+	Sessions      *SessionStore
+	TwoFactor     *TwoFactorStore
+	LoginAttempts *LoginAttemptStore
+}
+
+// UseInMemoryStores replaces the user, session, two-factor and login attempt
+// stores with in-memory ones until t finishes.
+func UseInMemoryStores(t testing.TB) *Stores {
+	t.Helper()
+
+	stores := &Stores{
+		Users:         NewUserRepository(),
+		Sessions:      NewSessionStore(),
+		TwoFactor:     NewTwoFactorStore(),
+		LoginAttempts: &LoginAttemptStore{},
+	}
+	auth.SetUserRepository(stores.Users)
+	auth.SetSessionStore(stores.Sessions)
+	auth.SetTwoFactorStore(stores.TwoFactor)
+	auth.SetLoginAttemptStore(stores.LoginAttempts)
+	t.Cleanup(func() {
+		auth.SetUserRepository(nil)
+		auth.SetSessionStore(nil)
+		auth.SetTwoFactorStore(nil)
+		auth.SetLoginAttemptStore(nil)
+	})
+	return stores
+}
+
+// Login stores user, opens a session for it and returns the access token
+// cookie to send with requests.
+func (s *Stores) Login(t testing.TB, user *model.User) *http.Cookie {
+	t.Helper()
+
+	if err := s.Users.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("failed to generate refresh token: %v", err)
+	}
+	now := time.Now()
+	session := &model.Session{UserID: user.ID, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := s.Sessions.CreateSession(session, &model.RefreshToken{TokenHash: hex.EncodeToString(secret)}); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	token, err := auth.GenerateToken(user.ID, session.ID)
+	if err != nil {
+		t.Fatalf("failed to generate access token: %v", err)
+	}
+	return &http.Cookie{Name: auth.AccessCookieName, Value: token}
+}
+
+// UserRepository is an in-memory auth.UserRepository.
+type UserRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	users  map[uint]*model.User
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[uint]*model.User)}
+}
+
+func (r *UserRepository) Create(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			return auth.ErrUsernameTaken
+		}
+	}
+
+	r.nextID++
+	now := time.Now()
+	clone := *user
+	clone.ID = r.nextID
+	clone.CreatedAt = now
+	clone.UpdatedAt = now
+	r.users[clone.ID] = &clone
+
+	user.ID = clone.ID
+	user.CreatedAt = clone.CreatedAt
+	user.UpdatedAt = clone.UpdatedAt
+	return nil
+}
+
+func (r *UserRepository) FindByUsername(username string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			clone := *user
+			return &clone, nil
+		}
+	}
+
+	return nil, auth.ErrUserNotFound
+}
+
+func (r *UserRepository) FindByID(id uint) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, auth.ErrUserNotFound
+	}
+
+	clone := *user
+	return &clone, nil
+}
+
+func (r *UserRepository) FindByVerifiedEmail(email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.EmailVerifiedAt != nil && strings.EqualFold(user.Email, email) {
+			clone := *user
+			return &clone, nil
+		}
+	}
+
+	return nil, auth.ErrUserNotFound
+}
+
+func (r *UserRepository) Update(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return auth.ErrUserNotFound
+	}
+
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = time.Now()
+	}
+
+	stored.Username = user.Username
+	stored.Password = user.Password
+	stored.Email = user.Email
+	stored.EmailVerifiedAt = user.EmailVerifiedAt
+	stored.CreatedAt = user.CreatedAt
+	stored.UpdatedAt = user.UpdatedAt
+	stored.LastLogin = user.LastLogin
+	stored.LastSeen = user.LastSeen
+	return nil
+}
+
+// SessionStore is an in-memory auth.SessionStore.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[uint]*model.Session
+	tokens   map[uint]*model.RefreshToken
+	nextID   uint
+}
+
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[uint]*model.Session), tokens: make(map[uint]*model.RefreshToken)}
+}
+
+func (s *SessionStore) CreateSession(session *model.Session, token *model.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	session.ID = s.nextID
+	clone := *session
+	s.sessions[session.ID] = &clone
+
+	s.nextID++
+	token.ID = s.nextID
+	token.SessionID = session.ID
+	stored := *token
+	s.tokens[token.ID] = &stored
+	return nil
+}
+
+func (s *SessionStore) FindSession(id uint) (*model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, auth.ErrSessionNotFound
+	}
+	clone := *session
+	return &clone, nil
+}
+
+func (s *SessionStore) FindRefreshToken(hash string) (*model.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == hash {
+			clone := *token
+			session := *s.sessions[token.SessionID]
+			clone.Session = &session
+			return &clone, nil
+		}
+	}
+	return nil, auth.ErrRefreshTokenNotFound
+}
+
+func (s *SessionStore) RotateRefreshToken(usedID uint, next *model.RefreshToken, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, ok := s.tokens[usedID]
+	if !ok || used.UsedAt != nil {
+		return auth.ErrRefreshTokenReused
+	}
+	used.UsedAt = &at
+
+	s.nextID++
+	next.ID = s.nextID
+	stored := *next
+	s.tokens[next.ID] = &stored
+	s.sessions[next.SessionID].LastUsedAt = at
+	return nil
+}
+
+func (s *SessionStore) TouchSession(id uint, ip string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[id]; ok {
+		session.IP = ip
+		session.LastUsedAt = at
+	}
+	return nil
+}
+
+func (s *SessionStore) ListActiveSessions(userID uint, now time.Time) ([]model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []model.Session
+	for id := uint(1); id <= s.nextID; id++ {
+		if session, ok := s.sessions[id]; ok && session.UserID == userID && session.Active(now) {
+			result = append(result, *session)
+		}
+	}
+	return result, nil
+}
+
+func (s *SessionStore) RevokeSession(userID, id uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return auth.ErrSessionNotFound
+	}
+	session.RevokedAt = &at
+	return nil
+}
+
+func (s *SessionStore) RevokeAllSessions(userID uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// TwoFactorStore is an in-memory auth.TwoFactorStore.
+type TwoFactorStore struct {
+	mu    sync.Mutex
+	rows  map[uint]*model.TwoFactor
+	codes []*model.RecoveryCode
+}
+
+func NewTwoFactorStore() *TwoFactorStore {
+	return &TwoFactorStore{rows: make(map[uint]*model.TwoFactor)}
+}
+
+func (s *TwoFactorStore) FindTwoFactor(userID uint) (*model.TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tf, ok := s.rows[userID]
+	if !ok {
+		return nil, auth.ErrTwoFactorNotFound
+	}
+	clone := *tf
+	return &clone, nil
+}
+
+func (s *TwoFactorStore) SaveTwoFactor(tf *model.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := *tf
+	s.rows[tf.UserID] = &clone
+	return nil
+}
+
+func (s *TwoFactorStore) DeleteTwoFactor(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rows, userID)
+	var kept []*model.RecoveryCode
+	for _, code := range s.codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	s.codes = kept
+	return nil
+}
+
+func (s *TwoFactorStore) AdvanceCounter(userID uint, counter int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tf, ok := s.rows[userID]
+	if !ok || tf.LastCounter >= counter {
+		return auth.ErrTOTPCodeReused
+	}
+	tf.LastCounter = counter
+	return nil
+}
+
+func (s *TwoFactorStore) ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []*model.RecoveryCode
+	for _, code := range s.codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	for i := range codes {
+		code := codes[i]
+		kept = append(kept, &code)
+	}
+	s.codes = kept
+	return nil
+}
+
+func (s *TwoFactorStore) UseRecoveryCode(userID uint, hash string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range s.codes {
+		if code.UserID == userID && code.CodeHash == hash && code.UsedAt == nil {
+			code.UsedAt = &at
+			return nil
+		}
+	}
+	return auth.ErrRecoveryCodeNotFound
+}
+
+func (s *TwoFactorStore) CountRecoveryCodes(userID uint) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, code := range s.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LoginAttemptStore is an in-memory auth.LoginAttemptStore. The zero value
+// is ready to use.
+type LoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts []model.LoginAttempt
+}
+
+func (s *LoginAttemptStore) RecordLoginAttempt(attempt *model.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt.ID = uint(len(s.attempts) + 1)
+	s.attempts = append(s.attempts, *attempt)
+	return nil
+}
+
+func (s *LoginAttemptStore) ListLoginAttempts(userID uint, offset, limit int) ([]model.LoginAttempt, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matching []model.LoginAttempt
+	for i := len(s.attempts) - 1; i >= 0; i-- {
+		if s.attempts[i].UserID == userID {
+			matching = append(matching, s.attempts[i])
+		}
+	}
+	total := int64(len(matching))
+	if offset >= len(matching) {
+		return nil, total, nil
+	}
+	matching = matching[offset:]
+	if limit >= 0 && limit < len(matching) {
+		matching = matching[:limit]
+	}
+	return matching, total, nil
+}
@@ -7,9 +7,29 @@ import (
 
 type contextKey string
 
-const UserKey contextKey = "user"
+const (
+	UserKey    contextKey = "user"
+	SessionKey contextKey = "session"
+	// APITokenKey is set instead of SessionKey for requests authenticated
+	// with a personal API token.
+	APITokenKey contextKey = "api_token"
+)
 
 func GetUserFromContext(ctx context.Context) (*model.User, bool) {
 	user, ok := ctx.Value(UserKey).(*model.User)
 	return user, ok
 }
+
+// GetSessionIDFromContext returns the session the request was authenticated
+// with.
+func GetSessionIDFromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(SessionKey).(uint)
+	return id, ok
+}
+
+// GetAPITokenFromContext returns the personal API token the request was
+// authenticated with, if any.
+func GetAPITokenFromContext(ctx context.Context) (*model.APIToken, bool) {
+	token, ok := ctx.Value(APITokenKey).(*model.APIToken)
+	return token, ok && token != nil
+}
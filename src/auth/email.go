@@ -0,0 +1,416 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/pkg/mailer"
+	"vsC1Y2025V01/pkg/ratelimit"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAppURL = "http://localhost:3000"
+
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+	userTokenBytes       = 32
+
+	// sendMailTimeout bounds mails sent after the response is written.
+	sendMailTimeout = 30 * time.Second
+)
+
+// Mails per address or user, and requests to the public endpoints per IP.
+// Counters are per process.
+var (
+	mailLimiter     = ratelimit.New(3, time.Hour)
+	recoveryLimiter = ratelimit.New(20, time.Hour)
+)
+
+var (
+	mailerMu      sync.RWMutex
+	accountMailer mailer.Mailer
+)
+
+// SetMailer overrides the mailer for verification and password reset mails.
+// Passing nil makes the next use load it from the environment again.
+func SetMailer(m mailer.Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+
+	accountMailer = m
+}
+
+func getMailer() (mailer.Mailer, error) {
+	mailerMu.RLock()
+	current := accountMailer
+	mailerMu.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+
+	if accountMailer == nil {
+		loaded, err := mailer.LoadFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		accountMailer = loaded
+	}
+
+	return accountMailer, nil
+}
+
+// appURL is the frontend the links in mails point to, read from APP_URL.
+func appURL() string {
+	if u := strings.TrimRight(strings.TrimSpace(os.Getenv("APP_URL")), "/"); u != "" {
+		return u
+	}
+	return defaultAppURL
+}
+
+// allowRequest counts a request against key and answers 429 with Retry-After
+// when the limit is reached.
+func allowRequest(w http.ResponseWriter, l *ratelimit.Limiter, key string) bool {
+	ok, retryAfter := l.Allow(key)
+	if !ok {
+		writeTooManyRequests(w, retryAfter, "Too many requests, try again later")
+	}
+	return ok
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
+
+// issueUserToken replaces the user's unused tokens for purpose with a new one
+// sent to their current email, and returns its plaintext.
+func issueUserToken(user *model.User, purpose string, ttl time.Duration) (string, error) {
+	buf := make([]byte, userTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	plaintext := hex.EncodeToString(buf)
+
+	store := getUserTokenStore()
+	if err := store.DeleteUserTokens(user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	token := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(plaintext),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := store.CreateUserToken(token); err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// findUserToken returns the user of a mailed token without using it up.
+func findUserToken(purpose, plaintext string) (*model.User, error) {
+	if strings.TrimSpace(plaintext) == "" {
+		return nil, ErrUserTokenInvalid
+	}
+
+	token, err := getUserTokenStore().FindUserToken(purpose, hashToken(strings.TrimSpace(plaintext)), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenUser(token)
+}
+
+// consumeUserToken uses up a mailed token and returns its user.
+func consumeUserToken(purpose, plaintext string) (*model.User, error) {
+	if strings.TrimSpace(plaintext) == "" {
+		return nil, ErrUserTokenInvalid
+	}
+
+	token, err := getUserTokenStore().ConsumeUserToken(purpose, hashToken(strings.TrimSpace(plaintext)), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenUser(token)
+}
+
+// tokenUser returns the user a token was mailed to. A token sent to an
+// address the user has since changed is refused.
+func tokenUser(token *model.UserToken) (*model.User, error) {
+	user, err := getUserRepository().FindByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrUserTokenInvalid
+		}
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, token.Email) {
+		return nil, ErrUserTokenInvalid
+	}
+
+	return user, nil
+}
+
+func verificationMail(user *model.User, token string) mailer.Message {
+	link := appURL() + "/verify-email?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you did not ask for it, you can ignore this email.\n", user.Username, link),
+	}
+}
+
+func passwordResetMail(user *model.User, token string) mailer.Message {
+	link := appURL() + "/reset-password?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone asked to reset the password of your account. Choose a new password here:\n\n%s\n\n"+
+			"The link expires in one hour and works once. If you did not ask for it, you can ignore this email.\n", user.Username, link),
+	}
+}
+
+// SendVerificationEmailHandler mails the signed-in user a link that confirms
+// their email address.
+func SendVerificationEmailHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while sending verification email")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if strings.TrimSpace(user.Email) == "" {
+			http.Error(w, "No email address set", http.StatusBadRequest)
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			http.Error(w, "Email address already verified", http.StatusConflict)
+			return
+		}
+		if !allowRequest(w, mailLimiter, fmt.Sprintf("verify:%d", user.ID)) {
+			return
+		}
+
+		m, err := getMailer()
+		if err != nil {
+			logger.WithError(err).Error("mailer is not configured")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		token, err := issueUserToken(user, model.UserTokenVerifyEmail, emailVerificationTTL)
+		if err != nil {
+			logger.WithError(err).Error("failed to create email verification token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := m.Send(r.Context(), verificationMail(user, token)); err != nil {
+			logger.WithError(err).WithField("user_id", user.ID).Error("failed to send verification email")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// VerifyEmailHandler marks the email address of the token's user as verified.
+func VerifyEmailHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, recoveryLimiter, "ip:"+clientIP(r)) {
+			return
+		}
+
+		var payload model.EmailTokenPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		user, err := consumeUserToken(model.UserTokenVerifyEmail, payload.Token)
+		if err != nil {
+			if errors.Is(err, ErrUserTokenInvalid) {
+				http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+				return
+			}
+			logger.WithError(err).Error("failed to consume email verification token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := getUserRepository().Update(user); err != nil {
+			logger.WithError(err).Error("failed to mark email as verified")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		logger.WithField("user_id", user.ID).Info("email address verified")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+// ForgotPasswordHandler mails a password reset link when the address belongs
+// to a verified account. It answers 202 either way, and the mail is sent after
+// the response so the timing does not reveal whether the account exists.
+func ForgotPasswordHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, recoveryLimiter, "ip:"+clientIP(r)) {
+			return
+		}
+
+		var payload model.ForgotPasswordPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		email := strings.ToLower(strings.TrimSpace(payload.Email))
+		if email == "" {
+			http.Error(w, "Email is required", http.StatusBadRequest)
+			return
+		}
+		if !allowRequest(w, mailLimiter, "reset:"+email) {
+			return
+		}
+
+		go sendPasswordReset(logger, email)
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func sendPasswordReset(logger *logrus.Entry, email string) {
+	user, err := getUserRepository().FindByVerifiedEmail(email)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			logger.WithError(err).Error("failed to look up user for password reset")
+		}
+		return
+	}
+
+	m, err := getMailer()
+	if err != nil {
+		logger.WithError(err).Error("mailer is not configured")
+		return
+	}
+
+	token, err := issueUserToken(user, model.UserTokenResetPassword, passwordResetTTL)
+	if err != nil {
+		logger.WithError(err).Error("failed to create password reset token")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendMailTimeout)
+	defer cancel()
+	if err := m.Send(ctx, passwordResetMail(user, token)); err != nil {
+		logger.WithError(err).WithField("user_id", user.ID).Error("failed to send password reset email")
+	}
+}
+
+// ResetPasswordHandler sets a new password with a token from
+// ForgotPasswordHandler. Every session of the user is revoked, so a stolen
+// session does not survive the reset, and a login lockout of the username is
+// lifted.
+func ResetPasswordHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(w, recoveryLimiter, "ip:"+clientIP(r)) {
+			return
+		}
+
+		var payload model.ResetPasswordPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		// The password policy needs the username, so the token's user is
+		// looked up first and the token is only used up for a valid password.
+		user, err := findUserToken(model.UserTokenResetPassword, payload.Token)
+		if err != nil {
+			if errors.Is(err, ErrUserTokenInvalid) {
+				http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+				return
+			}
+			logger.WithError(err).Error("failed to look up password reset token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := validatePassword(payload.Password, user.Username); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err = consumeUserToken(model.UserTokenResetPassword, payload.Token)
+		if err != nil {
+			if errors.Is(err, ErrUserTokenInvalid) {
+				http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+				return
+			}
+			logger.WithError(err).Error("failed to consume password reset token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+		if err != nil {
+			logger.WithError(err).Error("Hashing failed")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		user.Password = string(hash)
+		if err := getUserRepository().Update(user); err != nil {
+			logger.WithError(err).Error("failed to update password")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := getUserTokenStore().DeleteUserTokens(user.ID, model.UserTokenResetPassword); err != nil {
+			logger.WithError(err).Error("failed to delete remaining password reset tokens")
+		}
+		if err := getSessionStore().RevokeAllSessions(user.ID, time.Now()); err != nil {
+			logger.WithError(err).Error("failed to revoke sessions after password reset")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		clearSessionCookies(w)
+		recordLoginSuccess(user.Username)
+
+		logger.WithField("user_id", user.ID).Info("password reset")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"time"
+
+	"vsC1Y2025V01/pkg/ratelimit"
+)
+
+// Internals exercised by the tests in auth_test.go.
+const (
+	RefreshCookieName    = refreshCookieName
+	APITokenPrefix       = apiTokenPrefix
+	MaxTwoFactorAttempts = maxTwoFactorAttempts
+	RecoveryCodeCount    = recoveryCodeCount
+)
+
+var (
+	ErrInvalidSecondFactor = errInvalidSecondFactor
+	ErrTwoFactorLocked     = errTwoFactorLocked
+
+	ClientIP           = clientIP
+	IssueUserToken     = issueUserToken
+	SealTOTPSecret     = sealTOTPSecret
+	VerifySecondFactor = verifySecondFactor
+)
+
+// ResetLimits starts the mail, recovery and login limits over.
+func ResetLimits() {
+	mailLimiter = ratelimit.New(3, time.Hour)
+	recoveryLimiter = ratelimit.New(20, time.Hour)
+	usernameLockout = ratelimit.NewLockout(5, time.Minute, time.Hour)
+	ipLockout = ratelimit.NewLockout(20, time.Minute, time.Hour)
+}
+
+// ResetUsernameLockout forgets the failed logins recorded for username.
+func ResetUsernameLockout(username string) {
+	usernameLockout.Reset(username)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 	"vsC1Y2025V01/src/model"
 
@@ -16,14 +17,43 @@ type AuthPayload struct {
 	Password string `json:"password"`
 }
 
+// RegisterPayload is the body of RegisterHandler. InviteCode is only needed
+// when REGISTRATION_INVITE_CODES is set.
+type RegisterPayload struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	InviteCode string `json:"inviteCode"`
+}
+
 func RegisterHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var payload AuthPayload
+		var payload RegisterPayload
 		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 			http.Error(w, "Invalid payload", http.StatusBadRequest)
 			return
 		}
 
+		if codes := inviteCodes(); len(codes) > 0 {
+			if !allowRequest(w, recoveryLimiter, "invite:"+clientIP(r)) {
+				return
+			}
+			if !validInviteCode(codes, strings.TrimSpace(payload.InviteCode)) {
+				logger.Warn("Registration with an invalid invite code")
+				http.Error(w, "Invalid invite code", http.StatusForbidden)
+				return
+			}
+		}
+
+		username := strings.TrimSpace(payload.Username)
+		if err := validateUsername(username); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validatePassword(payload.Password, username); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		hash, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
 		if err != nil {
 			logger.WithError(err).Error("Hashing failed")
@@ -31,13 +61,17 @@ func RegisterHandler(logger *logrus.Entry) http.HandlerFunc {
 			return
 		}
 		user := model.User{
-			Username:  payload.Username,
+			Username:  username,
 			Password:  string(hash),
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
 
 		if err := getUserRepository().Create(&user); err != nil {
+			if errors.Is(err, ErrUsernameTaken) {
+				http.Error(w, "Username is already taken", http.StatusConflict)
+				return
+			}
 			logger.WithError(err).Error("User registration failed")
 			http.Error(w, "Registration error", http.StatusInternalServerError)
 			return
@@ -59,11 +93,23 @@ func LoginHandler(logger *logrus.Entry) http.HandlerFunc {
 			return
 		}
 
+		ip := clientIP(r)
+		if wait := loginLockedFor(payload.Username, ip); wait > 0 {
+			logger.WithField("username", payload.Username).Warn("Login refused, too many failed attempts")
+			if user, err := getUserRepository().FindByUsername(payload.Username); err == nil {
+				auditLogin(logger, r, user.ID, model.LoginFailureLockedOut)
+			}
+			writeTooManyRequests(w, wait, "Too many failed logins, try again later")
+			return
+		}
+
 		logger.WithField("username", payload.Username).Info("Looking up user")
 
 		user, err := getUserRepository().FindByUsername(payload.Username)
 		if err != nil {
 			if errors.Is(err, ErrUserNotFound) {
+				compareDummyPassword(payload.Password)
+				recordLoginFailure(payload.Username, ip)
 				logger.WithError(err).Warn("User not found or DB error")
 				http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 				return
@@ -77,9 +123,36 @@ func LoginHandler(logger *logrus.Entry) http.HandlerFunc {
 
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password)); err != nil {
 			logger.WithField("username", payload.Username).Warn("Password mismatch")
+			recordLoginFailure(payload.Username, ip)
+			auditLogin(logger, r, user.ID, model.LoginFailureInvalidPassword)
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
+		recordLoginSuccess(payload.Username)
+
+		requiresTwoFactor, err := loginRequiresTwoFactor(user)
+		if err != nil {
+			logger.WithError(err).Error("Failed to load two-factor settings for login")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if requiresTwoFactor {
+			challenge, err := generateChallengeToken(user.ID)
+			if err != nil {
+				logger.WithError(err).Error("Failed to generate two-factor challenge")
+				http.Error(w, "Token error", http.StatusInternalServerError)
+				return
+			}
+
+			logger.WithField("user_id", user.ID).Info("Password accepted, waiting for second factor")
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"status":    "2fa_required",
+				"challenge": challenge,
+				"expiresIn": int(twoFactorChallengeTTL.Seconds()),
+			})
+			return
+		}
 
 		logger.WithField("user_id", user.ID).Info("Login successful, updating timestamps")
 
@@ -90,27 +163,14 @@ func LoginHandler(logger *logrus.Entry) http.HandlerFunc {
 			logger.WithError(err).Error("Failed to update last login timestamps")
 		}
 
-		// Generate JWT token
-		token, err := GenerateToken(user.ID)
-		if err != nil {
-			logger.WithError(err).Error("Failed to generate token")
+		if err := startSession(w, r, user); err != nil {
+			logger.WithError(err).Error("Failed to start session")
 			http.Error(w, "Token error", http.StatusInternalServerError)
 			return
 		}
 
-		logger.WithField("user_id", user.ID).Info("Token generated, sending response")
-
-		//w.Header().Set("Content-Type", "application/json")
-		//json.NewEncoder(w).Encode(map[string]string{"token": token})
-		http.SetCookie(w, &http.Cookie{
-			Name:     "token",
-			Value:    token,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   true, // ⚠️ Only works on HTTPS
-			SameSite: http.SameSiteLaxMode,
-			MaxAge:   3600, // 1 hour
-		})
+		auditLogin(logger, r, user.ID, "")
+		logger.WithField("user_id", user.ID).Info("Session started, sending response")
 
 		//w.WriteHeader(http.StatusOK)
 
@@ -123,13 +183,15 @@ func LogoutHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("User logging out")
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     "token",
-			Value:    "",
-			Path:     "/",
-			MaxAge:   -1,
-			HttpOnly: true,
-		})
+		user, ok := GetUserFromContext(r.Context())
+		sessionID, hasSession := GetSessionIDFromContext(r.Context())
+		if ok && user != nil && hasSession {
+			if err := getSessionStore().RevokeSession(user.ID, sessionID, time.Now()); err != nil && !errors.Is(err, ErrSessionNotFound) {
+				logger.WithError(err).Error("Failed to revoke session on logout")
+			}
+		}
+
+		clearSessionCookies(w)
 
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Logged out"))
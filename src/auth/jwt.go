@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,24 +11,112 @@ import (
 
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 
-func GenerateToken(userID uint) (string, error) {
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// accessTokenTTL is how long an access token is accepted, read from
+// ACCESS_TOKEN_TTL.
+func accessTokenTTL() time.Duration {
+	return durationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// refreshTokenTTL is how long a session lasts after login, read from
+// REFRESH_TOKEN_TTL. Refreshing does not extend it.
+func refreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// GenerateToken issues a short-lived access token for the session.
+func GenerateToken(userID, sessionID uint) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"sid":     sessionID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(accessTokenTTL()).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ParseToken validates an access token and returns its user and session.
+func ParseToken(tokenStr string) (userID, sessionID uint, err error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, 0, errors.New("invalid token")
+	}
+
+	uid, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, 0, errors.New("token has no user")
+	}
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return 0, 0, errors.New("token has no session")
+	}
+
+	return uint(uid), uint(sid), nil
+}
+
+const (
+	challengePurpose      = "2fa"
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// generateChallengeToken issues the token that carries a login from the
+// password step to the second factor. It names no session, so ParseToken
+// refuses it as an access token.
+func generateChallengeToken(userID uint) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"purpose": challengePurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(twoFactorChallengeTTL).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtSecret)
 }
 
-func ParseToken(tokenStr string) (uint, error) {
+func parseChallengeToken(tokenStr string) (uint, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != challengePurpose {
+		return 0, errors.New("invalid challenge token")
+	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		id := uint(claims["user_id"].(float64))
-		return id, nil
+	uid, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("token has no user")
 	}
 
-	return 0, err
+	return uint(uid), nil
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+)
+
+type LoginAttemptStore interface {
+	RecordLoginAttempt(attempt *model.LoginAttempt) error
+	// ListLoginAttempts returns a page of the user's attempts, newest first,
+	// and the total count.
+	ListLoginAttempts(userID uint, offset, limit int) ([]model.LoginAttempt, int64, error)
+}
+
+var (
+	loginAttemptStoreMu sync.RWMutex
+	loginAttemptStore   LoginAttemptStore = &gormLoginAttemptStore{}
+)
+
+func SetLoginAttemptStore(s LoginAttemptStore) {
+	loginAttemptStoreMu.Lock()
+	defer loginAttemptStoreMu.Unlock()
+
+	if s == nil {
+		loginAttemptStore = &gormLoginAttemptStore{}
+		return
+	}
+
+	loginAttemptStore = s
+}
+
+func getLoginAttemptStore() LoginAttemptStore {
+	loginAttemptStoreMu.RLock()
+	current := loginAttemptStore
+	loginAttemptStoreMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	loginAttemptStoreMu.Lock()
+	defer loginAttemptStoreMu.Unlock()
+
+	if loginAttemptStore == nil {
+		loginAttemptStore = &gormLoginAttemptStore{}
+	}
+
+	return loginAttemptStore
+}
+
+type gormLoginAttemptStore struct{}
+
+func (s *gormLoginAttemptStore) RecordLoginAttempt(attempt *model.LoginAttempt) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(attempt).Error
+}
+
+func (s *gormLoginAttemptStore) ListLoginAttempts(userID uint, offset, limit int) ([]model.LoginAttempt, int64, error) {
+	if db.DB == nil {
+		return nil, 0, errors.New("database connection is not initialized")
+	}
+
+	var total int64
+	if err := db.DB.Model(&model.LoginAttempt{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []model.LoginAttempt
+	err := db.DB.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&attempts).Error
+	return attempts, total, err
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/pkg/ratelimit"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Wrong passwords lock out the username after five in a row and the IP after
+// twenty, for a minute at first and twice as long after every further
+// failure, up to an hour. Counters are per process.
+var (
+	usernameLockout = ratelimit.NewLockout(5, time.Minute, time.Hour)
+	ipLockout       = ratelimit.NewLockout(20, time.Minute, time.Hour)
+)
+
+func usernameLockoutKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// loginLockedFor returns how long logins as username from ip are refused.
+func loginLockedFor(username, ip string) time.Duration {
+	return max(usernameLockout.Locked(usernameLockoutKey(username)), ipLockout.Locked(ip))
+}
+
+func recordLoginFailure(username, ip string) {
+	usernameLockout.Fail(usernameLockoutKey(username))
+	ipLockout.Fail(ip)
+}
+
+// recordLoginSuccess clears the username's failures. The IP's failures stay,
+// so one known password does not unlock guessing others from the same IP.
+func recordLoginSuccess(username string) {
+	usernameLockout.Reset(usernameLockoutKey(username))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareDummyPassword spends the time of a password check, so unknown
+// usernames answer as slowly as wrong passwords.
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not the password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// auditLogin records a login attempt for the user, successful when
+// failureReason is empty. Failing to record it does not fail the login.
+func auditLogin(logger *logrus.Entry, r *http.Request, userID uint, failureReason string) {
+	attempt := &model.LoginAttempt{
+		UserID:        userID,
+		IP:            truncate(clientIP(r), 64),
+		UserAgent:     truncate(r.UserAgent(), 512),
+		Success:       failureReason == "",
+		FailureReason: failureReason,
+		CreatedAt:     time.Now(),
+	}
+	if err := getLoginAttemptStore().RecordLoginAttempt(attempt); err != nil {
+		logger.WithError(err).WithField("user_id", userID).Error("failed to record login attempt")
+	}
+}
+
+// ListLoginAttemptsHandler lists the caller's login attempts, newest first.
+func ListLoginAttemptsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing login attempts")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		offset, limit, err := query.ParseRange(r, 50, 200)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		attempts, total, err := getLoginAttemptStore().ListLoginAttempts(user.ID, offset, limit)
+		if err != nil {
+			logger.WithError(err).Error("failed to list login attempts")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.LoginAttemptResponse, 0, len(attempts))
+		for i := range attempts {
+			responses = append(responses, model.NewLoginAttemptResponse(&attempts[i]))
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		w.Header().Set("X-Total-Count", fmt.Sprintf("%d", total))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode login attempt list response")
+		}
+	}
+}
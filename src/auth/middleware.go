@@ -30,11 +30,27 @@ func AuthMiddleware(logger *logrus.Entry) func(http.Handler) http.Handler {
 			token := strings.TrimPrefix(authHeader, "Bearer ")
 			logger.Debug("Bearer token extracted")
 
-			userID, err := ParseToken(token)
-			if err != nil {
-				logger.WithError(err).Warn("Invalid or expired token")
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
-				return
+			// Personal API tokens and session access tokens are both accepted
+			ctx := r.Context()
+			var userID uint
+			if strings.HasPrefix(token, apiTokenPrefix) {
+				apiToken, err := authenticateAPIToken(token)
+				if err != nil {
+					logger.WithError(err).Warn("Invalid, expired or revoked API token")
+					http.Error(w, "Invalid token", http.StatusUnauthorized)
+					return
+				}
+				userID = apiToken.UserID
+				ctx = context.WithValue(ctx, APITokenKey, apiToken)
+			} else {
+				session, err := authenticateAccessToken(r, token)
+				if err != nil {
+					logger.WithError(err).Warn("Invalid or expired token")
+					http.Error(w, "Invalid token", http.StatusUnauthorized)
+					return
+				}
+				userID = session.UserID
+				ctx = context.WithValue(ctx, SessionKey, session.ID)
 			}
 			logger.WithField("user_id", userID).Debug("Token parsed successfully")
 
@@ -58,7 +74,7 @@ func AuthMiddleware(logger *logrus.Entry) func(http.Handler) http.Handler {
 				logger.WithField("username", user.Username).Debug("Last seen updated")
 			}
 
-			ctx := context.WithValue(r.Context(), UserKey, user)
+			ctx = context.WithValue(ctx, UserKey, user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -73,21 +89,21 @@ func RequireAuthMiddleware(logger *logrus.Entry) func(http.Handler) http.Handler
 			//}
 			logger.WithField("path", r.URL.Path).Info("Authenticating via cookie")
 
-			cookie, err := r.Cookie("token")
+			cookie, err := r.Cookie(AccessCookieName)
 			if err != nil {
 				logger.Warn("Missing token cookie")
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			userID, err := ParseToken(cookie.Value)
+			session, err := authenticateAccessToken(r, cookie.Value)
 			if err != nil {
 				logger.WithError(err).Warn("Invalid token in cookie")
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			user, err := getUserRepository().FindByID(userID)
+			user, err := getUserRepository().FindByID(session.UserID)
 			if err != nil {
 				if errors.Is(err, ErrUserNotFound) {
 					logger.WithError(err).Warn("User not found")
@@ -104,6 +120,7 @@ func RequireAuthMiddleware(logger *logrus.Entry) func(http.Handler) http.Handler
 			}
 
 			ctx := context.WithValue(r.Context(), UserKey, user)
+			ctx = context.WithValue(ctx, SessionKey, session.ID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
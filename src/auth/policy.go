@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt reads.
+	maxPasswordLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateUsername returns an error that can be shown to the user when
+// username does not meet the username policy.
+func validateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return fmt.Errorf("username must be %d to %d characters long", minUsernameLength, maxUsernameLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username may only contain letters, digits, '.', '_' and '-', and must start with a letter or digit")
+	}
+	return nil
+}
+
+// validatePassword returns an error that can be shown to the user when
+// password does not meet the password policy.
+func validatePassword(password, username string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes long", maxPasswordLength)
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("password must not contain the username")
+	}
+	return nil
+}
+
+// inviteCodes returns the codes listed in REGISTRATION_INVITE_CODES. When
+// there are none, registration is open.
+func inviteCodes() []string {
+	var codes []string
+	for _, code := range strings.Split(os.Getenv("REGISTRATION_INVITE_CODES"), ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func validInviteCode(codes []string, code string) bool {
+	valid := false
+	for _, c := range codes {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(code)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -11,12 +11,20 @@ import (
 	"gorm.io/gorm"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username is already taken")
+)
 
 type UserRepository interface {
+	// Create returns ErrUsernameTaken when the username is in use, compared
+	// case-insensitively.
 	Create(user *model.User) error
 	FindByUsername(username string) (*model.User, error)
 	FindByID(id uint) (*model.User, error)
+	// FindByVerifiedEmail returns the user who verified email, compared
+	// case-insensitively.
+	FindByVerifiedEmail(email string) (*model.User, error)
 	Update(user *model.User) error
 }
 
@@ -63,6 +71,14 @@ func (r *gormUserRepository) Create(user *model.User) error {
 		return errors.New("database connection is not initialized")
 	}
 
+	var clash int64
+	if err := db.DB.Model(&model.User{}).Where("LOWER(username) = LOWER(?)", user.Username).Count(&clash).Error; err != nil {
+		return err
+	}
+	if clash > 0 {
+		return ErrUsernameTaken
+	}
+
 	return db.DB.Create(user).Error
 }
 
@@ -100,6 +116,26 @@ func (r *gormUserRepository) FindByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
+func (r *gormUserRepository) FindByVerifiedEmail(email string) (*model.User, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var user model.User
+	err := db.DB.Where("LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL", email).
+		Order("email_verified_at").
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (r *gormUserRepository) Update(user *model.User) error {
 	if db.DB == nil {
 		return errors.New("database connection is not initialized")
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token already used")
+)
+
+type SessionStore interface {
+	// CreateSession stores session and its first refresh token.
+	CreateSession(session *model.Session, token *model.RefreshToken) error
+	FindSession(id uint) (*model.Session, error)
+	// FindRefreshToken returns the token with the given hash and its session.
+	FindRefreshToken(hash string) (*model.RefreshToken, error)
+	// RotateRefreshToken marks the token usedID as used and stores next in
+	// its place. It returns ErrRefreshTokenReused when usedID was already
+	// used, so only one of two concurrent refreshes wins.
+	RotateRefreshToken(usedID uint, next *model.RefreshToken, at time.Time) error
+	// TouchSession records that the session was used at at from ip.
+	TouchSession(id uint, ip string, at time.Time) error
+	// ListActiveSessions returns the user's sessions that are neither
+	// revoked nor expired at now, most recently used first.
+	ListActiveSessions(userID uint, now time.Time) ([]model.Session, error)
+	RevokeSession(userID, id uint, at time.Time) error
+	RevokeAllSessions(userID uint, at time.Time) error
+}
+
+var (
+	sessionStoreMu sync.RWMutex
+	sessionStore   SessionStore = &gormSessionStore{}
+)
+
+func SetSessionStore(s SessionStore) {
+	sessionStoreMu.Lock()
+	defer sessionStoreMu.Unlock()
+
+	if s == nil {
+		sessionStore = &gormSessionStore{}
+		return
+	}
+
+	sessionStore = s
+}
+
+func getSessionStore() SessionStore {
+	sessionStoreMu.RLock()
+	current := sessionStore
+	sessionStoreMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	sessionStoreMu.Lock()
+	defer sessionStoreMu.Unlock()
+
+	if sessionStore == nil {
+		sessionStore = &gormSessionStore{}
+	}
+
+	return sessionStore
+}
+
+type gormSessionStore struct{}
+
+func (s *gormSessionStore) CreateSession(session *model.Session, token *model.RefreshToken) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		token.SessionID = session.ID
+		return tx.Create(token).Error
+	})
+}
+
+func (s *gormSessionStore) FindSession(id uint) (*model.Session, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var session model.Session
+	if err := db.DB.First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *gormSessionStore) FindRefreshToken(hash string) (*model.RefreshToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.RefreshToken
+	if err := db.DB.Preload("Session").Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormSessionStore) RotateRefreshToken(usedID uint, next *model.RefreshToken, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.RefreshToken{}).Where("id = ? AND used_at IS NULL", usedID).Update("used_at", at)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Session{}).Where("id = ?", next.SessionID).Update("last_used_at", at).Error
+	})
+}
+
+func (s *gormSessionStore) TouchSession(id uint, ip string, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]any{"ip": ip, "last_used_at": at}).Error
+}
+
+func (s *gormSessionStore) ListActiveSessions(userID uint, now time.Time) ([]model.Session, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var sessions []model.Session
+	if err := db.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC, id DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *gormSessionStore) RevokeSession(userID, id uint, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	res := db.DB.Model(&model.Session{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).Update("revoked_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *gormSessionStore) RevokeAllSessions(userID uint, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Model(&model.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", at).Error
+}
@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AccessCookieName is the cookie RequireAuthMiddleware reads the session's
+	// access token from.
+	AccessCookieName  = "token"
+	refreshCookieName = "refresh_token"
+	// refreshCookiePath keeps the refresh token out of every other request.
+	refreshCookiePath = "/auth/refresh"
+
+	refreshTokenPrefix = "rt_"
+	refreshTokenBytes  = 32
+
+	// sessionTouchInterval limits how often a request updates the session's
+	// last use.
+	sessionTouchInterval = time.Minute
+)
+
+// errSessionInactive is returned for tokens of revoked, expired or foreign
+// sessions.
+var errSessionInactive = errors.New("session is revoked or expired")
+
+// newRefreshToken returns a fresh plaintext refresh token and the hash that is
+// stored.
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := refreshTokenPrefix + hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP returns the address the request came from. X-Forwarded-For is
+// only read when the connection comes from a proxy listed in TRUSTED_PROXIES;
+// the client is then the rightmost hop that is not a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	trusted := trustedProxies()
+	if !isTrustedProxy(trusted, host) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !isTrustedProxy(trusted, hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return host
+}
+
+// trustedProxies parses TRUSTED_PROXIES, a comma-separated list of IPs and
+// CIDR ranges. Invalid entries are skipped.
+func trustedProxies() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func isTrustedProxy(trusted []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// startSession opens a session for user on the requesting device and sets the
+// access and refresh token cookies.
+func startSession(w http.ResponseWriter, r *http.Request, user *model.User) error {
+	plaintext, hash, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(r.UserAgent(), 512),
+		IP:         truncate(clientIP(r), 64),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL()),
+	}
+	if err := getSessionStore().CreateSession(session, &model.RefreshToken{TokenHash: hash}); err != nil {
+		return err
+	}
+
+	return setSessionCookies(w, session, plaintext)
+}
+
+func setSessionCookies(w http.ResponseWriter, session *model.Session, refreshToken string) error {
+	access, err := GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessCookieName,
+		Value:    access,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(accessTokenTTL().Seconds()),
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		Path:     refreshCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(time.Until(session.ExpiresAt).Seconds()),
+	})
+	return nil
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		Path:     refreshCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// authenticateAccessToken checks an access token and its session, and returns
+// the session. The session's last use is updated at most once per
+// sessionTouchInterval.
+func authenticateAccessToken(r *http.Request, token string) (*model.Session, error) {
+	userID, sessionID, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := getSessionStore().FindSession(sessionID)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, errSessionInactive
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if session.UserID != userID || !session.Active(now) {
+		return nil, errSessionInactive
+	}
+
+	if now.Sub(session.LastUsedAt) >= sessionTouchInterval {
+		if err := getSessionStore().TouchSession(session.ID, truncate(clientIP(r), 64), now); err == nil {
+			session.LastUsedAt = now
+		}
+	}
+
+	return session, nil
+}
+
+// RefreshHandler exchanges the refresh token cookie for a new access token
+// and a new refresh token. A refresh token works once. Presenting one that was
+// already used means it was copied, so the whole session is revoked.
+func RefreshHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(refreshCookieName)
+		if err != nil || cookie.Value == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		store := getSessionStore()
+		token, err := store.FindRefreshToken(hashToken(cookie.Value))
+		if err != nil {
+			if !errors.Is(err, ErrRefreshTokenNotFound) {
+				logger.WithError(err).Error("failed to look up refresh token")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			clearSessionCookies(w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		session := token.Session
+		now := time.Now()
+		if session == nil || !session.Active(now) {
+			clearSessionCookies(w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		plaintext, hash, err := newRefreshToken()
+		if err != nil {
+			logger.WithError(err).Error("failed to generate refresh token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		err = ErrRefreshTokenReused
+		if token.UsedAt == nil {
+			err = store.RotateRefreshToken(token.ID, &model.RefreshToken{SessionID: session.ID, TokenHash: hash}, now)
+		}
+		if errors.Is(err, ErrRefreshTokenReused) {
+			logger.WithFields(logrus.Fields{"user_id": session.UserID, "session_id": session.ID}).Warn("refresh token reused, revoking session")
+			if err := store.RevokeSession(session.UserID, session.ID, now); err != nil && !errors.Is(err, ErrSessionNotFound) {
+				logger.WithError(err).Error("failed to revoke session after refresh token reuse")
+			}
+			clearSessionCookies(w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed to rotate refresh token")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := setSessionCookies(w, session, plaintext); err != nil {
+			logger.WithError(err).Error("failed to generate token")
+			http.Error(w, "Token error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+// ListSessionsHandler returns the caller's active sessions and marks the one
+// the request came from.
+func ListSessionsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing sessions")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		currentID, _ := GetSessionIDFromContext(r.Context())
+
+		sessions, err := getSessionStore().ListActiveSessions(user.ID, time.Now())
+		if err != nil {
+			logger.WithError(err).Error("failed to list sessions")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.SessionResponse, 0, len(sessions))
+		for i := range sessions {
+			responses = append(responses, model.NewSessionResponse(&sessions[i], currentID))
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		w.Header().Set("X-Total-Count", fmt.Sprintf("%d", len(responses)))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode session list response")
+		}
+	}
+}
+
+// RevokeSessionHandler signs out one of the caller's sessions.
+func RevokeSessionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while revoking session")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := getSessionStore().RevokeSession(user.ID, uint(id), time.Now()); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+			logger.WithError(err).Error("failed to revoke session")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if currentID, _ := GetSessionIDFromContext(r.Context()); currentID == uint(id) {
+			clearSessionCookies(w)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeAllSessionsHandler signs the caller out everywhere, including the
+// device the request came from.
+func RevokeAllSessionsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while revoking sessions")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := getSessionStore().RevokeAllSessions(user.ID, time.Now()); err != nil {
+			logger.WithError(err).Error("failed to revoke sessions")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		clearSessionCookies(w)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
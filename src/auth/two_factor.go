@@ -0,0 +1,568 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/pkg/qrcode"
+	"vsC1Y2025V01/pkg/totp"
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultTOTPIssuer = "Trading Journal"
+	recoveryCodeCount = 10
+
+	// maxTwoFactorAttempts wrong codes in a row lock the second factor for
+	// twoFactorLockout.
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+
+	qrCodeScale = 6
+)
+
+var (
+	errInvalidSecondFactor = errors.New("invalid two-factor code")
+	errTwoFactorLocked     = errors.New("too many invalid two-factor codes")
+)
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *credentials.Keyring
+)
+
+// SetKeyring overrides the keyring that seals TOTP secrets. Passing nil makes
+// the next use load it from the environment again.
+func SetKeyring(k *credentials.Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	keyring = k
+}
+
+func getKeyring() (*credentials.Keyring, error) {
+	keyringMu.RLock()
+	current := keyring
+	keyringMu.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	if keyring == nil {
+		loaded, err := credentials.LoadKeyringFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		keyring = loaded
+	}
+
+	return keyring, nil
+}
+
+func twoFactorAAD(userID uint) string {
+	return fmt.Sprintf("two_factor:%d", userID)
+}
+
+func sealTOTPSecret(tf *model.TwoFactor, secret string) error {
+	k, err := getKeyring()
+	if err != nil {
+		return err
+	}
+
+	sealed, err := k.Seal(twoFactorAAD(tf.UserID), secret)
+	if err != nil {
+		return err
+	}
+
+	tf.KeyVersion = sealed.KeyVersion
+	tf.DataKey = sealed.DataKey
+	tf.Secret = sealed.Fields[0]
+	return nil
+}
+
+func openTOTPSecret(tf *model.TwoFactor) (string, error) {
+	k, err := getKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	fields, err := k.Open(credentials.Sealed{KeyVersion: tf.KeyVersion, DataKey: tf.DataKey, Fields: []string{tf.Secret}}, twoFactorAAD(tf.UserID))
+	if err != nil {
+		return "", err
+	}
+	return fields[0], nil
+}
+
+func totpIssuer() string {
+	if issuer := strings.TrimSpace(os.Getenv("TOTP_ISSUER")); issuer != "" {
+		return issuer
+	}
+	return defaultTOTPIssuer
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// newRecoveryCodes returns fresh recovery codes, formatted as xxxxx-xxxxx,
+// and the rows that store their hashes.
+func newRecoveryCodes(userID uint) ([]string, []model.RecoveryCode, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	rows := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))[:10]
+		plain = append(plain, code[:5]+"-"+code[5:])
+		rows = append(rows, model.RecoveryCode{UserID: userID, CodeHash: hashToken(code)})
+	}
+	return plain, rows, nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code when code is
+// empty. Each wrong code counts towards the lockout.
+func verifySecondFactor(tf *model.TwoFactor, code, recoveryCode string) error {
+	store := getTwoFactorStore()
+	now := time.Now()
+	if tf.LockedUntil != nil && now.Before(*tf.LockedUntil) {
+		return errTwoFactorLocked
+	}
+
+	var err error
+	switch {
+	case strings.TrimSpace(code) != "":
+		secret, openErr := openTOTPSecret(tf)
+		if openErr != nil {
+			return openErr
+		}
+		counter, ok := totp.Validate(secret, code, now)
+		if !ok || counter <= tf.LastCounter {
+			err = errInvalidSecondFactor
+		} else if err = store.AdvanceCounter(tf.UserID, counter); err == nil {
+			tf.LastCounter = counter
+		} else if errors.Is(err, ErrTOTPCodeReused) {
+			err = errInvalidSecondFactor
+		}
+	case strings.TrimSpace(recoveryCode) != "":
+		err = store.UseRecoveryCode(tf.UserID, hashToken(normalizeRecoveryCode(recoveryCode)), now)
+		if errors.Is(err, ErrRecoveryCodeNotFound) {
+			err = errInvalidSecondFactor
+		}
+	default:
+		err = errInvalidSecondFactor
+	}
+
+	if errors.Is(err, errInvalidSecondFactor) {
+		tf.FailedAttempts++
+		if tf.FailedAttempts >= maxTwoFactorAttempts {
+			lockedUntil := now.Add(twoFactorLockout)
+			tf.LockedUntil = &lockedUntil
+			tf.FailedAttempts = 0
+		}
+		if saveErr := store.SaveTwoFactor(tf); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if tf.FailedAttempts > 0 || tf.LockedUntil != nil {
+		tf.FailedAttempts = 0
+		tf.LockedUntil = nil
+		return store.SaveTwoFactor(tf)
+	}
+	return nil
+}
+
+// writeSecondFactorError answers a failed verifySecondFactor.
+func writeSecondFactorError(w http.ResponseWriter, logger *logrus.Entry, err error) {
+	switch {
+	case errors.Is(err, errInvalidSecondFactor):
+		http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+	case errors.Is(err, errTwoFactorLocked):
+		http.Error(w, "Too many invalid codes, try again later", http.StatusTooManyRequests)
+	default:
+		logger.WithError(err).Error("failed to verify two-factor code")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// loginRequiresTwoFactor reports whether user has to pass a second factor
+// before a session is started.
+func loginRequiresTwoFactor(user *model.User) (bool, error) {
+	tf, err := getTwoFactorStore().FindTwoFactor(user.ID)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tf.EnabledAt != nil, nil
+}
+
+// LoginTwoFactorHandler finishes a login that LoginHandler answered with a
+// challenge. It takes the challenge and a TOTP or recovery code, and starts
+// the session.
+func LoginTwoFactorHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload model.LoginTwoFactorPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		userID, err := parseChallengeToken(payload.Challenge)
+		if err != nil {
+			logger.WithError(err).Warn("invalid two-factor challenge")
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := getUserRepository().FindByID(userID)
+		if err != nil {
+			logger.WithError(err).Warn("user of two-factor challenge not found")
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+			return
+		}
+
+		tf, err := getTwoFactorStore().FindTwoFactor(user.ID)
+		if err != nil || tf.EnabledAt == nil {
+			if err != nil && !errors.Is(err, ErrTwoFactorNotFound) {
+				logger.WithError(err).Error("failed to load two-factor settings")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+			return
+		}
+
+		if err := verifySecondFactor(tf, payload.Code, payload.RecoveryCode); err != nil {
+			logger.WithError(err).WithField("user_id", user.ID).Warn("two-factor login failed")
+			switch {
+			case errors.Is(err, errInvalidSecondFactor):
+				auditLogin(logger, r, user.ID, model.LoginFailureInvalidSecondFactor)
+			case errors.Is(err, errTwoFactorLocked):
+				auditLogin(logger, r, user.ID, model.LoginFailureLockedOut)
+			}
+			writeSecondFactorError(w, logger, err)
+			return
+		}
+
+		user.LastLogin = time.Now()
+		user.LastSeen = time.Now()
+		if err := getUserRepository().Update(user); err != nil {
+			logger.WithError(err).Error("Failed to update last login timestamps")
+		}
+
+		if err := startSession(w, r, user); err != nil {
+			logger.WithError(err).Error("Failed to start session")
+			http.Error(w, "Token error", http.StatusInternalServerError)
+			return
+		}
+
+		auditLogin(logger, r, user.ID, "")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+func TwoFactorStatusHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while reading two-factor status")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var resp model.TwoFactorStatusResponse
+		tf, err := getTwoFactorStore().FindTwoFactor(user.ID)
+		if err != nil && !errors.Is(err, ErrTwoFactorNotFound) {
+			logger.WithError(err).Error("failed to load two-factor settings")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if err == nil && tf.EnabledAt != nil {
+			resp.Enabled = true
+			resp.EnabledAt = tf.EnabledAt
+			if resp.RecoveryCodesLeft, err = getTwoFactorStore().CountRecoveryCodes(user.ID); err != nil {
+				logger.WithError(err).Error("failed to count recovery codes")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Error("failed to encode two-factor status response")
+		}
+	}
+}
+
+// EnrollTwoFactorHandler creates a new TOTP secret and returns it as an
+// otpauth:// URI and a QR code. Two-factor login stays off until a code from
+// the authenticator is sent to VerifyTwoFactorHandler. Enrolling again before
+// that replaces the secret.
+func EnrollTwoFactorHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while enrolling two-factor authentication")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		store := getTwoFactorStore()
+		tf, err := store.FindTwoFactor(user.ID)
+		switch {
+		case errors.Is(err, ErrTwoFactorNotFound):
+			tf = &model.TwoFactor{UserID: user.ID}
+		case err != nil:
+			logger.WithError(err).Error("failed to load two-factor settings")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		case tf.EnabledAt != nil:
+			http.Error(w, "two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			logger.WithError(err).Error("failed to generate totp secret")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if err := sealTOTPSecret(tf, secret); err != nil {
+			logger.WithError(err).Error("failed to seal totp secret")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		tf.LastCounter = 0
+		tf.FailedAttempts = 0
+		tf.LockedUntil = nil
+
+		uri := totp.URI(totpIssuer(), user.Username, secret)
+		code, err := qrcode.Encode([]byte(uri))
+		if err != nil {
+			logger.WithError(err).Error("failed to encode totp qr code")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		image, err := code.PNG(qrCodeScale)
+		if err != nil {
+			logger.WithError(err).Error("failed to render totp qr code")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := store.SaveTwoFactor(tf); err != nil {
+			logger.WithError(err).Error("failed to store totp secret")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.TwoFactorEnrollmentResponse{
+			Secret:     secret,
+			OTPAuthURI: uri,
+			QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
+		}); err != nil {
+			logger.WithError(err).Error("failed to encode two-factor enrollment response")
+		}
+	}
+}
+
+// VerifyTwoFactorHandler turns two-factor login on once the first code from
+// the authenticator checks out, and returns the recovery codes.
+func VerifyTwoFactorHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while verifying two-factor authentication")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.TwoFactorCodePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		store := getTwoFactorStore()
+		tf, err := store.FindTwoFactor(user.ID)
+		switch {
+		case errors.Is(err, ErrTwoFactorNotFound):
+			http.Error(w, "enroll before verifying", http.StatusConflict)
+			return
+		case err != nil:
+			logger.WithError(err).Error("failed to load two-factor settings")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		case tf.EnabledAt != nil:
+			http.Error(w, "two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		if err := verifySecondFactor(tf, payload.Code, ""); err != nil {
+			writeSecondFactorError(w, logger, err)
+			return
+		}
+
+		plain, rows, err := newRecoveryCodes(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to generate recovery codes")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if err := store.ReplaceRecoveryCodes(user.ID, rows); err != nil {
+			logger.WithError(err).Error("failed to store recovery codes")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		tf.EnabledAt = &now
+		if err := store.SaveTwoFactor(tf); err != nil {
+			logger.WithError(err).Error("failed to enable two-factor authentication")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.RecoveryCodesResponse{RecoveryCodes: plain}); err != nil {
+			logger.WithError(err).Error("failed to encode recovery codes response")
+		}
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces every recovery code. It needs a
+// current TOTP code.
+func RegenerateRecoveryCodesHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while regenerating recovery codes")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.TwoFactorCodePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		store := getTwoFactorStore()
+		tf, err := store.FindTwoFactor(user.ID)
+		if err != nil || tf.EnabledAt == nil {
+			if err != nil && !errors.Is(err, ErrTwoFactorNotFound) {
+				logger.WithError(err).Error("failed to load two-factor settings")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			http.Error(w, "two-factor authentication is not enabled", http.StatusConflict)
+			return
+		}
+
+		if err := verifySecondFactor(tf, payload.Code, ""); err != nil {
+			writeSecondFactorError(w, logger, err)
+			return
+		}
+
+		plain, rows, err := newRecoveryCodes(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to generate recovery codes")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if err := store.ReplaceRecoveryCodes(user.ID, rows); err != nil {
+			logger.WithError(err).Error("failed to store recovery codes")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.RecoveryCodesResponse{RecoveryCodes: plain}); err != nil {
+			logger.WithError(err).Error("failed to encode recovery codes response")
+		}
+	}
+}
+
+// DisableTwoFactorHandler turns two-factor login off. Being signed in is not
+// enough: the request has to repeat the password and pass the second factor.
+func DisableTwoFactorHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while disabling two-factor authentication")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.DisableTwoFactorPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		store := getTwoFactorStore()
+		tf, err := store.FindTwoFactor(user.ID)
+		if err != nil {
+			if errors.Is(err, ErrTwoFactorNotFound) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			logger.WithError(err).Error("failed to load two-factor settings")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password)); err != nil {
+			logger.WithField("user_id", user.ID).Warn("Password mismatch while disabling two-factor authentication")
+			http.Error(w, "Invalid password", http.StatusForbidden)
+			return
+		}
+
+		if tf.EnabledAt != nil {
+			if err := verifySecondFactor(tf, payload.Code, payload.RecoveryCode); err != nil {
+				if errors.Is(err, errInvalidSecondFactor) {
+					http.Error(w, "Invalid two-factor code", http.StatusForbidden)
+					return
+				}
+				writeSecondFactorError(w, logger, err)
+				return
+			}
+		}
+
+		if err := store.DeleteTwoFactor(user.ID); err != nil {
+			logger.WithError(err).Error("failed to disable two-factor authentication")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		logger.WithField("user_id", user.ID).Info("Two-factor authentication disabled")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTwoFactorNotFound    = errors.New("two-factor authentication is not set up")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrTOTPCodeReused       = errors.New("totp code already used")
+)
+
+type TwoFactorStore interface {
+	FindTwoFactor(userID uint) (*model.TwoFactor, error)
+	// SaveTwoFactor creates or updates the user's row.
+	SaveTwoFactor(tf *model.TwoFactor) error
+	// DeleteTwoFactor removes the secret and every recovery code.
+	DeleteTwoFactor(userID uint) error
+	// AdvanceCounter records counter as the last used time step. It returns
+	// ErrTOTPCodeReused unless counter is later than the stored one.
+	AdvanceCounter(userID uint, counter int64) error
+	// ReplaceRecoveryCodes deletes the user's recovery codes and stores codes.
+	ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error
+	// UseRecoveryCode marks the unused code with hash as used.
+	UseRecoveryCode(userID uint, hash string, at time.Time) error
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+var (
+	twoFactorStoreMu sync.RWMutex
+	twoFactorStore   TwoFactorStore = &gormTwoFactorStore{}
+)
+
+func SetTwoFactorStore(s TwoFactorStore) {
+	twoFactorStoreMu.Lock()
+	defer twoFactorStoreMu.Unlock()
+
+	if s == nil {
+		twoFactorStore = &gormTwoFactorStore{}
+		return
+	}
+
+	twoFactorStore = s
+}
+
+func getTwoFactorStore() TwoFactorStore {
+	twoFactorStoreMu.RLock()
+	current := twoFactorStore
+	twoFactorStoreMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	twoFactorStoreMu.Lock()
+	defer twoFactorStoreMu.Unlock()
+
+	if twoFactorStore == nil {
+		twoFactorStore = &gormTwoFactorStore{}
+	}
+
+	return twoFactorStore
+}
+
+type gormTwoFactorStore struct{}
+
+func (s *gormTwoFactorStore) FindTwoFactor(userID uint) (*model.TwoFactor, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var tf model.TwoFactor
+	if err := db.DB.Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTwoFactorNotFound
+		}
+
+		return nil, err
+	}
+
+	return &tf, nil
+}
+
+func (s *gormTwoFactorStore) SaveTwoFactor(tf *model.TwoFactor) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Save(tf).Error
+}
+
+func (s *gormTwoFactorStore) DeleteTwoFactor(userID uint) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.TwoFactor{}).Error
+	})
+}
+
+func (s *gormTwoFactorStore) AdvanceCounter(userID uint, counter int64) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	res := db.DB.Model(&model.TwoFactor{}).Where("user_id = ? AND last_counter < ?", userID, counter).Update("last_counter", counter)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTOTPCodeReused
+	}
+
+	return nil
+}
+
+func (s *gormTwoFactorStore) ReplaceRecoveryCodes(userID uint, codes []model.RecoveryCode) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (s *gormTwoFactorStore) UseRecoveryCode(userID uint, hash string, at time.Time) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	res := db.DB.Model(&model.RecoveryCode{}).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).Update("used_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (s *gormTwoFactorStore) CountRecoveryCodes(userID uint) (int64, error) {
+	if db.DB == nil {
+		return 0, errors.New("database connection is not initialized")
+	}
+
+	var count int64
+	err := db.DB.Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
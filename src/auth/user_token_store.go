@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrUserTokenInvalid is returned for unknown, used and expired tokens alike.
+var ErrUserTokenInvalid = errors.New("token is invalid or expired")
+
+type UserTokenStore interface {
+	CreateUserToken(token *model.UserToken) error
+	// FindUserToken returns the unused, unexpired token with hash and purpose
+	// without using it up.
+	FindUserToken(purpose, hash string, at time.Time) (*model.UserToken, error)
+	// ConsumeUserToken marks the unused, unexpired token with hash and purpose
+	// as used and returns it.
+	ConsumeUserToken(purpose, hash string, at time.Time) (*model.UserToken, error)
+	// DeleteUserTokens removes the user's unused tokens for purpose.
+	DeleteUserTokens(userID uint, purpose string) error
+}
+
+var (
+	userTokenStoreMu sync.RWMutex
+	userTokenStore   UserTokenStore = &gormUserTokenStore{}
+)
+
+func SetUserTokenStore(s UserTokenStore) {
+	userTokenStoreMu.Lock()
+	defer userTokenStoreMu.Unlock()
+
+	if s == nil {
+		userTokenStore = &gormUserTokenStore{}
+		return
+	}
+
+	userTokenStore = s
+}
+
+func getUserTokenStore() UserTokenStore {
+	userTokenStoreMu.RLock()
+	current := userTokenStore
+	userTokenStoreMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	userTokenStoreMu.Lock()
+	defer userTokenStoreMu.Unlock()
+
+	if userTokenStore == nil {
+		userTokenStore = &gormUserTokenStore{}
+	}
+
+	return userTokenStore
+}
+
+type gormUserTokenStore struct{}
+
+func (s *gormUserTokenStore) CreateUserToken(token *model.UserToken) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(token).Error
+}
+
+func (s *gormUserTokenStore) FindUserToken(purpose, hash string, at time.Time) (*model.UserToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.UserToken
+	err := db.DB.Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, at).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormUserTokenStore) ConsumeUserToken(purpose, hash string, at time.Time) (*model.UserToken, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var token model.UserToken
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.UserToken{}).
+			Where("purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, at).
+			Update("used_at", at)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserTokenInvalid
+		}
+
+		return tx.Where("token_hash = ?", hash).First(&token).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (s *gormUserTokenStore) DeleteUserTokens(userID uint, purpose string) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Delete(&model.UserToken{}).Error
+}
@@ -0,0 +1,252 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const masterKeySize = 32 // AES-256
+
+var (
+	ErrNoMasterKey       = errors.New("credentials master key is not configured")
+	ErrUnknownKeyVersion = errors.New("credentials key version is not available")
+	ErrDecrypt           = errors.New("credentials could not be decrypted")
+)
+
+// Keyring holds the master keys used to wrap per-record data keys. New
+// records are always sealed with the current version; older versions stay
+// readable so rows can be re-sealed after a key rotation.
+type Keyring struct {
+	current int
+	keys    map[int][]byte
+}
+
+func NewKeyring(current int, keys map[int][]byte) (*Keyring, error) {
+	if current <= 0 {
+		return nil, fmt.Errorf("invalid current key version %d", current)
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, ErrNoMasterKey
+	}
+
+	copied := make(map[int][]byte, len(keys))
+	for version, key := range keys {
+		if len(key) != masterKeySize {
+			return nil, fmt.Errorf("master key version %d must be %d bytes, got %d", version, masterKeySize, len(key))
+		}
+		copied[version] = append([]byte(nil), key...)
+	}
+
+	return &Keyring{current: current, keys: copied}, nil
+}
+
+// LoadKeyringFromEnv builds the keyring from:
+//
+//	CREDENTIALS_MASTER_KEY       base64 encoded 32-byte key, or
+//	CREDENTIALS_MASTER_KEY_FILE  file holding the key (base64 or 32 raw bytes)
+//	CREDENTIALS_KEY_VERSION      version of that key, defaults to 1
+//	CREDENTIALS_PREVIOUS_KEYS    optional "version:base64,..." list of retired keys
+func LoadKeyringFromEnv() (*Keyring, error) {
+	version := 1
+	if v := strings.TrimSpace(os.Getenv("CREDENTIALS_KEY_VERSION")); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CREDENTIALS_KEY_VERSION: %w", err)
+		}
+		version = parsed
+	}
+
+	var current []byte
+	if encoded := strings.TrimSpace(os.Getenv("CREDENTIALS_MASTER_KEY")); encoded != "" {
+		key, err := decodeMasterKey([]byte(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CREDENTIALS_MASTER_KEY: %w", err)
+		}
+		current = key
+	} else if path := strings.TrimSpace(os.Getenv("CREDENTIALS_MASTER_KEY_FILE")); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read CREDENTIALS_MASTER_KEY_FILE: %w", err)
+		}
+		key, err := decodeMasterKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in CREDENTIALS_MASTER_KEY_FILE: %w", err)
+		}
+		current = key
+	} else {
+		return nil, ErrNoMasterKey
+	}
+
+	keys := map[int][]byte{version: current}
+	for _, entry := range strings.Split(os.Getenv("CREDENTIALS_PREVIOUS_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("CREDENTIALS_PREVIOUS_KEYS entries must look like version:base64key")
+		}
+		prevVersion, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key version %q: %w", parts[0], err)
+		}
+		key, err := decodeMasterKey([]byte(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key version %d: %w", prevVersion, err)
+		}
+		if prevVersion != version {
+			keys[prevVersion] = key
+		}
+	}
+
+	return NewKeyring(version, keys)
+}
+
+func decodeMasterKey(raw []byte) ([]byte, error) {
+	if len(raw) == masterKeySize {
+		return raw, nil
+	}
+	trimmed := strings.TrimSpace(string(raw))
+	key, err := base64.StdEncoding.DecodeString(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+	return key, nil
+}
+
+func (k *Keyring) CurrentVersion() int {
+	return k.current
+}
+
+// Sealed is the stored form of a set of secrets: a random data key wrapped
+// with the master key of KeyVersion, plus one ciphertext per secret. Every
+// ciphertext carries its own nonce. Empty secrets stay empty.
+type Sealed struct {
+	KeyVersion int
+	DataKey    string
+	Fields     []string
+}
+
+// Seal encrypts plaintexts under a fresh data key. aad binds the ciphertexts
+// to their owner (e.g. the user/exchange pair) so they cannot be copied onto
+// another record; each field is additionally bound to its position.
+func (k *Keyring) Seal(aad string, plaintexts ...string) (*Sealed, error) {
+	dataKey := make([]byte, masterKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := sealBytes(k.keys[k.current], dataKey, []byte(aad))
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := &Sealed{
+		KeyVersion: k.current,
+		DataKey:    wrapped,
+		Fields:     make([]string, len(plaintexts)),
+	}
+	for i, plaintext := range plaintexts {
+		if plaintext == "" {
+			continue
+		}
+		field, err := sealBytes(dataKey, []byte(plaintext), fieldAAD(aad, i))
+		if err != nil {
+			return nil, err
+		}
+		sealed.Fields[i] = field
+	}
+
+	return sealed, nil
+}
+
+// Open reverses Seal and returns the plaintexts in the same order.
+func (k *Keyring) Open(s Sealed, aad string) ([]string, error) {
+	masterKey, ok := k.keys[s.KeyVersion]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	dataKey, err := openBytes(masterKey, s.DataKey, []byte(aad))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintexts := make([]string, len(s.Fields))
+	for i, field := range s.Fields {
+		if field == "" {
+			continue
+		}
+		plaintext, err := openBytes(dataKey, field, fieldAAD(aad, i))
+		if err != nil {
+			return nil, err
+		}
+		plaintexts[i] = string(plaintext)
+	}
+
+	return plaintexts, nil
+}
+
+func fieldAAD(aad string, index int) []byte {
+	return []byte(aad + "#" + strconv.Itoa(index))
+}
+
+func sealBytes(key, plaintext, aad []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(nonce, nonce, plaintext, aad)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func openBytes(key []byte, encoded string, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ExchangeCredentials are the decrypted API credentials of a user exchange.
+// They are never persisted or serialized in this form.
+type ExchangeCredentials struct {
+	APIKey        string
+	APISecret     string
+	APIPassphrase string
+}
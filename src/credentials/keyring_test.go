@@ -0,0 +1,114 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKeyring(t *testing.T) *Keyring {
+	t.Helper()
+
+	k, err := NewKeyring(1, map[int][]byte{1: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("failed to build keyring: %v", err)
+	}
+	return k
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	k := testKeyring(t)
+
+	sealed, err := k.Seal("user_exchange:1:2", "api-key", "", "passphrase")
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if sealed.KeyVersion != 1 || sealed.DataKey == "" {
+		t.Fatalf("expected wrapped data key under version 1, got %+v", sealed)
+	}
+	if sealed.Fields[1] != "" {
+		t.Fatalf("expected empty secret to stay empty, got %q", sealed.Fields[1])
+	}
+
+	again, err := k.Seal("user_exchange:1:2", "api-key", "", "passphrase")
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if again.Fields[0] == sealed.Fields[0] {
+		t.Fatalf("expected a fresh nonce and data key per seal")
+	}
+
+	plaintexts, err := k.Open(*sealed, "user_exchange:1:2")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if plaintexts[0] != "api-key" || plaintexts[1] != "" || plaintexts[2] != "passphrase" {
+		t.Fatalf("unexpected plaintexts: %q", plaintexts)
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	k := testKeyring(t)
+
+	sealed, err := k.Seal("user_exchange:1:2", "api-key", "secret")
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+
+	if _, err := k.Open(*sealed, "user_exchange:9:2"); err != ErrDecrypt {
+		t.Fatalf("expected ciphertext bound to its owner, got %v", err)
+	}
+
+	swapped := *sealed
+	swapped.Fields = []string{sealed.Fields[1], sealed.Fields[0]}
+	if _, err := k.Open(swapped, "user_exchange:1:2"); err != ErrDecrypt {
+		t.Fatalf("expected swapped fields to fail, got %v", err)
+	}
+
+	unknown := *sealed
+	unknown.KeyVersion = 7
+	if _, err := k.Open(unknown, "user_exchange:1:2"); err != ErrUnknownKeyVersion {
+		t.Fatalf("expected ErrUnknownKeyVersion, got %v", err)
+	}
+}
+
+func TestLoadKeyringFromEnv(t *testing.T) {
+	t.Setenv("CREDENTIALS_MASTER_KEY", "")
+	t.Setenv("CREDENTIALS_MASTER_KEY_FILE", "")
+	t.Setenv("CREDENTIALS_KEY_VERSION", "")
+	t.Setenv("CREDENTIALS_PREVIOUS_KEYS", "")
+
+	if _, err := LoadKeyringFromEnv(); err != ErrNoMasterKey {
+		t.Fatalf("expected ErrNoMasterKey, got %v", err)
+	}
+
+	current := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	previous := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, []byte(current+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	t.Setenv("CREDENTIALS_MASTER_KEY_FILE", path)
+	t.Setenv("CREDENTIALS_KEY_VERSION", "2")
+	t.Setenv("CREDENTIALS_PREVIOUS_KEYS", "1:"+previous)
+
+	k, err := LoadKeyringFromEnv()
+	if err != nil {
+		t.Fatalf("failed to load keyring: %v", err)
+	}
+	if k.CurrentVersion() != 2 {
+		t.Fatalf("expected current version 2, got %d", k.CurrentVersion())
+	}
+
+	old := testKeyring(t)
+	sealed, err := old.Seal("aad", "value")
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if _, err := k.Open(*sealed, "aad"); err != nil {
+		t.Fatalf("expected retired key to stay readable: %v", err)
+	}
+}
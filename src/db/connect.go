@@ -47,7 +47,7 @@ func InitDB(logger *logrus.Entry) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetConnMaxLifetime(1 * time.Hour)
 
-	if err := db.AutoMigrate(&model.Alert{}, &model.User{}, &model.Trade{}, &model.Exchange{}, &model.PairsCoins{}, &model.UserExchange{}); err != nil {
+	if err := db.AutoMigrate(&model.Alert{}, &model.User{}, &model.Trade{}, &model.Exchange{}, &model.PairsCoins{}, &model.UserExchange{}, &model.WebhookToken{}, &model.ExchangeSyncState{}, &model.CashFlow{}, &model.Position{}, &model.PositionExecution{}, &model.Tag{}, &model.Setup{}, &model.SetupChecklistItem{}, &model.TradeChecklistCheck{}, &model.TradeAttachment{}, &model.Session{}, &model.RefreshToken{}, &model.APIToken{}, &model.TwoFactor{}, &model.RecoveryCode{}, &model.UserToken{}, &model.LoginAttempt{}); err != nil {
 		logger.WithError(err).Fatal("Failed to migrate database")
 	}
 
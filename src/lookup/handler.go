@@ -4,56 +4,98 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"vsC1Y2025V01/internal/connectors"
 	"vsC1Y2025V01/src/db"
 	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
+var exchangeSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":   {Column: "id", Kind: query.Number},
+		"name": {Column: "name", Kind: query.String},
+	},
+	DefaultSort: query.Sort{Field: "id"},
+}
+
+var pairSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":      {Column: "id", Kind: query.Number},
+		"coin1":   {Column: "coin1", Kind: query.String},
+		"coin2":   {Column: "coin2", Kind: query.String},
+		"display": {Column: "display", Kind: query.String},
+	},
+	DefaultSort: query.Sort{Field: "id"},
+}
+
 // GET /exchanges
+// Each exchange carries the capabilities of its connector, if one exists.
 func ListExchanges(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := query.Parse(r, exchangeSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		var exchanges []model.Exchange
 		var total int64
 
 		// Count total first
-		if err := db.DB.Model(&model.Exchange{}).Count(&total).Error; err != nil {
+		matching := params.Where(db.DB.Model(&model.Exchange{})).Session(&gorm.Session{})
+		if err := matching.Count(&total).Error; err != nil {
 			logger.WithError(err).Error("Failed to count exchanges")
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		// Now fetch data (optionally add pagination later)
-		if err := db.DB.Find(&exchanges).Error; err != nil {
+		// Without a range param every exchange is returned
+		if err := params.Page(params.Order(matching)).Find(&exchanges).Error; err != nil {
 			logger.WithError(err).Error("Failed to fetch exchanges")
 			http.Error(w, "Error fetching exchanges", http.StatusInternalServerError)
 			return
 		}
 
+		resp := make([]model.ExchangeResponse, 0, len(exchanges))
+		for i := range exchanges {
+			capabilities, supported := connectors.CapabilitiesFor(exchanges[i])
+			resp = append(resp, model.NewExchangeResponse(&exchanges[i], supported, capabilities))
+		}
+
 		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
 		w.Header().Set("X-Total-Count", fmt.Sprintf("%d", total))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		json.NewEncoder(w).Encode(exchanges)
+		json.NewEncoder(w).Encode(resp)
 	}
 }
 
 // GET /pairs
 func ListPairs(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := query.Parse(r, pairSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		var pairs []model.PairsCoins
 		var total int64
 
 		// Count total first
-		if err := db.DB.Model(&model.PairsCoins{}).Count(&total).Error; err != nil {
+		matching := params.Where(db.DB.Model(&model.PairsCoins{})).Session(&gorm.Session{})
+		if err := matching.Count(&total).Error; err != nil {
 			logger.WithError(err).Error("Failed to count exchanges")
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		// Now fetch data (optionally add pagination later)
-		if err := db.DB.Find(&pairs).Error; err != nil {
+		// Without a range param every pair is returned
+		if err := params.Page(params.Order(matching)).Find(&pairs).Error; err != nil {
 			logger.WithError(err).Error("Failed to fetch exchanges")
 			http.Error(w, "Error fetching exchanges", http.StatusInternalServerError)
 			return
@@ -12,24 +12,25 @@ import "time"
 
 type Alert struct {
 	ID           uint       `gorm:"primaryKey" json:"id"`
-	AlertName    *string    `gorm:"column:alert_name"`    // nullable
-	Body         *string    `gorm:"type:jsonb"`           // jsonb, nullable
-	ReceivedAt   *time.Time `gorm:"column:received_at"`   // nullable
-	Event        *string    `gorm:"column:event"`         // nullable
-	Description  *string    `gorm:"column:description"`   // nullable
-	Symbol       *string    `gorm:"column:symbol"`        // nullable
-	Exchange     *string    `gorm:"column:exchange"`      // nullable
-	Interval     *string    `gorm:"column:interval"`      // nullable
-	Open         *float64   `gorm:"column:open"`          // numeric
-	Close        *float64   `gorm:"column:close"`         // numeric
-	High         *float64   `gorm:"column:high"`          // numeric
-	Low          *float64   `gorm:"column:low"`           // numeric
-	Volume       *float64   `gorm:"column:volume"`        // numeric
-	Currency     *string    `gorm:"column:currency"`      // nullable
-	BaseCurrency *string    `gorm:"column:base_currency"` // nullable
-	Plot         *string    `gorm:"column:plot"`          // nullable
-	AlertTime    *time.Time `gorm:"column:alert_time"`    // nullable
-	ServerTime   *time.Time `gorm:"column:server_time"`   // nullable
-	Action       *string    `gorm:"column:action"`        // needs to be added in DB too
-	CreatedAt    time.Time
+	UserID       *uint      `gorm:"index" json:"user_id,omitempty"`            // owner resolved from the webhook token
+	AlertName    *string    `gorm:"column:alert_name" json:"alert_name"`       // nullable
+	Body         *string    `gorm:"type:jsonb" json:"body"`                    // jsonb, nullable
+	ReceivedAt   *time.Time `gorm:"column:received_at" json:"received_at"`     // nullable
+	Event        *string    `gorm:"column:event" json:"event"`                 // nullable
+	Description  *string    `gorm:"column:description" json:"description"`     // nullable
+	Symbol       *string    `gorm:"column:symbol" json:"symbol"`               // nullable
+	Exchange     *string    `gorm:"column:exchange" json:"exchange"`           // nullable
+	Interval     *string    `gorm:"column:interval" json:"interval"`           // nullable
+	Open         *float64   `gorm:"column:open" json:"open"`                   // numeric
+	Close        *float64   `gorm:"column:close" json:"close"`                 // numeric
+	High         *float64   `gorm:"column:high" json:"high"`                   // numeric
+	Low          *float64   `gorm:"column:low" json:"low"`                     // numeric
+	Volume       *float64   `gorm:"column:volume" json:"volume"`               // numeric
+	Currency     *string    `gorm:"column:currency" json:"currency"`           // nullable
+	BaseCurrency *string    `gorm:"column:base_currency" json:"base_currency"` // nullable
+	Plot         *string    `gorm:"column:plot" json:"plot"`                   // nullable
+	AlertTime    *time.Time `gorm:"column:alert_time" json:"alert_time"`       // nullable
+	ServerTime   *time.Time `gorm:"column:server_time" json:"server_time"`     // nullable
+	Action       *string    `gorm:"column:action" json:"action"`               // needs to be added in DB too
+	CreatedAt    time.Time  `json:"created_at"`
 }
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes a personal API token can be granted.
+const (
+	ScopeTradesRead      = "trades:read"
+	ScopeTradesWrite     = "trades:write"
+	ScopeStatsRead       = "stats:read"
+	ScopeExchangesManage = "exchanges:manage"
+)
+
+var APITokenScopes = []string{ScopeTradesRead, ScopeTradesWrite, ScopeStatsRead, ScopeExchangesManage}
+
+// APIToken lets scripts call the API on behalf of a user with a limited set of
+// scopes. Only the SHA-256 of the token is stored; the plaintext is shown
+// once on create.
+type APIToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"size:100" json:"name"`
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	TokenPrefix string     `gorm:"size:16" json:"token_prefix"`
+	Scopes      string     `gorm:"size:255;not null" json:"scopes"` // space separated
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the token can still be used at now.
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+type CreateAPITokenPayload struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type APITokenResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"tokenPrefix"`
+	Token       string     `json:"token,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	Revoked     bool       `json:"revoked"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func NewAPITokenResponse(t *APIToken) APITokenResponse {
+	if t == nil {
+		return APITokenResponse{}
+	}
+
+	return APITokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.TokenPrefix,
+		Scopes:      t.ScopeList(),
+		Revoked:     t.RevokedAt != nil,
+		ExpiresAt:   t.ExpiresAt,
+		LastUsedAt:  t.LastUsedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AttachmentBefore = "before"
+	AttachmentAfter  = "after"
+	AttachmentOther  = "other"
+)
+
+// TradeAttachment is an image stored in the blob store next to a trade,
+// typically the chart before the entry and after the exit.
+type TradeAttachment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	TradeID      uint      `gorm:"not null;index" json:"trade_id"`
+	Kind         string    `gorm:"size:16;not null" json:"kind"`
+	FileName     string    `gorm:"size:255;not null" json:"file_name"`
+	ContentType  string    `gorm:"size:64;not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"` // bytes of the original file; counts against the quota
+	StorageKey   string    `gorm:"size:255;not null" json:"-"`
+	ThumbnailKey *string   `gorm:"size:255" json:"-"` // nil when the format cannot be decoded
+	CreatedAt    time.Time `json:"created_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type TradeAttachmentResponse struct {
+	ID           uint      `json:"id"`
+	TradeID      uint      `json:"tradeId"`
+	Kind         string    `json:"kind"`
+	FileName     string    `json:"fileName"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL *string   `json:"thumbnailUrl,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func NewTradeAttachmentResponse(a *TradeAttachment) TradeAttachmentResponse {
+	if a == nil {
+		return TradeAttachmentResponse{}
+	}
+
+	url := fmt.Sprintf("/trades/%d/attachments/%d", a.TradeID, a.ID)
+	resp := TradeAttachmentResponse{
+		ID:          a.ID,
+		TradeID:     a.TradeID,
+		Kind:        a.Kind,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		URL:         url,
+		CreatedAt:   a.CreatedAt,
+	}
+	if a.ThumbnailKey != nil {
+		thumbnail := url + "/thumbnail"
+		resp.ThumbnailURL = &thumbnail
+	}
+
+	return resp
+}
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+const (
+	CashFlowDeposit    = "deposit"
+	CashFlowWithdrawal = "withdrawal"
+)
+
+// CashFlow is money moved into or out of a user's trading account. It is not
+// profit or loss, so the equity curve adds it to the balance without counting
+// it towards drawdown.
+type CashFlow struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	Type       string    `gorm:"size:20;not null" json:"type"` // CashFlowDeposit or CashFlowWithdrawal
+	Amount     float64   `gorm:"not null" json:"amount"`       // always positive
+	OccurredAt time.Time `gorm:"not null;index" json:"occurred_at"`
+	Note       *string   `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// SignedAmount is Amount, negated for withdrawals.
+func (c CashFlow) SignedAmount() float64 {
+	if c.Type == CashFlowWithdrawal {
+		return -c.Amount
+	}
+	return c.Amount
+}
+
+type CreateCashFlowPayload struct {
+	Type       string  `json:"type"`
+	Amount     float64 `json:"amount"`
+	OccurredAt string  `json:"occurredAt"` // RFC3339
+	Note       *string `json:"note"`
+}
+
+type CashFlowResponse struct {
+	ID         uint      `json:"id"`
+	Type       string    `json:"type"`
+	Amount     float64   `json:"amount"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Note       *string   `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func NewCashFlowResponse(c *CashFlow) CashFlowResponse {
+	if c == nil {
+		return CashFlowResponse{}
+	}
+
+	return CashFlowResponse{
+		ID:         c.ID,
+		Type:       c.Type,
+		Amount:     c.Amount,
+		OccurredAt: c.OccurredAt,
+		Note:       c.Note,
+		CreatedAt:  c.CreatedAt,
+	}
+}
@@ -4,3 +4,32 @@ type Exchange struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
 	Name string `gorm:"uniqueIndex;not null" json:"name"`
 }
+
+// ExchangeCapabilities lists what the connector behind an exchange can do,
+// so the frontend only offers features that actually work.
+type ExchangeCapabilities struct {
+	Spot           bool `json:"spot"`
+	Futures        bool `json:"futures"`
+	OrderPlacement bool `json:"orderPlacement"`
+	HistorySync    bool `json:"historySync"`
+}
+
+type ExchangeResponse struct {
+	ID           uint                 `json:"id"`
+	Name         string               `json:"name"`
+	Supported    bool                 `json:"supported"` // a connector is registered for it
+	Capabilities ExchangeCapabilities `json:"capabilities"`
+}
+
+func NewExchangeResponse(exchange *Exchange, supported bool, capabilities ExchangeCapabilities) ExchangeResponse {
+	if exchange == nil {
+		return ExchangeResponse{}
+	}
+
+	return ExchangeResponse{
+		ID:           exchange.ID,
+		Name:         exchange.Name,
+		Supported:    supported,
+		Capabilities: capabilities,
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+const (
+	SyncStatusIdle        = "idle"
+	SyncStatusRunning     = "running"
+	SyncStatusSucceeded   = "succeeded"
+	SyncStatusFailed      = "failed"
+	SyncStatusUnsupported = "unsupported"
+)
+
+// ExchangeSyncState tracks the trade history sync of one UserExchange. The
+// cursors hold the time of the newest record already imported, so each run
+// only asks the exchange for what came after.
+type ExchangeSyncState struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserExchangeID  uint       `gorm:"not null;uniqueIndex" json:"user_exchange_id"`
+	Status          string     `gorm:"size:20;not null;default:idle" json:"status"`
+	LastError       string     `gorm:"type:text" json:"last_error"`
+	LastStartedAt   *time.Time `json:"last_started_at"`
+	LastFinishedAt  *time.Time `json:"last_finished_at"`
+	LastSuccessAt   *time.Time `json:"last_success_at"`
+	FillsCursor     *time.Time `json:"fills_cursor"`
+	PositionsCursor *time.Time `json:"positions_cursor"`
+	TradesImported  int64      `gorm:"not null;default:0" json:"trades_imported"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	UserExchange *UserExchange `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type ExchangeSyncStatusResponse struct {
+	ExchangeID      uint       `json:"exchangeId"`
+	Status          string     `json:"status"`
+	LastError       string     `json:"lastError,omitempty"`
+	LastStartedAt   *time.Time `json:"lastStartedAt,omitempty"`
+	LastFinishedAt  *time.Time `json:"lastFinishedAt,omitempty"`
+	LastSuccessAt   *time.Time `json:"lastSuccessAt,omitempty"`
+	FillsCursor     *time.Time `json:"fillsCursor,omitempty"`
+	PositionsCursor *time.Time `json:"positionsCursor,omitempty"`
+	TradesImported  int64      `json:"tradesImported"`
+}
+
+func NewExchangeSyncStatusResponse(exchangeID uint, s *ExchangeSyncState) ExchangeSyncStatusResponse {
+	if s == nil {
+		return ExchangeSyncStatusResponse{ExchangeID: exchangeID, Status: SyncStatusIdle}
+	}
+
+	return ExchangeSyncStatusResponse{
+		ExchangeID:      exchangeID,
+		Status:          s.Status,
+		LastError:       s.LastError,
+		LastStartedAt:   s.LastStartedAt,
+		LastFinishedAt:  s.LastFinishedAt,
+		LastSuccessAt:   s.LastSuccessAt,
+		FillsCursor:     s.FillsCursor,
+		PositionsCursor: s.PositionsCursor,
+		TradesImported:  s.TradesImported,
+	}
+}
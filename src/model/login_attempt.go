@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Reasons a login attempt failed.
+const (
+	LoginFailureInvalidPassword     = "invalid_password"
+	LoginFailureLockedOut           = "locked_out"
+	LoginFailureInvalidSecondFactor = "invalid_second_factor"
+)
+
+// LoginAttempt records a login to an existing account, so the user can spot
+// logins they did not make. Attempts for unknown usernames are not stored.
+type LoginAttempt struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	IP        string `gorm:"size:64" json:"ip"`
+	UserAgent string `gorm:"size:512" json:"user_agent"`
+	Success   bool   `gorm:"not null" json:"success"`
+	// FailureReason is one of the LoginFailure constants, empty on success.
+	FailureReason string    `gorm:"size:32" json:"failure_reason"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type LoginAttemptResponse struct {
+	ID            uint      `json:"id"`
+	Device        string    `json:"device"`
+	IP            string    `json:"ip"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func NewLoginAttemptResponse(a *LoginAttempt) LoginAttemptResponse {
+	if a == nil {
+		return LoginAttemptResponse{}
+	}
+
+	return LoginAttemptResponse{
+		ID:            a.ID,
+		Device:        a.UserAgent,
+		IP:            a.IP,
+		Success:       a.Success,
+		FailureReason: a.FailureReason,
+		CreatedAt:     a.CreatedAt,
+	}
+}
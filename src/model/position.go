@@ -0,0 +1,189 @@
+package model
+
+import "time"
+
+const (
+	PositionOpen   = "open"
+	PositionClosed = "closed"
+
+	ExecutionEntry = "entry" // opens or adds to the position
+	ExecutionExit  = "exit"  // reduces or closes it
+)
+
+// Position groups the executions of a single trade idea: the first entry,
+// any adds and every partial exit. The summary columns are derived from
+// Executions by package positions whenever they change and are stored so
+// positions can be listed and filtered without replaying their fills.
+type Position struct {
+	ID           uint     `gorm:"primaryKey" json:"id"`
+	UserID       uint     `gorm:"not null;index" json:"user_id"`
+	Exchange     *string  `json:"exchange,omitempty"`
+	Symbol       string   `gorm:"not null;index" json:"symbol"`
+	ContractType *string  `json:"contract_type"`
+	IsLong       bool     `json:"is_long"`
+	Leverage     *float64 `json:"leverage,omitempty"`
+	StopLoss     *float64 `json:"stop_loss,omitempty"`
+	TakeProfit   *float64 `json:"take_profit,omitempty"`
+	Notes        *string  `json:"notes,omitempty"`
+
+	Status        string     `gorm:"size:10;not null;index" json:"status"` // PositionOpen or PositionClosed
+	Quantity      float64    `json:"quantity"`                             // remaining size
+	AvgEntryPrice float64    `json:"avg_entry_price"`                      // average cost of the remaining (or last closed) size
+	AvgExitPrice  float64    `json:"avg_exit_price"`
+	RealizedPnL   float64    `json:"realized_pnl"` // net of fees
+	Fees          float64    `json:"fees"`
+	OpenedAt      time.Time  `gorm:"index" json:"opened_at"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+
+	Executions []PositionExecution `gorm:"constraint:OnDelete:CASCADE" json:"executions"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PositionExecution is one fill of a position.
+type PositionExecution struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	PositionID uint      `gorm:"not null;index" json:"position_id"`
+	TradeID    *uint     `gorm:"index" json:"trade_id,omitempty"` // journal trade the fill was taken from
+	Type       string    `gorm:"size:10;not null" json:"type"`    // ExecutionEntry or ExecutionExit
+	Quantity   float64   `gorm:"not null" json:"quantity"`
+	Price      float64   `gorm:"not null" json:"price"`
+	Fee        float64   `json:"fee"`
+	ExecutedAt time.Time `gorm:"not null" json:"executed_at"`
+
+	// Set on exits only: the P&L of the exited size against the average
+	// entry, net of the exit fee and the share of entry fees it carried.
+	RealizedPnL *float64 `json:"realized_pnl,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PositionExecutionPayload struct {
+	Type       string   `json:"type"` // "entry" or "exit"
+	Quantity   float64  `json:"quantity"`
+	Price      float64  `json:"price"`
+	Fee        *float64 `json:"fee"`
+	ExecutedAt string   `json:"executedAt"` // RFC3339
+}
+
+// CreatePositionPayload opens a position from explicit executions, from
+// existing journal trades (TradeIDs), or both.
+type CreatePositionPayload struct {
+	Exchange     *string                    `json:"exchange"`
+	Symbol       string                     `json:"symbol"`
+	ContractType *string                    `json:"contractType"`
+	Side         string                     `json:"side"` // "long" or "short"
+	Leverage     *float64                   `json:"leverage"`
+	StopLoss     *float64                   `json:"stopLoss"`
+	TakeProfit   *float64                   `json:"takeProfit"`
+	Notes        *string                    `json:"notes"`
+	Executions   []PositionExecutionPayload `json:"executions"`
+	TradeIDs     []uint                     `json:"tradeIds"`
+}
+
+type UpdatePositionPayload struct {
+	Leverage   **float64 `json:"leverage,omitempty"`
+	StopLoss   **float64 `json:"stopLoss,omitempty"`
+	TakeProfit **float64 `json:"takeProfit,omitempty"`
+	Notes      **string  `json:"notes,omitempty"`
+}
+
+type LinkPositionTradesPayload struct {
+	TradeIDs []uint `json:"tradeIds"`
+}
+
+type PositionExecutionResponse struct {
+	ID          uint      `json:"id"`
+	TradeID     *uint     `json:"tradeId,omitempty"`
+	Type        string    `json:"type"`
+	Quantity    float64   `json:"quantity"`
+	Price       float64   `json:"price"`
+	Fee         float64   `json:"fee"`
+	ExecutedAt  time.Time `json:"executedAt"`
+	RealizedPnL *float64  `json:"realizedPnl,omitempty"`
+}
+
+type PositionResponse struct {
+	ID            uint                        `json:"id"`
+	Exchange      *string                     `json:"exchange,omitempty"`
+	Symbol        string                      `json:"symbol"`
+	ContractType  *string                     `json:"contractType,omitempty"`
+	Side          string                      `json:"side"`
+	Leverage      *float64                    `json:"leverage,omitempty"`
+	StopLoss      *float64                    `json:"stopLoss,omitempty"`
+	TakeProfit    *float64                    `json:"takeProfit,omitempty"`
+	Notes         *string                     `json:"notes,omitempty"`
+	Status        string                      `json:"status"`
+	Quantity      float64                     `json:"quantity"`
+	AvgEntryPrice float64                     `json:"avgEntryPrice"`
+	AvgExitPrice  float64                     `json:"avgExitPrice"`
+	RealizedPnL   float64                     `json:"realizedPnl"`
+	Fees          float64                     `json:"fees"`
+	OpenedAt      time.Time                   `json:"openedAt"`
+	ClosedAt      *time.Time                  `json:"closedAt,omitempty"`
+	Executions    []PositionExecutionResponse `json:"executions"`
+}
+
+func NewPositionResponse(p *Position) PositionResponse {
+	if p == nil {
+		return PositionResponse{}
+	}
+
+	side := "short"
+	if p.IsLong {
+		side = "long"
+	}
+
+	executions := make([]PositionExecutionResponse, 0, len(p.Executions))
+	for _, e := range p.Executions {
+		executions = append(executions, PositionExecutionResponse{
+			ID:          e.ID,
+			TradeID:     e.TradeID,
+			Type:        e.Type,
+			Quantity:    e.Quantity,
+			Price:       e.Price,
+			Fee:         e.Fee,
+			ExecutedAt:  e.ExecutedAt,
+			RealizedPnL: e.RealizedPnL,
+		})
+	}
+
+	return PositionResponse{
+		ID:            p.ID,
+		Exchange:      p.Exchange,
+		Symbol:        p.Symbol,
+		ContractType:  p.ContractType,
+		Side:          side,
+		Leverage:      p.Leverage,
+		StopLoss:      p.StopLoss,
+		TakeProfit:    p.TakeProfit,
+		Notes:         p.Notes,
+		Status:        p.Status,
+		Quantity:      p.Quantity,
+		AvgEntryPrice: p.AvgEntryPrice,
+		AvgExitPrice:  p.AvgExitPrice,
+		RealizedPnL:   p.RealizedPnL,
+		Fees:          p.Fees,
+		OpenedAt:      p.OpenedAt,
+		ClosedAt:      p.ClosedAt,
+		Executions:    executions,
+	}
+}
+
+// OpenPositionResponse is an open position valued at the current market
+// price. The market fields are nil when no price could be fetched.
+type OpenPositionResponse struct {
+	PositionResponse
+	LastPrice             *float64 `json:"lastPrice"` // last spot trade, also for futures
+	PriceError            string   `json:"priceError,omitempty"`
+	UnrealizedPnL         *float64 `json:"unrealizedPnl"`
+	ReturnOnMargin        *float64 `json:"returnOnMargin"`        // UnrealizedPnL / margin, 0.25 is +25%
+	StopLossDistance      *float64 `json:"stopLossDistance"`      // price move left before the stop, negative once it is crossed
+	StopLossDistancePct   *float64 `json:"stopLossDistancePct"`   // StopLossDistance / LastPrice
+	TakeProfitDistance    *float64 `json:"takeProfitDistance"`    // price move left before the target, negative once it is crossed
+	TakeProfitDistancePct *float64 `json:"takeProfitDistancePct"` // TakeProfitDistance / LastPrice
+	LiquidationPrice      *float64 `json:"liquidationPrice"`      // estimate, leveraged positions only
+}
@@ -0,0 +1,127 @@
+package model
+
+import "time"
+
+// Tag labels trades. Names are unique per user.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tag_user_name" json:"user_id"`
+	Name      string    `gorm:"size:64;not null;uniqueIndex:idx_tag_user_name" json:"name"`
+	Color     *string   `gorm:"size:16" json:"color,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Setup is a user-defined playbook: when to take a trade, when to get out,
+// and a checklist to go through before entering.
+type Setup struct {
+	ID            uint                 `gorm:"primaryKey" json:"id"`
+	UserID        uint                 `gorm:"not null;uniqueIndex:idx_setup_user_name" json:"user_id"`
+	Name          string               `gorm:"size:128;not null;uniqueIndex:idx_setup_user_name" json:"name"`
+	Description   *string              `gorm:"type:text" json:"description,omitempty"`
+	EntryCriteria *string              `gorm:"type:text" json:"entry_criteria,omitempty"`
+	ExitCriteria  *string              `gorm:"type:text" json:"exit_criteria,omitempty"`
+	Checklist     []SetupChecklistItem `gorm:"constraint:OnDelete:CASCADE" json:"checklist"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type SetupChecklistItem struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	SetupID  uint   `gorm:"not null;index" json:"setup_id"`
+	Position int    `gorm:"not null" json:"position"` // display order, from 0
+	Text     string `gorm:"size:512;not null" json:"text"`
+}
+
+// TradeChecklistCheck records whether a trade followed one item of its
+// setup's checklist.
+type TradeChecklistCheck struct {
+	TradeID         uint `gorm:"primaryKey" json:"trade_id"`
+	ChecklistItemID uint `gorm:"primaryKey" json:"checklist_item_id"`
+	Checked         bool `json:"checked"`
+
+	ChecklistItem *SetupChecklistItem `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type TagPayload struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color"`
+}
+
+type TagResponse struct {
+	ID    uint    `json:"id"`
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
+func NewTagResponse(t *Tag) TagResponse {
+	if t == nil {
+		return TagResponse{}
+	}
+
+	return TagResponse{ID: t.ID, Name: t.Name, Color: t.Color}
+}
+
+// SetupChecklistItemPayload keeps an existing item when ID is set and adds a
+// new one otherwise.
+type SetupChecklistItemPayload struct {
+	ID   *uint  `json:"id"`
+	Text string `json:"text"`
+}
+
+// SetupPayload creates a setup or replaces one as a whole; checklist items
+// left out are deleted.
+type SetupPayload struct {
+	Name          string                      `json:"name"`
+	Description   *string                     `json:"description"`
+	EntryCriteria *string                     `json:"entryCriteria"`
+	ExitCriteria  *string                     `json:"exitCriteria"`
+	Checklist     []SetupChecklistItemPayload `json:"checklist"`
+}
+
+type SetupChecklistItemResponse struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+type SetupResponse struct {
+	ID            uint                         `json:"id"`
+	Name          string                       `json:"name"`
+	Description   *string                      `json:"description,omitempty"`
+	EntryCriteria *string                      `json:"entryCriteria,omitempty"`
+	ExitCriteria  *string                      `json:"exitCriteria,omitempty"`
+	Checklist     []SetupChecklistItemResponse `json:"checklist"`
+	CreatedAt     time.Time                    `json:"createdAt"`
+	UpdatedAt     time.Time                    `json:"updatedAt"`
+}
+
+func NewSetupResponse(s *Setup) SetupResponse {
+	if s == nil {
+		return SetupResponse{}
+	}
+
+	checklist := make([]SetupChecklistItemResponse, 0, len(s.Checklist))
+	for _, item := range s.Checklist {
+		checklist = append(checklist, SetupChecklistItemResponse{ID: item.ID, Text: item.Text})
+	}
+
+	return SetupResponse{
+		ID:            s.ID,
+		Name:          s.Name,
+		Description:   s.Description,
+		EntryCriteria: s.EntryCriteria,
+		ExitCriteria:  s.ExitCriteria,
+		Checklist:     checklist,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+	}
+}
+
+// TradeChecklistPayload answers one checklist item of the trade's setup.
+type TradeChecklistPayload struct {
+	ItemID  uint `json:"itemId"`
+	Checked bool `json:"checked"`
+}
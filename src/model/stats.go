@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// TradeStatsResponse summarizes the closed trades of a user. P&L figures are
+// net of fees and in the quote currency of each trade.
+type TradeStatsResponse struct {
+	From                 *time.Time           `json:"from,omitempty"`
+	To                   *time.Time           `json:"to,omitempty"`
+	Trades               int                  `json:"trades"`
+	Wins                 int                  `json:"wins"`
+	Losses               int                  `json:"losses"`
+	Breakeven            int                  `json:"breakeven"`
+	WinRate              float64              `json:"winRate"` // 0.55 is 55%
+	NetPnL               float64              `json:"netPnl"`
+	GrossProfit          float64              `json:"grossProfit"`
+	GrossLoss            float64              `json:"grossLoss"`
+	Fees                 float64              `json:"fees"`
+	ProfitFactor         *float64             `json:"profitFactor"` // nil without losing trades
+	Expectancy           float64              `json:"expectancy"`   // average net P&L per trade
+	AverageWin           float64              `json:"averageWin"`
+	AverageLoss          float64              `json:"averageLoss"`
+	LargestWin           float64              `json:"largestWin"`
+	LargestLoss          float64              `json:"largestLoss"`
+	MaxConsecutiveWins   int                  `json:"maxConsecutiveWins"`
+	MaxConsecutiveLosses int                  `json:"maxConsecutiveLosses"`
+	MaxDrawdown          float64              `json:"maxDrawdown"`  // largest peak-to-trough fall of cumulative P&L
+	SharpeRatio          *float64             `json:"sharpeRatio"`  // annualized over daily P&L, nil with too little data
+	SortinoRatio         *float64             `json:"sortinoRatio"` // annualized over daily P&L, nil without losing days
+	Breakdowns           TradeStatsBreakdowns `json:"breakdowns"`
+}
+
+type TradeStatsBreakdowns struct {
+	BySymbol    []TradeStatsBucket `json:"bySymbol"`
+	ByExchange  []TradeStatsBucket `json:"byExchange"`
+	BySide      []TradeStatsBucket `json:"bySide"`
+	ByOrderType []TradeStatsBucket `json:"byOrderType"`
+	ByWeekday   []TradeStatsBucket `json:"byWeekday"` // by entry time
+	ByHour      []TradeStatsBucket `json:"byHour"`    // by entry time, "00" to "23"
+	BySetup     []TradeStatsBucket `json:"bySetup"`   // setup name, "none" without one
+	ByTag       []TradeStatsBucket `json:"byTag"`     // a trade counts once per tag, "untagged" without any
+}
+
+type TradeStatsBucket struct {
+	Key     string  `json:"key"`
+	Trades  int     `json:"trades"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	WinRate float64 `json:"winRate"`
+	NetPnL  float64 `json:"netPnl"`
+}
+
+const (
+	EquityIntervalDay   = "day"
+	EquityIntervalWeek  = "week"
+	EquityIntervalMonth = "month"
+)
+
+// EquityCurveResponse is the account balance over time: StartingBalance plus
+// realized P&L plus deposits and withdrawals, one point per period.
+type EquityCurveResponse struct {
+	Interval        string        `json:"interval"`
+	StartingBalance float64       `json:"startingBalance"`
+	Points          []EquityPoint `json:"points"`
+}
+
+// EquityPoint is the state of the account at the end of the period starting
+// at Time. The peak moves with deposits and withdrawals, so only trading
+// losses show up as drawdown.
+type EquityPoint struct {
+	Time          time.Time `json:"time"`
+	PnL           float64   `json:"pnl"`      // realized in this period
+	CashFlow      float64   `json:"cashFlow"` // deposits minus withdrawals in this period
+	CumulativePnL float64   `json:"cumulativePnl"`
+	Equity        float64   `json:"equity"`
+	Peak          float64   `json:"peak"`
+	Drawdown      float64   `json:"drawdown"`     // Peak - Equity
+	DrawdownPct   float64   `json:"drawdownPct"`  // Drawdown / Peak, 0.1 is 10% below the peak
+	DrawdownDays  int       `json:"drawdownDays"` // days since the peak was set, 0 at a new peak
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+const (
+	TaxTermShort = "short"
+	TaxTermLong  = "long"
+)
+
+// TaxLotDisposal is one sale matched against one acquired lot, or against
+// nothing when more was sold than the journal shows was bought (Unmatched,
+// with a zero cost basis). Amounts are in the quote currency.
+type TaxLotDisposal struct {
+	Symbol       string     `json:"symbol"`
+	Quantity     float64    `json:"quantity"`
+	DateAcquired *time.Time `json:"dateAcquired"`
+	DateSold     time.Time  `json:"dateSold"`
+	Proceeds     float64    `json:"proceeds"`  // before fees
+	CostBasis    float64    `json:"costBasis"` // including purchase fees
+	Fees         float64    `json:"fees"`      // sale fees
+	Gain         float64    `json:"gain"`      // Proceeds - CostBasis - Fees
+	Term         string     `json:"term"`      // TaxTermShort or TaxTermLong
+	BuyTradeID   uint       `json:"buyTradeId,omitempty"`
+	SellTradeID  uint       `json:"sellTradeId"`
+	Unmatched    bool       `json:"unmatched,omitempty"`
+}
+
+type TaxReportTotals struct {
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Fees      float64 `json:"fees"`
+	Gain      float64 `json:"gain"`
+}
+
+// TaxReportResponse lists the realized gains of one tax year.
+type TaxReportResponse struct {
+	Year      int              `json:"year"`
+	Method    string           `json:"method"`
+	ShortTerm TaxReportTotals  `json:"shortTerm"`
+	LongTerm  TaxReportTotals  `json:"longTerm"`
+	Disposals []TaxLotDisposal `json:"disposals"`
+}
@@ -98,16 +98,34 @@ type Trade struct {
 	IsShort           bool     `json:"is_short"`
 	IsLong            bool     `json:"is_long"`
 
-	Type         string   `gorm:"not null" json:"type"`
-	ContractType *string  `json:"contract_type"`
-	EntryPrice   float64  `json:"entry_price"`
-	ExitPrice    float64  `json:"exit_price"`
-	Fee          *float64 `json:"fee,omitempty"`
-	Indicators   *string  `json:"indicators,omitempty"`
-	Sentiment    *string  `json:"sentiment,omitempty"`
+	Type         string     `gorm:"not null" json:"type"`
+	ContractType *string    `json:"contract_type"`
+	EntryPrice   float64    `json:"entry_price"`
+	ExitPrice    float64    `json:"exit_price"`
+	ExitDate     *time.Time `json:"exit_date,omitempty"`
+	Fee          *float64   `json:"fee,omitempty"`
+	Indicators   *string    `json:"indicators,omitempty"`
+	Sentiment    *string    `json:"sentiment,omitempty"`
 
 	Notes *string `json:"notes,omitempty"`
 
+	// Derived from the columns above by package pnl; nil while the trade is
+	// open.
+	PnL *TradePnL `gorm:"-" json:"pnl"`
+
+	// Set only for trades imported by the exchange sync; the pair is what
+	// makes re-syncing the same fill idempotent.
+	UserExchangeID *uint   `gorm:"uniqueIndex:idx_trade_external" json:"user_exchange_id,omitempty"`
+	ExternalID     *string `gorm:"size:128;uniqueIndex:idx_trade_external" json:"external_id,omitempty"`
+
+	// Set when the trade has been grouped into a position.
+	PositionID *uint `gorm:"index" json:"position_id,omitempty"`
+
+	Tags      []Tag                 `gorm:"many2many:trade_tags;constraint:OnDelete:CASCADE" json:"tags,omitempty"`
+	SetupID   *uint                 `gorm:"index" json:"setup_id,omitempty"`
+	Setup     *Setup                `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	Checklist []TradeChecklistCheck `gorm:"constraint:OnDelete:CASCADE" json:"checklist,omitempty"`
+
 	UserID    uint `json:"user_id"`                                        // FK
 	User      User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"` // Opcional
 	CreatedAt time.Time
@@ -140,9 +158,15 @@ type TradePayload struct {
 	Type         string   `json:"type"`
 	EntryPrice   float64  `json:"entryPrice"`
 	ExitPrice    float64  `json:"exitPrice"`
+	ExitDate     *string  `json:"exitDate"` // RFC3339, optional
 	Fee          *float64 `json:"fee"`
 	Indicators   *string  `json:"indicators"`
 	Sentiment    *string  `json:"sentiment"`
+	// Tags are matched by name and created when missing. SetupID 0 removes
+	// the setup; nil leaves tags, setup and checklist as they are on update.
+	Tags      []string                `json:"tags"`
+	SetupID   *uint                   `json:"setupId"`
+	Checklist []TradeChecklistPayload `json:"checklist"`
 }
 
 type UpdateTradePayload struct {
@@ -189,12 +213,29 @@ type TradeResponse struct {
 	//	TradeDate  string   `json:"trade_date"` // keep as string
 	Type string `json:"type"`
 	//	Leverage   *float64 `json:"leverage"`
-	EntryPrice float64  `json:"entry_price"`
-	ExitPrice  float64  `json:"exit_price"`
-	Fee        *float64 `json:"fee"`
-	Indicators *string  `json:"indicators"`
-	Sentiment  *string  `json:"sentiment"`
+	EntryPrice float64               `json:"entry_price"`
+	ExitPrice  float64               `json:"exit_price"`
+	ExitDate   *time.Time            `json:"exit_date,omitempty"`
+	Fee        *float64              `json:"fee"`
+	Indicators *string               `json:"indicators"`
+	Sentiment  *string               `json:"sentiment"`
+	PnL        *TradePnL             `json:"pnl"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	SetupID    *uint                 `json:"setup_id,omitempty"`
+	Checklist  []TradeChecklistCheck `json:"checklist,omitempty"`
 	//	StopLoss   *float64 `json:"stop_loss"`
 	//	TakeProfit *float64 `json:"take_profit"`
 	//	Exchange   *string  `json:"exchange"`
 }
+
+// TradePnL is the realized result of a closed trade. Amounts are in the quote
+// currency of the symbol.
+type TradePnL struct {
+	GrossPnL       float64  `json:"gross_pnl"`
+	Fees           float64  `json:"fees"` // negative for a net maker rebate
+	NetPnL         float64  `json:"net_pnl"`
+	Margin         float64  `json:"margin"`           // capital committed: notional for spot, notional / leverage for futures
+	ReturnOnMargin float64  `json:"return_on_margin"` // NetPnL / Margin, 0.25 is +25%
+	RMultiple      *float64 `json:"r_multiple"`       // nil without a usable stop loss
+	HoldingSeconds *int64   `json:"holding_seconds"`  // nil without an exit date
+}
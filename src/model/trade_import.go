@@ -0,0 +1,28 @@
+package model
+
+const (
+	ImportRowValid     = "valid"
+	ImportRowInvalid   = "invalid"
+	ImportRowDuplicate = "duplicate"
+)
+
+// TradeImportResponse reports what an import did, or would do on a dry run,
+// row by row. Row numbers are 1-based and count the header line.
+type TradeImportResponse struct {
+	Format     string                 `json:"format"`
+	DryRun     bool                   `json:"dryRun"`
+	Committed  bool                   `json:"committed"`
+	Total      int                    `json:"total"`
+	Valid      int                    `json:"valid"`
+	Invalid    int                    `json:"invalid"`
+	Duplicates int                    `json:"duplicates"`
+	Imported   int                    `json:"imported"`
+	Rows       []TradeImportRowResult `json:"rows"`
+}
+
+type TradeImportRowResult struct {
+	Row    int           `json:"row"`
+	Status string        `json:"status"` // ImportRowValid, ImportRowInvalid or ImportRowDuplicate
+	Error  string        `json:"error,omitempty"`
+	Trade  *TradePayload `json:"trade,omitempty"`
+}
@@ -16,4 +16,8 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Trades    []Trade `gorm:"foreignKey:UserID"` // One-to-many
+
+	// EmailVerifiedAt is set when the user opens the link mailed to Email and
+	// cleared when Email changes.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
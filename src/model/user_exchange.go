@@ -2,16 +2,26 @@ package model
 
 import "time"
 
+// UserExchange stores a user's API credentials for one exchange. The *Enc
+// columns are AES-GCM ciphertexts under a per-record data key, which is itself
+// wrapped with master key KeyVersion (see package credentials).
 type UserExchange struct {
-	ID                uint      `gorm:"primaryKey" json:"id"`
-	UserID            uint      `gorm:"not null;index:idx_user_exchange,unique" json:"user_id"`
-	ExchangeID        uint      `gorm:"not null;index:idx_user_exchange,unique" json:"exchange_id"`
-	APIKeyHash        string    `gorm:"column:api_key;type:text" json:"-"`
-	APISecretHash     string    `gorm:"column:api_secret;type:text" json:"-"`
-	APIPassphraseHash string    `gorm:"column:api_passphrase;type:text" json:"-"`
-	ShowInForms       bool      `gorm:"not null;default:false" json:"show_in_forms"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index:idx_user_exchange,unique" json:"user_id"`
+	ExchangeID       uint       `gorm:"not null;index:idx_user_exchange,unique" json:"exchange_id"`
+	APIKeyEnc        string     `gorm:"column:api_key;type:text" json:"-"`
+	APISecretEnc     string     `gorm:"column:api_secret;type:text" json:"-"`
+	APIPassphraseEnc string     `gorm:"column:api_passphrase;type:text" json:"-"`
+	DataKey          string     `gorm:"column:data_key;type:text" json:"-"`
+	KeyVersion       int        `gorm:"not null;default:0" json:"-"`
+	NeedsReentry     bool       `gorm:"not null;default:false" json:"needs_reentry"` // legacy hashed credentials were discarded
+	ShowInForms      bool       `gorm:"not null;default:false" json:"show_in_forms"`
+	LastVerifiedAt   *time.Time `json:"last_verified_at"`
+	LastError        string     `gorm:"type:text" json:"last_error"` // empty when the last verification succeeded
+	CanTrade         *bool      `json:"can_trade"`                   // nil when the exchange does not report key permissions
+	CanWithdraw      *bool      `json:"can_withdraw"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 
 	User     *User     `gorm:"constraint:OnDelete:CASCADE" json:"-"`
 	Exchange *Exchange `gorm:"constraint:OnDelete:CASCADE" json:"exchange"`
@@ -26,13 +36,25 @@ type UpsertUserExchangePayload struct {
 }
 
 type UserExchangeResponse struct {
-	ID               uint   `json:"id"`
-	ExchangeID       uint   `json:"exchangeId"`
-	ExchangeName     string `json:"exchangeName,omitempty"`
-	ShowInForms      bool   `json:"showInForms"`
-	HasAPIKey        bool   `json:"hasApiKey"`
-	HasAPISecret     bool   `json:"hasApiSecret"`
-	HasAPIPassphrase bool   `json:"hasApiPassphrase"`
+	ID               uint                             `json:"id"`
+	ExchangeID       uint                             `json:"exchangeId"`
+	ExchangeName     string                           `json:"exchangeName,omitempty"`
+	ShowInForms      bool                             `json:"showInForms"`
+	HasAPIKey        bool                             `json:"hasApiKey"`
+	HasAPISecret     bool                             `json:"hasApiSecret"`
+	HasAPIPassphrase bool                             `json:"hasApiPassphrase"`
+	NeedsReentry     bool                             `json:"needsReentry"`
+	LastVerifiedAt   *time.Time                       `json:"lastVerifiedAt"`
+	LastError        string                           `json:"lastError,omitempty"`
+	Permissions      *UserExchangePermissionsResponse `json:"permissions"`
+}
+
+// UserExchangePermissionsResponse is what the stored API key may do. It is
+// only present once an exchange that reports key permissions has verified it.
+type UserExchangePermissionsResponse struct {
+	CanTrade    bool `json:"canTrade"`
+	CanWithdraw bool `json:"canWithdraw"`
+	ReadOnly    bool `json:"readOnly"`
 }
 
 func NewUserExchangeResponse(ue *UserExchange) UserExchangeResponse {
@@ -44,9 +66,20 @@ func NewUserExchangeResponse(ue *UserExchange) UserExchangeResponse {
 		ID:               ue.ID,
 		ExchangeID:       ue.ExchangeID,
 		ShowInForms:      ue.ShowInForms,
-		HasAPIKey:        ue.APIKeyHash != "",
-		HasAPISecret:     ue.APISecretHash != "",
-		HasAPIPassphrase: ue.APIPassphraseHash != "",
+		HasAPIKey:        ue.APIKeyEnc != "",
+		HasAPISecret:     ue.APISecretEnc != "",
+		HasAPIPassphrase: ue.APIPassphraseEnc != "",
+		NeedsReentry:     ue.NeedsReentry,
+		LastVerifiedAt:   ue.LastVerifiedAt,
+		LastError:        ue.LastError,
+	}
+
+	if ue.CanTrade != nil && ue.CanWithdraw != nil {
+		resp.Permissions = &UserExchangePermissionsResponse{
+			CanTrade:    *ue.CanTrade,
+			CanWithdraw: *ue.CanWithdraw,
+			ReadOnly:    !*ue.CanTrade && !*ue.CanWithdraw,
+		}
 	}
 
 	if ue.Exchange != nil {
@@ -22,6 +22,8 @@ type UserResponse struct {
 	LastSeen  string `json:"last_seen,omitempty"`
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at,omitempty"`
+
+	EmailVerified bool `json:"email_verified"`
 }
 
 func (u *User) ToResponse() UserResponse {
@@ -33,6 +35,8 @@ func (u *User) ToResponse() UserResponse {
 		LastName:  u.LastName,
 		Bio:       u.Bio,
 		AvatarURL: u.AvatarURL,
+
+		EmailVerified: u.EmailVerifiedAt != nil,
 	}
 
 	if !u.LastLogin.IsZero() {
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+)
+
+// UserToken is a single-use token mailed to a user, either to confirm their
+// email address or to set a new password. Only the SHA-256 of the token is
+// stored.
+type UserToken struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	Purpose   string `gorm:"size:32;not null" json:"purpose"`
+	TokenHash string `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	// Email is the address the token was sent to.
+	Email     string     `gorm:"size:255;not null" json:"email"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type EmailTokenPayload struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
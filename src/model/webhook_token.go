@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// WebhookToken authenticates TradingView webhooks for a single user. Only the
+// SHA-256 of the token is stored; the plaintext is shown once on create/rotate.
+type WebhookToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"size:100" json:"name"`
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	TokenPrefix string     `gorm:"size:16" json:"token_prefix"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type CreateWebhookTokenPayload struct {
+	Name string `json:"name"`
+}
+
+type WebhookTokenResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"tokenPrefix"`
+	Token       string     `json:"token,omitempty"`
+	WebhookPath string     `json:"webhookPath,omitempty"`
+	Revoked     bool       `json:"revoked"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+func NewWebhookTokenResponse(t *WebhookToken) WebhookTokenResponse {
+	if t == nil {
+		return WebhookTokenResponse{}
+	}
+
+	return WebhookTokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.TokenPrefix,
+		Revoked:     t.RevokedAt != nil,
+		LastUsedAt:  t.LastUsedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
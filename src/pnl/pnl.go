@@ -0,0 +1,70 @@
+// Package pnl derives realized profit and loss from journaled trades.
+package pnl
+
+import (
+	"math"
+	"strings"
+
+	"vsC1Y2025V01/src/model"
+)
+
+// IsFutures reports whether t was traded on a derivatives market. Trades
+// without a contract type are treated as spot.
+func IsFutures(t model.Trade) bool {
+	if t.ContractType == nil {
+		return false
+	}
+	contractType := strings.ToLower(strings.TrimSpace(*t.ContractType))
+	return contractType != "" && contractType != "spot"
+}
+
+// IsClosed reports whether t has everything needed to compute its P&L.
+func IsClosed(t model.Trade) bool {
+	return t.EntryPrice > 0 && t.ExitPrice > 0 && t.Quantity > 0
+}
+
+// Compute returns the realized P&L of t, or nil while t is still open.
+//
+// Quantity is in units of the base asset for both spot and (linear) futures
+// trades, and Fee is the total fee of the round trip in the quote currency.
+// A negative Fee is a net maker rebate and adds to the P&L.
+// Spot trades commit their full entry notional; futures trades commit the
+// notional divided by their leverage.
+func Compute(t model.Trade) *model.TradePnL {
+	if !IsClosed(t) {
+		return nil
+	}
+
+	direction := 1.0
+	if t.IsShort && !t.IsLong {
+		direction = -1
+	}
+
+	result := &model.TradePnL{
+		GrossPnL: direction * (t.ExitPrice - t.EntryPrice) * t.Quantity,
+	}
+	if t.Fee != nil {
+		result.Fees = *t.Fee
+	}
+	result.NetPnL = result.GrossPnL - result.Fees
+
+	result.Margin = t.EntryPrice * t.Quantity
+	if IsFutures(t) && t.Leverage != nil && *t.Leverage > 0 {
+		result.Margin /= *t.Leverage
+	}
+	result.ReturnOnMargin = result.NetPnL / result.Margin
+
+	// 1R is what the trade stood to lose between entry and stop.
+	if t.StopLoss != nil && *t.StopLoss > 0 && *t.StopLoss != t.EntryPrice {
+		risk := math.Abs(t.EntryPrice-*t.StopLoss) * t.Quantity
+		r := result.NetPnL / risk
+		result.RMultiple = &r
+	}
+
+	if t.ExitDate != nil && !t.ExitDate.Before(t.TradeDate) {
+		seconds := int64(t.ExitDate.Sub(t.TradeDate).Seconds())
+		result.HoldingSeconds = &seconds
+	}
+
+	return result
+}
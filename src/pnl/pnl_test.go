@@ -0,0 +1,91 @@
+package pnl
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCompute(t *testing.T) {
+	opened := time.Date(2025, 7, 11, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		trade     model.Trade
+		gross     float64
+		net       float64
+		margin    float64
+		rMultiple *float64
+		holding   *int64
+	}{
+		{
+			name: "spot long",
+			trade: model.Trade{
+				ContractType: ptr("spot"), IsLong: true, EntryPrice: 100, ExitPrice: 110, Quantity: 2,
+				Fee: ptr(1.0), StopLoss: ptr(95.0), Leverage: ptr(10.0),
+				TradeDate: opened, ExitDate: ptr(opened.Add(90 * time.Minute)),
+			},
+			gross: 20, net: 19, margin: 200, rMultiple: ptr(1.9), holding: ptr(int64(5400)),
+		},
+		{
+			name: "futures short",
+			trade: model.Trade{
+				ContractType: ptr("futures"), IsShort: true, EntryPrice: 3200, ExitPrice: 3100, Quantity: 2,
+				Fee: ptr(4.0), StopLoss: ptr(3300.0), Leverage: ptr(5.0),
+			},
+			gross: 200, net: 196, margin: 1280, rMultiple: ptr(0.98),
+		},
+		{
+			name: "futures short loss without stop",
+			trade: model.Trade{
+				ContractType: ptr("futures"), IsShort: true, EntryPrice: 100, ExitPrice: 105, Quantity: 1,
+			},
+			gross: -5, net: -5, margin: 100,
+		},
+		{
+			name: "maker rebate",
+			trade: model.Trade{
+				ContractType: ptr("futures"), IsLong: true, EntryPrice: 100, ExitPrice: 101, Quantity: 1,
+				Fee: ptr(-0.5),
+			},
+			gross: 1, net: 1.5, margin: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.trade)
+			if got == nil {
+				t.Fatalf("expected P&L for closed trade")
+			}
+			if !approx(got.GrossPnL, tt.gross) || !approx(got.NetPnL, tt.net) || !approx(got.Margin, tt.margin) {
+				t.Fatalf("unexpected P&L: %+v", got)
+			}
+			if !approx(got.ReturnOnMargin, tt.net/tt.margin) {
+				t.Fatalf("expected return on margin %v, got %v", tt.net/tt.margin, got.ReturnOnMargin)
+			}
+			if (got.RMultiple == nil) != (tt.rMultiple == nil) || (got.RMultiple != nil && !approx(*got.RMultiple, *tt.rMultiple)) {
+				t.Fatalf("expected R-multiple %v, got %v", tt.rMultiple, got.RMultiple)
+			}
+			if (got.HoldingSeconds == nil) != (tt.holding == nil) || (got.HoldingSeconds != nil && *got.HoldingSeconds != *tt.holding) {
+				t.Fatalf("expected holding time %v, got %v", tt.holding, got.HoldingSeconds)
+			}
+		})
+	}
+}
+
+func TestComputeOpenTrade(t *testing.T) {
+	if got := Compute(model.Trade{IsLong: true, EntryPrice: 100, Quantity: 1}); got != nil {
+		t.Fatalf("expected no P&L for an open trade, got %+v", got)
+	}
+}
@@ -0,0 +1,481 @@
+package positions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func parseExecution(payload model.PositionExecutionPayload) (model.PositionExecution, error) {
+	kind := strings.ToLower(strings.TrimSpace(payload.Type))
+	if kind != model.ExecutionEntry && kind != model.ExecutionExit {
+		return model.PositionExecution{}, errors.New("execution type must be entry or exit")
+	}
+	if payload.Quantity <= 0 {
+		return model.PositionExecution{}, errors.New("execution quantity must be > 0")
+	}
+	if payload.Price <= 0 {
+		return model.PositionExecution{}, errors.New("execution price must be > 0")
+	}
+	executedAt, err := time.Parse(time.RFC3339, strings.TrimSpace(payload.ExecutedAt))
+	if err != nil {
+		return model.PositionExecution{}, errors.New("executedAt must be an RFC3339 timestamp")
+	}
+
+	execution := model.PositionExecution{
+		Type:       kind,
+		Quantity:   payload.Quantity,
+		Price:      payload.Price,
+		ExecutedAt: executedAt,
+	}
+	if payload.Fee != nil {
+		if *payload.Fee < 0 {
+			return model.PositionExecution{}, errors.New("execution fee must be >= 0")
+		}
+		execution.Fee = *payload.Fee
+	}
+
+	return execution, nil
+}
+
+// addTrades appends the executions of the given journal trades to p.
+func addTrades(p *model.Position, userID uint, tradeIDs []uint) error {
+	if len(tradeIDs) == 0 {
+		return nil
+	}
+
+	trades, err := getPositionStore().FindTrades(userID, tradeIDs)
+	if err != nil {
+		return err
+	}
+	if len(trades) != len(uniqueIDs(tradeIDs)) {
+		return errInvalidPosition{errors.New("one or more trades were not found")}
+	}
+
+	for _, t := range trades {
+		for _, e := range p.Executions {
+			if e.TradeID != nil && *e.TradeID == t.ID {
+				return errInvalidPosition{fmt.Errorf("trade %d is already part of the position", t.ID)}
+			}
+		}
+
+		executions, err := executionsFromTrade(p, t)
+		if err != nil {
+			return errInvalidPosition{err}
+		}
+		p.Executions = append(p.Executions, executions...)
+	}
+
+	return nil
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// errInvalidPosition marks errors caused by the request rather than the
+// store, so handlers can answer 400.
+type errInvalidPosition struct{ err error }
+
+func (e errInvalidPosition) Error() string { return e.err.Error() }
+func (e errInvalidPosition) Unwrap() error { return e.err }
+
+// saveRecalculated recomputes p and stores it, answering the request with
+// the position on success.
+func saveRecalculated(w http.ResponseWriter, logger *logrus.Entry, p *model.Position, status int) {
+	if err := Recalculate(p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := getPositionStore().SavePosition(p); err != nil {
+		logger.WithError(err).Error("failed to save position")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	writePosition(w, logger, p, status)
+}
+
+func writePosition(w http.ResponseWriter, logger *logrus.Entry, p *model.Position, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(model.NewPositionResponse(p)); err != nil {
+		logger.WithError(err).Error("failed to encode position response")
+	}
+}
+
+// loadPosition resolves the {id} path param to one of the caller's
+// positions, answering the request itself when it cannot.
+func loadPosition(w http.ResponseWriter, r *http.Request, logger *logrus.Entry, userID uint) (*model.Position, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	position, err := getPositionStore().GetPosition(userID, uint(id))
+	if err != nil {
+		if errors.Is(err, ErrPositionNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return nil, false
+		}
+
+		logger.WithError(err).Error("failed to load position")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return position, true
+}
+
+// ListPositionsHandler returns the caller's positions, most recently opened
+// first, optionally narrowed with status=open|closed.
+func ListPositionsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing positions")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		status := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
+		if status != "" && status != model.PositionOpen && status != model.PositionClosed {
+			http.Error(w, "status must be open or closed", http.StatusBadRequest)
+			return
+		}
+
+		offset, limit, err := query.ParseRange(r, 10, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		positions, total, err := getPositionStore().ListPositions(user.ID, status, offset, limit)
+		if err != nil {
+			logger.WithError(err).Error("failed to list positions")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.PositionResponse, 0, len(positions))
+		for i := range positions {
+			responses = append(responses, model.NewPositionResponse(&positions[i]))
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		w.Header().Set("X-Total-Count", fmt.Sprintf("%d", total))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode position list response")
+		}
+	}
+}
+
+func GetPositionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while fetching position")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		position, ok := loadPosition(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		writePosition(w, logger, position, http.StatusOK)
+	}
+}
+
+// CreatePositionHandler opens a position from explicit executions and/or
+// existing journal trades, which are linked to it.
+func CreatePositionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating position")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.CreatePositionPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid position payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		symbol := strings.TrimSpace(payload.Symbol)
+		if symbol == "" {
+			http.Error(w, "symbol is required", http.StatusBadRequest)
+			return
+		}
+
+		var isLong bool
+		switch strings.ToLower(strings.TrimSpace(payload.Side)) {
+		case "long":
+			isLong = true
+		case "short":
+		default:
+			http.Error(w, "side must be long or short", http.StatusBadRequest)
+			return
+		}
+
+		if len(payload.Executions) == 0 && len(payload.TradeIDs) == 0 {
+			http.Error(w, ErrNoExecutions.Error(), http.StatusBadRequest)
+			return
+		}
+
+		position := &model.Position{
+			UserID:       user.ID,
+			Exchange:     payload.Exchange,
+			Symbol:       symbol,
+			ContractType: payload.ContractType,
+			IsLong:       isLong,
+			Leverage:     payload.Leverage,
+			StopLoss:     payload.StopLoss,
+			TakeProfit:   payload.TakeProfit,
+			Notes:        payload.Notes,
+		}
+
+		for _, p := range payload.Executions {
+			execution, err := parseExecution(p)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			position.Executions = append(position.Executions, execution)
+		}
+
+		if err := addTrades(position, user.ID, payload.TradeIDs); err != nil {
+			var invalid errInvalidPosition
+			if errors.As(err, &invalid) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			logger.WithError(err).Error("failed to load trades for position")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		saveRecalculated(w, logger, position, http.StatusCreated)
+	}
+}
+
+// UpdatePositionHandler edits the risk levels and notes of a position. Its
+// size and prices only change through executions.
+func UpdatePositionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while updating position")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.UpdatePositionPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid position update payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		position, ok := loadPosition(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		if payload.Leverage != nil {
+			position.Leverage = *payload.Leverage
+		}
+		if payload.StopLoss != nil {
+			position.StopLoss = *payload.StopLoss
+		}
+		if payload.TakeProfit != nil {
+			position.TakeProfit = *payload.TakeProfit
+		}
+		if payload.Notes != nil {
+			position.Notes = *payload.Notes
+		}
+
+		saveRecalculated(w, logger, position, http.StatusOK)
+	}
+}
+
+func DeletePositionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting position")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := getPositionStore().DeletePosition(user.ID, uint(id))
+		if err != nil {
+			logger.WithError(err).Error("failed to delete position")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !deleted {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AddExecutionHandler records an add or a partial exit on a position.
+func AddExecutionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while adding execution")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.PositionExecutionPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid execution payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		execution, err := parseExecution(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		position, ok := loadPosition(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		position.Executions = append(position.Executions, execution)
+		saveRecalculated(w, logger, position, http.StatusCreated)
+	}
+}
+
+// DeleteExecutionHandler removes one execution. Removing an execution taken
+// from a journal trade unlinks that trade, together with the other half of
+// a round trip.
+func DeleteExecutionHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting execution")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		executionID, err := strconv.ParseUint(chi.URLParam(r, "executionID"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid execution id", http.StatusBadRequest)
+			return
+		}
+
+		position, ok := loadPosition(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		var removed *model.PositionExecution
+		for i := range position.Executions {
+			if position.Executions[i].ID == uint(executionID) {
+				removed = &position.Executions[i]
+				break
+			}
+		}
+		if removed == nil {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		removedID, removedTrade := removed.ID, removed.TradeID
+		kept := position.Executions[:0]
+		for _, e := range position.Executions {
+			if e.ID == removedID || (removedTrade != nil && e.TradeID != nil && *e.TradeID == *removedTrade) {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		position.Executions = kept
+
+		saveRecalculated(w, logger, position, http.StatusOK)
+	}
+}
+
+// LinkTradesHandler adds existing journal trades to a position.
+func LinkTradesHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while linking trades")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.LinkPositionTradesPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil || len(payload.TradeIDs) == 0 {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		position, ok := loadPosition(w, r, logger, user.ID)
+		if !ok {
+			return
+		}
+
+		if err := addTrades(position, user.ID, payload.TradeIDs); err != nil {
+			var invalid errInvalidPosition
+			if errors.As(err, &invalid) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			logger.WithError(err).Error("failed to load trades for position")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		saveRecalculated(w, logger, position, http.StatusOK)
+	}
+}
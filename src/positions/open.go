@@ -0,0 +1,155 @@
+package positions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maintenanceMarginRate is the lowest tier of the major perpetual
+	// exchanges. Real liquidation also depends on the tier, the margin mode
+	// and funding, so the price derived from it is only an estimate.
+	maintenanceMarginRate = 0.005
+
+	priceTimeout = 10 * time.Second
+)
+
+// liquidationPrice estimates where an isolated margin position would be
+// liquidated. It is nil for positions without leverage.
+func liquidationPrice(p *model.Position) *float64 {
+	if p.Leverage == nil || *p.Leverage <= 1 || p.AvgEntryPrice <= 0 {
+		return nil
+	}
+
+	initialMargin := 1 / *p.Leverage
+	price := p.AvgEntryPrice * (1 - initialMargin + maintenanceMarginRate)
+	if !p.IsLong {
+		price = p.AvgEntryPrice * (1 + initialMargin - maintenanceMarginRate)
+	}
+	return &price
+}
+
+// distanceTo is how far the price can still move before reaching level:
+// the stop below a long (above a short) or the target above a long (below
+// a short). It turns negative once the level has been crossed.
+func distanceTo(price, level float64, towardsLoss, isLong bool) (float64, float64) {
+	distance := level - price
+	if isLong == towardsLoss {
+		distance = price - level
+	}
+	return distance, distance / price
+}
+
+// valueOpenPosition adds the market view of p at price. A nil price leaves the
+// market fields empty.
+func valueOpenPosition(p *model.Position, price *float64) model.OpenPositionResponse {
+	view := model.OpenPositionResponse{
+		PositionResponse: model.NewPositionResponse(p),
+		LiquidationPrice: liquidationPrice(p),
+	}
+	if price == nil || *price <= 0 {
+		return view
+	}
+
+	direction := 1.0
+	if !p.IsLong {
+		direction = -1.0
+	}
+
+	unrealized := direction * (*price - p.AvgEntryPrice) * p.Quantity
+	view.LastPrice = price
+	view.UnrealizedPnL = &unrealized
+
+	margin := p.AvgEntryPrice * p.Quantity
+	if p.Leverage != nil && *p.Leverage > 1 {
+		margin /= *p.Leverage
+	}
+	if margin > 0 {
+		roi := unrealized / margin
+		view.ReturnOnMargin = &roi
+	}
+
+	if p.StopLoss != nil && *p.StopLoss > 0 {
+		distance, pct := distanceTo(*price, *p.StopLoss, true, p.IsLong)
+		view.StopLossDistance, view.StopLossDistancePct = &distance, &pct
+	}
+	if p.TakeProfit != nil && *p.TakeProfit > 0 {
+		distance, pct := distanceTo(*price, *p.TakeProfit, false, p.IsLong)
+		view.TakeProfitDistance, view.TakeProfitDistancePct = &distance, &pct
+	}
+
+	return view
+}
+
+// OpenPositionsHandler returns every open position of the caller valued at
+// the current market price. A symbol that cannot be priced does not fail
+// the request; its position comes back with priceError set instead.
+func OpenPositionsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing open positions")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		positions, _, err := getPositionStore().ListPositions(user.ID, model.PositionOpen, 0, -1)
+		if err != nil {
+			logger.WithError(err).Error("failed to list open positions")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), priceTimeout)
+		defer cancel()
+
+		type quote struct {
+			price *float64
+			err   error
+		}
+		quotes := make(map[string]quote)
+		source := getPriceSource()
+
+		responses := make([]model.OpenPositionResponse, 0, len(positions))
+		for i := range positions {
+			p := &positions[i]
+
+			exchange := ""
+			if p.Exchange != nil {
+				exchange = strings.TrimSpace(*p.Exchange)
+			}
+			key := strings.ToLower(exchange) + "|" + strings.ToUpper(p.Symbol)
+
+			q, seen := quotes[key]
+			if !seen {
+				price, err := source.LastPrice(ctx, exchange, p.Symbol)
+				if err != nil {
+					logger.WithError(err).WithField("symbol", p.Symbol).Warn("failed to fetch last price")
+					q = quote{err: err}
+				} else {
+					q = quote{price: &price}
+				}
+				quotes[key] = q
+			}
+
+			view := valueOpenPosition(p, q.price)
+			if q.err != nil {
+				view.PriceError = q.err.Error()
+			}
+			responses = append(responses, view)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode open position response")
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package positions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakePriceSource struct {
+	prices map[string]float64
+	calls  int
+}
+
+func (s *fakePriceSource) LastPrice(ctx context.Context, exchange, symbol string) (float64, error) {
+	s.calls++
+	price, ok := s.prices[symbol]
+	if !ok {
+		return 0, ErrNoPrice
+	}
+	return price, nil
+}
+
+func TestValueOpenPosition(t *testing.T) {
+	long := &model.Position{IsLong: true, Quantity: 2, AvgEntryPrice: 100, Leverage: ptr(10.0), StopLoss: ptr(95.0), TakeProfit: ptr(120.0)}
+
+	view := valueOpenPosition(long, ptr(110.0))
+	if !near(*view.UnrealizedPnL, 20) || !near(*view.ReturnOnMargin, 1) {
+		t.Fatalf("unexpected long P&L: pnl=%v roi=%v", *view.UnrealizedPnL, *view.ReturnOnMargin)
+	}
+	if !near(*view.StopLossDistance, 15) || !near(*view.TakeProfitDistance, 10) || !near(*view.StopLossDistancePct, 15.0/110) {
+		t.Fatalf("unexpected long distances: sl=%v tp=%v", *view.StopLossDistance, *view.TakeProfitDistance)
+	}
+	if !near(*view.LiquidationPrice, 90.5) {
+		t.Fatalf("unexpected long liquidation price: %v", *view.LiquidationPrice)
+	}
+
+	short := &model.Position{IsLong: false, Quantity: 1, AvgEntryPrice: 100, Leverage: ptr(5.0), StopLoss: ptr(105.0), TakeProfit: ptr(90.0)}
+
+	view = valueOpenPosition(short, ptr(107.0))
+	if !near(*view.UnrealizedPnL, -7) || !near(*view.StopLossDistance, -2) || !near(*view.TakeProfitDistance, 17) {
+		t.Fatalf("unexpected short view: pnl=%v sl=%v tp=%v", *view.UnrealizedPnL, *view.StopLossDistance, *view.TakeProfitDistance)
+	}
+	if !near(*view.LiquidationPrice, 119.5) {
+		t.Fatalf("unexpected short liquidation price: %v", *view.LiquidationPrice)
+	}
+
+	spot := &model.Position{IsLong: true, Quantity: 1, AvgEntryPrice: 100}
+	view = valueOpenPosition(spot, nil)
+	if view.LastPrice != nil || view.UnrealizedPnL != nil || view.LiquidationPrice != nil {
+		t.Fatalf("expected no market fields without a price or leverage: %+v", view)
+	}
+}
+
+func TestOpenPositionsHandler(t *testing.T) {
+	store := newInMemoryPositionStore()
+	store.positions[1] = &model.Position{ID: 1, UserID: 1, Symbol: "BTCUSDT", IsLong: true, Status: model.PositionOpen, Quantity: 1, AvgEntryPrice: 100}
+	store.positions[2] = &model.Position{ID: 2, UserID: 1, Symbol: "DOGEUSDT", IsLong: true, Status: model.PositionOpen, Quantity: 1, AvgEntryPrice: 1}
+	store.positions[3] = &model.Position{ID: 3, UserID: 1, Symbol: "BTCUSDT", IsLong: false, Status: model.PositionClosed}
+	SetPositionStore(store)
+	t.Cleanup(func() { SetPositionStore(nil) })
+
+	prices := &fakePriceSource{prices: map[string]float64{"BTCUSDT": 125}}
+	SetPriceSource(prices)
+	t.Cleanup(func() { SetPriceSource(nil) })
+
+	req := httptest.NewRequest(http.MethodGet, "/positions/open", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec := httptest.NewRecorder()
+	OpenPositionsHandler(logrus.NewEntry(logrus.New()))(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var views []model.OpenPositionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&views); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(views) != 2 {
+		t.Fatalf("expected the two open positions, got %d", len(views))
+	}
+
+	for _, v := range views {
+		switch v.Symbol {
+		case "BTCUSDT":
+			if v.LastPrice == nil || *v.LastPrice != 125 || *v.UnrealizedPnL != 25 {
+				t.Fatalf("unexpected BTC view: %+v", v)
+			}
+		case "DOGEUSDT":
+			if v.LastPrice != nil || v.PriceError != ErrNoPrice.Error() {
+				t.Fatalf("expected a price error for DOGE, got %+v", v)
+			}
+		}
+	}
+}
+
+type stubPriceProvider struct {
+	price float64
+	err   error
+}
+
+func (p stubPriceProvider) GetPrice(ctx context.Context, symbol string) (float64, error) {
+	return p.price, p.err
+}
+
+func TestConnectorPriceSourceFallsBack(t *testing.T) {
+	source := newConnectorPriceSource()
+	source.providers["kucoin"] = nil
+	source.providers["binance"] = stubPriceProvider{err: errors.New("invalid symbol")}
+	source.providers["mexc"] = stubPriceProvider{price: 0.42}
+
+	price, err := source.LastPrice(context.Background(), "KuCoin", "XYZUSDT")
+	if err != nil || price != 0.42 {
+		t.Fatalf("expected the MEXC price, got %v, %v", price, err)
+	}
+}
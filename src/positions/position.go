@@ -0,0 +1,166 @@
+package positions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"vsC1Y2025V01/src/model"
+)
+
+// quantityEpsilon absorbs float noise when an exit closes the whole size.
+const quantityEpsilon = 1e-9
+
+var (
+	ErrNoExecutions  = errors.New("position needs at least one execution")
+	ErrOversizedExit = errors.New("exit is larger than the open size")
+)
+
+// sortExecutions orders executions by time. Entries go first when they share
+// a timestamp with an exit, so a round trip recorded with a single date still
+// closes itself.
+func sortExecutions(executions []model.PositionExecution) {
+	sort.SliceStable(executions, func(i, j int) bool {
+		a, b := executions[i], executions[j]
+		if !a.ExecutedAt.Equal(b.ExecutedAt) {
+			return a.ExecutedAt.Before(b.ExecutedAt)
+		}
+		return a.Type == model.ExecutionEntry && b.Type != model.ExecutionEntry
+	})
+}
+
+// Recalculate replays p.Executions in time order and updates the summary
+// columns of p and the realized P&L of each exit. Realized P&L uses the
+// average cost method, the way exchanges report it: adds move the average
+// entry, exits do not. Entry fees are spread over the size they bought and
+// charged to exits in proportion, so the exits add up to the position total.
+func Recalculate(p *model.Position) error {
+	if len(p.Executions) == 0 {
+		return ErrNoExecutions
+	}
+
+	sortExecutions(p.Executions)
+
+	direction := 1.0
+	if !p.IsLong {
+		direction = -1.0
+	}
+
+	var (
+		remaining, avgEntry, openFees float64
+		exitQty, exitNotional         float64
+		realized, fees                float64
+		lastExit                      = p.Executions[0].ExecutedAt
+	)
+
+	for i := range p.Executions {
+		e := &p.Executions[i]
+		fees += e.Fee
+
+		switch e.Type {
+		case model.ExecutionEntry:
+			avgEntry = (avgEntry*remaining + e.Price*e.Quantity) / (remaining + e.Quantity)
+			remaining += e.Quantity
+			openFees += e.Fee
+			e.RealizedPnL = nil
+
+		case model.ExecutionExit:
+			if e.Quantity > remaining+quantityEpsilon {
+				return fmt.Errorf("%w: %g exited at %s with %g open", ErrOversizedExit, e.Quantity, e.ExecutedAt.Format("2006-01-02 15:04:05"), remaining)
+			}
+
+			quantity := math.Min(e.Quantity, remaining)
+			entryFees := openFees * quantity / remaining
+			pnl := direction*(e.Price-avgEntry)*quantity - e.Fee - entryFees
+			e.RealizedPnL = &pnl
+
+			realized += pnl
+			openFees -= entryFees
+			remaining -= quantity
+			if remaining < quantityEpsilon {
+				remaining = 0
+				openFees = 0
+			}
+			exitQty += quantity
+			exitNotional += e.Price * quantity
+			lastExit = e.ExecutedAt
+
+		default:
+			return fmt.Errorf("unknown execution type %q", e.Type)
+		}
+	}
+
+	p.Quantity = remaining
+	p.AvgEntryPrice = avgEntry
+	p.AvgExitPrice = 0
+	if exitQty > 0 {
+		p.AvgExitPrice = exitNotional / exitQty
+	}
+	p.RealizedPnL = realized
+	p.Fees = fees
+	p.OpenedAt = p.Executions[0].ExecutedAt
+
+	if remaining > 0 || exitQty == 0 {
+		p.Status = model.PositionOpen
+		p.ClosedAt = nil
+	} else {
+		closedAt := lastExit
+		p.Status = model.PositionClosed
+		p.ClosedAt = &closedAt
+	}
+
+	return nil
+}
+
+// executionsFromTrade turns a journal trade into executions of p. A single
+// execution on the side of the position is an entry and one on the other
+// side an exit; a round trip becomes an entry and an exit with its fee split
+// between them.
+func executionsFromTrade(p *model.Position, t model.Trade) ([]model.PositionExecution, error) {
+	if !strings.EqualFold(t.Symbol, p.Symbol) {
+		return nil, fmt.Errorf("trade %d is for %s, not %s", t.ID, t.Symbol, p.Symbol)
+	}
+	if t.PositionID != nil && (p.ID == 0 || *t.PositionID != p.ID) {
+		return nil, fmt.Errorf("trade %d already belongs to position %d", t.ID, *t.PositionID)
+	}
+
+	price := t.EntryPrice
+	if price <= 0 {
+		price = t.Price
+	}
+	if t.Quantity <= 0 || price <= 0 {
+		return nil, fmt.Errorf("trade %d has no size or price", t.ID)
+	}
+
+	fee := 0.0
+	if t.Fee != nil {
+		fee = math.Abs(*t.Fee)
+	}
+	tradeID := t.ID
+	isLong := !(t.IsShort && !t.IsLong)
+
+	if t.ExitPrice <= 0 {
+		kind := model.ExecutionEntry
+		if isLong != p.IsLong {
+			kind = model.ExecutionExit
+		}
+		return []model.PositionExecution{
+			{TradeID: &tradeID, Type: kind, Quantity: t.Quantity, Price: price, Fee: fee, ExecutedAt: t.TradeDate},
+		}, nil
+	}
+
+	if isLong != p.IsLong {
+		return nil, fmt.Errorf("trade %d is a round trip on the other side of the position", t.ID)
+	}
+
+	closedAt := t.TradeDate
+	if t.ExitDate != nil {
+		closedAt = *t.ExitDate
+	}
+	return []model.PositionExecution{
+		{TradeID: &tradeID, Type: model.ExecutionEntry, Quantity: t.Quantity, Price: price, Fee: fee / 2, ExecutedAt: t.TradeDate},
+		{TradeID: &tradeID, Type: model.ExecutionExit, Quantity: t.Quantity, Price: t.ExitPrice, Fee: fee / 2, ExecutedAt: closedAt},
+	}, nil
+}
@@ -0,0 +1,299 @@
+package positions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+var start = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func execution(kind string, minutes int, qty, price, fee float64) model.PositionExecution {
+	return model.PositionExecution{Type: kind, Quantity: qty, Price: price, Fee: fee, ExecutedAt: start.Add(time.Duration(minutes) * time.Minute)}
+}
+
+func TestRecalculateScalesInAndOut(t *testing.T) {
+	p := &model.Position{IsLong: true, Executions: []model.PositionExecution{
+		execution(model.ExecutionExit, 30, 1, 130, 0),
+		execution(model.ExecutionEntry, 0, 1, 100, 1),
+		execution(model.ExecutionEntry, 10, 1, 120, 1),
+	}}
+
+	if err := Recalculate(p); err != nil {
+		t.Fatalf("Recalculate: %v", err)
+	}
+
+	if p.Executions[0].Type != model.ExecutionEntry || p.Executions[2].Type != model.ExecutionExit {
+		t.Fatalf("executions not sorted by time: %+v", p.Executions)
+	}
+	if p.Status != model.PositionOpen || !near(p.Quantity, 1) || !near(p.AvgEntryPrice, 110) {
+		t.Fatalf("unexpected open position: status=%s qty=%v avg=%v", p.Status, p.Quantity, p.AvgEntryPrice)
+	}
+	// (130-110)*1 minus half of the 2 paid on entry
+	if p.Executions[2].RealizedPnL == nil || !near(*p.Executions[2].RealizedPnL, 19) {
+		t.Fatalf("unexpected partial exit P&L: %v", p.Executions[2].RealizedPnL)
+	}
+	if p.ClosedAt != nil || !p.OpenedAt.Equal(start) {
+		t.Fatalf("unexpected dates: opened=%v closed=%v", p.OpenedAt, p.ClosedAt)
+	}
+
+	p.Executions = append(p.Executions, execution(model.ExecutionExit, 60, 1, 100, 0.5))
+	if err := Recalculate(p); err != nil {
+		t.Fatalf("Recalculate: %v", err)
+	}
+
+	if p.Status != model.PositionClosed || p.Quantity != 0 || p.ClosedAt == nil || !p.ClosedAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected closed position, got status=%s qty=%v closed=%v", p.Status, p.Quantity, p.ClosedAt)
+	}
+	// 19 + ((100-110) - 1 - 0.5)
+	if !near(p.RealizedPnL, 7.5) || !near(p.Fees, 2.5) || !near(p.AvgExitPrice, 115) {
+		t.Fatalf("unexpected totals: pnl=%v fees=%v exit=%v", p.RealizedPnL, p.Fees, p.AvgExitPrice)
+	}
+}
+
+func TestRecalculateShortAndOversizedExit(t *testing.T) {
+	p := &model.Position{IsLong: false, Executions: []model.PositionExecution{
+		execution(model.ExecutionEntry, 0, 2, 50, 0),
+		execution(model.ExecutionExit, 5, 2, 45, 0),
+	}}
+	if err := Recalculate(p); err != nil {
+		t.Fatalf("Recalculate: %v", err)
+	}
+	if !near(p.RealizedPnL, 10) || p.Status != model.PositionClosed {
+		t.Fatalf("unexpected short result: pnl=%v status=%s", p.RealizedPnL, p.Status)
+	}
+
+	p.Executions = append(p.Executions, execution(model.ExecutionExit, 10, 1, 40, 0))
+	if err := Recalculate(p); !errors.Is(err, ErrOversizedExit) {
+		t.Fatalf("expected ErrOversizedExit, got %v", err)
+	}
+
+	if err := Recalculate(&model.Position{}); !errors.Is(err, ErrNoExecutions) {
+		t.Fatalf("expected ErrNoExecutions, got %v", err)
+	}
+}
+
+func TestExecutionsFromTrade(t *testing.T) {
+	p := &model.Position{Symbol: "BTCUSDT", IsLong: true}
+
+	sell := model.Trade{ID: 7, Symbol: "btcusdt", IsShort: true, Quantity: 1, EntryPrice: 100, TradeDate: start, Fee: ptr(0.2)}
+	executions, err := executionsFromTrade(p, sell)
+	if err != nil {
+		t.Fatalf("executionsFromTrade: %v", err)
+	}
+	if len(executions) != 1 || executions[0].Type != model.ExecutionExit || *executions[0].TradeID != 7 {
+		t.Fatalf("expected a single exit, got %+v", executions)
+	}
+
+	roundTrip := model.Trade{ID: 8, Symbol: "BTCUSDT", IsLong: true, Quantity: 1, EntryPrice: 100, ExitPrice: 110, TradeDate: start, Fee: ptr(1.0)}
+	executions, err = executionsFromTrade(p, roundTrip)
+	if err != nil {
+		t.Fatalf("executionsFromTrade: %v", err)
+	}
+	if len(executions) != 2 || executions[1].Price != 110 || executions[0].Fee != 0.5 {
+		t.Fatalf("expected entry and exit, got %+v", executions)
+	}
+
+	if _, err := executionsFromTrade(p, model.Trade{ID: 9, Symbol: "ETHUSDT", Quantity: 1, EntryPrice: 1}); err == nil {
+		t.Fatal("expected a symbol mismatch error")
+	}
+	if _, err := executionsFromTrade(p, model.Trade{ID: 10, Symbol: "BTCUSDT", Quantity: 1, EntryPrice: 1, PositionID: ptr(uint(3))}); err == nil {
+		t.Fatal("expected an error for a trade of another position")
+	}
+}
+
+type inMemoryPositionStore struct {
+	positions map[uint]*model.Position
+	trades    []model.Trade
+	nextID    uint
+}
+
+func newInMemoryPositionStore() *inMemoryPositionStore {
+	return &inMemoryPositionStore{positions: make(map[uint]*model.Position)}
+}
+
+func (s *inMemoryPositionStore) ListPositions(userID uint, status string, offset, limit int) ([]model.Position, int64, error) {
+	var result []model.Position
+	for _, p := range s.positions {
+		if p.UserID == userID && (status == "" || p.Status == status) {
+			result = append(result, *p)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (s *inMemoryPositionStore) GetPosition(userID, id uint) (*model.Position, error) {
+	p, ok := s.positions[id]
+	if !ok || p.UserID != userID {
+		return nil, ErrPositionNotFound
+	}
+	copied := *p
+	copied.Executions = append([]model.PositionExecution(nil), p.Executions...)
+	return &copied, nil
+}
+
+func (s *inMemoryPositionStore) FindTrades(userID uint, ids []uint) ([]model.Trade, error) {
+	var result []model.Trade
+	for _, t := range s.trades {
+		if _, ok := uniqueIDs(ids)[t.ID]; ok && t.UserID == userID {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryPositionStore) SavePosition(p *model.Position) error {
+	if p.ID == 0 {
+		s.nextID++
+		p.ID = s.nextID
+	}
+	for i := range p.Executions {
+		if p.Executions[i].ID == 0 {
+			s.nextID++
+			p.Executions[i].ID = s.nextID
+		}
+		p.Executions[i].PositionID = p.ID
+	}
+	for i := range s.trades {
+		if s.trades[i].PositionID != nil && *s.trades[i].PositionID == p.ID {
+			s.trades[i].PositionID = nil
+		}
+		for _, e := range p.Executions {
+			if e.TradeID != nil && *e.TradeID == s.trades[i].ID {
+				s.trades[i].PositionID = ptr(p.ID)
+			}
+		}
+	}
+	copied := *p
+	copied.Executions = append([]model.PositionExecution(nil), p.Executions...)
+	s.positions[p.ID] = &copied
+	return nil
+}
+
+func (s *inMemoryPositionStore) DeletePosition(userID, id uint) (bool, error) {
+	p, ok := s.positions[id]
+	if !ok || p.UserID != userID {
+		return false, nil
+	}
+	delete(s.positions, id)
+	return true, nil
+}
+
+func TestPositionLifecycle(t *testing.T) {
+	store := newInMemoryPositionStore()
+	store.trades = []model.Trade{
+		{ID: 1, UserID: 1, Symbol: "BTCUSDT", IsLong: true, Quantity: 1, EntryPrice: 100, TradeDate: start},
+		{ID: 2, UserID: 2, Symbol: "BTCUSDT", IsLong: true, Quantity: 1, EntryPrice: 100, TradeDate: start},
+	}
+	SetPositionStore(store)
+	t.Cleanup(func() { SetPositionStore(nil) })
+
+	logger := logrus.NewEntry(logrus.New())
+	router := chi.NewRouter()
+	router.Post("/positions", CreatePositionHandler(logger))
+	router.Get("/positions", ListPositionsHandler(logger))
+	router.Post("/positions/{id}/executions", AddExecutionHandler(logger))
+	router.Delete("/positions/{id}/executions/{executionID}", DeleteExecutionHandler(logger))
+
+	do := func(method, target string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			if err := json.NewEncoder(&buf).Encode(body); err != nil {
+				t.Fatalf("encode body: %v", err)
+			}
+		}
+		req := httptest.NewRequest(method, target, &buf)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/positions", model.CreatePositionPayload{Symbol: "BTCUSDT", Side: "long", TradeIDs: []uint{2}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for another user's trade, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/positions", model.CreatePositionPayload{
+		Symbol:   "BTCUSDT",
+		Side:     "long",
+		TradeIDs: []uint{1},
+		Executions: []model.PositionExecutionPayload{
+			{Type: "entry", Quantity: 1, Price: 110, ExecutedAt: start.Add(time.Minute).Format(time.RFC3339)},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.PositionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.Status != model.PositionOpen || created.Quantity != 2 || created.AvgEntryPrice != 105 {
+		t.Fatalf("unexpected position: %+v", created)
+	}
+	if store.trades[0].PositionID == nil || *store.trades[0].PositionID != created.ID {
+		t.Fatalf("trade was not linked to the position")
+	}
+
+	target := "/positions/" + strconv.FormatUint(uint64(created.ID), 10) + "/executions"
+	rec = do(http.MethodPost, target, model.PositionExecutionPayload{Type: "exit", Quantity: 3, Price: 120, ExecutedAt: start.Add(time.Hour).Format(time.RFC3339)})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an oversized exit, got %d", rec.Code)
+	}
+
+	rec = do(http.MethodPost, target, model.PositionExecutionPayload{Type: "exit", Quantity: 2, Price: 120, ExecutedAt: start.Add(time.Hour).Format(time.RFC3339)})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var closed model.PositionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&closed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if closed.Status != model.PositionClosed || closed.RealizedPnL != 30 || closed.ClosedAt == nil {
+		t.Fatalf("unexpected closed position: %+v", closed)
+	}
+
+	rec = do(http.MethodGet, "/positions?status=open", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != "0" {
+		t.Fatalf("expected no open positions, got %d %s", rec.Code, rec.Header().Get("X-Total-Count"))
+	}
+
+	base := "/positions/" + strconv.FormatUint(uint64(created.ID), 10) + "/executions/"
+	rec = do(http.MethodDelete, base+strconv.FormatUint(uint64(closed.Executions[2].ID), 10), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing the exit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(http.MethodDelete, base+strconv.FormatUint(uint64(closed.Executions[0].ID), 10), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 removing the trade entry, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var reopened model.PositionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&reopened); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if reopened.Status != model.PositionOpen || reopened.Quantity != 1 || reopened.AvgEntryPrice != 110 {
+		t.Fatalf("unexpected position after removals: %+v", reopened)
+	}
+	if store.trades[0].PositionID != nil {
+		t.Fatal("expected the trade to be unlinked")
+	}
+}
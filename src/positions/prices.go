@@ -0,0 +1,115 @@
+package positions
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+)
+
+var ErrNoPrice = errors.New("no market data source can price this symbol")
+
+// PriceSource quotes the last traded price of symbol. exchange is the one the
+// position was recorded on and may be empty.
+type PriceSource interface {
+	LastPrice(ctx context.Context, exchange, symbol string) (float64, error)
+}
+
+var (
+	priceSourceMu sync.RWMutex
+	priceSource   PriceSource = newConnectorPriceSource()
+)
+
+// SetPriceSource overrides where open positions get their prices from.
+// Passing nil restores the exchange market data connectors.
+func SetPriceSource(s PriceSource) {
+	priceSourceMu.Lock()
+	defer priceSourceMu.Unlock()
+
+	if s == nil {
+		priceSource = newConnectorPriceSource()
+		return
+	}
+
+	priceSource = s
+}
+
+func getPriceSource() PriceSource {
+	priceSourceMu.RLock()
+	defer priceSourceMu.RUnlock()
+
+	return priceSource
+}
+
+// fallbackPriceExchanges are asked, in order, when the position's own
+// exchange has no public market data connector or cannot quote the symbol.
+var fallbackPriceExchanges = []string{"binance", "mexc"}
+
+// connectorPriceSource quotes the last traded spot price through the
+// credential-less market data clients of the exchange connectors. Futures
+// positions are valued at that spot price too, as an approximation of their
+// mark price; the two differ by the basis, which is usually small for
+// perpetuals.
+type connectorPriceSource struct {
+	mu        sync.Mutex
+	providers map[string]connectors.PriceProvider // by connectors.Identifier; nil when the exchange cannot quote
+}
+
+func newConnectorPriceSource() *connectorPriceSource {
+	return &connectorPriceSource{providers: make(map[string]connectors.PriceProvider)}
+}
+
+func (s *connectorPriceSource) provider(exchange string) connectors.PriceProvider {
+	id := connectors.Identifier(exchange)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.providers[id]; ok {
+		return p
+	}
+
+	var provider connectors.PriceProvider
+	connector, err := connectors.New(model.Exchange{Name: exchange}, credentials.ExchangeCredentials{})
+	if err == nil {
+		provider, _ = connector.(connectors.PriceProvider)
+	}
+	s.providers[id] = provider
+	return provider
+}
+
+func (s *connectorPriceSource) LastPrice(ctx context.Context, exchange, symbol string) (float64, error) {
+	candidates := fallbackPriceExchanges
+	if exchange != "" {
+		candidates = append([]string{exchange}, fallbackPriceExchanges...)
+	}
+
+	lastErr := ErrNoPrice
+	tried := make(map[string]bool, len(candidates))
+	for _, name := range candidates {
+		id := connectors.Identifier(name)
+		if tried[id] {
+			continue
+		}
+		tried[id] = true
+
+		provider := s.provider(name)
+		if provider == nil {
+			continue
+		}
+
+		price, err := provider.GetPrice(ctx, symbol)
+		if err == nil {
+			return price, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		lastErr = err
+	}
+
+	return 0, lastErr
+}
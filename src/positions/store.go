@@ -0,0 +1,205 @@
+package positions
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrPositionNotFound = errors.New("position not found")
+
+type PositionStore interface {
+	// ListPositions returns the user's positions, most recently opened first.
+	// An empty status lists both open and closed positions.
+	ListPositions(userID uint, status string, offset, limit int) ([]model.Position, int64, error)
+	// GetPosition returns the position with its executions in time order.
+	GetPosition(userID, id uint) (*model.Position, error)
+	// FindTrades returns those of ids that belong to the user.
+	FindTrades(userID uint, ids []uint) ([]model.Trade, error)
+	// SavePosition creates or updates p. p.Executions replaces the stored
+	// executions, and exactly the trades they reference are linked to p.
+	SavePosition(p *model.Position) error
+	// DeletePosition removes the position and unlinks its trades.
+	DeletePosition(userID, id uint) (bool, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   PositionStore = &gormPositionStore{}
+)
+
+func SetPositionStore(s PositionStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormPositionStore{}
+		return
+	}
+
+	store = s
+}
+
+func getPositionStore() PositionStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormPositionStore{}
+	}
+
+	return store
+}
+
+type gormPositionStore struct{}
+
+func (s *gormPositionStore) ListPositions(userID uint, status string, offset, limit int) ([]model.Position, int64, error) {
+	if db.DB == nil {
+		return nil, 0, errors.New("database connection is not initialized")
+	}
+
+	query := db.DB.Model(&model.Position{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var positions []model.Position
+	if err := query.Preload("Executions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("executed_at ASC, id ASC")
+	}).Order("opened_at DESC, id DESC").Offset(offset).Limit(limit).Find(&positions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return positions, total, nil
+}
+
+func (s *gormPositionStore) GetPosition(userID, id uint) (*model.Position, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var position model.Position
+	if err := db.DB.Preload("Executions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("executed_at ASC, id ASC")
+	}).Where("user_id = ?", userID).First(&position, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPositionNotFound
+		}
+
+		return nil, err
+	}
+
+	return &position, nil
+}
+
+func (s *gormPositionStore) FindTrades(userID uint, ids []uint) ([]model.Trade, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var trades []model.Trade
+	if err := db.DB.Where("user_id = ? AND id IN ?", userID, ids).Order("trade_date ASC, id ASC").Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+func (s *gormPositionStore) SavePosition(p *model.Position) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Executions", "User").Save(p).Error; err != nil {
+			return err
+		}
+
+		keep := make([]uint, 0, len(p.Executions))
+		var tradeIDs []uint
+		for i := range p.Executions {
+			e := &p.Executions[i]
+			e.PositionID = p.ID
+			if err := tx.Save(e).Error; err != nil {
+				return err
+			}
+			keep = append(keep, e.ID)
+			if e.TradeID != nil {
+				tradeIDs = append(tradeIDs, *e.TradeID)
+			}
+		}
+
+		stale := tx.Where("position_id = ?", p.ID)
+		if len(keep) > 0 {
+			stale = stale.Where("id NOT IN ?", keep)
+		}
+		if err := stale.Delete(&model.PositionExecution{}).Error; err != nil {
+			return err
+		}
+
+		unlink := tx.Model(&model.Trade{}).Where("position_id = ?", p.ID)
+		if len(tradeIDs) > 0 {
+			unlink = unlink.Where("id NOT IN ?", tradeIDs)
+		}
+		if err := unlink.Update("position_id", nil).Error; err != nil {
+			return err
+		}
+		if len(tradeIDs) > 0 {
+			if err := tx.Model(&model.Trade{}).
+				Where("user_id = ? AND id IN ?", p.UserID, tradeIDs).
+				Update("position_id", p.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *gormPositionStore) DeletePosition(userID, id uint) (bool, error) {
+	if db.DB == nil {
+		return false, errors.New("database connection is not initialized")
+	}
+
+	var deleted bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&model.Position{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		deleted = true
+
+		if err := tx.Where("position_id = ?", id).Delete(&model.PositionExecution{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Trade{}).Where("user_id = ? AND position_id = ?", userID, id).Update("position_id", nil).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
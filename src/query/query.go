@@ -0,0 +1,414 @@
+// Package query turns the react-admin filter, sort and range params of a
+// list request into gorm clauses. Only fields listed in a Schema can be
+// filtered or sorted on, and values are always bound as parameters, so no
+// client input reaches the SQL text.
+//
+// Filter keys are a field name, optionally followed by an operator:
+//
+//	{"symbol": "BTCUSDT"}                  equality
+//	{"id": [1, 2, 3]}                      a list means any of
+//	{"trade_date_gte": "2025-01-01"}       gt, gte, lt, lte
+//	{"exchange_ne": "mexc"}                not equal
+//	{"exchange_in": ["binance", "mexc"]}   any of
+//	{"notes_like": "breakout"}             case-insensitive substring
+//	{"exit_date_null": true}               IS NULL (false: IS NOT NULL)
+//
+// Time fields take RFC3339 timestamps or bare dates. A bare date covers the
+// whole day: "trade_date": "2025-01-01" matches any time that day and
+// "trade_date_lte": "2025-01-31" includes the 31st.
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxLimit caps the page size of schemas that do not set MaxLimit.
+const maxLimit = 1000
+
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Bool
+	Time
+)
+
+// Field is a column clients may filter and sort on.
+type Field struct {
+	Column string
+	Kind   Kind
+}
+
+type Schema struct {
+	// Fields maps the names used in the filter and sort params to columns.
+	Fields map[string]Field
+	// DefaultSort applies when the request has no sort param.
+	DefaultSort Sort
+	// DefaultLimit is the page size without a range param; 0 returns
+	// every row.
+	DefaultLimit int
+	// MaxLimit caps the page size a range can ask for; 0 means 1000.
+	MaxLimit int
+}
+
+type Op string
+
+const (
+	Eq   Op = "eq"
+	Ne   Op = "ne"
+	Gt   Op = "gt"
+	Gte  Op = "gte"
+	Lt   Op = "lt"
+	Lte  Op = "lte"
+	In   Op = "in"
+	Like Op = "like"
+	Null Op = "null"
+)
+
+var suffixOps = []Op{Ne, Gte, Gt, Lte, Lt, In, Like, Null}
+
+// Condition is one parsed filter. Value holds the converted value: a
+// string, float64, bool or time.Time, or a slice of them for In.
+type Condition struct {
+	Field  string
+	Op     Op
+	Value  any
+	column string
+}
+
+type Sort struct {
+	Field  string
+	Desc   bool
+	column string
+}
+
+type Params struct {
+	Conditions []Condition
+	Sort       Sort
+	Offset     int
+	// Limit is -1 when every row is wanted.
+	Limit int
+}
+
+// Parse reads the filter, sort and range params of r. Malformed params and
+// fields missing from schema are errors the caller should answer with 400.
+func Parse(r *http.Request, schema Schema) (Params, error) {
+	var p Params
+	var err error
+
+	if p.Conditions, err = parseFilter(r.URL.Query().Get("filter"), schema); err != nil {
+		return Params{}, err
+	}
+	if p.Sort, err = parseSort(r.URL.Query().Get("sort"), schema); err != nil {
+		return Params{}, err
+	}
+	if p.Offset, p.Limit, err = ParseRange(r, schema.DefaultLimit, schema.MaxLimit); err != nil {
+		return Params{}, err
+	}
+
+	return p, nil
+}
+
+// ParseRange reads the react-admin range param, "[first,last]" with both
+// ends included. Without it the first defaultLimit rows are returned, or
+// all of them when defaultLimit is 0. maxRows caps the page; 0 means 1000.
+func ParseRange(r *http.Request, defaultLimit, maxRows int) (offset, limit int, err error) {
+	if maxRows <= 0 {
+		maxRows = maxLimit
+	}
+
+	raw := r.URL.Query().Get("range")
+	if raw == "" {
+		if defaultLimit <= 0 {
+			return 0, -1, nil
+		}
+		return 0, min(defaultLimit, maxRows), nil
+	}
+
+	var bounds [2]int
+	if err := json.Unmarshal([]byte(raw), &bounds); err != nil {
+		return 0, 0, fmt.Errorf("range must look like [first,last]")
+	}
+	if bounds[0] < 0 || bounds[1] < bounds[0] {
+		return 0, 0, fmt.Errorf("range [%d,%d] is invalid", bounds[0], bounds[1])
+	}
+
+	return bounds[0], min(bounds[1]-bounds[0]+1, maxRows), nil
+}
+
+func parseSort(raw string, schema Schema) (Sort, error) {
+	if raw == "" {
+		s := schema.DefaultSort
+		s.column = schema.Fields[s.Field].Column
+		return s, nil
+	}
+
+	var pair [2]string
+	if err := json.Unmarshal([]byte(raw), &pair); err != nil {
+		return Sort{}, fmt.Errorf(`sort must look like ["field","ASC"]`)
+	}
+
+	field, ok := schema.Fields[pair[0]]
+	if !ok {
+		return Sort{}, fmt.Errorf("cannot sort on %q", pair[0])
+	}
+
+	switch strings.ToUpper(pair[1]) {
+	case "", "ASC":
+		return Sort{Field: pair[0], column: field.Column}, nil
+	case "DESC":
+		return Sort{Field: pair[0], Desc: true, column: field.Column}, nil
+	default:
+		return Sort{}, fmt.Errorf("sort order must be ASC or DESC, got %q", pair[1])
+	}
+}
+
+// splitKey finds the field and operator of a filter key. A key naming a
+// field is an equality even if it happens to end like an operator.
+func splitKey(key string, schema Schema) (string, Field, Op, bool) {
+	if field, ok := schema.Fields[key]; ok {
+		return key, field, Eq, true
+	}
+	for _, op := range suffixOps {
+		name, found := strings.CutSuffix(key, "_"+string(op))
+		if !found {
+			continue
+		}
+		if field, ok := schema.Fields[name]; ok {
+			return name, field, op, true
+		}
+	}
+	return "", Field{}, "", false
+}
+
+func parseFilter(raw string, schema Schema) ([]Condition, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+	var filters map[string]any
+	if err := decoder.Decode(&filters); err != nil {
+		return nil, fmt.Errorf("filter must be a JSON object")
+	}
+
+	// Map order is random; sorting keeps the generated SQL stable.
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	for _, key := range keys {
+		name, field, op, ok := splitKey(key, schema)
+		if !ok {
+			return nil, fmt.Errorf("cannot filter on %q", key)
+		}
+
+		parsed, err := parseCondition(name, field, op, filters[key])
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", key, err)
+		}
+		conditions = append(conditions, parsed...)
+	}
+
+	return conditions, nil
+}
+
+func parseCondition(name string, field Field, op Op, raw any) ([]Condition, error) {
+	cond := Condition{Field: name, Op: op, column: field.Column}
+
+	if list, isList := raw.([]any); isList {
+		if op != Eq && op != In {
+			return nil, fmt.Errorf("a list is only allowed for equality")
+		}
+		values := make([]any, 0, len(list))
+		for _, item := range list {
+			v, _, err := convert(field.Kind, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		cond.Op, cond.Value = In, values
+		return []Condition{cond}, nil
+	}
+
+	switch op {
+	case In:
+		return nil, fmt.Errorf("expected a list")
+	case Null:
+		isNull, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		cond.Value = isNull
+		return []Condition{cond}, nil
+	case Like:
+		if field.Kind != String {
+			return nil, fmt.Errorf("like only applies to text fields")
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string")
+		}
+		cond.Value = s
+		return []Condition{cond}, nil
+	case Gt, Gte, Lt, Lte:
+		if field.Kind == Bool {
+			return nil, fmt.Errorf("%s does not apply to true/false fields", op)
+		}
+	}
+
+	v, wholeDay, err := convert(field.Kind, raw)
+	if err != nil {
+		return nil, err
+	}
+	cond.Value = v
+	if !wholeDay {
+		return []Condition{cond}, nil
+	}
+
+	// A bare date stands for the whole day.
+	start := v.(time.Time)
+	next := start.AddDate(0, 0, 1)
+	switch op {
+	case Eq:
+		return []Condition{
+			{Field: name, Op: Gte, Value: start, column: field.Column},
+			{Field: name, Op: Lt, Value: next, column: field.Column},
+		}, nil
+	case Ne:
+		return nil, fmt.Errorf("ne needs a full timestamp")
+	case Gt:
+		cond.Op, cond.Value = Gte, next
+	case Lte:
+		cond.Op, cond.Value = Lt, next
+	}
+	return []Condition{cond}, nil
+}
+
+// convert checks raw against kind. wholeDay is set for a time given as a
+// bare date.
+func convert(kind Kind, raw any) (value any, wholeDay bool, err error) {
+	switch kind {
+	case String:
+		switch v := raw.(type) {
+		case string:
+			return v, false, nil
+		case json.Number:
+			return v.String(), false, nil
+		}
+		return nil, false, fmt.Errorf("expected a string")
+	case Number:
+		switch v := raw.(type) {
+		case json.Number:
+			f, err := v.Float64()
+			return f, false, err
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, false, fmt.Errorf("expected a number")
+			}
+			return f, false, nil
+		}
+		return nil, false, fmt.Errorf("expected a number")
+	case Bool:
+		switch v := raw.(type) {
+		case bool:
+			return v, false, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, false, fmt.Errorf("expected true or false")
+			}
+			return b, false, nil
+		}
+		return nil, false, fmt.Errorf("expected true or false")
+	case Time:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("expected a date or an RFC3339 timestamp")
+		}
+		s = strings.TrimSpace(s)
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, false, nil
+		}
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, true, nil
+		}
+		return nil, false, fmt.Errorf("expected a date or an RFC3339 timestamp")
+	}
+	return nil, false, fmt.Errorf("unsupported field kind")
+}
+
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
+
+func (c Condition) expression() clause.Expression {
+	col := clause.Column{Name: c.column}
+	switch c.Op {
+	case Ne:
+		return clause.Neq{Column: col, Value: c.Value}
+	case Gt:
+		return clause.Gt{Column: col, Value: c.Value}
+	case Gte:
+		return clause.Gte{Column: col, Value: c.Value}
+	case Lt:
+		return clause.Lt{Column: col, Value: c.Value}
+	case Lte:
+		return clause.Lte{Column: col, Value: c.Value}
+	case In:
+		return clause.IN{Column: col, Values: c.Value.([]any)}
+	case Like:
+		return clause.Like{Column: clause.Expr{SQL: "LOWER(?)", Vars: []any{col}}, Value: likePattern(c.Value.(string))}
+	case Null:
+		if c.Value.(bool) {
+			return clause.Eq{Column: col, Value: nil}
+		}
+		return clause.Neq{Column: col, Value: nil}
+	default:
+		return clause.Eq{Column: col, Value: c.Value}
+	}
+}
+
+// Where adds the filter conditions to tx.
+func (p Params) Where(tx *gorm.DB) *gorm.DB {
+	for _, c := range p.Conditions {
+		tx = tx.Where(c.expression())
+	}
+	return tx
+}
+
+// Order adds the sort to tx.
+func (p Params) Order(tx *gorm.DB) *gorm.DB {
+	if p.Sort.column == "" {
+		return tx
+	}
+	return tx.Order(clause.OrderByColumn{Column: clause.Column{Name: p.Sort.column}, Desc: p.Sort.Desc})
+}
+
+// Page adds the range to tx.
+func (p Params) Page(tx *gorm.DB) *gorm.DB {
+	return tx.Offset(p.Offset).Limit(p.Limit)
+}
+
+// Apply adds the filter, sort and range to tx.
+func (p Params) Apply(tx *gorm.DB) *gorm.DB {
+	return p.Page(p.Order(p.Where(tx)))
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testSchema = Schema{
+	Fields: map[string]Field{
+		"id":         {Column: "id", Kind: Number},
+		"symbol":     {Column: "symbol", Kind: String},
+		"notes":      {Column: "notes", Kind: String},
+		"is_long":    {Column: "is_long", Kind: Bool},
+		"trade_date": {Column: "trade_date", Kind: Time},
+		"exit_date":  {Column: "exit_date", Kind: Time},
+	},
+	DefaultSort:  Sort{Field: "id"},
+	DefaultLimit: 10,
+	MaxLimit:     100,
+}
+
+type row struct {
+	ID uint
+}
+
+func parse(t *testing.T, filter, sort, rng string) (Params, error) {
+	t.Helper()
+	values := url.Values{}
+	if filter != "" {
+		values.Set("filter", filter)
+	}
+	if sort != "" {
+		values.Set("sort", sort)
+	}
+	if rng != "" {
+		values.Set("range", rng)
+	}
+	return Parse(httptest.NewRequest("GET", "/trades?"+values.Encode(), nil), testSchema)
+}
+
+// toSQL renders the statement p builds without a database connection.
+func toSQL(t *testing.T, p Params) (string, []any) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open dry-run db: %v", err)
+	}
+	stmt := p.Apply(db.Table("trades")).Find(&[]row{}).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestParseOperators(t *testing.T) {
+	p, err := parse(t, `{"symbol":"BTCUSDT","id":[1,2],"trade_date_gte":"2025-01-01","trade_date_lte":"2025-01-31","notes_like":"50%_off","exit_date_null":true,"is_long":"true"}`, `["trade_date","desc"]`, "[20,29]")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	sql, vars := toSQL(t, p)
+	expected := `SELECT * FROM "trades" WHERE "exit_date" IS NULL AND "id" IN ($1,$2) AND "is_long" = $3 AND LOWER("notes") LIKE $4 AND "symbol" = $5 AND "trade_date" >= $6 AND "trade_date" < $7 ORDER BY "trade_date" DESC LIMIT $8 OFFSET $9`
+	if sql != expected {
+		t.Fatalf("unexpected SQL:\n got %s\nwant %s", sql, expected)
+	}
+
+	want := []any{
+		1.0, 2.0, true, `%50\%\_off%`, "BTCUSDT",
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		10, 20,
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Fatalf("unexpected vars:\n got %#v\nwant %#v", vars, want)
+	}
+}
+
+func TestParseBareDateEquality(t *testing.T) {
+	p, err := parse(t, `{"trade_date":"2025-03-04"}`, "", "")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(p.Conditions) != 2 || p.Conditions[0].Op != Gte || p.Conditions[1].Op != Lt {
+		t.Fatalf("expected the date to become a one-day range, got %+v", p.Conditions)
+	}
+	if !p.Conditions[1].Value.(time.Time).Equal(time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected end of day: %v", p.Conditions[1].Value)
+	}
+	if p.Offset != 0 || p.Limit != 10 || p.Sort.Field != "id" || p.Sort.Desc {
+		t.Fatalf("expected the schema defaults, got %+v", p)
+	}
+}
+
+func TestParseRejectsUnsafeInput(t *testing.T) {
+	cases := []struct {
+		name, filter, sort, rng string
+	}{
+		{"unknown filter field", `{"user_id":2}`, "", ""},
+		{"injected filter key", `{"1=1 OR symbol":"x"}`, "", ""},
+		{"unknown sort field", "", `["password","ASC"]`, ""},
+		{"injected sort direction", "", `["id","ASC; DROP TABLE trades"]`, ""},
+		{"like on a number", `{"id_like":"1"}`, "", ""},
+		{"bad number", `{"id":"abc"}`, "", ""},
+		{"bad date", `{"trade_date_gte":"yesterday"}`, "", ""},
+		{"null without bool", `{"exit_date_null":"yes"}`, "", ""},
+		{"reversed range", "", "", "[10,0]"},
+		{"malformed filter", `["symbol"]`, "", ""},
+	}
+	for _, c := range cases {
+		if _, err := parse(t, c.filter, c.sort, c.rng); err == nil {
+			t.Fatalf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	p, err := parse(t, "", "", "[0,499]")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if p.Limit != 100 {
+		t.Fatalf("expected the page to be capped at 100, got %d", p.Limit)
+	}
+
+	offset, limit, err := ParseRange(httptest.NewRequest("GET", "/exchanges", nil), 0, 0)
+	if err != nil || offset != 0 || limit != -1 {
+		t.Fatalf("expected every row without a range, got %d %d %v", offset, limit, err)
+	}
+
+	if sql, _ := toSQL(t, Params{Limit: -1}); strings.Contains(sql, "LIMIT") {
+		t.Fatalf("expected no LIMIT, got %s", sql)
+	}
+}
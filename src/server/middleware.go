@@ -1,13 +1,15 @@
 package server
 
 import (
+	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"os"
 	"time"
 )
 
-// Logs every request with timing
+// Logs every request with timing. The route pattern is logged instead of the
+// path, so secrets in the path such as webhook tokens stay out of the logs.
 func requestLogger(logger *logrus.Entry) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -15,13 +17,24 @@ func requestLogger(logger *logrus.Entry) func(http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			logger.WithFields(logrus.Fields{
 				"method": r.Method,
-				"path":   r.URL.Path,
+				"path":   routePattern(r),
 				"took":   time.Since(start),
 			}).Info("Request")
 		})
 	}
 }
 
+// routePattern returns the pattern of the matched route, such as
+// /alerts/{token}. Requests that matched no route are logged without a path.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "(no route)"
+}
+
 // Auth check using header "X-Secret-Key"
 func sharedSecretAuth(logger *logrus.Entry) func(http.Handler) http.Handler {
 	secret := os.Getenv("SHARED_SECRET")
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRequestLoggerRedactsPathParams(t *testing.T) {
+	base, hook := test.NewNullLogger()
+	logger := logrus.NewEntry(base)
+
+	r := chi.NewRouter()
+	r.Use(requestLogger(logger))
+	r.Post("/alerts/{token}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/alerts/whk_secret", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/alerts/whk_secret", nil))
+
+	entries := hook.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+	if got := entries[0].Data["path"]; got != "/alerts/{token}" {
+		t.Fatalf("expected the route pattern, got %v", got)
+	}
+	for _, entry := range entries {
+		if s, _ := entry.String(); strings.Contains(s, "whk_secret") {
+			t.Fatalf("token leaked into the log: %s", s)
+		}
+	}
+}
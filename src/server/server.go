@@ -27,9 +27,16 @@ import (
 	"syscall"
 	"time"
 	"vsC1Y2025V01/src/alerts"
+	"vsC1Y2025V01/src/attachments"
 	"vsC1Y2025V01/src/auth"
 	"vsC1Y2025V01/src/lookup"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/positions"
+	"vsC1Y2025V01/src/setups"
+	"vsC1Y2025V01/src/stats"
+	"vsC1Y2025V01/src/taxlots"
 	"vsC1Y2025V01/src/trades"
+	"vsC1Y2025V01/src/tradesync"
 	"vsC1Y2025V01/src/userexchanges"
 	"vsC1Y2025V01/src/users"
 
@@ -53,7 +60,6 @@ func StartServer(port string, logger *logrus.Entry) {
 	//	MaxAge:           300,
 	//}))
 	r.Use(requestLogger(logger))
-	r.Use(sharedSecretAuth(logger)) // <- Our custom auth middleware
 
 	//r.Method("OPTIONS", "/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 	//	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
@@ -63,43 +69,142 @@ func StartServer(port string, logger *logrus.Entry) {
 	//	w.WriteHeader(http.StatusOK)
 	//}))
 
-	// Public routes
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("OK"))
-	})
-
-	r.Route("/lookup", func(r chi.Router) {
-		r.Get("/exchanges", lookup.ListExchanges(logger))
-		r.Get("/pairs", lookup.ListPairs(logger))
-	})
-
+	// TradingView webhooks authenticate with a per-user token in the path or
+	// payload, since TradingView cannot send the shared secret header.
 	r.Post("/alerts", alerts.AlertHandler(logger))
-	r.Post("/auth/register", auth.RegisterHandler(logger))
-	r.Post("/auth/login", auth.LoginHandler(logger))
+	r.Post("/alerts/{token}", alerts.AlertHandler(logger))
 
-	// Protected routes (JWT required)
 	r.Group(func(r chi.Router) {
+		r.Use(sharedSecretAuth(logger)) // <- Our custom auth middleware
 
-		r.Use(auth.RequireAuthMiddleware(logger)) // ✅ <— protect the routes
-
-		r.Get("/me", auth.MeHandler(logger))
-		r.Put("/me", users.UpdateUserHandler(logger))
-		r.Get("/logout", auth.LogoutHandler(logger))
+		// Public routes
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("OK"))
+		})
 
-		// CRUD Routes for Trades
-		r.Get("/trades", trades.ListTradesHandler(logger))
-		r.Get("/trades/{id}", trades.GetTradeHandler(logger))
-		r.Post("/trades", trades.CreateTradeHandler(logger))
-		r.Put("/trades/{id}", trades.UpdateTradeHandler(logger))
-		r.Delete("/trades", trades.DeleteManyTradesHandler(logger))
-		r.Delete("/trades/{id}", trades.DeleteTradeHandler(logger))
+		r.Route("/lookup", func(r chi.Router) {
+			r.Get("/exchanges", lookup.ListExchanges(logger))
+			r.Get("/pairs", lookup.ListPairs(logger))
+		})
 
-		r.Route("/user-exchanges", func(r chi.Router) {
-			r.Post("/", userexchanges.UpsertUserExchangeHandler(logger))
-			r.Get("/forms", userexchanges.ListFormUserExchangesHandler(logger))
-			r.Delete("/{exchangeID}", userexchanges.DeleteUserExchangeHandler(logger))
+		r.Post("/auth/register", auth.RegisterHandler(logger))
+		r.Post("/auth/login", auth.LoginHandler(logger))
+		r.Post("/auth/login/2fa", auth.LoginTwoFactorHandler(logger))
+		r.Post("/auth/refresh", auth.RefreshHandler(logger))
+		r.Post("/auth/verify-email", auth.VerifyEmailHandler(logger))
+		r.Post("/auth/forgot-password", auth.ForgotPasswordHandler(logger))
+		r.Post("/auth/reset-password", auth.ResetPasswordHandler(logger))
+
+		// Protected routes (JWT required)
+		r.Group(func(r chi.Router) {
+
+			r.Use(auth.RequireAuthMiddleware(logger)) // ✅ <— protect the routes
+
+			r.Get("/me", auth.MeHandler(logger))
+			r.Put("/me", users.UpdateUserHandler(logger))
+			r.Post("/me/email/verification", auth.SendVerificationEmailHandler(logger))
+			r.Get("/logout", auth.LogoutHandler(logger))
+			r.Route("/me/sessions", func(r chi.Router) {
+				r.Get("/", auth.ListSessionsHandler(logger))
+				r.Delete("/", auth.RevokeAllSessionsHandler(logger))
+				r.Delete("/{id}", auth.RevokeSessionHandler(logger))
+			})
+
+			r.Get("/me/login-attempts", auth.ListLoginAttemptsHandler(logger))
+
+			r.Route("/me/2fa", func(r chi.Router) {
+				r.Get("/", auth.TwoFactorStatusHandler(logger))
+				r.Post("/enroll", auth.EnrollTwoFactorHandler(logger))
+				r.Post("/verify", auth.VerifyTwoFactorHandler(logger))
+				r.Post("/recovery-codes", auth.RegenerateRecoveryCodesHandler(logger))
+				r.Post("/disable", auth.DisableTwoFactorHandler(logger))
+			})
+
+			r.Route("/me/tokens", func(r chi.Router) {
+				r.Get("/", auth.ListAPITokensHandler(logger))
+				r.Post("/", auth.CreateAPITokenHandler(logger))
+				r.Delete("/{id}", auth.RevokeAPITokenHandler(logger))
+			})
+
+			r.Get("/alerts", alerts.ListAlertsHandler(logger))
+			r.Route("/webhook-tokens", func(r chi.Router) {
+				r.Get("/", alerts.ListWebhookTokensHandler(logger))
+				r.Post("/", alerts.CreateWebhookTokenHandler(logger))
+				r.Post("/{id}/rotate", alerts.RotateWebhookTokenHandler(logger))
+				r.Delete("/{id}", alerts.RevokeWebhookTokenHandler(logger))
+			})
 		})
 
+		// Routes personal API tokens can use, each behind the scope it needs.
+		// A session cookie satisfies every scope.
+		r.Group(func(r chi.Router) {
+			readTrades := auth.RequireScope(logger, model.ScopeTradesRead)
+			writeTrades := auth.RequireScope(logger, model.ScopeTradesWrite)
+			readStats := auth.RequireScope(logger, model.ScopeStatsRead)
+			manageExchanges := auth.RequireScope(logger, model.ScopeExchangesManage)
+
+			// CRUD Routes for Trades
+			r.With(readTrades).Get("/trades", trades.ListTradesHandler(logger))
+			r.With(readTrades).Get("/trades/export", trades.ExportTradesHandler(logger))
+			r.With(readTrades).Get("/trades/{id}", trades.GetTradeHandler(logger))
+			r.With(writeTrades).Post("/trades", trades.CreateTradeHandler(logger))
+			r.With(writeTrades).Post("/trades/import", trades.ImportTradesHandler(logger))
+			r.With(writeTrades).Put("/trades/{id}", trades.UpdateTradeHandler(logger))
+			r.With(writeTrades).Delete("/trades", trades.DeleteManyTradesHandler(logger))
+			r.With(writeTrades).Delete("/trades/{id}", trades.DeleteTradeHandler(logger))
+			r.Route("/trades/{id}/attachments", func(r chi.Router) {
+				r.With(readTrades).Get("/", attachments.ListAttachmentsHandler(logger))
+				r.With(writeTrades).Post("/", attachments.UploadAttachmentHandler(logger))
+				r.With(readTrades).Get("/{attachmentID}", attachments.GetAttachmentHandler(logger))
+				r.With(readTrades).Get("/{attachmentID}/thumbnail", attachments.GetThumbnailHandler(logger))
+				r.With(writeTrades).Delete("/{attachmentID}", attachments.DeleteAttachmentHandler(logger))
+			})
+
+			r.With(readStats).Get("/stats", stats.StatsHandler(logger))
+			r.With(readStats).Get("/stats/equity", stats.EquityHandler(logger))
+			r.With(readStats).Get("/tax/report", taxlots.ReportHandler(logger))
+
+			r.Route("/positions", func(r chi.Router) {
+				r.With(readTrades).Get("/", positions.ListPositionsHandler(logger))
+				r.With(writeTrades).Post("/", positions.CreatePositionHandler(logger))
+				r.With(readTrades).Get("/open", positions.OpenPositionsHandler(logger))
+				r.With(readTrades).Get("/{id}", positions.GetPositionHandler(logger))
+				r.With(writeTrades).Put("/{id}", positions.UpdatePositionHandler(logger))
+				r.With(writeTrades).Delete("/{id}", positions.DeletePositionHandler(logger))
+				r.With(writeTrades).Post("/{id}/executions", positions.AddExecutionHandler(logger))
+				r.With(writeTrades).Delete("/{id}/executions/{executionID}", positions.DeleteExecutionHandler(logger))
+				r.With(writeTrades).Post("/{id}/trades", positions.LinkTradesHandler(logger))
+			})
+
+			r.Route("/setups", func(r chi.Router) {
+				r.With(readTrades).Get("/", setups.ListSetupsHandler(logger))
+				r.With(writeTrades).Post("/", setups.CreateSetupHandler(logger))
+				r.With(readTrades).Get("/{id}", setups.GetSetupHandler(logger))
+				r.With(writeTrades).Put("/{id}", setups.UpdateSetupHandler(logger))
+				r.With(writeTrades).Delete("/{id}", setups.DeleteSetupHandler(logger))
+			})
+			r.Route("/tags", func(r chi.Router) {
+				r.With(readTrades).Get("/", setups.ListTagsHandler(logger))
+				r.With(writeTrades).Post("/", setups.CreateTagHandler(logger))
+				r.With(writeTrades).Delete("/{id}", setups.DeleteTagHandler(logger))
+			})
+
+			r.Route("/cash-flows", func(r chi.Router) {
+				r.With(readStats).Get("/", stats.ListCashFlowsHandler(logger))
+				r.With(writeTrades).Post("/", stats.CreateCashFlowHandler(logger))
+				r.With(writeTrades).Delete("/{id}", stats.DeleteCashFlowHandler(logger))
+			})
+
+			r.Route("/user-exchanges", func(r chi.Router) {
+				r.Use(manageExchanges)
+				r.Post("/", userexchanges.UpsertUserExchangeHandler(logger))
+				r.Get("/forms", userexchanges.ListFormUserExchangesHandler(logger))
+				r.Post("/{exchangeID}/test", userexchanges.TestUserExchangeHandler(logger))
+				r.Delete("/{exchangeID}", userexchanges.DeleteUserExchangeHandler(logger))
+				r.Get("/{exchangeID}/sync", tradesync.GetSyncStatusHandler(logger))
+				r.Post("/{exchangeID}/sync", tradesync.TriggerSyncHandler(logger))
+			})
+		})
 	})
 	// Graceful server
 	// Server setup
@@ -109,6 +214,11 @@ func StartServer(port string, logger *logrus.Entry) {
 		Handler: r,
 	}
 
+	// Background trade history sync, stopped on shutdown
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	tradesync.Start(syncCtx, logger, tradesync.IntervalFromEnv())
+
 	// Start server in goroutine
 	go func() {
 		logger.Infof("Listening on %s", addr)
@@ -0,0 +1,263 @@
+package setups
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// applySetupPayload validates payload and copies it onto setup. Checklist
+// items are matched to the current ones by ID; any other ID is rejected so
+// a setup cannot take over the items of another.
+func applySetupPayload(setup *model.Setup, payload model.SetupPayload) error {
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if len(name) > 128 {
+		return errors.New("name must be at most 128 characters")
+	}
+
+	current := make(map[uint]bool, len(setup.Checklist))
+	for _, item := range setup.Checklist {
+		current[item.ID] = true
+	}
+
+	checklist := make([]model.SetupChecklistItem, 0, len(payload.Checklist))
+	for i, p := range payload.Checklist {
+		text := strings.TrimSpace(p.Text)
+		if text == "" {
+			return fmt.Errorf("checklist item %d has no text", i+1)
+		}
+		if len(text) > 512 {
+			return fmt.Errorf("checklist item %d must be at most 512 characters", i+1)
+		}
+
+		item := model.SetupChecklistItem{Position: i, Text: text}
+		if p.ID != nil {
+			if !current[*p.ID] {
+				return fmt.Errorf("checklist item %d is not part of this setup", *p.ID)
+			}
+			delete(current, *p.ID)
+			item.ID = *p.ID
+		}
+		checklist = append(checklist, item)
+	}
+
+	setup.Name = name
+	setup.Description = payload.Description
+	setup.EntryCriteria = payload.EntryCriteria
+	setup.ExitCriteria = payload.ExitCriteria
+	setup.Checklist = checklist
+	return nil
+}
+
+func parseID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	return uint(id), err
+}
+
+func writeSetup(w http.ResponseWriter, logger *logrus.Entry, setup *model.Setup, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(model.NewSetupResponse(setup)); err != nil {
+		logger.WithError(err).Error("failed to encode setup response")
+	}
+}
+
+func ListSetupsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing setups")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		setups, err := getSetupStore().ListSetups(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to list setups")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.SetupResponse, 0, len(setups))
+		for i := range setups {
+			responses = append(responses, model.NewSetupResponse(&setups[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode setup list response")
+		}
+	}
+}
+
+func GetSetupHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while fetching setup")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := parseID(r)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		setup, err := getSetupStore().GetSetup(user.ID, id)
+		if err != nil {
+			if errors.Is(err, ErrSetupNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+
+			logger.WithError(err).Error("failed to load setup")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		writeSetup(w, logger, setup, http.StatusOK)
+	}
+}
+
+func CreateSetupHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating setup")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.SetupPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid setup payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		setup := &model.Setup{UserID: user.ID}
+		if err := applySetupPayload(setup, payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := getSetupStore().SaveSetup(setup); err != nil {
+			if errors.Is(err, ErrDuplicateName) {
+				http.Error(w, "a setup with this name already exists", http.StatusConflict)
+				return
+			}
+
+			logger.WithError(err).Error("failed to create setup")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		writeSetup(w, logger, setup, http.StatusCreated)
+	}
+}
+
+// UpdateSetupHandler replaces a setup. Checklist items sent back with their
+// id keep the answers trades gave them; items left out are deleted.
+func UpdateSetupHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while updating setup")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := parseID(r)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		var payload model.SetupPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid setup payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		setup, err := getSetupStore().GetSetup(user.ID, id)
+		if err != nil {
+			if errors.Is(err, ErrSetupNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+
+			logger.WithError(err).Error("failed to load setup")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := applySetupPayload(setup, payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := getSetupStore().SaveSetup(setup); err != nil {
+			if errors.Is(err, ErrDuplicateName) {
+				http.Error(w, "a setup with this name already exists", http.StatusConflict)
+				return
+			}
+
+			logger.WithError(err).Error("failed to update setup")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		writeSetup(w, logger, setup, http.StatusOK)
+	}
+}
+
+// DeleteSetupHandler removes a setup; its trades are kept without one.
+func DeleteSetupHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting setup")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := parseID(r)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := getSetupStore().DeleteSetup(user.ID, id)
+		if err != nil {
+			logger.WithError(err).Error("failed to delete setup")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !deleted {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
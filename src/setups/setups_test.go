@@ -0,0 +1,228 @@
+package setups
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type inMemorySetupStore struct {
+	setups map[uint]*model.Setup
+	tags   []model.Tag
+	nextID uint
+}
+
+func (s *inMemorySetupStore) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *inMemorySetupStore) ListSetups(userID uint) ([]model.Setup, error) {
+	var result []model.Setup
+	for _, setup := range s.setups {
+		if setup.UserID == userID {
+			result = append(result, *setup)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemorySetupStore) GetSetup(userID, id uint) (*model.Setup, error) {
+	setup, ok := s.setups[id]
+	if !ok || setup.UserID != userID {
+		return nil, ErrSetupNotFound
+	}
+	copied := *setup
+	copied.Checklist = append([]model.SetupChecklistItem(nil), setup.Checklist...)
+	return &copied, nil
+}
+
+func (s *inMemorySetupStore) SaveSetup(setup *model.Setup) error {
+	for _, existing := range s.setups {
+		if existing.UserID == setup.UserID && existing.Name == setup.Name && existing.ID != setup.ID {
+			return ErrDuplicateName
+		}
+	}
+	if setup.ID == 0 {
+		setup.ID = s.id()
+	}
+	for i := range setup.Checklist {
+		if setup.Checklist[i].ID == 0 {
+			setup.Checklist[i].ID = s.id()
+		}
+		setup.Checklist[i].SetupID = setup.ID
+	}
+	copied := *setup
+	copied.Checklist = append([]model.SetupChecklistItem(nil), setup.Checklist...)
+	s.setups[setup.ID] = &copied
+	return nil
+}
+
+func (s *inMemorySetupStore) DeleteSetup(userID, id uint) (bool, error) {
+	setup, ok := s.setups[id]
+	if !ok || setup.UserID != userID {
+		return false, nil
+	}
+	delete(s.setups, id)
+	return true, nil
+}
+
+func (s *inMemorySetupStore) ListTags(userID uint) ([]model.Tag, error) {
+	var result []model.Tag
+	for _, tag := range s.tags {
+		if tag.UserID == userID {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemorySetupStore) CreateTag(tag *model.Tag) error {
+	for _, existing := range s.tags {
+		if existing.UserID == tag.UserID && existing.Name == tag.Name {
+			return ErrDuplicateName
+		}
+	}
+	tag.ID = s.id()
+	s.tags = append(s.tags, *tag)
+	return nil
+}
+
+func (s *inMemorySetupStore) DeleteTag(userID, id uint) (bool, error) {
+	for i, tag := range s.tags {
+		if tag.UserID == userID && tag.ID == id {
+			s.tags = append(s.tags[:i], s.tags[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestRouter(t *testing.T) (*chi.Mux, *inMemorySetupStore) {
+	store := &inMemorySetupStore{setups: make(map[uint]*model.Setup)}
+	SetSetupStore(store)
+	t.Cleanup(func() { SetSetupStore(nil) })
+
+	logger := logrus.NewEntry(logrus.New())
+	router := chi.NewRouter()
+	router.Get("/setups", ListSetupsHandler(logger))
+	router.Post("/setups", CreateSetupHandler(logger))
+	router.Get("/setups/{id}", GetSetupHandler(logger))
+	router.Put("/setups/{id}", UpdateSetupHandler(logger))
+	router.Delete("/setups/{id}", DeleteSetupHandler(logger))
+	router.Get("/tags", ListTagsHandler(logger))
+	router.Post("/tags", CreateTagHandler(logger))
+	router.Delete("/tags/{id}", DeleteTagHandler(logger))
+	return router, store
+}
+
+func do(t *testing.T, router http.Handler, userID uint, method, target string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: userID}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSetupLifecycle(t *testing.T) {
+	router, store := newTestRouter(t)
+
+	rec := do(t, router, 1, http.MethodPost, "/setups", model.SetupPayload{
+		Name:          "Breakout",
+		EntryCriteria: ptr("Close above the range high on volume"),
+		Checklist: []model.SetupChecklistItemPayload{
+			{Text: "Higher timeframe trend agrees"},
+			{Text: "Stop below the range"},
+		},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created model.SetupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(created.Checklist) != 2 || created.Checklist[0].Text != "Higher timeframe trend agrees" {
+		t.Fatalf("unexpected checklist: %+v", created.Checklist)
+	}
+
+	rec = do(t, router, 1, http.MethodPost, "/setups", model.SetupPayload{Name: "Breakout"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate name, got %d", rec.Code)
+	}
+
+	target := "/setups/" + strconv.FormatUint(uint64(created.ID), 10)
+	kept := created.Checklist[1].ID
+	rec = do(t, router, 1, http.MethodPut, target, model.SetupPayload{
+		Name:      "Range breakout",
+		Checklist: []model.SetupChecklistItemPayload{{ID: &kept, Text: "Stop below the range"}, {Text: "Volume above average"}},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	checklist := store.setups[created.ID].Checklist
+	if len(checklist) != 2 || checklist[0].ID != kept || checklist[0].Position != 0 || checklist[1].Text != "Volume above average" {
+		t.Fatalf("unexpected checklist after update: %+v", checklist)
+	}
+
+	foreign := uint(999)
+	rec = do(t, router, 1, http.MethodPut, target, model.SetupPayload{
+		Name:      "Range breakout",
+		Checklist: []model.SetupChecklistItemPayload{{ID: &foreign, Text: "Stolen"}},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for another setup's item, got %d", rec.Code)
+	}
+
+	if rec = do(t, router, 2, http.MethodGet, target, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's setup, got %d", rec.Code)
+	}
+
+	if rec = do(t, router, 1, http.MethodDelete, target, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+}
+
+func TestTagHandlers(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	rec := do(t, router, 1, http.MethodPost, "/tags", model.TagPayload{Name: " FOMO ", Color: ptr("#ff0000")})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec = do(t, router, 1, http.MethodPost, "/tags", model.TagPayload{Name: "FOMO"}); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate tag, got %d", rec.Code)
+	}
+
+	rec = do(t, router, 1, http.MethodGet, "/tags", nil)
+	var tags []model.TagResponse
+	if err := json.NewDecoder(rec.Body).Decode(&tags); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "FOMO" || *tags[0].Color != "#ff0000" {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+
+	if rec = do(t, router, 2, http.MethodDelete, "/tags/"+strconv.FormatUint(uint64(tags[0].ID), 10), nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another user's tag, got %d", rec.Code)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,228 @@
+package setups
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSetupNotFound = errors.New("setup not found")
+	ErrDuplicateName = errors.New("name is already in use")
+)
+
+type SetupStore interface {
+	ListSetups(userID uint) ([]model.Setup, error)
+	GetSetup(userID, id uint) (*model.Setup, error)
+	// SaveSetup creates or updates s. s.Checklist replaces the stored
+	// checklist: items without an ID are added and stored items missing from
+	// it are deleted, together with the answers trades gave them.
+	SaveSetup(s *model.Setup) error
+	DeleteSetup(userID, id uint) (bool, error)
+
+	ListTags(userID uint) ([]model.Tag, error)
+	CreateTag(tag *model.Tag) error
+	DeleteTag(userID, id uint) (bool, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   SetupStore = &gormSetupStore{}
+)
+
+func SetSetupStore(s SetupStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormSetupStore{}
+		return
+	}
+
+	store = s
+}
+
+func getSetupStore() SetupStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormSetupStore{}
+	}
+
+	return store
+}
+
+type gormSetupStore struct{}
+
+func orderedChecklist(tx *gorm.DB) *gorm.DB {
+	return tx.Order("position ASC, id ASC")
+}
+
+func (s *gormSetupStore) ListSetups(userID uint) ([]model.Setup, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var setups []model.Setup
+	if err := db.DB.Preload("Checklist", orderedChecklist).Where("user_id = ?", userID).Order("name ASC").Find(&setups).Error; err != nil {
+		return nil, err
+	}
+
+	return setups, nil
+}
+
+func (s *gormSetupStore) GetSetup(userID, id uint) (*model.Setup, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var setup model.Setup
+	if err := db.DB.Preload("Checklist", orderedChecklist).Where("user_id = ?", userID).First(&setup, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSetupNotFound
+		}
+
+		return nil, err
+	}
+
+	return &setup, nil
+}
+
+func (s *gormSetupStore) SaveSetup(setup *model.Setup) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var clash int64
+		if err := tx.Model(&model.Setup{}).
+			Where("user_id = ? AND name = ? AND id <> ?", setup.UserID, setup.Name, setup.ID).
+			Count(&clash).Error; err != nil {
+			return err
+		}
+		if clash > 0 {
+			return ErrDuplicateName
+		}
+
+		if err := tx.Omit("Checklist", "User").Save(setup).Error; err != nil {
+			return err
+		}
+
+		keep := make([]uint, 0, len(setup.Checklist))
+		for i := range setup.Checklist {
+			item := &setup.Checklist[i]
+			item.SetupID = setup.ID
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+			keep = append(keep, item.ID)
+		}
+
+		stale := tx.Model(&model.SetupChecklistItem{}).Select("id").Where("setup_id = ?", setup.ID)
+		if len(keep) > 0 {
+			stale = stale.Where("id NOT IN ?", keep)
+		}
+		if err := tx.Where("checklist_item_id IN (?)", stale).Delete(&model.TradeChecklistCheck{}).Error; err != nil {
+			return err
+		}
+
+		remove := tx.Where("setup_id = ?", setup.ID)
+		if len(keep) > 0 {
+			remove = remove.Where("id NOT IN ?", keep)
+		}
+		return remove.Delete(&model.SetupChecklistItem{}).Error
+	})
+}
+
+func (s *gormSetupStore) DeleteSetup(userID, id uint) (bool, error) {
+	if db.DB == nil {
+		return false, errors.New("database connection is not initialized")
+	}
+
+	var deleted bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&model.Setup{}, id)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		deleted = true
+
+		items := tx.Model(&model.SetupChecklistItem{}).Select("id").Where("setup_id = ?", id)
+		if err := tx.Where("checklist_item_id IN (?)", items).Delete(&model.TradeChecklistCheck{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("setup_id = ?", id).Delete(&model.SetupChecklistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Trade{}).Where("user_id = ? AND setup_id = ?", userID, id).Update("setup_id", nil).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+func (s *gormSetupStore) ListTags(userID uint) ([]model.Tag, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var tags []model.Tag
+	if err := db.DB.Where("user_id = ?", userID).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *gormSetupStore) CreateTag(tag *model.Tag) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	var clash int64
+	if err := db.DB.Model(&model.Tag{}).Where("user_id = ? AND name = ?", tag.UserID, tag.Name).Count(&clash).Error; err != nil {
+		return err
+	}
+	if clash > 0 {
+		return ErrDuplicateName
+	}
+
+	return db.DB.Create(tag).Error
+}
+
+func (s *gormSetupStore) DeleteTag(userID, id uint) (bool, error) {
+	if db.DB == nil {
+		return false, errors.New("database connection is not initialized")
+	}
+
+	var deleted bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&model.Tag{}, id)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		deleted = true
+
+		return tx.Exec("DELETE FROM trade_tags WHERE tag_id = ?", id).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,119 @@
+package setups
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+func ListTagsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing tags")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tags, err := getSetupStore().ListTags(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to list tags")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.TagResponse, 0, len(tags))
+		for i := range tags {
+			responses = append(responses, model.NewTagResponse(&tags[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode tag list response")
+		}
+	}
+}
+
+// CreateTagHandler adds a tag up front. Tags named on a trade are created
+// on the fly, so this is only needed to pick a color.
+func CreateTagHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating tag")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.TagPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid tag payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		name := strings.TrimSpace(payload.Name)
+		if name == "" || len(name) > 64 {
+			http.Error(w, "name is required and must be at most 64 characters", http.StatusBadRequest)
+			return
+		}
+
+		tag := &model.Tag{UserID: user.ID, Name: name, Color: payload.Color}
+		if err := getSetupStore().CreateTag(tag); err != nil {
+			if errors.Is(err, ErrDuplicateName) {
+				http.Error(w, "a tag with this name already exists", http.StatusConflict)
+				return
+			}
+
+			logger.WithError(err).Error("failed to create tag")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(model.NewTagResponse(tag)); err != nil {
+			logger.WithError(err).Error("failed to encode tag response")
+		}
+	}
+}
+
+// DeleteTagHandler removes a tag from the caller's trades and deletes it.
+func DeleteTagHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting tag")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := parseID(r)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := getSetupStore().DeleteTag(user.ID, id)
+		if err != nil {
+			logger.WithError(err).Error("failed to delete tag")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !deleted {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ListCashFlowsHandler returns every deposit and withdrawal of the caller,
+// oldest first.
+func ListCashFlowsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while listing cash flows")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		flows, err := getStatsStore().ListCashFlows(user.ID, nil, nil)
+		if err != nil {
+			logger.WithError(err).Error("failed to list cash flows")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		responses := make([]model.CashFlowResponse, 0, len(flows))
+		for i := range flows {
+			responses = append(responses, model.NewCashFlowResponse(&flows[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(responses); err != nil {
+			logger.WithError(err).Error("failed to encode cash flow list response")
+		}
+	}
+}
+
+func CreateCashFlowHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while creating cash flow")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.CreateCashFlowPayload
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			logger.WithError(err).Warn("invalid cash flow payload")
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		payload.Type = strings.ToLower(strings.TrimSpace(payload.Type))
+		if payload.Type != model.CashFlowDeposit && payload.Type != model.CashFlowWithdrawal {
+			http.Error(w, "type must be deposit or withdrawal", http.StatusBadRequest)
+			return
+		}
+		if payload.Amount <= 0 {
+			http.Error(w, "amount must be > 0", http.StatusBadRequest)
+			return
+		}
+
+		occurredAt, err := time.Parse(time.RFC3339, strings.TrimSpace(payload.OccurredAt))
+		if err != nil {
+			http.Error(w, "occurredAt must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+
+		flow := &model.CashFlow{
+			UserID:     user.ID,
+			Type:       payload.Type,
+			Amount:     payload.Amount,
+			OccurredAt: occurredAt,
+			Note:       payload.Note,
+		}
+		if err := getStatsStore().CreateCashFlow(flow); err != nil {
+			logger.WithError(err).Error("failed to create cash flow")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(model.NewCashFlowResponse(flow)); err != nil {
+			logger.WithError(err).Error("failed to encode cash flow response")
+		}
+	}
+}
+
+func DeleteCashFlowHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting cash flow")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := getStatsStore().DeleteCashFlow(user.ID, uint(id))
+		if err != nil {
+			logger.WithError(err).Error("failed to delete cash flow")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if !deleted {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,190 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+)
+
+// periodStart returns the start of the interval period containing t. Weeks
+// start on Monday.
+func periodStart(t time.Time, interval string, loc *time.Location) time.Time {
+	day := startOfDay(t, loc)
+	switch interval {
+	case model.EquityIntervalWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case model.EquityIntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return day
+	}
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case model.EquityIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case model.EquityIntervalMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// MaxEquityPoints caps the periods of one equity curve.
+const MaxEquityPoints = 5000
+
+var ErrTooManyPoints = fmt.Errorf("the range spans more than %d periods, pick a shorter range or a longer interval", MaxEquityPoints)
+
+// equityState is the running balance of an equity curve.
+type equityState struct {
+	equity, peak, cumulative float64
+	peakAt                   time.Time
+}
+
+// apply adds the P&L and cash flow of the period starting at start.
+func (s *equityState) apply(start time.Time, pnl, cashFlow float64) {
+	s.cumulative += pnl
+	s.equity += pnl + cashFlow
+	// Money moved in or out shifts the peak with it rather than counting as
+	// a new high or a drawdown.
+	s.peak += cashFlow
+	if s.equity >= s.peak {
+		s.peak = s.equity
+		s.peakAt = start
+	}
+}
+
+// skipTo accounts for the empty periods before start: while the balance is at
+// its peak, each of them is a new peak too.
+func (s *equityState) skipTo(start time.Time, interval string, loc *time.Location) {
+	if s.equity >= s.peak {
+		if prev := periodStart(start.Add(-time.Nanosecond), interval, loc); prev.After(s.peakAt) {
+			s.peakAt = prev
+		}
+	}
+}
+
+// EquityCurve builds the balance series from trades and cash flows. Trades
+// count when they close. The series covers [from, to) when given and
+// otherwise runs from the first to the last event, with a point for every
+// period in between. Events before from are carried into the balance, peak
+// and cumulative P&L, and events from to on are left out. It returns ErrTooManyPoints when the series would have more than
+// MaxEquityPoints points.
+func EquityCurve(trades []model.Trade, flows []model.CashFlow, startingBalance float64, interval string, from, to *time.Time, loc *time.Location) (model.EquityCurveResponse, error) {
+	resp := model.EquityCurveResponse{
+		Interval:        interval,
+		StartingBalance: startingBalance,
+		Points:          []model.EquityPoint{},
+	}
+
+	type event struct {
+		at            time.Time
+		pnl, cashFlow float64
+	}
+	events := make([]event, 0, len(trades)+len(flows))
+	for _, t := range closedTrades(trades) {
+		events = append(events, event{at: t.closedAt, pnl: t.pnl.NetPnL})
+	}
+	for _, f := range flows {
+		events = append(events, event{at: f.OccurredAt, cashFlow: f.SignedAmount()})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	// Events from to on are left out. The first point covers the whole
+	// period containing from, so only events before that period are carried.
+	var before, within []event
+	var firstStart time.Time
+	if from != nil {
+		firstStart = periodStart(*from, interval, loc)
+	}
+	for _, e := range events {
+		switch {
+		case to != nil && !e.at.Before(*to):
+		case from != nil && e.at.Before(firstStart):
+			before = append(before, e)
+		default:
+			within = append(within, e)
+		}
+	}
+
+	if from == nil && len(within) == 0 {
+		return resp, nil
+	}
+	first := firstStart
+	if from == nil {
+		first = within[0].at
+	}
+	var last time.Time
+	switch {
+	case to != nil:
+		last = to.Add(-time.Nanosecond)
+	case len(within) > 0:
+		last = within[len(within)-1].at
+	default:
+		return resp, nil
+	}
+	if last.Before(first) {
+		return resp, nil
+	}
+
+	index := make(map[int64]int)
+	for start := periodStart(first, interval, loc); !start.After(last); start = nextPeriod(start, interval) {
+		if len(resp.Points) == MaxEquityPoints {
+			return model.EquityCurveResponse{}, ErrTooManyPoints
+		}
+		index[start.Unix()] = len(resp.Points)
+		resp.Points = append(resp.Points, model.EquityPoint{Time: start})
+	}
+
+	for _, e := range within {
+		if i, ok := index[periodStart(e.at, interval, loc).Unix()]; ok {
+			resp.Points[i].PnL += e.pnl
+			resp.Points[i].CashFlow += e.cashFlow
+		}
+	}
+
+	// Replay the events before the first point period by period, the same
+	// way the points are computed, so the balance, peak and cumulative P&L
+	// carry over.
+	state := equityState{equity: startingBalance, peak: startingBalance, peakAt: resp.Points[0].Time}
+	for i := 0; i < len(before); {
+		start := periodStart(before[i].at, interval, loc)
+		if i == 0 {
+			state.peakAt = start
+		}
+		state.skipTo(start, interval, loc)
+
+		var pnl, cashFlow float64
+		for ; i < len(before) && periodStart(before[i].at, interval, loc).Equal(start); i++ {
+			pnl += before[i].pnl
+			cashFlow += before[i].cashFlow
+		}
+		state.apply(start, pnl, cashFlow)
+	}
+	if len(before) > 0 {
+		state.skipTo(resp.Points[0].Time, interval, loc)
+	}
+
+	for i := range resp.Points {
+		point := &resp.Points[i]
+		state.apply(point.Time, point.PnL, point.CashFlow)
+
+		point.CumulativePnL = state.cumulative
+		point.Equity = state.equity
+		point.Peak = state.peak
+		point.Drawdown = state.peak - state.equity
+		if point.Drawdown > 0 {
+			if state.peak > 0 {
+				point.DrawdownPct = point.Drawdown / state.peak
+			}
+			point.DrawdownDays = int(math.Round(point.Time.Sub(state.peakAt).Hours() / 24))
+		}
+	}
+
+	return resp, nil
+}
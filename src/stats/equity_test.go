@@ -0,0 +1,171 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEquityCurve(t *testing.T) {
+	day := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC) // a Monday
+	trades := []model.Trade{
+		testTrade("BTCUSDT", day, 100),
+		testTrade("BTCUSDT", day.AddDate(0, 0, 1), -50),
+		testTrade("BTCUSDT", day.AddDate(0, 0, 3), 80),
+	}
+	flows := []model.CashFlow{
+		{Type: model.CashFlowDeposit, Amount: 500, OccurredAt: day.AddDate(0, 0, 2)},
+	}
+
+	resp, err := EquityCurve(trades, flows, 1000, model.EquityIntervalDay, nil, nil, time.UTC)
+	if err != nil {
+		t.Fatalf("equity curve: %v", err)
+	}
+	if len(resp.Points) != 4 {
+		t.Fatalf("expected a point per day, got %+v", resp.Points)
+	}
+
+	want := []struct {
+		equity, peak, drawdown float64
+		days                   int
+	}{
+		{1100, 1100, 0, 0},
+		{1050, 1100, 50, 1},
+		{1550, 1600, 50, 2}, // the deposit moves the peak, not the drawdown
+		{1630, 1630, 0, 0},
+	}
+	for i, w := range want {
+		p := resp.Points[i]
+		if !approx(p.Equity, w.equity) || !approx(p.Peak, w.peak) || !approx(p.Drawdown, w.drawdown) || p.DrawdownDays != w.days {
+			t.Fatalf("point %d: expected %+v, got %+v", i, w, p)
+		}
+	}
+	if !approx(resp.Points[3].CumulativePnL, 130) {
+		t.Fatalf("expected cumulative P&L 130, got %v", resp.Points[3].CumulativePnL)
+	}
+
+	weekly, _ := EquityCurve(trades, flows, 1000, model.EquityIntervalWeek, nil, nil, time.UTC)
+	if len(weekly.Points) != 1 || !weekly.Points[0].Time.Equal(time.Date(2025, 7, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected one week starting on Monday, got %+v", weekly.Points)
+	}
+}
+
+func TestEquityCurveWindow(t *testing.T) {
+	day := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+	carried := testTrade("BTCUSDT", day, -50)
+	openedBefore := testTrade("BTCUSDT", day.AddDate(0, 0, 1), 40)
+	openedBefore.ExitDate = ptr(day.AddDate(0, 0, 3))
+	closedAfter := testTrade("BTCUSDT", day.AddDate(0, 0, 3), 70)
+	closedAfter.ExitDate = ptr(day.AddDate(0, 0, 6))
+	trades := []model.Trade{carried, openedBefore, closedAfter}
+	flows := []model.CashFlow{
+		{Type: model.CashFlowDeposit, Amount: 500, OccurredAt: day.AddDate(0, 0, 1)},
+	}
+
+	from := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 7, 12, 0, 0, 0, 0, time.UTC)
+	resp, err := EquityCurve(trades, flows, 1000, model.EquityIntervalDay, &from, &to, time.UTC)
+	if err != nil {
+		t.Fatalf("equity curve: %v", err)
+	}
+	if len(resp.Points) != 2 {
+		t.Fatalf("expected two days, got %+v", resp.Points)
+	}
+
+	// 1000 - 50 + 500 before the window, then the trade closed inside it.
+	// The trade closed after the window is left out.
+	first := resp.Points[0]
+	if !approx(first.PnL, 40) || !approx(first.Equity, 1490) || !approx(first.CumulativePnL, -10) || !approx(first.Peak, 1500) || !approx(first.Drawdown, 10) {
+		t.Fatalf("unexpected first point %+v", first)
+	}
+	if first.DrawdownDays != 3 {
+		t.Fatalf("expected the drawdown to date from before the window, got %d days", first.DrawdownDays)
+	}
+	if !approx(resp.Points[1].Equity, 1490) {
+		t.Fatalf("unexpected second point %+v", resp.Points[1])
+	}
+
+	ancient := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := EquityCurve(trades, flows, 0, model.EquityIntervalDay, &ancient, nil, time.UTC); !errors.Is(err, ErrTooManyPoints) {
+		t.Fatalf("expected ErrTooManyPoints, got %v", err)
+	}
+}
+
+func TestEquityAndCashFlowHandlers(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	statsStore := &inMemoryStatsStore{trades: []model.Trade{
+		testTrade("BTCUSDT", time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC), 10),
+	}}
+	SetStatsStore(statsStore)
+	t.Cleanup(func() {
+		SetStatsStore(nil)
+	})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.UserKey, &model.User{ID: 1})))
+		})
+	})
+	router.Get("/stats/equity", EquityHandler(logger))
+	router.Post("/cash-flows", CreateCashFlowHandler(logger))
+	router.Delete("/cash-flows/{id}", DeleteCashFlowHandler(logger))
+
+	body, _ := json.Marshal(map[string]interface{}{"type": "deposit", "amount": 250, "occurredAt": "2025-07-08T10:00:00Z"})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cash-flows", bytes.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected create 201, got %d", rec.Code)
+	}
+
+	body, _ = json.Marshal(map[string]interface{}{"type": "deposit", "amount": -5, "occurredAt": "2025-07-08T10:00:00Z"})
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cash-flows", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a negative amount, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/equity?startingBalance=1000&interval=day", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected equity 200, got %d", rec.Code)
+	}
+
+	var resp model.EquityCurveResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode equity response: %v", err)
+	}
+	if len(resp.Points) != 2 || !approx(resp.Points[1].Equity, 1260) {
+		t.Fatalf("unexpected equity curve: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/equity?from=0001-01-01&interval=day", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a range of too many points, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/equity?interval=hourly", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown interval, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/cash-flows/1", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected delete 204, got %d", rec.Code)
+	}
+}
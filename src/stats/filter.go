@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SideLong  = "long"
+	SideShort = "short"
+)
+
+// TradeFilter narrows the trades a statistic is computed over. The date range
+// applies to the entry date and To is exclusive.
+type TradeFilter struct {
+	From         *time.Time
+	To           *time.Time
+	Symbol       string
+	Exchange     string
+	Side         string // SideLong or SideShort
+	OrderType    string
+	ContractType string
+	Tag          string // tag name
+	SetupID      *uint
+}
+
+// parseTradeFilter reads from, to, symbol, exchange, side, orderType,
+// contractType, tag and setup (an id) from the query string. Dates are
+// RFC3339 or YYYY-MM-DD in loc; a bare date for "to" includes that whole day.
+func parseTradeFilter(r *http.Request, loc *time.Location) (TradeFilter, error) {
+	q := r.URL.Query()
+	filter := TradeFilter{
+		Symbol:       strings.TrimSpace(q.Get("symbol")),
+		Exchange:     strings.TrimSpace(q.Get("exchange")),
+		Side:         strings.ToLower(strings.TrimSpace(q.Get("side"))),
+		OrderType:    strings.TrimSpace(q.Get("orderType")),
+		ContractType: strings.TrimSpace(q.Get("contractType")),
+		Tag:          strings.TrimSpace(q.Get("tag")),
+	}
+
+	if filter.Side != "" && filter.Side != SideLong && filter.Side != SideShort {
+		return TradeFilter{}, fmt.Errorf("side must be %q or %q", SideLong, SideShort)
+	}
+
+	if setup := strings.TrimSpace(q.Get("setup")); setup != "" {
+		id, err := strconv.ParseUint(setup, 10, 64)
+		if err != nil {
+			return TradeFilter{}, fmt.Errorf("setup must be a setup id")
+		}
+		setupID := uint(id)
+		filter.SetupID = &setupID
+	}
+
+	var err error
+	if filter.From, err = parseBound(q.Get("from"), loc, false); err != nil {
+		return TradeFilter{}, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseBound(q.Get("to"), loc, true); err != nil {
+		return TradeFilter{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return TradeFilter{}, fmt.Errorf("from must be before to")
+	}
+
+	return filter, nil
+}
+
+func parseBound(value string, loc *time.Location, endOfDay bool) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseLocation reads the optional IANA tz query param used to bucket trades
+// by day, weekday and hour. It defaults to UTC.
+func parseLocation(r *http.Request) (*time.Location, error) {
+	name := strings.TrimSpace(r.URL.Query().Get("tz"))
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatsHandler serves GET /stats over the caller's closed trades, narrowed by
+// the from, to, symbol, exchange, side, orderType, contractType, tag and setup
+// query params. tz picks the timezone for daily, weekday and hour buckets.
+func StatsHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while computing stats")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		loc, err := parseLocation(r)
+		if err != nil {
+			http.Error(w, "invalid tz", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseTradeFilter(r, loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		trades, err := getStatsStore().ListClosedTrades(user.ID, filter)
+		if err != nil {
+			logger.WithError(err).Error("failed to load trades for stats")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		resp := Summarize(trades, loc)
+		resp.From = filter.From
+		resp.To = filter.To
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Error("failed to encode stats response")
+		}
+	}
+}
+
+// EquityHandler serves GET /stats/equity: the caller's balance per interval
+// (day, week or month) from startingBalance, realized P&L and recorded cash
+// flows. It takes the same filters as StatsHandler, except that from and to
+// apply to the close date. Everything before from is carried into the first
+// point, and cash flows are not narrowed by the other filters. Ranges of more
+// than MaxEquityPoints periods are rejected.
+func EquityHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while computing equity curve")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		loc, err := parseLocation(r)
+		if err != nil {
+			http.Error(w, "invalid tz", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseTradeFilter(r, loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		interval := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("interval")))
+		switch interval {
+		case "":
+			interval = model.EquityIntervalDay
+		case model.EquityIntervalDay, model.EquityIntervalWeek, model.EquityIntervalMonth:
+		default:
+			http.Error(w, "interval must be day, week or month", http.StatusBadRequest)
+			return
+		}
+
+		var startingBalance float64
+		if v := strings.TrimSpace(r.URL.Query().Get("startingBalance")); v != "" {
+			startingBalance, err = strconv.ParseFloat(v, 64)
+			if err != nil || startingBalance < 0 {
+				http.Error(w, "invalid startingBalance", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// The balance at from depends on everything before it, and trades are
+		// placed by close date, so the date range is applied by EquityCurve
+		// rather than the store.
+		undated := filter
+		undated.From, undated.To = nil, nil
+		trades, err := getStatsStore().ListClosedTrades(user.ID, undated)
+		if err != nil {
+			logger.WithError(err).Error("failed to load trades for equity curve")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		flows, err := getStatsStore().ListCashFlows(user.ID, nil, filter.To)
+		if err != nil {
+			logger.WithError(err).Error("failed to load cash flows for equity curve")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := EquityCurve(trades, flows, startingBalance, interval, filter.From, filter.To, loc)
+		if err != nil {
+			if errors.Is(err, ErrTooManyPoints) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.WithError(err).Error("failed to compute equity curve")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Error("failed to encode equity curve response")
+		}
+	}
+}
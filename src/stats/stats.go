@@ -0,0 +1,288 @@
+// Package stats aggregates a user's closed trades into performance figures.
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/pnl"
+)
+
+// Crypto markets trade every day of the year.
+const tradingDaysPerYear = 365
+
+// closedTrade is a trade together with its realized result.
+type closedTrade struct {
+	model.Trade
+	pnl      model.TradePnL
+	closedAt time.Time
+}
+
+// closedTrades computes the P&L of every closed trade and orders them by the
+// time they were closed.
+func closedTrades(trades []model.Trade) []closedTrade {
+	closed := make([]closedTrade, 0, len(trades))
+	for _, t := range trades {
+		result := pnl.Compute(t)
+		if result == nil {
+			continue
+		}
+		closedAt := t.TradeDate
+		if t.ExitDate != nil {
+			closedAt = *t.ExitDate
+		}
+		closed = append(closed, closedTrade{Trade: t, pnl: *result, closedAt: closedAt})
+	}
+
+	sort.SliceStable(closed, func(i, j int) bool {
+		return closed[i].closedAt.Before(closed[j].closedAt)
+	})
+	return closed
+}
+
+// Summarize computes the performance statistics of trades. Daily P&L, weekday
+// and hour are taken in loc.
+func Summarize(trades []model.Trade, loc *time.Location) model.TradeStatsResponse {
+	closed := closedTrades(trades)
+
+	var resp model.TradeStatsResponse
+	var winStreak, lossStreak int
+	var equity, peak float64
+
+	for _, t := range closed {
+		net := t.pnl.NetPnL
+		resp.Trades++
+		resp.NetPnL += net
+		resp.Fees += t.pnl.Fees
+
+		switch {
+		case net > 0:
+			resp.Wins++
+			resp.GrossProfit += net
+			resp.LargestWin = math.Max(resp.LargestWin, net)
+			winStreak++
+			lossStreak = 0
+		case net < 0:
+			resp.Losses++
+			resp.GrossLoss += net
+			resp.LargestLoss = math.Min(resp.LargestLoss, net)
+			lossStreak++
+			winStreak = 0
+		default:
+			resp.Breakeven++
+			winStreak, lossStreak = 0, 0
+		}
+		resp.MaxConsecutiveWins = max(resp.MaxConsecutiveWins, winStreak)
+		resp.MaxConsecutiveLosses = max(resp.MaxConsecutiveLosses, lossStreak)
+
+		equity += net
+		peak = math.Max(peak, equity)
+		resp.MaxDrawdown = math.Max(resp.MaxDrawdown, peak-equity)
+	}
+
+	if resp.Trades > 0 {
+		resp.WinRate = float64(resp.Wins) / float64(resp.Trades)
+		resp.Expectancy = resp.NetPnL / float64(resp.Trades)
+	}
+	if resp.Wins > 0 {
+		resp.AverageWin = resp.GrossProfit / float64(resp.Wins)
+	}
+	if resp.Losses > 0 {
+		resp.AverageLoss = resp.GrossLoss / float64(resp.Losses)
+		profitFactor := resp.GrossProfit / math.Abs(resp.GrossLoss)
+		resp.ProfitFactor = &profitFactor
+	}
+
+	daily := dailyPnL(closed, loc)
+	resp.SharpeRatio = sharpeRatio(daily)
+	resp.SortinoRatio = sortinoRatio(daily)
+	resp.Breakdowns = breakdowns(closed, loc)
+
+	return resp
+}
+
+// dailyPnL returns the net P&L of every calendar day from the first to the
+// last close, including days without trades.
+func dailyPnL(closed []closedTrade, loc *time.Location) []float64 {
+	if len(closed) == 0 {
+		return nil
+	}
+
+	first := startOfDay(closed[0].closedAt, loc)
+	last := startOfDay(closed[len(closed)-1].closedAt, loc)
+	days := int(math.Round(last.Sub(first).Hours()/24)) + 1
+
+	daily := make([]float64, days)
+	for _, t := range closed {
+		day := int(math.Round(startOfDay(t.closedAt, loc).Sub(first).Hours() / 24))
+		daily[day] += t.pnl.NetPnL
+	}
+	return daily
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sharpeRatio is the annualized mean over standard deviation of daily P&L.
+// Without a reference balance, P&L stands in for returns; the ratio is the
+// same as long as the capital at risk does not change much.
+func sharpeRatio(daily []float64) *float64 {
+	if len(daily) < 2 {
+		return nil
+	}
+
+	m := mean(daily)
+	var variance float64
+	for _, v := range daily {
+		variance += (v - m) * (v - m)
+	}
+	stddev := math.Sqrt(variance / float64(len(daily)-1))
+	if stddev == 0 {
+		return nil
+	}
+
+	ratio := m / stddev * math.Sqrt(tradingDaysPerYear)
+	return &ratio
+}
+
+// sortinoRatio is like sharpeRatio but only penalizes losing days.
+func sortinoRatio(daily []float64) *float64 {
+	if len(daily) < 2 {
+		return nil
+	}
+
+	var downside float64
+	for _, v := range daily {
+		if v < 0 {
+			downside += v * v
+		}
+	}
+	if downside == 0 {
+		return nil
+	}
+
+	ratio := mean(daily) / math.Sqrt(downside/float64(len(daily))) * math.Sqrt(tradingDaysPerYear)
+	return &ratio
+}
+
+type bucketSet struct {
+	buckets map[string]*model.TradeStatsBucket
+}
+
+func newBucketSet() *bucketSet {
+	return &bucketSet{buckets: make(map[string]*model.TradeStatsBucket)}
+}
+
+func (s *bucketSet) add(key string, net float64) {
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &model.TradeStatsBucket{Key: key}
+		s.buckets[key] = bucket
+	}
+
+	bucket.Trades++
+	bucket.NetPnL += net
+	switch {
+	case net > 0:
+		bucket.Wins++
+	case net < 0:
+		bucket.Losses++
+	}
+	bucket.WinRate = float64(bucket.Wins) / float64(bucket.Trades)
+}
+
+// sorted returns the buckets in key order, or in the given order when keys
+// are listed.
+func (s *bucketSet) sorted(order ...string) []model.TradeStatsBucket {
+	if len(order) == 0 {
+		for key := range s.buckets {
+			order = append(order, key)
+		}
+		sort.Strings(order)
+	}
+
+	result := make([]model.TradeStatsBucket, 0, len(s.buckets))
+	for _, key := range order {
+		if bucket, ok := s.buckets[key]; ok {
+			result = append(result, *bucket)
+		}
+	}
+	return result
+}
+
+// Bucket keys for trades without a setup or without tags.
+const (
+	noSetup  = "none"
+	untagged = "untagged"
+)
+
+var weekdayOrder = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+func breakdowns(closed []closedTrade, loc *time.Location) model.TradeStatsBreakdowns {
+	symbols, exchanges, sides, orderTypes := newBucketSet(), newBucketSet(), newBucketSet(), newBucketSet()
+	weekdays, hours := newBucketSet(), newBucketSet()
+	setups, tags := newBucketSet(), newBucketSet()
+
+	for _, t := range closed {
+		net := t.pnl.NetPnL
+		opened := t.TradeDate.In(loc)
+
+		symbols.add(t.Symbol, net)
+		exchanges.add(valueOr(t.Exchange, "unknown"), net)
+		if t.IsShort && !t.IsLong {
+			sides.add(SideShort, net)
+		} else {
+			sides.add(SideLong, net)
+		}
+		orderType := t.OrderType
+		if orderType == "" {
+			orderType = "unknown"
+		}
+		orderTypes.add(orderType, net)
+		weekdays.add(opened.Weekday().String(), net)
+		hours.add(fmt.Sprintf("%02d", opened.Hour()), net)
+
+		if t.Setup != nil {
+			setups.add(t.Setup.Name, net)
+		} else {
+			setups.add(noSetup, net)
+		}
+		if len(t.Tags) == 0 {
+			tags.add(untagged, net)
+		}
+		for _, tag := range t.Tags {
+			tags.add(tag.Name, net)
+		}
+	}
+
+	return model.TradeStatsBreakdowns{
+		BySymbol:    symbols.sorted(),
+		ByExchange:  exchanges.sorted(),
+		BySide:      sides.sorted(SideLong, SideShort),
+		ByOrderType: orderTypes.sorted(),
+		ByWeekday:   weekdays.sorted(weekdayOrder...),
+		ByHour:      hours.sorted(),
+		BySetup:     setups.sorted(),
+		ByTag:       tags.sorted(),
+	}
+}
+
+func valueOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
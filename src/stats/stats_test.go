@@ -0,0 +1,227 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+type inMemoryStatsStore struct {
+	trades  []model.Trade
+	flows   []model.CashFlow
+	filters []TradeFilter
+}
+
+func (s *inMemoryStatsStore) ListClosedTrades(userID uint, filter TradeFilter) ([]model.Trade, error) {
+	s.filters = append(s.filters, filter)
+
+	var result []model.Trade
+	for _, t := range s.trades {
+		if t.UserID == userID {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryStatsStore) ListCashFlows(userID uint, from, to *time.Time) ([]model.CashFlow, error) {
+	var result []model.CashFlow
+	for _, f := range s.flows {
+		if f.UserID != userID || (from != nil && f.OccurredAt.Before(*from)) || (to != nil && !f.OccurredAt.Before(*to)) {
+			continue
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func (s *inMemoryStatsStore) CreateCashFlow(flow *model.CashFlow) error {
+	flow.ID = uint(len(s.flows) + 1)
+	s.flows = append(s.flows, *flow)
+	return nil
+}
+
+func (s *inMemoryStatsStore) DeleteCashFlow(userID, id uint) (bool, error) {
+	for i, f := range s.flows {
+		if f.UserID == userID && f.ID == id {
+			s.flows = append(s.flows[:i], s.flows[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// testTrade is a closed spot trade of one unit opened at opened and closed an
+// hour later with the given net P&L.
+func testTrade(symbol string, opened time.Time, net float64) model.Trade {
+	return model.Trade{
+		UserID:       1,
+		Symbol:       symbol,
+		Exchange:     ptr("Binance"),
+		OrderType:    "limit",
+		ContractType: ptr("spot"),
+		IsLong:       true,
+		Quantity:     1,
+		EntryPrice:   100,
+		ExitPrice:    100 + net,
+		TradeDate:    opened,
+		ExitDate:     ptr(opened.Add(time.Hour)),
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC) // a Monday
+	trades := []model.Trade{
+		testTrade("BTCUSDT", day, 10),
+		testTrade("BTCUSDT", day.AddDate(0, 0, 1), 20),
+		testTrade("ETHUSDT", day.AddDate(0, 0, 2), -15),
+		testTrade("ETHUSDT", day.AddDate(0, 0, 3), -5),
+		testTrade("BTCUSDT", day.AddDate(0, 0, 4), 30),
+		{UserID: 1, Symbol: "SOLUSDT", IsLong: true, Quantity: 1, EntryPrice: 100, TradeDate: day}, // still open
+	}
+
+	resp := Summarize(trades, time.UTC)
+
+	if resp.Trades != 5 || resp.Wins != 3 || resp.Losses != 2 {
+		t.Fatalf("unexpected counts: %+v", resp)
+	}
+	if !approx(resp.WinRate, 0.6) || !approx(resp.NetPnL, 40) || !approx(resp.Expectancy, 8) {
+		t.Fatalf("unexpected totals: %+v", resp)
+	}
+	if resp.ProfitFactor == nil || !approx(*resp.ProfitFactor, 3) {
+		t.Fatalf("expected profit factor 3, got %v", resp.ProfitFactor)
+	}
+	if !approx(resp.AverageWin, 20) || !approx(resp.AverageLoss, -10) || !approx(resp.LargestWin, 30) || !approx(resp.LargestLoss, -15) {
+		t.Fatalf("unexpected averages: %+v", resp)
+	}
+	if resp.MaxConsecutiveWins != 2 || resp.MaxConsecutiveLosses != 2 {
+		t.Fatalf("unexpected streaks: wins %d losses %d", resp.MaxConsecutiveWins, resp.MaxConsecutiveLosses)
+	}
+	if !approx(resp.MaxDrawdown, 20) {
+		t.Fatalf("expected max drawdown 20, got %v", resp.MaxDrawdown)
+	}
+	if resp.SharpeRatio == nil || *resp.SharpeRatio <= 0 || resp.SortinoRatio == nil || *resp.SortinoRatio <= 0 {
+		t.Fatalf("expected positive Sharpe and Sortino ratios, got %v %v", resp.SharpeRatio, resp.SortinoRatio)
+	}
+
+	bySymbol := resp.Breakdowns.BySymbol
+	if len(bySymbol) != 2 || bySymbol[0].Key != "BTCUSDT" || bySymbol[0].Trades != 3 || !approx(bySymbol[0].NetPnL, 60) {
+		t.Fatalf("unexpected symbol breakdown: %+v", bySymbol)
+	}
+	byWeekday := resp.Breakdowns.ByWeekday
+	if len(byWeekday) != 5 || byWeekday[0].Key != "Monday" || byWeekday[4].Key != "Friday" {
+		t.Fatalf("unexpected weekday breakdown: %+v", byWeekday)
+	}
+	if byHour := resp.Breakdowns.ByHour; len(byHour) != 1 || byHour[0].Key != "09" || byHour[0].Trades != 5 {
+		t.Fatalf("unexpected hour breakdown: %+v", byHour)
+	}
+}
+
+func TestSummarizeBySetupAndTag(t *testing.T) {
+	day := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+	breakout := &model.Setup{ID: 1, Name: "Breakout"}
+
+	first := testTrade("BTCUSDT", day, 10)
+	first.Setup, first.Tags = breakout, []model.Tag{{Name: "a+"}, {Name: "trend"}}
+	second := testTrade("BTCUSDT", day.AddDate(0, 0, 1), -4)
+	second.Setup, second.Tags = breakout, []model.Tag{{Name: "trend"}}
+	third := testTrade("ETHUSDT", day.AddDate(0, 0, 2), 3)
+
+	resp := Summarize([]model.Trade{first, second, third}, time.UTC)
+
+	bySetup := resp.Breakdowns.BySetup
+	if len(bySetup) != 2 || bySetup[0].Key != "Breakout" || bySetup[0].Trades != 2 || !approx(bySetup[0].NetPnL, 6) || bySetup[1].Key != noSetup {
+		t.Fatalf("unexpected setup breakdown: %+v", bySetup)
+	}
+	byTag := resp.Breakdowns.ByTag
+	if len(byTag) != 3 || byTag[0].Key != "a+" || byTag[1].Key != "trend" || byTag[1].Trades != 2 || byTag[2].Key != untagged {
+		t.Fatalf("unexpected tag breakdown: %+v", byTag)
+	}
+}
+
+func TestSummarizeWithoutTrades(t *testing.T) {
+	resp := Summarize(nil, time.UTC)
+	if resp.Trades != 0 || resp.ProfitFactor != nil || resp.SharpeRatio != nil {
+		t.Fatalf("expected empty stats, got %+v", resp)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	statsStore := &inMemoryStatsStore{trades: []model.Trade{
+		testTrade("BTCUSDT", time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC), 10),
+	}}
+	SetStatsStore(statsStore)
+	t.Cleanup(func() {
+		SetStatsStore(nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/stats?from=2025-07-01&to=2025-07-31&side=long&symbol=BTCUSDT", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec := httptest.NewRecorder()
+	StatsHandler(logger).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var resp model.TradeStatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode stats response: %v", err)
+	}
+	if resp.Trades != 1 || !approx(resp.NetPnL, 10) {
+		t.Fatalf("unexpected stats: %+v", resp)
+	}
+
+	filter := statsStore.filters[0]
+	if filter.Symbol != "BTCUSDT" || filter.Side != SideLong {
+		t.Fatalf("unexpected filter: %+v", filter)
+	}
+	if !filter.To.Equal(time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected bare to date to include the whole day, got %v", filter.To)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stats?tag=trend&setup=3", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec = httptest.NewRecorder()
+	StatsHandler(logger).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if filter := statsStore.filters[1]; filter.Tag != "trend" || filter.SetupID == nil || *filter.SetupID != 3 {
+		t.Fatalf("unexpected tag and setup filter: %+v", filter)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stats?setup=breakout", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec = httptest.NewRecorder()
+	StatsHandler(logger).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a setup that is not an id, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stats?side=sideways", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec = httptest.NewRecorder()
+	StatsHandler(logger).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown side, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package stats
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+)
+
+type StatsStore interface {
+	// ListClosedTrades returns the user's trades that have an exit price and
+	// match filter, oldest first, with their tags and setup.
+	ListClosedTrades(userID uint, filter TradeFilter) ([]model.Trade, error)
+	// ListCashFlows returns the user's deposits and withdrawals in [from, to),
+	// oldest first. Nil bounds are open.
+	ListCashFlows(userID uint, from, to *time.Time) ([]model.CashFlow, error)
+	CreateCashFlow(flow *model.CashFlow) error
+	DeleteCashFlow(userID, id uint) (bool, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   StatsStore = &gormStatsStore{}
+)
+
+func SetStatsStore(s StatsStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormStatsStore{}
+		return
+	}
+
+	store = s
+}
+
+func getStatsStore() StatsStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormStatsStore{}
+	}
+
+	return store
+}
+
+type gormStatsStore struct{}
+
+func (s *gormStatsStore) ListClosedTrades(userID uint, filter TradeFilter) ([]model.Trade, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	query := db.DB.Where("user_id = ? AND exit_price > 0", userID)
+	if filter.From != nil {
+		query = query.Where("trade_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("trade_date < ?", *filter.To)
+	}
+	if filter.Symbol != "" {
+		query = query.Where("symbol = ?", filter.Symbol)
+	}
+	if filter.Exchange != "" {
+		query = query.Where("exchange = ?", filter.Exchange)
+	}
+	if filter.OrderType != "" {
+		query = query.Where("order_type = ?", filter.OrderType)
+	}
+	if filter.ContractType != "" {
+		query = query.Where("contract_type = ?", filter.ContractType)
+	}
+	switch filter.Side {
+	case SideLong:
+		query = query.Where("is_long = ?", true)
+	case SideShort:
+		query = query.Where("is_short = ?", true)
+	}
+	if filter.SetupID != nil {
+		query = query.Where("setup_id = ?", *filter.SetupID)
+	}
+	if filter.Tag != "" {
+		tagged := db.DB.Table("trade_tags").
+			Select("trade_tags.trade_id").
+			Joins("JOIN tags ON tags.id = trade_tags.tag_id").
+			Where("tags.user_id = ? AND tags.name = ?", userID, filter.Tag)
+		query = query.Where("id IN (?)", tagged)
+	}
+
+	var trades []model.Trade
+	if err := query.Preload("Tags").Preload("Setup").Order("trade_date ASC, id ASC").Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+func (s *gormStatsStore) ListCashFlows(userID uint, from, to *time.Time) ([]model.CashFlow, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	query := db.DB.Where("user_id = ?", userID)
+	if from != nil {
+		query = query.Where("occurred_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("occurred_at < ?", *to)
+	}
+
+	var flows []model.CashFlow
+	if err := query.Order("occurred_at ASC, id ASC").Find(&flows).Error; err != nil {
+		return nil, err
+	}
+
+	return flows, nil
+}
+
+func (s *gormStatsStore) CreateCashFlow(flow *model.CashFlow) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Create(flow).Error
+}
+
+func (s *gormStatsStore) DeleteCashFlow(userID, id uint) (bool, error) {
+	if db.DB == nil {
+		return false, errors.New("database connection is not initialized")
+	}
+
+	res := db.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&model.CashFlow{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
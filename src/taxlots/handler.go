@@ -0,0 +1,96 @@
+package taxlots
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReportHandler serves GET /tax/report?year=2025&method=fifo&format=json.
+// method is fifo, lifo, hifo or average (default fifo); format is json, csv
+// or 8949 (a Form 8949-style CSV). year defaults to the current one and tz
+// (default UTC) decides which year a sale falls in.
+func ReportHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while building tax report")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+
+		loc := time.UTC
+		if name := strings.TrimSpace(q.Get("tz")); name != "" {
+			var err error
+			if loc, err = time.LoadLocation(name); err != nil {
+				http.Error(w, "invalid tz", http.StatusBadRequest)
+				return
+			}
+		}
+
+		year := time.Now().In(loc).Year()
+		if v := strings.TrimSpace(q.Get("year")); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1970 || parsed > 9999 {
+				http.Error(w, "invalid year", http.StatusBadRequest)
+				return
+			}
+			year = parsed
+		}
+
+		method := strings.ToLower(strings.TrimSpace(q.Get("method")))
+		if method == "" {
+			method = MethodFIFO
+		}
+
+		format := strings.ToLower(strings.TrimSpace(q.Get("format")))
+		switch format {
+		case "":
+			format = "json"
+		case "json", "csv", "8949":
+		default:
+			http.Error(w, "format must be json, csv or 8949", http.StatusBadRequest)
+			return
+		}
+
+		trades, err := getTaxLotStore().ListSpotTrades(user.ID)
+		if err != nil {
+			logger.WithError(err).Error("failed to load trades for tax report")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		disposals, err := Match(trades, method)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report := Report(disposals, year, method, loc)
+
+		switch format {
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+			err = json.NewEncoder(w).Encode(report)
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="capital-gains-%d-%s.csv"`, year, method))
+			err = writeCSV(w, report)
+		case "8949":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="form-8949-%d-%s.csv"`, year, method))
+			err = write8949(w, report, loc)
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed to write tax report")
+		}
+	}
+}
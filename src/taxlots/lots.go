@@ -0,0 +1,216 @@
+// Package taxlots matches spot sales against earlier purchases to compute
+// realized capital gains.
+package taxlots
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/pnl"
+)
+
+const (
+	MethodFIFO    = "fifo"
+	MethodLIFO    = "lifo"
+	MethodHIFO    = "hifo"
+	MethodAverage = "average"
+)
+
+var ErrUnknownMethod = errors.New("method must be fifo, lifo, hifo or average")
+
+// quantityEpsilon absorbs float64 rounding when lots are split; anything
+// smaller counts as fully consumed.
+const quantityEpsilon = 1e-9
+
+// lot is an acquisition with what is left of it.
+type lot struct {
+	tradeID   uint
+	acquired  time.Time
+	quantity  float64
+	unitCost  float64 // including the purchase fee
+	remaining float64
+}
+
+// event is a purchase or sale of a symbol, derived from a trade.
+type event struct {
+	tradeID  uint
+	symbol   string
+	at       time.Time
+	buy      bool
+	quantity float64
+	price    float64
+	fee      float64
+}
+
+// events turns spot trades into purchases and sales. A trade with an exit is
+// a round trip: the opening side at TradeDate and the closing side at its
+// exit date, with the fee split evenly. A trade without an exit is a single
+// execution. Futures trades are skipped.
+func events(trades []model.Trade) []event {
+	var result []event
+	for _, t := range trades {
+		if pnl.IsFutures(t) || t.Quantity <= 0 || t.EntryPrice <= 0 {
+			continue
+		}
+
+		fee := 0.0
+		if t.Fee != nil {
+			fee = math.Abs(*t.Fee)
+		}
+		symbol := strings.ToUpper(t.Symbol)
+		isBuy := !(t.IsShort && !t.IsLong)
+
+		if t.ExitPrice <= 0 {
+			result = append(result, event{tradeID: t.ID, symbol: symbol, at: t.TradeDate, buy: isBuy, quantity: t.Quantity, price: t.EntryPrice, fee: fee})
+			continue
+		}
+
+		closedAt := t.TradeDate
+		if t.ExitDate != nil {
+			closedAt = *t.ExitDate
+		}
+		result = append(result,
+			event{tradeID: t.ID, symbol: symbol, at: t.TradeDate, buy: isBuy, quantity: t.Quantity, price: t.EntryPrice, fee: fee / 2},
+			event{tradeID: t.ID, symbol: symbol, at: closedAt, buy: !isBuy, quantity: t.Quantity, price: t.ExitPrice, fee: fee / 2},
+		)
+	}
+
+	// Purchases go first when they share a timestamp with a sale, so a round
+	// trip recorded with a single date still matches itself.
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].at.Equal(result[j].at) {
+			return result[i].at.Before(result[j].at)
+		}
+		return result[i].buy && !result[j].buy
+	})
+	return result
+}
+
+// isLongTerm applies the more-than-one-year holding period.
+func isLongTerm(acquired, sold time.Time) bool {
+	return sold.After(acquired.AddDate(1, 0, 0))
+}
+
+// Match runs every sale in trades against the open lots of its symbol using
+// method and returns one disposal per matched lot, in sale order.
+func Match(trades []model.Trade, method string) ([]model.TaxLotDisposal, error) {
+	switch method {
+	case MethodFIFO, MethodLIFO, MethodHIFO, MethodAverage:
+	default:
+		return nil, ErrUnknownMethod
+	}
+
+	open := make(map[string][]*lot)
+	var disposals []model.TaxLotDisposal
+
+	for _, e := range events(trades) {
+		if e.buy {
+			open[e.symbol] = append(open[e.symbol], &lot{
+				tradeID:   e.tradeID,
+				acquired:  e.at,
+				quantity:  e.quantity,
+				unitCost:  (e.price*e.quantity + e.fee) / e.quantity,
+				remaining: e.quantity,
+			})
+			if method == MethodAverage {
+				averageLots(open[e.symbol])
+			}
+			continue
+		}
+
+		lots := open[e.symbol]
+		orderLots(lots, method)
+
+		remaining := e.quantity
+		for _, l := range lots {
+			if remaining <= quantityEpsilon {
+				break
+			}
+			if l.remaining <= quantityEpsilon {
+				continue
+			}
+
+			quantity := math.Min(l.remaining, remaining)
+			l.remaining -= quantity
+			remaining -= quantity
+
+			acquired := l.acquired
+			disposals = append(disposals, disposal(e, quantity, &acquired, quantity*l.unitCost, l.tradeID))
+		}
+		if remaining > quantityEpsilon {
+			d := disposal(e, remaining, nil, 0, 0)
+			d.Unmatched = true
+			disposals = append(disposals, d)
+		}
+
+		open[e.symbol] = compactLots(lots)
+	}
+
+	return disposals, nil
+}
+
+// disposal builds the record for quantity units of sale e, allocating the
+// proceeds and sale fee pro rata.
+func disposal(e event, quantity float64, acquired *time.Time, costBasis float64, buyTradeID uint) model.TaxLotDisposal {
+	share := quantity / e.quantity
+	d := model.TaxLotDisposal{
+		Symbol:       e.symbol,
+		Quantity:     quantity,
+		DateAcquired: acquired,
+		DateSold:     e.at,
+		Proceeds:     quantity * e.price,
+		CostBasis:    costBasis,
+		Fees:         e.fee * share,
+		Term:         model.TaxTermShort,
+		BuyTradeID:   buyTradeID,
+		SellTradeID:  e.tradeID,
+	}
+	d.Gain = d.Proceeds - d.CostBasis - d.Fees
+	if acquired != nil && isLongTerm(*acquired, e.at) {
+		d.Term = model.TaxTermLong
+	}
+	return d
+}
+
+// orderLots sorts lots into the order method consumes them. Lots are kept in
+// acquisition order otherwise, which average cost uses to date its
+// disposals.
+func orderLots(lots []*lot, method string) {
+	switch method {
+	case MethodLIFO:
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].acquired.After(lots[j].acquired) })
+	case MethodHIFO:
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].unitCost > lots[j].unitCost })
+	default:
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].acquired.Before(lots[j].acquired) })
+	}
+}
+
+// averageLots gives every open lot the pooled cost per unit.
+func averageLots(lots []*lot) {
+	var quantity, cost float64
+	for _, l := range lots {
+		quantity += l.remaining
+		cost += l.remaining * l.unitCost
+	}
+	if quantity <= quantityEpsilon {
+		return
+	}
+	for _, l := range lots {
+		l.unitCost = cost / quantity
+	}
+}
+
+func compactLots(lots []*lot) []*lot {
+	kept := lots[:0]
+	for _, l := range lots {
+		if l.remaining > quantityEpsilon {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,141 @@
+package taxlots
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func execution(id uint, at time.Time, buy bool, quantity, price, fee float64) model.Trade {
+	spot := "spot"
+	return model.Trade{
+		ID:           id,
+		Symbol:       "BTCUSDT",
+		ContractType: &spot,
+		IsLong:       buy,
+		IsShort:      !buy,
+		Quantity:     quantity,
+		EntryPrice:   price,
+		Fee:          &fee,
+		TradeDate:    at,
+	}
+}
+
+func testTrades() []model.Trade {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	return []model.Trade{
+		execution(1, start, true, 1, 100, 0),                     // lot A
+		execution(2, start.AddDate(0, 6, 0), true, 1, 300, 0),    // lot B
+		execution(3, start.AddDate(1, 1, 0), true, 1, 200, 0),    // lot C
+		execution(4, start.AddDate(1, 2, 0), false, 1.5, 400, 3), // sell 1.5
+	}
+}
+
+func TestMatchMethods(t *testing.T) {
+	tests := []struct {
+		method    string
+		costBasis float64
+		lots      int
+		longTerm  float64 // quantity held over a year
+	}{
+		{MethodFIFO, 100 + 150, 2, 1},
+		{MethodLIFO, 200 + 150, 2, 0},
+		{MethodHIFO, 300 + 100, 2, 0},
+		{MethodAverage, 300, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			disposals, err := Match(testTrades(), tt.method)
+			if err != nil {
+				t.Fatalf("match failed: %v", err)
+			}
+			if len(disposals) != tt.lots {
+				t.Fatalf("expected %d disposals, got %+v", tt.lots, disposals)
+			}
+
+			var quantity, basis, proceeds, fees, longTerm float64
+			for _, d := range disposals {
+				quantity += d.Quantity
+				basis += d.CostBasis
+				proceeds += d.Proceeds
+				fees += d.Fees
+				if d.Term == model.TaxTermLong {
+					longTerm += d.Quantity
+				}
+			}
+			if !approx(quantity, 1.5) || !approx(proceeds, 600) || !approx(fees, 3) {
+				t.Fatalf("unexpected totals: quantity %v proceeds %v fees %v", quantity, proceeds, fees)
+			}
+			if !approx(basis, tt.costBasis) {
+				t.Fatalf("expected cost basis %v, got %v", tt.costBasis, basis)
+			}
+			if !approx(longTerm, tt.longTerm) {
+				t.Fatalf("expected %v long-term, got %v", tt.longTerm, longTerm)
+			}
+		})
+	}
+}
+
+func TestMatchRoundTripAndUnmatched(t *testing.T) {
+	day := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	spot := "spot"
+	fee := 2.0
+	exit := day.Add(time.Hour)
+	roundTrip := model.Trade{
+		ID: 1, Symbol: "ETHUSDT", ContractType: &spot, IsLong: true,
+		Quantity: 0.3, EntryPrice: 1000, ExitPrice: 1100, ExitDate: &exit, Fee: &fee,
+	}
+	roundTrip.TradeDate = day
+
+	oversold := execution(2, day.AddDate(0, 0, 1), false, 0.1, 50000, 0)
+
+	disposals, err := Match([]model.Trade{roundTrip, oversold}, MethodFIFO)
+	if err != nil {
+		t.Fatalf("match failed: %v", err)
+	}
+	if len(disposals) != 2 {
+		t.Fatalf("expected 2 disposals, got %+v", disposals)
+	}
+	if d := disposals[0]; !approx(d.CostBasis, 301) || !approx(d.Proceeds, 330) || !approx(d.Gain, 28) || d.Term != model.TaxTermShort {
+		t.Fatalf("unexpected round trip disposal: %+v", d)
+	}
+	if d := disposals[1]; !d.Unmatched || d.DateAcquired != nil || d.CostBasis != 0 {
+		t.Fatalf("expected unmatched sale, got %+v", d)
+	}
+
+	if _, err := Match(nil, "random"); err != ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	disposals, _ := Match(testTrades(), MethodFIFO)
+
+	report := Report(disposals, 2024, MethodFIFO, time.UTC)
+	if len(report.Disposals) != 2 || !approx(report.LongTerm.Gain, 298) || !approx(report.ShortTerm.Gain, 49) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if empty := Report(disposals, 2023, MethodFIFO, time.UTC); len(empty.Disposals) != 0 {
+		t.Fatalf("expected no disposals in 2023, got %+v", empty.Disposals)
+	}
+
+	var buf bytes.Buffer
+	if err := write8949(&buf, report, time.UTC); err != nil {
+		t.Fatalf("failed to write 8949: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"II,1 BTCUSDT,03/01/2023,05/01/2024,398.00,100.00,,,298.00", "I,Totals,,,199.00,150.00,,,49.00"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in 8949 output, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package taxlots
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+)
+
+// Report keeps the disposals sold in year, in loc, and totals them by term.
+func Report(disposals []model.TaxLotDisposal, year int, method string, loc *time.Location) model.TaxReportResponse {
+	resp := model.TaxReportResponse{
+		Year:      year,
+		Method:    method,
+		Disposals: []model.TaxLotDisposal{},
+	}
+
+	for _, d := range disposals {
+		if d.DateSold.In(loc).Year() != year {
+			continue
+		}
+		resp.Disposals = append(resp.Disposals, d)
+
+		totals := &resp.ShortTerm
+		if d.Term == model.TaxTermLong {
+			totals = &resp.LongTerm
+		}
+		totals.Proceeds += d.Proceeds
+		totals.CostBasis += d.CostBasis
+		totals.Fees += d.Fees
+		totals.Gain += d.Gain
+	}
+
+	return resp
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeCSV writes one line per disposal.
+func writeCSV(w io.Writer, report model.TaxReportResponse) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"symbol", "quantity", "date_acquired", "date_sold", "proceeds", "cost_basis", "fees", "gain", "term", "unmatched"}); err != nil {
+		return err
+	}
+
+	for _, d := range report.Disposals {
+		acquired := ""
+		if d.DateAcquired != nil {
+			acquired = d.DateAcquired.Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{
+			d.Symbol, formatQuantity(d.Quantity), acquired, d.DateSold.Format(time.RFC3339),
+			formatAmount(d.Proceeds), formatAmount(d.CostBasis), formatAmount(d.Fees), formatAmount(d.Gain),
+			d.Term, strconv.FormatBool(d.Unmatched),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// write8949 lays the disposals out like IRS Form 8949: Part I for short-term
+// and Part II for long-term sales, columns (a) to (h). Sale fees are netted
+// into the proceeds, as the form expects, so column (g) stays empty.
+func write8949(w io.Writer, report model.TaxReportResponse, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"part", "(a) description of property", "(b) date acquired", "(c) date sold or disposed of",
+		"(d) proceeds", "(e) cost or other basis", "(f) code", "(g) amount of adjustment", "(h) gain or (loss)",
+	}); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		name string
+		term string
+	}{
+		{"I", model.TaxTermShort},
+		{"II", model.TaxTermLong},
+	}
+	for _, part := range parts {
+		var proceeds, basis, gain float64
+		for _, d := range report.Disposals {
+			if d.Term != part.term {
+				continue
+			}
+
+			acquired := "VARIOUS"
+			if d.DateAcquired != nil {
+				acquired = d.DateAcquired.In(loc).Format("01/02/2006")
+			}
+			netProceeds := d.Proceeds - d.Fees
+			proceeds += netProceeds
+			basis += d.CostBasis
+			gain += d.Gain
+
+			if err := cw.Write([]string{
+				part.name,
+				fmt.Sprintf("%s %s", formatQuantity(d.Quantity), d.Symbol),
+				acquired,
+				d.DateSold.In(loc).Format("01/02/2006"),
+				formatAmount(netProceeds),
+				formatAmount(d.CostBasis),
+				"",
+				"",
+				formatAmount(d.Gain),
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err := cw.Write([]string{part.name, "Totals", "", "", formatAmount(proceeds), formatAmount(basis), "", "", formatAmount(gain)}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
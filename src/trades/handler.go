@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"vsC1Y2025V01/src/attachments"
 	"vsC1Y2025V01/src/auth"
 	"vsC1Y2025V01/src/db"
 	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/pnl"
+	"vsC1Y2025V01/src/query"
 )
 
 type TradeListResponse struct {
@@ -40,34 +44,46 @@ type TradeListResponse struct {
 //		}
 //	}
 
-func parseRangeParams(r *http.Request) (offset, limit int) {
-	rangeStr := r.URL.Query().Get("range")
-	var rangeVals [2]int
-	if rangeStr == "" || json.Unmarshal([]byte(rangeStr), &rangeVals) != nil {
-		return 0, 10 // default
-	}
-	return rangeVals[0], rangeVals[1] - rangeVals[0] + 1
+// tradeSchema lists what GET /trades and the export can filter and sort
+// on. Names are the column names the frontend already uses.
+var tradeSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":               {Column: "id", Kind: query.Number},
+		"symbol":           {Column: "symbol", Kind: query.String},
+		"exchange":         {Column: "exchange", Kind: query.String},
+		"type":             {Column: "type", Kind: query.String},
+		"contract_type":    {Column: "contract_type", Kind: query.String},
+		"order_type":       {Column: "order_type", Kind: query.String},
+		"margin_mode":      {Column: "margin_mode", Kind: query.String},
+		"asset_mode":       {Column: "asset_mode", Kind: query.String},
+		"trade_date":       {Column: "trade_date", Kind: query.Time},
+		"exit_date":        {Column: "exit_date", Kind: query.Time},
+		"price":            {Column: "price", Kind: query.Number},
+		"entry_price":      {Column: "entry_price", Kind: query.Number},
+		"exit_price":       {Column: "exit_price", Kind: query.Number},
+		"quantity":         {Column: "quantity", Kind: query.Number},
+		"leverage":         {Column: "leverage", Kind: query.Number},
+		"fee":              {Column: "fee", Kind: query.Number},
+		"stop_loss":        {Column: "stop_loss", Kind: query.Number},
+		"take_profit":      {Column: "take_profit", Kind: query.Number},
+		"is_long":          {Column: "is_long", Kind: query.Bool},
+		"is_short":         {Column: "is_short", Kind: query.Bool},
+		"sentiment":        {Column: "sentiment", Kind: query.String},
+		"indicators":       {Column: "indicators", Kind: query.String},
+		"notes":            {Column: "notes", Kind: query.String},
+		"setup_id":         {Column: "setup_id", Kind: query.Number},
+		"position_id":      {Column: "position_id", Kind: query.Number},
+		"user_exchange_id": {Column: "user_exchange_id", Kind: query.Number},
+		"created_at":       {Column: "created_at", Kind: query.Time},
+		"updated_at":       {Column: "updated_at", Kind: query.Time},
+	},
+	DefaultSort:  query.Sort{Field: "id"},
+	DefaultLimit: 10,
 }
 
-func parseSortParams(r *http.Request) (field, direction string) {
-	sortStr := r.URL.Query().Get("sort")
-	var sortVals [2]string
-	if sortStr == "" || json.Unmarshal([]byte(sortStr), &sortVals) != nil {
-		return "id", "ASC"
-	}
-	return sortVals[0], sortVals[1]
-}
-
-func parseFilterParams(r *http.Request) map[string]string {
-	filterStr := r.URL.Query().Get("filter")
-	if filterStr == "" {
-		return nil
-	}
-	var filters map[string]string
-	if err := json.Unmarshal([]byte(filterStr), &filters); err != nil {
-		return nil
-	}
-	return filters
+// userTradesQuery selects the user's trades matching the filter of params.
+func userTradesQuery(userID uint, params query.Params) *gorm.DB {
+	return params.Where(db.DB.Model(&model.Trade{}).Where("user_id = ?", userID))
 }
 
 func ListTradesHandler(logger *logrus.Entry) http.HandlerFunc {
@@ -78,29 +94,15 @@ func ListTradesHandler(logger *logrus.Entry) http.HandlerFunc {
 			return
 		}
 
-		offset, limit := parseRangeParams(r)
-		sortField, sortDir := parseSortParams(r)
-		filters := parseFilterParams(r)
-
-		query := db.DB.Model(&model.Trade{}).Where("user_id = ?", user.ID)
-
-		// Apply filters
-		for k, v := range filters {
-			query = query.Where(fmt.Sprintf("%s = ?", k), v)
-		}
-
-		var trades []model.Trade
-		if err := query.Order(fmt.Sprintf("%s %s", sortField, sortDir)).
-			Offset(offset).Limit(limit).
-			Find(&trades).Error; err != nil {
-			logger.WithError(err).Error("Failed to fetch trades")
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		params, err := query.Parse(r, tradeSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		var total int64
-		if err := query.Count(&total).Error; err != nil {
-			logger.WithError(err).Error("Failed to count trades")
+		trades, total, err := getTradeRepository().List(user.ID, params)
+		if err != nil {
+			logger.WithError(err).Error("Failed to fetch trades")
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -110,6 +112,10 @@ func ListTradesHandler(logger *logrus.Entry) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
+		for i := range trades {
+			trades[i].PnL = pnl.Compute(trades[i])
+		}
+
 		json.NewEncoder(w).Encode(trades)
 	}
 }
@@ -179,7 +185,34 @@ func parseTradeDate(dateStr, timeStr string, loc *time.Location) (time.Time, err
 	return time.Date(d.Year(), d.Month(), d.Day(), tt.Hour(), tt.Minute(), 0, 0, loc), nil
 }
 
+// parseExitDate parses the optional RFC3339 exit timestamp of a trade.
+func parseExitDate(exitDate *string) (*time.Time, error) {
+	if exitDate == nil || strings.TrimSpace(*exitDate) == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(*exitDate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid exitDate: %w", err)
+	}
+	return &t, nil
+}
+
 func CreateTrade(user model.User, payload model.TradePayload, loc *time.Location) (*model.Trade, error) {
+	trade, err := buildTrade(user, payload, loc)
+	if err != nil {
+		return nil, errInvalidTrade{err}
+	}
+
+	if err := getTradeRepository().Create(trade, payload); err != nil {
+		return nil, err
+	}
+
+	trade.PnL = pnl.Compute(*trade)
+	return trade, nil
+}
+
+// buildTrade validates payload and turns it into an unsaved trade of user.
+func buildTrade(user model.User, payload model.TradePayload, loc *time.Location) (*model.Trade, error) {
 	if err := validateTradePayload(payload); err != nil {
 		return nil, err
 	}
@@ -189,6 +222,11 @@ func CreateTrade(user model.User, payload model.TradePayload, loc *time.Location
 		return nil, err
 	}
 
+	exitDate, err := parseExitDate(payload.ExitDate)
+	if err != nil {
+		return nil, err
+	}
+
 	if payload.IsLong {
 		payload.Type = "Buy/Long"
 	} else {
@@ -218,6 +256,7 @@ func CreateTrade(user model.User, payload model.TradePayload, loc *time.Location
 		Type:       payload.Type,
 		EntryPrice: payload.EntryPrice,
 		ExitPrice:  payload.ExitPrice,
+		ExitDate:   exitDate,
 		Fee:        payload.Fee,
 		Indicators: payload.Indicators,
 		Sentiment:  payload.Sentiment,
@@ -232,34 +271,62 @@ func CreateTrade(user model.User, payload model.TradePayload, loc *time.Location
 		trade.TakeProfit = nil
 	}
 
-	if err := db.DB.Create(&trade).Error; err != nil {
-		return nil, err
+	return &trade, nil
+}
+
+// parseTradeID reads the id URL parameter, answering 400 when it is missing
+// or not a number.
+func parseTradeID(w http.ResponseWriter, r *http.Request, logger *logrus.Entry) (uint, bool) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		logger.WithFields(map[string]interface{}{"id": idStr}).Error("Missing trade ID")
+		http.Error(w, "Missing trade ID", http.StatusBadRequest)
+		return 0, false
 	}
 
-	return &trade, nil
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// writeTradeError answers with 404 for trades the user does not own, 400
+// for invalid input and 500 otherwise.
+func writeTradeError(w http.ResponseWriter, logger *logrus.Entry, err error, action string) {
+	var invalid errInvalidTrade
+	switch {
+	case errors.Is(err, ErrTradeNotFound):
+		http.Error(w, "Trade not found", http.StatusNotFound)
+	case errors.As(err, &invalid):
+		logger.WithError(err).Warn("Invalid trade payload")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.WithError(err).Error("Failed to " + action + " trade")
+		http.Error(w, "Failed to "+action+" trade", http.StatusInternalServerError)
+	}
 }
 
 func GetTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-
-		idStr := chi.URLParam(r, "id")
-		if idStr == "" {
-			logger.WithFields(map[string]interface{}{"id": idStr}).Error("Missing trade ID")
-			http.Error(w, "Missing trade ID", http.StatusBadRequest)
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while fetching trade")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		logger.WithFields(map[string]interface{}{"id": idStr}).Info("Getting trade with id")
 
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		id, ok := parseTradeID(w, r, logger)
+		if !ok {
 			return
 		}
+		logger.WithFields(map[string]interface{}{"id": id}).Info("Getting trade with id")
 
-		var trade model.Trade
-		if err := db.DB.First(&trade, id).Error; err != nil {
-			logger.WithError(err).Error("Trade not found")
-			http.Error(w, "Trade not found", http.StatusNotFound)
+		trade, err := getTradeRepository().FindByID(user.ID, id)
+		if err != nil {
+			writeTradeError(w, logger, err, "load")
 			return
 		}
 
@@ -272,12 +339,17 @@ func GetTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 			EntryPrice: trade.EntryPrice,
 
 			ExitPrice:  trade.ExitPrice,
+			ExitDate:   trade.ExitDate,
 			Fee:        trade.Fee,
 			Indicators: trade.Indicators,
 			Sentiment:  trade.Sentiment,
 			StopLoss:   trade.StopLoss,
 			TakeProfit: trade.TakeProfit,
 			Exchange:   trade.Exchange,
+			PnL:        pnl.Compute(*trade),
+			Tags:       trade.Tags,
+			SetupID:    trade.SetupID,
+			Checklist:  trade.Checklist,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -313,8 +385,8 @@ func CreateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 		}
 
 		//user, ok := r.Context().Value(UserKey).(*model.User)
-		userPayload, ok := auth.GetUserFromContext(r.Context())
-		if !ok || userPayload == nil {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
 			logger.Warn("No user found in context")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -322,17 +394,9 @@ func CreateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 
 		loc := new(time.Location)
 
-		var user model.User
-		if err := db.DB.Where("username = ?", userPayload.Username).First(&user).Error; err != nil {
-			logger.WithError(err).Warn("User not found or DB error")
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-			return
-		}
-
-		trade, err := CreateTrade(user, payload, loc)
+		trade, err := CreateTrade(*user, payload, loc)
 		if err != nil {
-			logger.WithError(err).Warn("Invalid trade payload")
-			http.Error(w, "Bad Request", http.StatusBadRequest)
+			writeTradeError(w, logger, err, "create")
 			return
 		}
 
@@ -468,24 +532,23 @@ func ApplyTradePatch(t *model.Trade, p model.UpdateTradePayload, loc *time.Locat
 
 func UpdateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		if idStr == "" {
-			logger.WithFields(map[string]interface{}{"id": idStr}).Error("Missing trade ID")
-			http.Error(w, "Missing trade ID", http.StatusBadRequest)
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while updating trade")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		logger.WithFields(map[string]interface{}{"id": idStr}).Info("Getting trade with id")
 
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		id, ok := parseTradeID(w, r, logger)
+		if !ok {
 			return
 		}
+		logger.WithFields(map[string]interface{}{"id": id}).Info("Updating trade with id")
 
-		var trade model.Trade
-		if err := db.DB.First(&trade, id).Error; err != nil {
-			logger.WithError(err).Error("Trade not found")
-			http.Error(w, "Trade not found", http.StatusNotFound)
+		trades := getTradeRepository()
+		trade, err := trades.FindByID(user.ID, id)
+		if err != nil {
+			writeTradeError(w, logger, err, "load")
 			return
 		}
 
@@ -509,6 +572,12 @@ func UpdateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 		trade.Leverage = payload.Leverage
 		trade.EntryPrice = payload.EntryPrice
 		trade.ExitPrice = payload.ExitPrice
+		trade.ExitDate, err = parseExitDate(payload.ExitDate)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid exit date format")
+			http.Error(w, "Invalid exit date format", http.StatusBadRequest)
+			return
+		}
 		trade.Fee = payload.Fee
 		trade.Indicators = payload.Indicators
 		trade.Sentiment = payload.Sentiment
@@ -518,11 +587,11 @@ func UpdateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 
 		trade.UpdatedAt = time.Now()
 
-		if err := db.DB.Save(&trade).Error; err != nil {
-			logger.WithError(err).Error("Failed to update trade")
-			http.Error(w, "Failed to update trade", http.StatusInternalServerError)
+		if err := trades.Update(trade, payload); err != nil {
+			writeTradeError(w, logger, err, "update")
 			return
 		}
+		trade.PnL = pnl.Compute(*trade)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -534,39 +603,43 @@ func UpdateTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 
 func DeleteTradeHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		if idStr == "" {
-			logger.WithFields(map[string]interface{}{"id": idStr}).Error("Missing trade ID")
-			http.Error(w, "Missing trade ID", http.StatusBadRequest)
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting trade")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		logger.WithFields(map[string]interface{}{"id": idStr}).Info("Getting trade with id")
 
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "Invalid trade ID", http.StatusBadRequest)
+		id, ok := parseTradeID(w, r, logger)
+		if !ok {
 			return
 		}
+		logger.WithFields(map[string]interface{}{"id": id}).Info("Deleting trade with id")
 
-		var trade model.Trade
-		if err := db.DB.First(&trade, id).Error; err != nil {
-			logger.WithError(err).Error("Trade not found")
-			http.Error(w, "Trade not found", http.StatusNotFound)
+		if err := getTradeRepository().Delete(user.ID, id); err != nil {
+			writeTradeError(w, logger, err, "delete")
 			return
 		}
 
-		if err := db.DB.Delete(&trade).Error; err != nil {
-			logger.WithError(err).Error("Failed to delete trade")
-			http.Error(w, "Failed to delete trade", http.StatusInternalServerError)
-			return
+		if err := attachments.DeleteTradeAttachments(r.Context(), logger, []uint{id}); err != nil {
+			logger.WithError(err).Warn("Failed to delete attachments of deleted trade")
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// DeleteManyTradesHandler deletes the caller's trades among the given IDs
+// and returns the ones it deleted; IDs of other users' trades are ignored.
 func DeleteManyTradesHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while deleting trades")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		var payload struct {
 			IDs []uint `json:"id"`
 		}
@@ -582,17 +655,25 @@ func DeleteManyTradesHandler(logger *logrus.Entry) http.HandlerFunc {
 			return
 		}
 
-		if err := db.DB.Delete(&model.Trade{}, payload.IDs).Error; err != nil {
+		deleted, err := getTradeRepository().DeleteMany(user.ID, payload.IDs)
+		if err != nil {
 			logger.WithError(err).Error("Failed to delete trades")
 			http.Error(w, "Failed to delete trades", http.StatusInternalServerError)
 			return
 		}
 
-		logger.WithField("ids", payload.IDs).Info("Deleted trades")
+		if err := attachments.DeleteTradeAttachments(r.Context(), logger, deleted); err != nil {
+			logger.WithError(err).Warn("Failed to delete attachments of deleted trades")
+		}
+
+		logger.WithField("ids", deleted).Info("Deleted trades")
 
+		if deleted == nil {
+			deleted = []uint{}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"data": payload.IDs,
+			"data": deleted,
 		})
 	}
 }
@@ -0,0 +1,260 @@
+package trades
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+type inMemoryTradeRepository struct {
+	trades     map[uint]*model.Trade
+	nextID     uint
+	lastParams query.Params
+}
+
+func (r *inMemoryTradeRepository) List(userID uint, params query.Params) ([]model.Trade, int64, error) {
+	r.lastParams = params
+	var result []model.Trade
+	for id := uint(1); id <= r.nextID; id++ {
+		if t, ok := r.trades[id]; ok && t.UserID == userID {
+			result = append(result, *t)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (r *inMemoryTradeRepository) Each(userID uint, params query.Params, fn func(model.Trade) error) error {
+	r.lastParams = params
+	for id := uint(1); id <= r.nextID; id++ {
+		if t, ok := r.trades[id]; ok && t.UserID == userID {
+			if err := fn(*t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryTradeRepository) FindByID(userID, id uint) (*model.Trade, error) {
+	t, ok := r.trades[id]
+	if !ok || t.UserID != userID {
+		return nil, ErrTradeNotFound
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (r *inMemoryTradeRepository) Create(t *model.Trade, _ model.TradePayload) error {
+	r.nextID++
+	t.ID = r.nextID
+	copied := *t
+	r.trades[t.ID] = &copied
+	return nil
+}
+
+func (r *inMemoryTradeRepository) Update(t *model.Trade, _ model.TradePayload) error {
+	existing, ok := r.trades[t.ID]
+	if !ok || existing.UserID != t.UserID {
+		return ErrTradeNotFound
+	}
+	copied := *t
+	r.trades[t.ID] = &copied
+	return nil
+}
+
+func (r *inMemoryTradeRepository) Delete(userID, id uint) error {
+	t, ok := r.trades[id]
+	if !ok || t.UserID != userID {
+		return ErrTradeNotFound
+	}
+	delete(r.trades, id)
+	return nil
+}
+
+func (r *inMemoryTradeRepository) DeleteMany(userID uint, ids []uint) ([]uint, error) {
+	var deleted []uint
+	for _, id := range ids {
+		if t, ok := r.trades[id]; ok && t.UserID == userID {
+			delete(r.trades, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (r *inMemoryTradeRepository) ExistingKeys(userID uint, from, to time.Time) (map[string]bool, error) {
+	keys := map[string]bool{}
+	for _, t := range r.trades {
+		if t.UserID == userID && !t.TradeDate.Before(from) && !t.TradeDate.After(to) {
+			keys[tradeDedupKey(*t)] = true
+		}
+	}
+	return keys, nil
+}
+
+func (r *inMemoryTradeRepository) CreateMany(userID uint, trades []model.Trade) error {
+	for i := range trades {
+		trades[i].UserID = userID
+		if err := r.Create(&trades[i], model.TradePayload{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestRouter(t *testing.T) (*chi.Mux, *inMemoryTradeRepository) {
+	repo := &inMemoryTradeRepository{trades: map[uint]*model.Trade{
+		1: {ID: 1, UserID: 1, Symbol: "BTCUSDT", Type: "spot", IsLong: true},
+		2: {ID: 2, UserID: 1, Symbol: "ETHUSDT", Type: "spot", IsLong: true},
+		3: {ID: 3, UserID: 2, Symbol: "SOLUSDT", Type: "spot", IsLong: true},
+	}, nextID: 3}
+	SetTradeRepository(repo)
+	t.Cleanup(func() { SetTradeRepository(nil) })
+
+	logger := logrus.NewEntry(logrus.New())
+	router := chi.NewRouter()
+	router.Get("/trades", ListTradesHandler(logger))
+	router.Get("/trades/export", ExportTradesHandler(logger))
+	router.Get("/trades/{id}", GetTradeHandler(logger))
+	router.Post("/trades", CreateTradeHandler(logger))
+	router.Put("/trades/{id}", UpdateTradeHandler(logger))
+	router.Delete("/trades", DeleteManyTradesHandler(logger))
+	router.Delete("/trades/{id}", DeleteTradeHandler(logger))
+	router.Post("/trades/import", ImportTradesHandler(logger))
+	return router, repo
+}
+
+func do(t *testing.T, router http.Handler, userID uint, method, target string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: userID}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTradeOwnership(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	if rec := do(t, router, 1, http.MethodGet, "/trades/1", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an own trade, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	update := model.TradePayload{Symbol: "XRPUSDT", TradeDate: "2025-07-11", Type: "spot"}
+	for _, c := range []struct {
+		method string
+		body   any
+	}{
+		{http.MethodGet, nil},
+		{http.MethodPut, update},
+		{http.MethodDelete, nil},
+	} {
+		if rec := do(t, router, 2, c.method, "/trades/1", c.body); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s of another user's trade, got %d", c.method, rec.Code)
+		}
+	}
+	if repo.trades[1] == nil || repo.trades[1].Symbol != "BTCUSDT" {
+		t.Fatalf("another user's request changed the trade: %+v", repo.trades[1])
+	}
+
+	if rec := do(t, router, 1, http.MethodPut, "/trades/1", update); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 when updating an own trade, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if repo.trades[1].Symbol != "XRPUSDT" {
+		t.Fatalf("expected the update to be stored, got %+v", repo.trades[1])
+	}
+
+	if rec := do(t, router, 1, http.MethodDelete, "/trades/1", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := do(t, router, 1, http.MethodGet, "/trades/abc", nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed id, got %d", rec.Code)
+	}
+}
+
+func TestDeleteManyTradesSkipsForeignIDs(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	rec := do(t, router, 1, http.MethodDelete, "/trades", map[string][]uint{"id": {2, 3, 99}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Data []uint `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0] != 2 {
+		t.Fatalf("expected only trade 2 to be reported deleted, got %v", resp.Data)
+	}
+	if repo.trades[3] == nil {
+		t.Fatal("another user's trade was deleted")
+	}
+}
+
+func TestCreateTradeUsesContextUser(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	contractType := "spot"
+	rec := do(t, router, 2, http.MethodPost, "/trades", model.TradePayload{
+		Symbol:       "BTCUSDT",
+		ContractType: &contractType,
+		TradeDate:    "2025-07-11T21:16:05Z",
+		Type:         "spot",
+		IsLong:       true,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if created := repo.trades[4]; created == nil || created.UserID != 2 {
+		t.Fatalf("expected the trade to belong to the caller, got %+v", created)
+	}
+
+	rec = do(t, router, 2, http.MethodPost, "/trades", model.TradePayload{TradeDate: "2025-07-11T21:16:05Z", IsLong: true, IsShort: true})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid trade, got %d", rec.Code)
+	}
+}
+
+func TestListTradesValidatesParams(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	rec := do(t, router, 1, http.MethodGet, `/trades?filter={"symbol_in":["BTCUSDT","ETHUSDT"]}&sort=["trade_date","DESC"]&range=[0,24]`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Total-Count") != "2" {
+		t.Fatalf("expected the caller's 2 trades, got %s", rec.Header().Get("X-Total-Count"))
+	}
+	p := repo.lastParams
+	if len(p.Conditions) != 1 || p.Conditions[0].Op != query.In || p.Sort.Field != "trade_date" || !p.Sort.Desc || p.Limit != 25 {
+		t.Fatalf("unexpected params: %+v", p)
+	}
+
+	for _, target := range []string{
+		`/trades?sort=["id%20DESC,(SELECT%201)","ASC"]`,
+		`/trades?filter={"user_id":2}`,
+	} {
+		if rec := do(t, router, 1, http.MethodGet, target, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+}
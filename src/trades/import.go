@@ -0,0 +1,532 @@
+package trades
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxImportBytes = 10 << 20
+
+// importField is a TradePayload value a CSV column can be mapped onto.
+type importField int
+
+const (
+	fieldDateTime importField = iota // one timestamp column, exchange exports
+	fieldDate                        // generic: tradeDate
+	fieldTime                        // generic: tradeTime
+	fieldSymbol
+	fieldSide
+	fieldPrice // execution price, exchange exports
+	fieldEntryPrice
+	fieldExitPrice
+	fieldExitDate
+	fieldQuantity
+	fieldFee
+	fieldOrderType
+	fieldExchange
+	fieldContractType
+	fieldStopLoss
+	fieldTakeProfit
+	fieldLeverage
+	fieldMarginMode
+	fieldNotes
+)
+
+// importFormat maps the columns of one CSV layout onto TradePayload. Header
+// names are matched ignoring case, spaces and punctuation; the first alias
+// present wins. Exchange exports list single executions, so they record the
+// fill price as the entry price and leave the exit open.
+type importFormat struct {
+	name         string
+	exchange     string // recorded on every row unless a column provides it
+	contractType string
+	columns      map[importField][]string
+	required     []importField
+}
+
+var importFormats = map[string]importFormat{
+	"binance": {
+		name:         "binance",
+		exchange:     "Binance",
+		contractType: "spot",
+		columns: map[importField][]string{
+			fieldDateTime: {"Date(UTC)", "Date", "Time"},
+			fieldSymbol:   {"Pair", "Symbol"},
+			fieldSide:     {"Side"},
+			fieldPrice:    {"Price"},
+			fieldQuantity: {"Executed", "Quantity", "Qty"},
+			fieldFee:      {"Fee"},
+		},
+		required: []importField{fieldDateTime, fieldSymbol, fieldSide, fieldPrice, fieldQuantity},
+	},
+	"kucoin": {
+		name:         "kucoin",
+		exchange:     "Kucoin",
+		contractType: "spot",
+		columns: map[importField][]string{
+			fieldDateTime:  {"Filled Time(UTC)", "tradeCreatedAt", "Time"},
+			fieldSymbol:    {"Symbol"},
+			fieldSide:      {"Side"},
+			fieldPrice:     {"Avg. Filled Price", "Filled Price", "Price"},
+			fieldQuantity:  {"Filled Amount", "Size", "Amount"},
+			fieldFee:       {"Fee"},
+			fieldOrderType: {"Order Type", "Type"},
+		},
+		required: []importField{fieldDateTime, fieldSymbol, fieldSide, fieldPrice, fieldQuantity},
+	},
+	"mexc": {
+		name:         "mexc",
+		exchange:     "Mexc",
+		contractType: "spot",
+		columns: map[importField][]string{
+			fieldDateTime: {"Time", "Date(UTC)"},
+			fieldSymbol:   {"Pairs", "Pair", "Symbol"},
+			fieldSide:     {"Side", "Direction"},
+			fieldPrice:    {"Filled Price", "Price"},
+			fieldQuantity: {"Executed Amount", "Quantity"},
+			fieldFee:      {"Fee"},
+		},
+		required: []importField{fieldDateTime, fieldSymbol, fieldSide, fieldPrice, fieldQuantity},
+	},
+	"bybit": {
+		name:         "bybit",
+		exchange:     "Bybit",
+		contractType: "spot",
+		columns: map[importField][]string{
+			fieldDateTime:  {"Timestamp (UTC)", "Filled Time", "Order Time"},
+			fieldSymbol:    {"Spot Pairs", "Symbol", "Contracts"},
+			fieldSide:      {"Direction", "Side"},
+			fieldPrice:     {"Filled Price", "Price"},
+			fieldQuantity:  {"Filled Quantity", "Qty"},
+			fieldFee:       {"Fees", "Trading Fee"},
+			fieldOrderType: {"Order Type"},
+		},
+		required: []importField{fieldDateTime, fieldSymbol, fieldSide, fieldPrice, fieldQuantity},
+	},
+	// generic uses the TradePayload JSON names and can carry round trips.
+	"generic": {
+		name: "generic",
+		columns: map[importField][]string{
+			fieldDate:         {"tradeDate"},
+			fieldTime:         {"tradeTime"},
+			fieldSymbol:       {"symbol"},
+			fieldSide:         {"side"},
+			fieldEntryPrice:   {"entryPrice"},
+			fieldExitPrice:    {"exitPrice"},
+			fieldExitDate:     {"exitDate"},
+			fieldQuantity:     {"size"},
+			fieldFee:          {"fee"},
+			fieldOrderType:    {"orderType"},
+			fieldExchange:     {"exchange"},
+			fieldContractType: {"contractType"},
+			fieldStopLoss:     {"stopLoss"},
+			fieldTakeProfit:   {"takeProfit"},
+			fieldLeverage:     {"leverage"},
+			fieldMarginMode:   {"marginMode"},
+			fieldNotes:        {"notes"},
+		},
+		required: []importField{fieldDate, fieldSymbol, fieldSide, fieldEntryPrice, fieldQuantity},
+	},
+}
+
+// importTimeLayouts are the timestamp layouts seen in exchange exports, all
+// in UTC.
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"02/01/2006 15:04:05",
+}
+
+// importRow is one parsed CSV record; err is set when it could not be mapped.
+type importRow struct {
+	line    int
+	payload *model.TradePayload
+	err     error
+}
+
+var headerNormalizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalizeHeader(h string) string {
+	return headerNormalizer.ReplaceAllString(strings.ToLower(strings.TrimPrefix(h, "\ufeff")), "")
+}
+
+// parseImport reads every record of a CSV in format f. The error is only for
+// a file that cannot be read at all; per-row problems are kept on the row.
+func parseImport(f importFormat, r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		if _, ok := positions[normalizeHeader(h)]; !ok {
+			positions[normalizeHeader(h)] = i
+		}
+	}
+
+	columns := make(map[importField]int)
+	for field, aliases := range f.columns {
+		for _, alias := range aliases {
+			if i, ok := positions[normalizeHeader(alias)]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+	for _, field := range f.required {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("missing column %q for %s format", f.columns[field][0], f.name)
+		}
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, importRow{line: line, err: err})
+				continue
+			}
+			return nil, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		value := func(field importField) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		payload, err := f.payload(value)
+		rows = append(rows, importRow{line: line, payload: payload, err: err})
+	}
+
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// payload maps one record onto a TradePayload.
+func (f importFormat) payload(value func(importField) string) (*model.TradePayload, error) {
+	p := &model.TradePayload{
+		Symbol:     strings.ToUpper(value(fieldSymbol)),
+		OrderType:  strings.ToLower(value(fieldOrderType)),
+		MarginMode: value(fieldMarginMode),
+	}
+
+	switch strings.ToLower(value(fieldSide)) {
+	case "buy", "long":
+		p.IsLong = true
+	case "sell", "short":
+		p.IsShort = true
+	default:
+		return nil, fmt.Errorf("unknown side %q", value(fieldSide))
+	}
+
+	if exchange := value(fieldExchange); exchange != "" {
+		p.Exchange = &exchange
+	} else if f.exchange != "" {
+		exchange := f.exchange
+		p.Exchange = &exchange
+	}
+
+	contractType := value(fieldContractType)
+	if contractType == "" {
+		contractType = f.contractType
+	}
+	if contractType == "" {
+		contractType = "spot"
+	}
+	p.ContractType = &contractType
+
+	if raw := value(fieldDateTime); raw != "" {
+		t, err := parseImportTime(raw)
+		if err != nil {
+			return nil, err
+		}
+		p.TradeDate = t.Format(time.RFC3339)
+		p.TradeTime = t.Format("15:04")
+	} else {
+		p.TradeDate = value(fieldDate)
+		p.TradeTime = value(fieldTime)
+	}
+
+	if raw := value(fieldExitDate); raw != "" {
+		p.ExitDate = &raw
+	}
+	if raw := value(fieldNotes); raw != "" {
+		p.Notes = &raw
+	}
+
+	var err error
+	if p.Quantity, err = parseImportAmount(value(fieldQuantity), "quantity"); err != nil {
+		return nil, err
+	}
+	if raw := value(fieldPrice); raw != "" {
+		if p.Price, err = parseImportAmount(raw, "price"); err != nil {
+			return nil, err
+		}
+		p.EntryPrice = p.Price
+	}
+	if raw := value(fieldEntryPrice); raw != "" {
+		if p.EntryPrice, err = parseImportAmount(raw, "entryPrice"); err != nil {
+			return nil, err
+		}
+		if p.Price == 0 {
+			p.Price = p.EntryPrice
+		}
+	}
+	if raw := value(fieldExitPrice); raw != "" {
+		if p.ExitPrice, err = parseImportAmount(raw, "exitPrice"); err != nil {
+			return nil, err
+		}
+	}
+	if p.Quantity <= 0 || p.EntryPrice <= 0 {
+		return nil, errors.New("price and quantity must be > 0")
+	}
+
+	optional := []struct {
+		field importField
+		name  string
+		dst   **float64
+	}{
+		{fieldFee, "fee", &p.Fee},
+		{fieldStopLoss, "stopLoss", &p.StopLoss},
+		{fieldTakeProfit, "takeProfit", &p.TakeProfit},
+		{fieldLeverage, "leverage", &p.Leverage},
+	}
+	for _, o := range optional {
+		raw := value(o.field)
+		if raw == "" {
+			continue
+		}
+		v, err := parseImportAmount(raw, o.name)
+		if err != nil {
+			return nil, err
+		}
+		*o.dst = &v
+	}
+	if p.TakeProfit != nil {
+		p.TakeProfitEnabled = true
+	}
+
+	return p, nil
+}
+
+func parseImportTime(raw string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(raw, 10, 64); err == nil && ms > 1e11 {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", raw)
+}
+
+var leadingNumber = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// parseImportAmount reads a number that exports may write with thousands
+// separators or an asset suffix, e.g. "1,234.5" or "0.01BTC".
+func parseImportAmount(raw, name string) (float64, error) {
+	number := leadingNumber.FindString(strings.ReplaceAll(strings.TrimSpace(raw), ",", ""))
+	if number == "" {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return strconv.ParseFloat(number, 64)
+}
+
+// tradeDedupKey identifies a trade for duplicate detection: the same symbol,
+// side, time, prices and size count as the same trade.
+func tradeDedupKey(t model.Trade) string {
+	return fmt.Sprintf("%s|%d|%t|%g|%g|%g",
+		strings.ToUpper(t.Symbol), t.TradeDate.Unix(), t.IsLong, t.EntryPrice, t.ExitPrice, t.Quantity)
+}
+
+// classifyImportRows validates the parsed rows as trades of user and marks
+// rows that repeat an existing trade or an earlier row. It returns the report
+// and the trades that would be created.
+func classifyImportRows(user model.User, rows []importRow, existing map[string]bool) (model.TradeImportResponse, []model.Trade) {
+	resp := model.TradeImportResponse{Total: len(rows), Rows: make([]model.TradeImportRowResult, 0, len(rows))}
+	seen := make(map[string]bool, len(existing))
+	for key := range existing {
+		seen[key] = true
+	}
+
+	var trades []model.Trade
+	for _, row := range rows {
+		result := model.TradeImportRowResult{Row: row.line, Trade: row.payload}
+
+		err := row.err
+		var trade *model.Trade
+		if err == nil {
+			trade, err = buildTrade(user, *row.payload, time.UTC)
+		}
+
+		switch {
+		case err != nil:
+			result.Status = model.ImportRowInvalid
+			result.Error = err.Error()
+			resp.Invalid++
+		case seen[tradeDedupKey(*trade)]:
+			result.Status = model.ImportRowDuplicate
+			resp.Duplicates++
+		default:
+			seen[tradeDedupKey(*trade)] = true
+			result.Status = model.ImportRowValid
+			resp.Valid++
+			trades = append(trades, *trade)
+		}
+
+		resp.Rows = append(resp.Rows, result)
+	}
+
+	return resp, trades
+}
+
+// ImportTradesHandler serves POST /trades/import?format=binance|kucoin|mexc|bybit|generic.
+// The CSV is the "file" field of a multipart form or the raw request body.
+// With dryRun=true it only reports what would happen. Otherwise every valid,
+// non-duplicate row is created in one transaction; if any row is invalid,
+// nothing is written and the report comes back with 422.
+func ImportTradesHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while importing trades")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		formatName := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+		if formatName == "" {
+			formatName = "generic"
+		}
+		format, ok := importFormats[formatName]
+		if !ok {
+			http.Error(w, "format must be one of binance, kucoin, mexc, bybit, generic", http.StatusBadRequest)
+			return
+		}
+		dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		var body io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, "file is required", http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			body = file
+		}
+
+		rows, err := parseImport(format, body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, "import file too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var from, to time.Time
+		for _, row := range rows {
+			if row.err != nil {
+				continue
+			}
+			if t, err := parseTradeDate(row.payload.TradeDate, row.payload.TradeTime, time.UTC); err == nil {
+				if from.IsZero() || t.Before(from) {
+					from = t
+				}
+				if t.After(to) {
+					to = t
+				}
+			}
+		}
+
+		existing := map[string]bool{}
+		if !from.IsZero() {
+			existing, err = getTradeRepository().ExistingKeys(user.ID, from, to)
+			if err != nil {
+				logger.WithError(err).Error("failed to load trades for duplicate detection")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		resp, trades := classifyImportRows(*user, rows, existing)
+		resp.Format = format.name
+		resp.DryRun = dryRun
+
+		status := http.StatusOK
+		switch {
+		case dryRun:
+		case resp.Invalid > 0:
+			status = http.StatusUnprocessableEntity
+		case len(trades) > 0:
+			if err := getTradeRepository().CreateMany(user.ID, trades); err != nil {
+				logger.WithError(err).Error("failed to import trades")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			resp.Committed = true
+			resp.Imported = len(trades)
+			logger.WithFields(logrus.Fields{
+				"user_id":  user.ID,
+				"format":   format.name,
+				"imported": resp.Imported,
+			}).Info("trades imported")
+		default:
+			resp.Committed = true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.WithError(err).Error("failed to encode trade import response")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package trades
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+)
+
+func TestParseImportBinance(t *testing.T) {
+	csv := "\ufeffDate(UTC),Pair,Side,Price,Executed,Amount,Fee\n" +
+		"2025-07-11 21:16:05,BTCUSDT,BUY,\"64,000.5\",0.01BTC,640.005USDT,0.00001BTC\n" +
+		"2025-07-11 22:00:00,BTCUSDT,HOLD,64000,0.01BTC,640USDT,0\n"
+
+	rows, err := parseImport(importFormats["binance"], strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+
+	p := rows[0].payload
+	if rows[0].err != nil || p == nil {
+		t.Fatalf("expected first row to parse, got %v", rows[0].err)
+	}
+	if p.Symbol != "BTCUSDT" || !p.IsLong || p.EntryPrice != 64000.5 || p.Quantity != 0.01 {
+		t.Fatalf("unexpected payload: %+v", p)
+	}
+	if p.TradeDate != "2025-07-11T21:16:05Z" || *p.Exchange != "Binance" || *p.ContractType != "spot" {
+		t.Fatalf("unexpected payload metadata: %+v", p)
+	}
+	if rows[1].err == nil || rows[1].line != 3 {
+		t.Fatalf("expected an error on line 3, got %+v", rows[1])
+	}
+}
+
+func TestParseImportMissingColumn(t *testing.T) {
+	if _, err := parseImport(importFormats["mexc"], strings.NewReader("Pairs,Side\nBTC_USDT,BUY\n")); err == nil {
+		t.Fatalf("expected missing column error")
+	}
+}
+
+func TestClassifyImportRows(t *testing.T) {
+	csv := "symbol,side,tradeDate,tradeTime,entryPrice,exitPrice,size,fee,stopLoss,contractType,leverage\n" +
+		"ETHUSDT,short,2025-07-10,09:30,3200,3100,2,4,3300,futures,5\n" +
+		"ETHUSDT,short,2025-07-10,09:30,3200,3100,2,4,3300,futures,5\n" +
+		"ETHUSDT,long,2025-07-11,10:00,3100,3150,1,,,futures,\n" +
+		"ETHUSDT,long,not-a-date,10:00,3100,3150,1,,,futures,\n"
+
+	rows, err := parseImport(importFormats["generic"], strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	existing := map[string]bool{}
+	resp, trades := classifyImportRows(model.User{ID: 7}, rows, existing)
+	if resp.Total != 4 || resp.Valid != 2 || resp.Duplicates != 1 || resp.Invalid != 1 {
+		t.Fatalf("unexpected classification: %+v", resp)
+	}
+	if resp.Rows[1].Status != model.ImportRowDuplicate || resp.Rows[3].Status != model.ImportRowInvalid {
+		t.Fatalf("unexpected row statuses: %+v", resp.Rows)
+	}
+	if len(trades) != 2 || trades[0].UserID != 7 || !trades[0].IsShort || *trades[0].Leverage != 5 {
+		t.Fatalf("unexpected trades: %+v", trades)
+	}
+
+	existing[tradeDedupKey(trades[1])] = true
+	resp, trades = classifyImportRows(model.User{ID: 7}, rows[2:3], existing)
+	if resp.Duplicates != 1 || len(trades) != 0 {
+		t.Fatalf("expected row matching a stored trade to be a duplicate, got %+v", resp)
+	}
+}
+
+func TestImportTradesHandlerSkipsStoredTrades(t *testing.T) {
+	router, repo := newTestRouter(t)
+	repo.trades[4] = &model.Trade{ID: 4, UserID: 1, Symbol: "ETHUSDT", IsLong: true,
+		TradeDate: time.Date(2025, 7, 11, 10, 0, 0, 0, time.UTC), EntryPrice: 3100, ExitPrice: 3150, Quantity: 1}
+	repo.nextID = 4
+
+	csv := "symbol,side,tradeDate,tradeTime,entryPrice,exitPrice,size\n" +
+		"ETHUSDT,long,2025-07-11,10:00,3100,3150,1\n" +
+		"ETHUSDT,short,2025-07-12,09:30,3200,3100,2\n"
+	req := httptest.NewRequest(http.MethodPost, "/trades/import?format=generic", strings.NewReader(csv))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp model.TradeImportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Committed || resp.Imported != 1 || resp.Duplicates != 1 {
+		t.Fatalf("unexpected import report: %+v", resp)
+	}
+	if created, ok := repo.trades[5]; !ok || created.UserID != 1 || !created.IsShort {
+		t.Fatalf("expected the new trade to be stored for user 1, got %+v", created)
+	}
+}
@@ -0,0 +1,155 @@
+package trades
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+)
+
+// errInvalidTrade marks errors caused by the request rather than the
+// store, so handlers can answer 400.
+type errInvalidTrade struct{ err error }
+
+func (e errInvalidTrade) Error() string { return e.err.Error() }
+func (e errInvalidTrade) Unwrap() error { return e.err }
+
+// tagNames trims names and drops blanks and repeats, keeping their order.
+func tagNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// resolveTags returns the user's tags with the given names, creating the
+// ones that do not exist yet.
+func resolveTags(tx *gorm.DB, userID uint, names []string) ([]model.Tag, error) {
+	names = tagNames(names)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var existing []model.Tag
+	if err := tx.Where("user_id = ? AND name IN ?", userID, names).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]model.Tag, len(existing))
+	for _, t := range existing {
+		byName[t.Name] = t
+	}
+
+	tags := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		tag, ok := byName[name]
+		if !ok {
+			if len(name) > 64 {
+				return nil, errInvalidTrade{fmt.Errorf("tag %q is longer than 64 characters", name)}
+			}
+			tag = model.Tag{UserID: userID, Name: name}
+			if err := tx.Create(&tag).Error; err != nil {
+				return nil, err
+			}
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// applyTradeLinks stores the tags, setup and checklist answers of payload
+// on the saved trade t. Fields left nil in payload are not touched. Moving
+// a trade to another setup drops the answers given for the previous one.
+func applyTradeLinks(tx *gorm.DB, t *model.Trade, payload model.TradePayload) error {
+	if payload.Tags != nil {
+		tags, err := resolveTags(tx, t.UserID, payload.Tags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(t).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		t.Tags = tags
+	}
+
+	setupChanged := false
+	if payload.SetupID != nil {
+		var setupID *uint
+		if *payload.SetupID != 0 {
+			var setup model.Setup
+			if err := tx.Where("user_id = ?", t.UserID).First(&setup, *payload.SetupID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errInvalidTrade{fmt.Errorf("setup %d not found", *payload.SetupID)}
+				}
+				return err
+			}
+			setupID = &setup.ID
+		}
+
+		setupChanged = !sameID(t.SetupID, setupID)
+		if err := tx.Model(t).Update("setup_id", setupID).Error; err != nil {
+			return err
+		}
+		t.SetupID = setupID
+	}
+
+	if payload.Checklist == nil && !setupChanged {
+		return nil
+	}
+
+	if err := tx.Where("trade_id = ?", t.ID).Delete(&model.TradeChecklistCheck{}).Error; err != nil {
+		return err
+	}
+	t.Checklist = nil
+	if len(payload.Checklist) == 0 {
+		return nil
+	}
+	if t.SetupID == nil {
+		return errInvalidTrade{errors.New("checklist answers need a setup")}
+	}
+
+	var items []model.SetupChecklistItem
+	if err := tx.Where("setup_id = ?", *t.SetupID).Find(&items).Error; err != nil {
+		return err
+	}
+	valid := make(map[uint]bool, len(items))
+	for _, item := range items {
+		valid[item.ID] = true
+	}
+
+	answered := make(map[uint]bool, len(payload.Checklist))
+	checks := make([]model.TradeChecklistCheck, 0, len(payload.Checklist))
+	for _, answer := range payload.Checklist {
+		if !valid[answer.ItemID] {
+			return errInvalidTrade{fmt.Errorf("checklist item %d is not part of the trade's setup", answer.ItemID)}
+		}
+		if answered[answer.ItemID] {
+			return errInvalidTrade{fmt.Errorf("checklist item %d is answered twice", answer.ItemID)}
+		}
+		answered[answer.ItemID] = true
+		checks = append(checks, model.TradeChecklistCheck{TradeID: t.ID, ChecklistItemID: answer.ItemID, Checked: answer.Checked})
+	}
+
+	if err := tx.Create(&checks).Error; err != nil {
+		return err
+	}
+	t.Checklist = checks
+	return nil
+}
+
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
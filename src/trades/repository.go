@@ -0,0 +1,248 @@
+package trades
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTradeNotFound = errors.New("trade not found")
+
+// TradeRepository reads and writes trades on behalf of one user. Every
+// lookup is scoped by user, so the ID of someone else's trade behaves as if
+// it did not exist.
+type TradeRepository interface {
+	// List returns one page of the user's trades matching params, with
+	// their tags, and the number of matches across all pages.
+	List(userID uint, params query.Params) ([]model.Trade, int64, error)
+	// Each calls fn with every trade of the user matching params, in the
+	// order of params, reading one row at a time. It stops at the first
+	// error fn returns.
+	Each(userID uint, params query.Params, fn func(model.Trade) error) error
+	// FindByID returns the trade with its tags and checklist answers.
+	FindByID(userID, id uint) (*model.Trade, error)
+	// Create inserts t and stores the tags, setup and checklist of payload.
+	Create(t *model.Trade, payload model.TradePayload) error
+	// Update saves t, which must already belong to t.UserID, and stores the
+	// tags, setup and checklist of payload.
+	Update(t *model.Trade, payload model.TradePayload) error
+	Delete(userID, id uint) error
+	// DeleteMany deletes the user's trades among ids and returns the IDs
+	// that were deleted.
+	DeleteMany(userID uint, ids []uint) ([]uint, error)
+	// ExistingKeys returns the dedup keys (see tradeDedupKey) of the user's
+	// trades opened between from and to, both included.
+	ExistingKeys(userID uint, from, to time.Time) (map[string]bool, error)
+	// CreateMany inserts trades for the user in one transaction. Either all
+	// of them are created or none.
+	CreateMany(userID uint, trades []model.Trade) error
+}
+
+var (
+	tradeRepo   TradeRepository = &gormTradeRepository{}
+	tradeRepoMu sync.RWMutex
+)
+
+func SetTradeRepository(repo TradeRepository) {
+	tradeRepoMu.Lock()
+	defer tradeRepoMu.Unlock()
+
+	if repo == nil {
+		tradeRepo = &gormTradeRepository{}
+		return
+	}
+
+	tradeRepo = repo
+}
+
+func getTradeRepository() TradeRepository {
+	tradeRepoMu.RLock()
+	repo := tradeRepo
+	tradeRepoMu.RUnlock()
+
+	if repo != nil {
+		return repo
+	}
+
+	tradeRepoMu.Lock()
+	defer tradeRepoMu.Unlock()
+
+	if tradeRepo == nil {
+		tradeRepo = &gormTradeRepository{}
+	}
+
+	return tradeRepo
+}
+
+type gormTradeRepository struct{}
+
+func (r *gormTradeRepository) List(userID uint, params query.Params) ([]model.Trade, int64, error) {
+	if db.DB == nil {
+		return nil, 0, errors.New("database connection is not initialized")
+	}
+
+	matching := userTradesQuery(userID, params).Session(&gorm.Session{})
+
+	var total int64
+	if err := matching.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var trades []model.Trade
+	if err := params.Page(params.Order(matching.Preload("Tags"))).Find(&trades).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return trades, total, nil
+}
+
+func (r *gormTradeRepository) Each(userID uint, params query.Params, fn func(model.Trade) error) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	rows, err := params.Order(userTradesQuery(userID, params)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trade model.Trade
+		if err := db.DB.ScanRows(rows, &trade); err != nil {
+			return err
+		}
+		if err := fn(trade); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *gormTradeRepository) FindByID(userID, id uint) (*model.Trade, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var trade model.Trade
+	if err := db.DB.Preload("Tags").Preload("Checklist").Where("user_id = ?", userID).First(&trade, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTradeNotFound
+		}
+
+		return nil, err
+	}
+
+	return &trade, nil
+}
+
+func (r *gormTradeRepository) Create(t *model.Trade, payload model.TradePayload) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		return applyTradeLinks(tx, t, payload)
+	})
+}
+
+func (r *gormTradeRepository) Update(t *model.Trade, payload model.TradePayload) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		// Save would insert the row when the update matches nothing, so
+		// update every column explicitly instead.
+		res := tx.Model(t).Omit(clause.Associations).Where("user_id = ?", t.UserID).Select("*").Updates(t)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTradeNotFound
+		}
+		return applyTradeLinks(tx, t, payload)
+	})
+}
+
+func (r *gormTradeRepository) Delete(userID, id uint) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	res := db.DB.Where("user_id = ?", userID).Delete(&model.Trade{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTradeNotFound
+	}
+
+	return nil
+}
+
+func (r *gormTradeRepository) DeleteMany(userID uint, ids []uint) ([]uint, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var owned []uint
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Trade{}).Where("user_id = ? AND id IN ?", userID, ids).Pluck("id", &owned).Error; err != nil {
+			return err
+		}
+		if len(owned) == 0 {
+			return nil
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.Trade{}, owned).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+func (r *gormTradeRepository) ExistingKeys(userID uint, from, to time.Time) (map[string]bool, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var existing []model.Trade
+	if err := db.DB.Where("user_id = ? AND trade_date BETWEEN ? AND ?", userID, from, to).
+		Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		keys[tradeDedupKey(t)] = true
+	}
+	return keys, nil
+}
+
+func (r *gormTradeRepository) CreateMany(userID uint, trades []model.Trade) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+	if len(trades) == 0 {
+		return nil
+	}
+
+	for i := range trades {
+		trades[i].UserID = userID
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(trades, 500).Error
+	})
+}
@@ -0,0 +1,105 @@
+package tradesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// GET /user-exchanges/{exchangeID}/sync
+func GetSyncStatusHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ue, ok := loadUserExchange(w, r, logger)
+		if !ok {
+			return
+		}
+
+		state, err := getSyncStore().GetSyncState(ue.ID)
+		if err != nil && !errors.Is(err, ErrSyncStateNotFound) {
+			logger.WithError(err).Error("failed to load sync state")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.NewExchangeSyncStatusResponse(ue.ExchangeID, state)); err != nil {
+			logger.WithError(err).Error("failed to encode sync status response")
+		}
+	}
+}
+
+// POST /user-exchanges/{exchangeID}/sync starts a sync in the background and
+// answers 202 straight away; poll the GET endpoint for the outcome.
+func TriggerSyncHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ue, ok := loadUserExchange(w, r, logger)
+		if !ok {
+			return
+		}
+
+		if ue.NeedsReentry || ue.DataKey == "" {
+			http.Error(w, "exchange credentials must be entered before syncing", http.StatusConflict)
+			return
+		}
+
+		if IsSyncRunning(ue.ID) {
+			http.Error(w, ErrSyncInProgress.Error(), http.StatusConflict)
+			return
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), syncRunTimeout)
+			defer cancel()
+
+			if err := SyncUserExchange(ctx, logger, ue); err != nil {
+				logger.WithError(err).WithField("user_exchange_id", ue.ID).Warn("manual trade sync failed")
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(model.ExchangeSyncStatusResponse{
+			ExchangeID: ue.ExchangeID,
+			Status:     model.SyncStatusRunning,
+		}); err != nil {
+			logger.WithError(err).Error("failed to encode sync trigger response")
+		}
+	}
+}
+
+func loadUserExchange(w http.ResponseWriter, r *http.Request, logger *logrus.Entry) (*model.UserExchange, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok || user == nil {
+		logger.Warn("user not found in context while handling trade sync")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	exchangeID, err := strconv.ParseUint(chi.URLParam(r, "exchangeID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid exchangeID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	ue, err := getSyncStore().FindUserExchange(user.ID, uint(exchangeID))
+	if err != nil {
+		if errors.Is(err, ErrUserExchangeNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return nil, false
+		}
+
+		logger.WithError(err).Error("failed to load user exchange for trade sync")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return ue, true
+}
@@ -0,0 +1,99 @@
+package tradesync
+
+import (
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/model"
+)
+
+const (
+	fillExternalPrefix     = "fill:"
+	positionExternalPrefix = "position:"
+)
+
+func exchangeName(ue *model.UserExchange) *string {
+	if ue.Exchange == nil || ue.Exchange.Name == "" {
+		return nil
+	}
+	name := ue.Exchange.Name
+	return &name
+}
+
+func sideType(isLong bool) string {
+	if isLong {
+		return "Buy/Long"
+	}
+	return "Sell/Short"
+}
+
+// tradeFromFill turns a single execution into a journal row. A fill has no
+// exit of its own, so only the execution price is recorded.
+func tradeFromFill(ue *model.UserExchange, f connectors.Fill) model.Trade {
+	externalID := fillExternalPrefix + f.TradeID
+	isLong := f.Side == connectors.SideBuy
+	contractType := f.ContractType
+	if contractType == "" {
+		contractType = "spot"
+	}
+	fee := f.Fee
+
+	return model.Trade{
+		Exchange:     exchangeName(ue),
+		Symbol:       f.Symbol,
+		TradeDate:    f.Time,
+		TradeTime:    f.Time.Format("15:04"),
+		OrderType:    f.OrderType,
+		Quantity:     f.Quantity,
+		Price:        f.Price,
+		EntryPrice:   f.Price,
+		IsLong:       isLong,
+		IsShort:      !isLong,
+		Type:         sideType(isLong),
+		ContractType: &contractType,
+		Fee:          &fee,
+
+		UserID:         ue.UserID,
+		UserExchangeID: &ue.ID,
+		ExternalID:     &externalID,
+	}
+}
+
+func tradeFromPosition(ue *model.UserExchange, p connectors.ClosedPosition) model.Trade {
+	externalID := positionExternalPrefix + p.PositionID
+	contractType := "futures"
+	fee := p.Fee
+
+	closed := p.ClosedAt
+	opened := p.OpenedAt
+	if opened.IsZero() {
+		opened = p.ClosedAt
+	}
+
+	trade := model.Trade{
+		Exchange:     exchangeName(ue),
+		Symbol:       p.Symbol,
+		TradeDate:    opened,
+		TradeTime:    opened.Format("15:04"),
+		MarginMode:   p.MarginMode,
+		Quantity:     p.Quantity,
+		Price:        p.EntryPrice,
+		EntryPrice:   p.EntryPrice,
+		ExitPrice:    p.ExitPrice,
+		ExitDate:     &closed,
+		IsLong:       p.IsLong,
+		IsShort:      !p.IsLong,
+		Type:         sideType(p.IsLong),
+		ContractType: &contractType,
+		Fee:          &fee,
+
+		UserID:         ue.UserID,
+		UserExchangeID: &ue.ID,
+		ExternalID:     &externalID,
+	}
+
+	if p.Leverage > 0 {
+		leverage := p.Leverage
+		trade.Leverage = &leverage
+	}
+
+	return trade
+}
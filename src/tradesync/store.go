@@ -0,0 +1,173 @@
+package tradesync
+
+import (
+	"errors"
+	"sync"
+
+	"vsC1Y2025V01/src/db"
+	"vsC1Y2025V01/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUserExchangeNotFound = errors.New("user exchange not found")
+	ErrSyncStateNotFound    = errors.New("sync state not found")
+)
+
+type SyncStore interface {
+	// ListSyncableUserExchanges returns every user exchange with usable
+	// credentials, with Exchange preloaded.
+	ListSyncableUserExchanges() ([]model.UserExchange, error)
+	FindUserExchange(userID, exchangeID uint) (*model.UserExchange, error)
+	GetSyncState(userExchangeID uint) (*model.ExchangeSyncState, error)
+	SaveSyncState(state *model.ExchangeSyncState) error
+	// ListTradedSymbols returns the distinct symbols of the user's journal
+	// trades that were synced from ue or recorded under its exchange's name.
+	ListTradedSymbols(ue *model.UserExchange) ([]string, error)
+	// InsertTrades inserts trades, skipping those that already exist for the
+	// same (user_exchange_id, external_id) so edits made in the journal are
+	// kept. It returns how many rows were inserted.
+	InsertTrades(trades []model.Trade) (int, error)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   SyncStore = &gormSyncStore{}
+)
+
+func SetSyncStore(s SyncStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if s == nil {
+		store = &gormSyncStore{}
+		return
+	}
+
+	store = s
+}
+
+func getSyncStore() SyncStore {
+	storeMu.RLock()
+	current := store
+	storeMu.RUnlock()
+
+	if current != nil {
+		return current
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if store == nil {
+		store = &gormSyncStore{}
+	}
+
+	return store
+}
+
+type gormSyncStore struct{}
+
+func (s *gormSyncStore) ListSyncableUserExchanges() ([]model.UserExchange, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var userExchanges []model.UserExchange
+	if err := db.DB.Preload("Exchange").
+		Where("data_key <> '' AND needs_reentry = ?", false).
+		Order("id ASC").
+		Find(&userExchanges).Error; err != nil {
+		return nil, err
+	}
+
+	return userExchanges, nil
+}
+
+func (s *gormSyncStore) FindUserExchange(userID, exchangeID uint) (*model.UserExchange, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var userExchange model.UserExchange
+	if err := db.DB.Where("user_id = ? AND exchange_id = ?", userID, exchangeID).Preload("Exchange").First(&userExchange).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserExchangeNotFound
+		}
+
+		return nil, err
+	}
+
+	return &userExchange, nil
+}
+
+func (s *gormSyncStore) GetSyncState(userExchangeID uint) (*model.ExchangeSyncState, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var state model.ExchangeSyncState
+	if err := db.DB.Where("user_exchange_id = ?", userExchangeID).First(&state).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSyncStateNotFound
+		}
+
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (s *gormSyncStore) SaveSyncState(state *model.ExchangeSyncState) error {
+	if db.DB == nil {
+		return errors.New("database connection is not initialized")
+	}
+
+	return db.DB.Save(state).Error
+}
+
+func (s *gormSyncStore) ListTradedSymbols(ue *model.UserExchange) ([]string, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	query := db.DB.Model(&model.Trade{}).Where("user_id = ?", ue.UserID)
+	if name := exchangeName(ue); name != nil {
+		query = query.Where("user_exchange_id = ? OR LOWER(exchange) = LOWER(?)", ue.ID, *name)
+	} else {
+		query = query.Where("user_exchange_id = ?", ue.ID)
+	}
+
+	var symbols []string
+	if err := query.Distinct("symbol").Pluck("symbol", &symbols).Error; err != nil {
+		return nil, err
+	}
+
+	return symbols, nil
+}
+
+func (s *gormSyncStore) InsertTrades(trades []model.Trade) (int, error) {
+	if db.DB == nil {
+		return 0, errors.New("database connection is not initialized")
+	}
+	if len(trades) == 0 {
+		return 0, nil
+	}
+
+	var inserted int64
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_exchange_id"}, {Name: "external_id"}},
+			DoNothing: true,
+		}).CreateInBatches(trades, 200)
+		inserted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(inserted), nil
+}
@@ -0,0 +1,281 @@
+package tradesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/userexchanges"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSyncUnsupported = errors.New("trade history sync is not supported for this exchange")
+	ErrSyncInProgress  = errors.New("a sync is already running for this exchange")
+)
+
+const (
+	defaultSyncInterval = 15 * time.Minute
+	syncRunTimeout      = 10 * time.Minute
+)
+
+// HistorySourceFactory builds the connector used to read a user exchange's
+// history from its decrypted credentials. It returns ErrSyncUnsupported for
+// exchanges whose connector cannot list fills.
+type HistorySourceFactory func(ue *model.UserExchange, creds credentials.ExchangeCredentials) (connectors.TradeHistoryProvider, error)
+
+var (
+	factoryMu sync.RWMutex
+	factory   HistorySourceFactory = defaultHistorySource
+)
+
+func SetHistorySourceFactory(f HistorySourceFactory) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+
+	if f == nil {
+		factory = defaultHistorySource
+		return
+	}
+
+	factory = f
+}
+
+func getHistorySourceFactory() HistorySourceFactory {
+	factoryMu.RLock()
+	defer factoryMu.RUnlock()
+
+	return factory
+}
+
+func defaultHistorySource(ue *model.UserExchange, creds credentials.ExchangeCredentials) (connectors.TradeHistoryProvider, error) {
+	if ue.Exchange == nil {
+		return nil, ErrSyncUnsupported
+	}
+
+	capabilities, ok := connectors.CapabilitiesFor(*ue.Exchange)
+	if !ok || !capabilities.HistorySync {
+		return nil, ErrSyncUnsupported
+	}
+
+	return connectors.New(*ue.Exchange, creds)
+}
+
+var inFlight sync.Map // user exchange ID -> struct{}
+
+func IsSyncRunning(userExchangeID uint) bool {
+	_, running := inFlight.Load(userExchangeID)
+	return running
+}
+
+// SyncUserExchange imports the fills (and closed positions, when the
+// connector reports them) that appeared since the last run, then advances the
+// cursors. Trades are keyed by their exchange-side ID, so overlapping windows
+// never create duplicates and never overwrite a trade already in the
+// journal. The outcome is recorded on the sync state.
+func SyncUserExchange(ctx context.Context, logger *logrus.Entry, ue *model.UserExchange) error {
+	if _, running := inFlight.LoadOrStore(ue.ID, struct{}{}); running {
+		return ErrSyncInProgress
+	}
+	defer inFlight.Delete(ue.ID)
+
+	state, err := getSyncStore().GetSyncState(ue.ID)
+	if err != nil {
+		if !errors.Is(err, ErrSyncStateNotFound) {
+			return err
+		}
+		state = &model.ExchangeSyncState{UserExchangeID: ue.ID}
+	}
+
+	started := time.Now()
+	state.Status = model.SyncStatusRunning
+	state.LastStartedAt = &started
+	if err := getSyncStore().SaveSyncState(state); err != nil {
+		return err
+	}
+
+	imported, err := runSync(ctx, ue, state)
+
+	finished := time.Now()
+	state.LastFinishedAt = &finished
+	switch {
+	case err == nil:
+		state.Status = model.SyncStatusSucceeded
+		state.LastError = ""
+		state.LastSuccessAt = &finished
+		state.TradesImported += int64(imported)
+	case errors.Is(err, ErrSyncUnsupported):
+		state.Status = model.SyncStatusUnsupported
+		state.LastError = err.Error()
+	default:
+		state.Status = model.SyncStatusFailed
+		state.LastError = err.Error()
+	}
+
+	if saveErr := getSyncStore().SaveSyncState(state); saveErr != nil {
+		logger.WithError(saveErr).WithField("user_exchange_id", ue.ID).Error("failed to save sync state")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"user_exchange_id": ue.ID,
+		"status":           state.Status,
+		"imported":         imported,
+		"took":             finished.Sub(started),
+	}).Info("trade sync finished")
+
+	return err
+}
+
+func runSync(ctx context.Context, ue *model.UserExchange, state *model.ExchangeSyncState) (int, error) {
+	creds, err := userexchanges.DecryptCredentials(ue)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt credentials: %w", err)
+	}
+
+	source, err := getHistorySourceFactory()(ue, creds)
+	if err != nil {
+		return 0, err
+	}
+
+	var trades []model.Trade
+	seen := make(map[string]bool)
+
+	// Exchanges that list fills per symbol also look at the markets already in
+	// the journal, so round trips that left no balance behind are not missed.
+	known, err := getSyncStore().ListTradedSymbols(ue)
+	if err != nil {
+		return 0, fmt.Errorf("list journal symbols: %w", err)
+	}
+
+	fillsCursor := state.FillsCursor
+	fills, err := source.GetFills(ctx, connectors.HistoryRequest{Since: cursorTime(state.FillsCursor), KnownSymbols: known})
+	if err != nil {
+		return 0, fmt.Errorf("fetch fills: %w", err)
+	}
+	for _, fill := range fills {
+		trade := tradeFromFill(ue, fill)
+		if seen[*trade.ExternalID] {
+			continue
+		}
+		seen[*trade.ExternalID] = true
+		trades = append(trades, trade)
+		fillsCursor = laterOf(fillsCursor, fill.Time)
+	}
+
+	positionsCursor := state.PositionsCursor
+	if positionSource, ok := source.(connectors.PositionHistoryProvider); ok {
+		positions, err := positionSource.GetClosedPositions(ctx, connectors.HistoryRequest{Since: cursorTime(state.PositionsCursor)})
+		if err != nil {
+			return 0, fmt.Errorf("fetch closed positions: %w", err)
+		}
+		for _, position := range positions {
+			trade := tradeFromPosition(ue, position)
+			if seen[*trade.ExternalID] {
+				continue
+			}
+			seen[*trade.ExternalID] = true
+			trades = append(trades, trade)
+			positionsCursor = laterOf(positionsCursor, position.ClosedAt)
+		}
+	}
+
+	imported, err := getSyncStore().InsertTrades(trades)
+	if err != nil {
+		return 0, fmt.Errorf("store trades: %w", err)
+	}
+
+	// Only move the cursors once the trades are safely stored.
+	state.FillsCursor = fillsCursor
+	state.PositionsCursor = positionsCursor
+	return imported, nil
+}
+
+// cursorTime returns the lower bound of the next request. The record on the
+// cursor was already imported, so it starts one millisecond later, the
+// finest resolution exchanges report.
+func cursorTime(cursor *time.Time) time.Time {
+	if cursor == nil {
+		return time.Time{}
+	}
+	return cursor.Add(time.Millisecond)
+}
+
+func laterOf(cursor *time.Time, t time.Time) *time.Time {
+	if t.IsZero() || (cursor != nil && !t.After(*cursor)) {
+		return cursor
+	}
+	return &t
+}
+
+// SyncAll runs one sync pass over every user exchange with credentials.
+func SyncAll(ctx context.Context, logger *logrus.Entry) {
+	userExchanges, err := getSyncStore().ListSyncableUserExchanges()
+	if err != nil {
+		logger.WithError(err).Error("failed to list user exchanges for trade sync")
+		return
+	}
+
+	for i := range userExchanges {
+		if ctx.Err() != nil {
+			return
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, syncRunTimeout)
+		err := SyncUserExchange(runCtx, logger, &userExchanges[i])
+		cancel()
+
+		if err != nil && !errors.Is(err, ErrSyncInProgress) && !errors.Is(err, ErrSyncUnsupported) {
+			logger.WithError(err).WithField("user_exchange_id", userExchanges[i].ID).Warn("trade sync failed")
+		}
+	}
+}
+
+// Start runs SyncAll every interval until ctx is cancelled. A non-positive
+// interval disables the background sync.
+func Start(ctx context.Context, logger *logrus.Entry, interval time.Duration) {
+	if interval <= 0 {
+		logger.Info("background trade sync disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			SyncAll(ctx, logger)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// IntervalFromEnv reads TRADE_SYNC_INTERVAL (a Go duration such as "15m");
+// "0" or "off" disables the background sync.
+func IntervalFromEnv() time.Duration {
+	value := strings.TrimSpace(os.Getenv("TRADE_SYNC_INTERVAL"))
+	switch value {
+	case "":
+		return defaultSyncInterval
+	case "0", "off":
+		return 0
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultSyncInterval
+	}
+	return interval
+}
@@ -0,0 +1,230 @@
+package tradesync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+type inMemorySyncStore struct {
+	mu             sync.Mutex
+	states         map[uint]*model.ExchangeSyncState
+	trades         map[string]model.Trade
+	journalSymbols []string
+}
+
+func newInMemorySyncStore() *inMemorySyncStore {
+	return &inMemorySyncStore{
+		states: make(map[uint]*model.ExchangeSyncState),
+		trades: make(map[string]model.Trade),
+	}
+}
+
+func (s *inMemorySyncStore) ListSyncableUserExchanges() ([]model.UserExchange, error) {
+	return nil, nil
+}
+
+func (s *inMemorySyncStore) FindUserExchange(userID, exchangeID uint) (*model.UserExchange, error) {
+	return nil, ErrUserExchangeNotFound
+}
+
+func (s *inMemorySyncStore) GetSyncState(userExchangeID uint) (*model.ExchangeSyncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[userExchangeID]
+	if !ok {
+		return nil, ErrSyncStateNotFound
+	}
+	clone := *state
+	return &clone, nil
+}
+
+func (s *inMemorySyncStore) SaveSyncState(state *model.ExchangeSyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clone := *state
+	s.states[state.UserExchangeID] = &clone
+	return nil
+}
+
+func (s *inMemorySyncStore) ListTradedSymbols(ue *model.UserExchange) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journalSymbols, nil
+}
+
+func (s *inMemorySyncStore) InsertTrades(trades []model.Trade) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inserted := 0
+	for _, trade := range trades {
+		if _, exists := s.trades[*trade.ExternalID]; exists {
+			continue
+		}
+		s.trades[*trade.ExternalID] = trade
+		inserted++
+	}
+	return inserted, nil
+}
+
+type fakeHistorySource struct {
+	fills     []connectors.Fill
+	positions []connectors.ClosedPosition
+	err       error
+	requests  []connectors.HistoryRequest
+}
+
+func (f *fakeHistorySource) GetFills(ctx context.Context, req connectors.HistoryRequest) ([]connectors.Fill, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var result []connectors.Fill
+	for _, fill := range f.fills {
+		if !fill.Time.Before(req.Since) {
+			result = append(result, fill)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeHistorySource) GetClosedPositions(ctx context.Context, req connectors.HistoryRequest) ([]connectors.ClosedPosition, error) {
+	var result []connectors.ClosedPosition
+	for _, position := range f.positions {
+		if !position.ClosedAt.Before(req.Since) {
+			result = append(result, position)
+		}
+	}
+	return result, nil
+}
+
+func TestSyncUserExchange(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	syncStore := newInMemorySyncStore()
+	SetSyncStore(syncStore)
+	t.Cleanup(func() {
+		SetSyncStore(nil)
+	})
+
+	t1 := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	source := &fakeHistorySource{
+		fills: []connectors.Fill{
+			{TradeID: "a", Symbol: "BTC-USDT", Side: connectors.SideBuy, Price: 60000, Quantity: 0.1, Fee: 6, Time: t1},
+			{TradeID: "b", Symbol: "BTC-USDT", Side: connectors.SideSell, Price: 61000, Quantity: 0.1, Fee: 6.1, Time: t2},
+		},
+		positions: []connectors.ClosedPosition{
+			{PositionID: "p1", Symbol: "ETHUSDTM", IsLong: false, Leverage: 5, Quantity: 2, EntryPrice: 3200, ExitPrice: 3100, OpenedAt: t1, ClosedAt: t2},
+		},
+	}
+	SetHistorySourceFactory(func(ue *model.UserExchange, creds credentials.ExchangeCredentials) (connectors.TradeHistoryProvider, error) {
+		return source, nil
+	})
+	t.Cleanup(func() {
+		SetHistorySourceFactory(nil)
+	})
+
+	ue := &model.UserExchange{ID: 3, UserID: 9, ExchangeID: 1, Exchange: &model.Exchange{ID: 1, Name: "Kucoin"}}
+	syncStore.journalSymbols = []string{"SOL-USDT"}
+
+	if err := SyncUserExchange(context.Background(), logger, ue); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+	if known := source.requests[0].KnownSymbols; len(known) != 1 || known[0] != "SOL-USDT" {
+		t.Fatalf("expected journal symbols to be passed to the connector, got %v", known)
+	}
+
+	if len(syncStore.trades) != 3 {
+		t.Fatalf("expected 3 imported trades, got %d", len(syncStore.trades))
+	}
+
+	buy := syncStore.trades["fill:a"]
+	if !buy.IsLong || buy.Type != "Buy/Long" || buy.Price != 60000 || buy.UserID != 9 || *buy.UserExchangeID != 3 {
+		t.Fatalf("unexpected mapping for buy fill: %+v", buy)
+	}
+	if buy.Exchange == nil || *buy.Exchange != "Kucoin" {
+		t.Fatalf("expected exchange name on synced trade, got %v", buy.Exchange)
+	}
+
+	position := syncStore.trades["position:p1"]
+	if !position.IsShort || position.EntryPrice != 3200 || position.ExitPrice != 3100 || *position.Leverage != 5 {
+		t.Fatalf("unexpected mapping for closed position: %+v", position)
+	}
+
+	state := syncStore.states[ue.ID]
+	if state.Status != model.SyncStatusSucceeded || state.LastError != "" {
+		t.Fatalf("expected succeeded status, got %+v", state)
+	}
+	if state.FillsCursor == nil || !state.FillsCursor.Equal(t2) {
+		t.Fatalf("expected fills cursor at newest fill, got %v", state.FillsCursor)
+	}
+
+	// A second run asks only for what is new: the fill on the cursor is not
+	// fetched again, and the user's edit to it survives.
+	sell := syncStore.trades["fill:b"]
+	notes := "took profit early"
+	sell.Notes = &notes
+	syncStore.trades["fill:b"] = sell
+
+	if err := SyncUserExchange(context.Background(), logger, ue); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	if !source.requests[1].Since.Equal(t2.Add(time.Millisecond)) {
+		t.Fatalf("expected second run to start just after the cursor, got %v", source.requests[1].Since)
+	}
+	if len(syncStore.trades) != 3 {
+		t.Fatalf("expected re-sync to stay idempotent, got %d trades", len(syncStore.trades))
+	}
+	if state := syncStore.states[ue.ID]; state.TradesImported != 3 {
+		t.Fatalf("expected an empty run to import nothing, got %d", state.TradesImported)
+	}
+	if got := syncStore.trades["fill:b"].Notes; got == nil || *got != notes {
+		t.Fatalf("expected the edited trade to be kept, got %v", got)
+	}
+
+	source.err = errors.New("exchange unavailable")
+	if err := SyncUserExchange(context.Background(), logger, ue); err == nil {
+		t.Fatalf("expected sync error to be returned")
+	}
+	state = syncStore.states[ue.ID]
+	if state.Status != model.SyncStatusFailed || state.LastError == "" {
+		t.Fatalf("expected failed status with last error, got %+v", state)
+	}
+	if !state.FillsCursor.Equal(t2) {
+		t.Fatalf("expected cursor to stay put after a failed run, got %v", state.FillsCursor)
+	}
+}
+
+func TestSyncUserExchangeUnsupported(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	syncStore := newInMemorySyncStore()
+	SetSyncStore(syncStore)
+	t.Cleanup(func() {
+		SetSyncStore(nil)
+	})
+
+	ue := &model.UserExchange{ID: 4, UserID: 9, ExchangeID: 2, Exchange: &model.Exchange{ID: 2, Name: "Unknown"}}
+
+	err := SyncUserExchange(context.Background(), logger, ue)
+	if !errors.Is(err, ErrSyncUnsupported) {
+		t.Fatalf("expected ErrSyncUnsupported, got %v", err)
+	}
+	if syncStore.states[ue.ID].Status != model.SyncStatusUnsupported {
+		t.Fatalf("expected unsupported status, got %s", syncStore.states[ue.ID].Status)
+	}
+}
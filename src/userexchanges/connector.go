@@ -0,0 +1,59 @@
+package userexchanges
+
+import (
+	"sync"
+
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+)
+
+// ConnectorFactory builds a connector for exchange from decrypted credentials.
+type ConnectorFactory func(exchange model.Exchange, creds credentials.ExchangeCredentials) (connectors.ExchangeConnector, error)
+
+var (
+	connectorFactoryMu sync.RWMutex
+	connectorFactory   ConnectorFactory = connectors.New
+)
+
+// SetConnectorFactory overrides how connectors are built. Passing nil
+// restores the connector registry.
+func SetConnectorFactory(f ConnectorFactory) {
+	connectorFactoryMu.Lock()
+	defer connectorFactoryMu.Unlock()
+
+	if f == nil {
+		connectorFactory = connectors.New
+		return
+	}
+
+	connectorFactory = f
+}
+
+func getConnectorFactory() ConnectorFactory {
+	connectorFactoryMu.RLock()
+	defer connectorFactoryMu.RUnlock()
+
+	return connectorFactory
+}
+
+// NewConnector builds the connector registered for ue's exchange from its
+// decrypted credentials. It returns connectors.ErrUnsupportedExchange when
+// no connector exists for that exchange.
+func NewConnector(ue *model.UserExchange) (connectors.ExchangeConnector, error) {
+	exchange := ue.Exchange
+	if exchange == nil {
+		loaded, err := getUserExchangeStore().GetExchangeByID(ue.ExchangeID)
+		if err != nil {
+			return nil, err
+		}
+		exchange = loaded
+	}
+
+	creds, err := DecryptCredentials(ue)
+	if err != nil {
+		return nil, err
+	}
+
+	return getConnectorFactory()(*exchange, creds)
+}
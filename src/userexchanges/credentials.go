@@ -0,0 +1,178 @@
+package userexchanges
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"vsC1Y2025V01/src/credentials"
+	"vsC1Y2025V01/src/model"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrLegacyCredentials = errors.New("user exchange still holds hashed credentials and must be re-entered")
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *credentials.Keyring
+)
+
+// SetKeyring overrides the keyring loaded from the environment. Passing nil
+// makes the next use reload it from the environment.
+func SetKeyring(k *credentials.Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	keyring = k
+}
+
+func getKeyring() (*credentials.Keyring, error) {
+	keyringMu.RLock()
+	current := keyring
+	keyringMu.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	if keyring == nil {
+		loaded, err := credentials.LoadKeyringFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		keyring = loaded
+	}
+
+	return keyring, nil
+}
+
+func credentialsAAD(ue *model.UserExchange) string {
+	return fmt.Sprintf("user_exchange:%d:%d", ue.UserID, ue.ExchangeID)
+}
+
+// isLegacyHash reports whether a credential column still holds one of the
+// bcrypt hashes written before credentials were encrypted.
+func isLegacyHash(value string) bool {
+	return len(value) == 60 && (strings.HasPrefix(value, "$2a$") ||
+		strings.HasPrefix(value, "$2b$") ||
+		strings.HasPrefix(value, "$2y$"))
+}
+
+func hasLegacyHashes(ue *model.UserExchange) bool {
+	return isLegacyHash(ue.APIKeyEnc) || isLegacyHash(ue.APISecretEnc) || isLegacyHash(ue.APIPassphraseEnc)
+}
+
+// DecryptCredentials returns the plaintext credentials of ue.
+func DecryptCredentials(ue *model.UserExchange) (credentials.ExchangeCredentials, error) {
+	if hasLegacyHashes(ue) || ue.NeedsReentry {
+		return credentials.ExchangeCredentials{}, ErrLegacyCredentials
+	}
+	if ue.DataKey == "" {
+		return credentials.ExchangeCredentials{}, nil
+	}
+
+	k, err := getKeyring()
+	if err != nil {
+		return credentials.ExchangeCredentials{}, err
+	}
+
+	fields, err := k.Open(credentials.Sealed{
+		KeyVersion: ue.KeyVersion,
+		DataKey:    ue.DataKey,
+		Fields:     []string{ue.APIKeyEnc, ue.APISecretEnc, ue.APIPassphraseEnc},
+	}, credentialsAAD(ue))
+	if err != nil {
+		return credentials.ExchangeCredentials{}, err
+	}
+
+	return credentials.ExchangeCredentials{
+		APIKey:        fields[0],
+		APISecret:     fields[1],
+		APIPassphrase: fields[2],
+	}, nil
+}
+
+// encryptCredentials seals creds onto ue under a fresh data key and the
+// current master key version.
+func encryptCredentials(ue *model.UserExchange, creds credentials.ExchangeCredentials) error {
+	k, err := getKeyring()
+	if err != nil {
+		return err
+	}
+
+	sealed, err := k.Seal(credentialsAAD(ue), creds.APIKey, creds.APISecret, creds.APIPassphrase)
+	if err != nil {
+		return err
+	}
+
+	ue.KeyVersion = sealed.KeyVersion
+	ue.DataKey = sealed.DataKey
+	ue.APIKeyEnc = sealed.Fields[0]
+	ue.APISecretEnc = sealed.Fields[1]
+	ue.APIPassphraseEnc = sealed.Fields[2]
+	ue.NeedsReentry = ue.NeedsReentry && (creds.APIKey == "" || creds.APISecret == "")
+	return nil
+}
+
+// MigrateCredentials is the one-time upgrade of stored credentials. Rows
+// still holding bcrypt hashes cannot be recovered, so their hashes are
+// cleared and the row is flagged for re-entry. Rows sealed under a retired
+// master key version are re-sealed under the current one. It is safe to run
+// on every start.
+func MigrateCredentials(logger *logrus.Entry) error {
+	userExchanges, err := getUserExchangeStore().ListAllUserExchanges()
+	if err != nil {
+		return err
+	}
+
+	k, keyErr := getKeyring()
+	if keyErr != nil {
+		logger.WithError(keyErr).Warn("credentials keyring unavailable, skipping re-encryption")
+	}
+
+	var cleared, resealed int
+	for i := range userExchanges {
+		ue := &userExchanges[i]
+
+		switch {
+		case hasLegacyHashes(ue):
+			ue.APIKeyEnc = ""
+			ue.APISecretEnc = ""
+			ue.APIPassphraseEnc = ""
+			ue.DataKey = ""
+			ue.KeyVersion = 0
+			ue.NeedsReentry = true
+			cleared++
+		case k != nil && ue.DataKey != "" && ue.KeyVersion != k.CurrentVersion():
+			creds, err := DecryptCredentials(ue)
+			if err != nil {
+				logger.WithError(err).WithField("user_exchange_id", ue.ID).Error("failed to decrypt credentials for re-encryption")
+				continue
+			}
+			if err := encryptCredentials(ue, creds); err != nil {
+				return err
+			}
+			resealed++
+		default:
+			continue
+		}
+
+		if err := getUserExchangeStore().SaveUserExchange(ue); err != nil {
+			return err
+		}
+	}
+
+	if cleared > 0 || resealed > 0 {
+		logger.WithFields(logrus.Fields{
+			"cleared_legacy_hashes": cleared,
+			"resealed":              resealed,
+		}).Info("user exchange credentials migrated")
+	}
+
+	return nil
+}
@@ -7,12 +7,14 @@ import (
 	"strconv"
 	"strings"
 
+	"vsC1Y2025V01/internal/connectors"
 	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/credentials"
 	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func UpsertUserExchangeHandler(logger *logrus.Entry) http.HandlerFunc {
@@ -68,34 +70,40 @@ func UpsertUserExchangeHandler(logger *logrus.Entry) http.HandlerFunc {
 			}
 		}
 
-		if payload.APIKey != "" {
-			hash, err := bcrypt.GenerateFromPassword([]byte(payload.APIKey), bcrypt.DefaultCost)
-			if err != nil {
-				logger.WithError(err).Error("failed to hash api key")
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		// Merge with what is already stored so a partial update (e.g. only a new
+		// secret) keeps the other credentials, then re-seal everything under a
+		// fresh data key. Rows flagged for re-entry have nothing left to merge
+		// with, so they need the key and secret together.
+		creds := credentials.ExchangeCredentials{}
+		if hasLegacyHashes(userExchange) || userExchange.NeedsReentry {
+			if payload.APIKey == "" || payload.APISecret == "" {
+				http.Error(w, "apiKey and apiSecret are required to re-enter credentials", http.StatusBadRequest)
 				return
 			}
-			userExchange.APIKeyHash = string(hash)
-		}
-
-		if payload.APISecret != "" {
-			hash, err := bcrypt.GenerateFromPassword([]byte(payload.APISecret), bcrypt.DefaultCost)
+			userExchange.NeedsReentry = true
+		} else {
+			creds, err = DecryptCredentials(userExchange)
 			if err != nil {
-				logger.WithError(err).Error("failed to hash api secret")
+				logger.WithError(err).Error("failed to decrypt stored user exchange credentials")
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
 			}
-			userExchange.APISecretHash = string(hash)
 		}
 
+		if payload.APIKey != "" {
+			creds.APIKey = payload.APIKey
+		}
+		if payload.APISecret != "" {
+			creds.APISecret = payload.APISecret
+		}
 		if payload.APIPassphrase != "" {
-			hash, err := bcrypt.GenerateFromPassword([]byte(payload.APIPassphrase), bcrypt.DefaultCost)
-			if err != nil {
-				logger.WithError(err).Error("failed to hash api passphrase")
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
-			userExchange.APIPassphraseHash = string(hash)
+			creds.APIPassphrase = payload.APIPassphrase
+		}
+
+		if err := encryptCredentials(userExchange, creds); err != nil {
+			logger.WithError(err).Error("failed to encrypt user exchange credentials")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
 		}
 
 		userExchange.ShowInForms = payload.ShowInForms
@@ -108,6 +116,19 @@ func UpsertUserExchangeHandler(logger *logrus.Entry) http.HandlerFunc {
 
 		userExchange.Exchange = exchange
 
+		// Verify right away so the user learns about a bad key while still on
+		// the form. Exchanges without a connector, or rows still missing a key
+		// or secret, are simply left unverified.
+		if err := VerifyCredentials(r.Context(), userExchange); err != nil {
+			if !errors.Is(err, connectors.ErrUnsupportedExchange) && !errors.Is(err, ErrMissingCredentials) && !errors.Is(err, ErrLegacyCredentials) {
+				logger.WithError(err).Warn("failed to verify user exchange credentials")
+			}
+		} else if err := getUserExchangeStore().SaveUserExchange(userExchange); err != nil {
+			logger.WithError(err).Error("failed to store user exchange verification")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(model.NewUserExchangeResponse(userExchange)); err != nil {
 			logger.WithError(err).Error("failed to encode user exchange response")
@@ -115,6 +136,78 @@ func UpsertUserExchangeHandler(logger *logrus.Entry) http.HandlerFunc {
 	}
 }
 
+// TestUserExchangeHandler re-verifies the caller's stored credentials for
+// one exchange and returns the updated user exchange.
+func TestUserExchangeHandler(logger *logrus.Entry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || user == nil {
+			logger.Warn("user not found in context while testing user exchange")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		exchangeID, err := strconv.ParseUint(chi.URLParam(r, "exchangeID"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid exchangeID", http.StatusBadRequest)
+			return
+		}
+
+		userExchange, err := getUserExchangeStore().FindUserExchange(user.ID, uint(exchangeID))
+		if err != nil {
+			if errors.Is(err, ErrUserExchangeNotFound) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+
+			logger.WithError(err).Error("failed to fetch user exchange")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := VerifyCredentials(r.Context(), userExchange); err != nil {
+			switch {
+			case errors.Is(err, connectors.ErrUnsupportedExchange):
+				http.Error(w, "exchange does not support connection tests", http.StatusBadRequest)
+			case errors.Is(err, ErrMissingCredentials):
+				http.Error(w, "apiKey and apiSecret are required", http.StatusBadRequest)
+			case errors.Is(err, ErrLegacyCredentials):
+				http.Error(w, "credentials must be re-entered", http.StatusConflict)
+			default:
+				logger.WithError(err).Error("failed to verify user exchange credentials")
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := getUserExchangeStore().SaveUserExchange(userExchange); err != nil {
+			logger.WithError(err).Error("failed to store user exchange verification")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.NewUserExchangeResponse(userExchange)); err != nil {
+			logger.WithError(err).Error("failed to encode user exchange response")
+		}
+	}
+}
+
+// formExchangeSchema lists what GET /user-exchanges/forms can filter and
+// sort on.
+var formExchangeSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":               {Column: "id", Kind: query.Number},
+		"exchange_id":      {Column: "exchange_id", Kind: query.Number},
+		"needs_reentry":    {Column: "needs_reentry", Kind: query.Bool},
+		"can_trade":        {Column: "can_trade", Kind: query.Bool},
+		"can_withdraw":     {Column: "can_withdraw", Kind: query.Bool},
+		"last_verified_at": {Column: "last_verified_at", Kind: query.Time},
+		"created_at":       {Column: "created_at", Kind: query.Time},
+	},
+	DefaultSort: query.Sort{Field: "id"},
+}
+
 func ListFormUserExchangesHandler(logger *logrus.Entry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, ok := auth.GetUserFromContext(r.Context())
@@ -124,7 +217,13 @@ func ListFormUserExchangesHandler(logger *logrus.Entry) http.HandlerFunc {
 			return
 		}
 
-		userExchanges, err := getUserExchangeStore().ListFormUserExchanges(user.ID)
+		params, err := query.Parse(r, formExchangeSchema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		userExchanges, total, err := getUserExchangeStore().ListFormUserExchanges(user.ID, params)
 		if err != nil {
 			logger.WithError(err).Error("failed to list user exchanges for forms")
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -136,6 +235,8 @@ func ListFormUserExchangesHandler(logger *logrus.Entry) http.HandlerFunc {
 			responses = append(responses, model.NewUserExchangeResponse(&userExchanges[i]))
 		}
 
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
+		w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(responses); err != nil {
 			logger.WithError(err).Error("failed to encode user exchange list response")
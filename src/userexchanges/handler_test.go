@@ -2,7 +2,9 @@ package userexchanges
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -10,91 +12,17 @@ import (
 	"testing"
 	"time"
 
+	"vsC1Y2025V01/internal/connectors"
 	"vsC1Y2025V01/src/auth"
+	"vsC1Y2025V01/src/auth/authtest"
+	"vsC1Y2025V01/src/credentials"
 	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type inMemoryUserRepository struct {
-	mu     sync.Mutex
-	nextID uint
-	users  map[uint]*model.User
-}
-
-func newInMemoryUserRepository() *inMemoryUserRepository {
-	return &inMemoryUserRepository{users: make(map[uint]*model.User)}
-}
-
-func (r *inMemoryUserRepository) Create(user *model.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.nextID++
-	now := time.Now()
-	clone := *user
-	clone.ID = r.nextID
-	clone.CreatedAt = now
-	clone.UpdatedAt = now
-	r.users[clone.ID] = &clone
-
-	user.ID = clone.ID
-	user.CreatedAt = clone.CreatedAt
-	user.UpdatedAt = clone.UpdatedAt
-	return nil
-}
-
-func (r *inMemoryUserRepository) FindByUsername(username string) (*model.User, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	for _, user := range r.users {
-		if user.Username == username {
-			clone := *user
-			return &clone, nil
-		}
-	}
-
-	return nil, auth.ErrUserNotFound
-}
-
-func (r *inMemoryUserRepository) FindByID(id uint) (*model.User, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	user, ok := r.users[id]
-	if !ok {
-		return nil, auth.ErrUserNotFound
-	}
-
-	clone := *user
-	return &clone, nil
-}
-
-func (r *inMemoryUserRepository) Update(user *model.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	stored, ok := r.users[user.ID]
-	if !ok {
-		return auth.ErrUserNotFound
-	}
-
-	if user.UpdatedAt.IsZero() {
-		user.UpdatedAt = time.Now()
-	}
-
-	stored.Username = user.Username
-	stored.Password = user.Password
-	stored.CreatedAt = user.CreatedAt
-	stored.UpdatedAt = user.UpdatedAt
-	stored.LastLogin = user.LastLogin
-	stored.LastSeen = user.LastSeen
-	return nil
-}
-
 type inMemoryUserExchangeStore struct {
 	mu                 sync.Mutex
 	nextExchangeID     uint
@@ -187,7 +115,7 @@ func (s *inMemoryUserExchangeStore) SaveUserExchange(ue *model.UserExchange) err
 	return nil
 }
 
-func (s *inMemoryUserExchangeStore) ListFormUserExchanges(userID uint) ([]model.UserExchange, error) {
+func (s *inMemoryUserExchangeStore) ListFormUserExchanges(userID uint, _ query.Params) ([]model.UserExchange, int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -206,7 +134,7 @@ func (s *inMemoryUserExchangeStore) ListFormUserExchanges(userID uint) ([]model.
 		result = append(result, clone)
 	}
 
-	return result, nil
+	return result, int64(len(result)), nil
 }
 
 func (s *inMemoryUserExchangeStore) DeleteUserExchange(userID, exchangeID uint) (bool, error) {
@@ -222,14 +150,83 @@ func (s *inMemoryUserExchangeStore) DeleteUserExchange(userID, exchangeID uint)
 	return true, nil
 }
 
-func TestUserExchangeLifecycle(t *testing.T) {
-	logger := logrus.NewEntry(logrus.StandardLogger())
+func (s *inMemoryUserExchangeStore) ListAllUserExchanges() ([]model.UserExchange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []model.UserExchange
+	for _, ue := range s.userExchanges {
+		result = append(result, *ue)
+	}
+
+	return result, nil
+}
+
+// fakeConnector answers the calls made by VerifyCredentials; anything else
+// panics through the nil embedded interface.
+type fakeConnector struct {
+	connectors.ExchangeConnector
+	err         error
+	permissions *connectors.KeyPermissions
+}
+
+func (c *fakeConnector) TestConnection(ctx context.Context) error {
+	return c.err
+}
+
+func (c *fakeConnector) GetBalances(ctx context.Context) ([]connectors.Balance, error) {
+	return nil, nil
+}
 
-	userRepo := newInMemoryUserRepository()
-	auth.SetUserRepository(userRepo)
+func (c *fakeConnector) GetKeyPermissions(ctx context.Context) (*connectors.KeyPermissions, error) {
+	return c.permissions, nil
+}
+
+func useFakeConnector(t *testing.T, c *fakeConnector) {
+	t.Helper()
+
+	SetConnectorFactory(func(exchange model.Exchange, creds credentials.ExchangeCredentials) (connectors.ExchangeConnector, error) {
+		return c, nil
+	})
 	t.Cleanup(func() {
-		auth.SetUserRepository(nil)
+		SetConnectorFactory(nil)
 	})
+}
+
+func newTestKeyring(t *testing.T, current int, versions ...int) *credentials.Keyring {
+	t.Helper()
+
+	keys := make(map[int][]byte)
+	for _, version := range append(versions, current) {
+		keys[version] = bytes.Repeat([]byte{byte(version)}, 32)
+	}
+
+	k, err := credentials.NewKeyring(current, keys)
+	if err != nil {
+		t.Fatalf("failed to build keyring: %v", err)
+	}
+	return k
+}
+
+func useTestKeyring(t *testing.T, k *credentials.Keyring) {
+	t.Helper()
+
+	SetKeyring(k)
+	t.Cleanup(func() {
+		SetKeyring(nil)
+	})
+}
+
+func TestUserExchangeLifecycle(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	useTestKeyring(t, newTestKeyring(t, 1))
+	connector := &fakeConnector{permissions: &connectors.KeyPermissions{Read: true, Trade: true}}
+	useFakeConnector(t, connector)
+
+	stores := authtest.UseInMemoryStores(t)
+	user := &model.User{Username: "alice"}
+	tokenCookie := stores.Login(t, user)
 
 	exchangeStore := newInMemoryUserExchangeStore()
 	SetUserExchangeStore(exchangeStore)
@@ -238,13 +235,12 @@ func TestUserExchangeLifecycle(t *testing.T) {
 	})
 
 	router := chi.NewRouter()
-	router.Post("/auth/register", auth.RegisterHandler(logger))
-	router.Post("/auth/login", auth.LoginHandler(logger))
 	router.Group(func(r chi.Router) {
 		r.Use(auth.RequireAuthMiddleware(logger))
 		r.Route("/user-exchanges", func(r chi.Router) {
 			r.Post("/", UpsertUserExchangeHandler(logger))
 			r.Get("/forms", ListFormUserExchangesHandler(logger))
+			r.Post("/{exchangeID}/test", TestUserExchangeHandler(logger))
 			r.Delete("/{exchangeID}", DeleteUserExchangeHandler(logger))
 		})
 	})
@@ -254,37 +250,6 @@ func TestUserExchangeLifecycle(t *testing.T) {
 		t.Fatalf("failed to seed exchange: %v", err)
 	}
 
-	credentials := map[string]string{
-		"username": "alice",
-		"password": "SuperSecret123",
-	}
-
-	body, _ := json.Marshal(credentials)
-	registerReq := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(body))
-	registerRec := httptest.NewRecorder()
-	router.ServeHTTP(registerRec, registerReq)
-	if registerRec.Code != http.StatusCreated {
-		t.Fatalf("expected register 201, got %d", registerRec.Code)
-	}
-
-	loginReq := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
-	loginRec := httptest.NewRecorder()
-	router.ServeHTTP(loginRec, loginReq)
-	if loginRec.Code != http.StatusOK {
-		t.Fatalf("expected login 200, got %d", loginRec.Code)
-	}
-
-	var tokenCookie *http.Cookie
-	for _, cookie := range loginRec.Result().Cookies() {
-		if cookie.Name == "token" {
-			tokenCookie = cookie
-			break
-		}
-	}
-	if tokenCookie == nil {
-		t.Fatalf("expected token cookie in login response")
-	}
-
 	upsertPayload := map[string]interface{}{
 		"exchangeId":    exchange.ID,
 		"apiKey":        "initial-api-key",
@@ -312,10 +277,11 @@ func TestUserExchangeLifecycle(t *testing.T) {
 	if !created.HasAPIKey || !created.HasAPISecret || !created.HasAPIPassphrase {
 		t.Fatalf("expected all credential flags to be true, got %+v", created)
 	}
-
-	user, err := userRepo.FindByUsername("alice")
-	if err != nil {
-		t.Fatalf("failed to load user: %v", err)
+	if created.LastVerifiedAt == nil || created.LastError != "" {
+		t.Fatalf("expected upsert to verify the credentials, got %+v", created)
+	}
+	if created.Permissions == nil || !created.Permissions.CanTrade || created.Permissions.CanWithdraw || created.Permissions.ReadOnly {
+		t.Fatalf("expected trade-only permissions, got %+v", created.Permissions)
 	}
 
 	stored, err := exchangeStore.FindUserExchange(user.ID, exchange.ID)
@@ -323,17 +289,19 @@ func TestUserExchangeLifecycle(t *testing.T) {
 		t.Fatalf("failed to load stored user exchange: %v", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(stored.APIKeyHash), []byte("initial-api-key")); err != nil {
-		t.Fatalf("expected api key to be hashed correctly: %v", err)
+	if stored.APIKeyEnc == "initial-api-key" || stored.APISecretEnc == "initial-api-secret" {
+		t.Fatalf("expected credentials to be stored encrypted")
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(stored.APISecretHash), []byte("initial-api-secret")); err != nil {
-		t.Fatalf("expected api secret to be hashed correctly: %v", err)
+
+	creds, err := DecryptCredentials(stored)
+	if err != nil {
+		t.Fatalf("failed to decrypt stored credentials: %v", err)
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(stored.APIPassphraseHash), []byte("initial-api-passphrase")); err != nil {
-		t.Fatalf("expected api passphrase to be hashed correctly: %v", err)
+	if creds.APIKey != "initial-api-key" || creds.APISecret != "initial-api-secret" || creds.APIPassphrase != "initial-api-passphrase" {
+		t.Fatalf("expected decrypted credentials to round-trip, got %+v", creds)
 	}
 
-	originalSecretHash := stored.APISecretHash
+	originalSecretEnc := stored.APISecretEnc
 
 	listReq := httptest.NewRequest(http.MethodGet, "/user-exchanges/forms", nil)
 	listReq.AddCookie(tokenCookie)
@@ -380,11 +348,18 @@ func TestUserExchangeLifecycle(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to load stored user exchange after update: %v", err)
 	}
-	if storedAfterUpdate.APISecretHash == originalSecretHash {
-		t.Fatalf("expected api secret hash to change after update")
+	if storedAfterUpdate.APISecretEnc == originalSecretEnc {
+		t.Fatalf("expected api secret ciphertext to change after update")
+	}
+	creds, err = DecryptCredentials(storedAfterUpdate)
+	if err != nil {
+		t.Fatalf("failed to decrypt updated credentials: %v", err)
+	}
+	if creds.APISecret != "updated-api-secret" {
+		t.Fatalf("expected updated api secret, got %q", creds.APISecret)
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(storedAfterUpdate.APISecretHash), []byte("updated-api-secret")); err != nil {
-		t.Fatalf("expected updated api secret to be hashed correctly: %v", err)
+	if creds.APIKey != "initial-api-key" || creds.APIPassphrase != "initial-api-passphrase" {
+		t.Fatalf("expected partial update to keep the other credentials, got %+v", creds)
 	}
 
 	listReq = httptest.NewRequest(http.MethodGet, "/user-exchanges/forms", nil)
@@ -402,6 +377,38 @@ func TestUserExchangeLifecycle(t *testing.T) {
 		t.Fatalf("expected 0 exchanges after hiding, got %d", len(listResp))
 	}
 
+	connector.err = &connectors.ExchangeError{Exchange: "binance", StatusCode: http.StatusUnauthorized, Message: "Invalid API-key", Kind: connectors.ErrAuth}
+	testReq := httptest.NewRequest(http.MethodPost, "/user-exchanges/"+strconv.Itoa(int(exchange.ID))+"/test", nil)
+	testReq.AddCookie(tokenCookie)
+	testRec := httptest.NewRecorder()
+	router.ServeHTTP(testRec, testReq)
+	if testRec.Code != http.StatusOK {
+		t.Fatalf("expected test 200, got %d", testRec.Code)
+	}
+
+	var tested model.UserExchangeResponse
+	if err := json.Unmarshal(testRec.Body.Bytes(), &tested); err != nil {
+		t.Fatalf("failed to decode test response: %v", err)
+	}
+	if tested.LastError == "" || tested.Permissions != nil {
+		t.Fatalf("expected failed verification to be reported, got %+v", tested)
+	}
+	storedAfterTest, err := exchangeStore.FindUserExchange(user.ID, exchange.ID)
+	if err != nil {
+		t.Fatalf("failed to load stored user exchange after test: %v", err)
+	}
+	if storedAfterTest.LastError != tested.LastError || storedAfterTest.LastVerifiedAt == nil {
+		t.Fatalf("expected verification result to be stored, got %+v", storedAfterTest)
+	}
+
+	testReq = httptest.NewRequest(http.MethodPost, "/user-exchanges/999/test", nil)
+	testReq.AddCookie(tokenCookie)
+	testRec = httptest.NewRecorder()
+	router.ServeHTTP(testRec, testReq)
+	if testRec.Code != http.StatusNotFound {
+		t.Fatalf("expected test of unknown exchange 404, got %d", testRec.Code)
+	}
+
 	deleteReq := httptest.NewRequest(http.MethodDelete, "/user-exchanges/"+strconv.Itoa(int(exchange.ID)), nil)
 	deleteReq.AddCookie(tokenCookie)
 	deleteRec := httptest.NewRecorder()
@@ -425,3 +432,167 @@ func TestUserExchangeLifecycle(t *testing.T) {
 		t.Fatalf("expected no exchanges after delete, got %d", len(listResp))
 	}
 }
+
+func TestMigrateCredentials(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	exchangeStore := newInMemoryUserExchangeStore()
+	SetUserExchangeStore(exchangeStore)
+	t.Cleanup(func() {
+		SetUserExchangeStore(nil)
+	})
+
+	// Seal one row under key version 1, then rotate to version 2.
+	useTestKeyring(t, newTestKeyring(t, 1))
+
+	rotated := &model.UserExchange{UserID: 1, ExchangeID: 1}
+	if err := encryptCredentials(rotated, credentials.ExchangeCredentials{APIKey: "key", APISecret: "secret"}); err != nil {
+		t.Fatalf("failed to encrypt credentials: %v", err)
+	}
+	if err := exchangeStore.SaveUserExchange(rotated); err != nil {
+		t.Fatalf("failed to seed rotated row: %v", err)
+	}
+
+	legacy := &model.UserExchange{
+		UserID:       1,
+		ExchangeID:   2,
+		APIKeyEnc:    "$2a$10$abcdefghijklmnopqrstuuN0Aq3hGm7dC1CCo5B0iJ1PUZl3ySKSa",
+		APISecretEnc: "$2a$10$abcdefghijklmnopqrstuuN0Aq3hGm7dC1CCo5B0iJ1PUZl3ySKSa",
+	}
+	if err := exchangeStore.SaveUserExchange(legacy); err != nil {
+		t.Fatalf("failed to seed legacy row: %v", err)
+	}
+
+	useTestKeyring(t, newTestKeyring(t, 2, 1))
+
+	if err := MigrateCredentials(logger); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+
+	migratedLegacy, err := exchangeStore.FindUserExchange(1, 2)
+	if err != nil {
+		t.Fatalf("failed to load legacy row: %v", err)
+	}
+	if !migratedLegacy.NeedsReentry || migratedLegacy.APIKeyEnc != "" || migratedLegacy.APISecretEnc != "" {
+		t.Fatalf("expected legacy hashes to be cleared and flagged, got %+v", migratedLegacy)
+	}
+	if _, err := DecryptCredentials(migratedLegacy); err != ErrLegacyCredentials {
+		t.Fatalf("expected ErrLegacyCredentials, got %v", err)
+	}
+
+	migratedRotated, err := exchangeStore.FindUserExchange(1, 1)
+	if err != nil {
+		t.Fatalf("failed to load rotated row: %v", err)
+	}
+	if migratedRotated.KeyVersion != 2 {
+		t.Fatalf("expected row to be re-sealed with key version 2, got %d", migratedRotated.KeyVersion)
+	}
+
+	useTestKeyring(t, newTestKeyring(t, 2))
+	creds, err := DecryptCredentials(migratedRotated)
+	if err != nil {
+		t.Fatalf("expected re-sealed row to decrypt without the retired key: %v", err)
+	}
+	if creds.APIKey != "key" || creds.APISecret != "secret" {
+		t.Fatalf("unexpected credentials after re-seal: %+v", creds)
+	}
+}
+
+func TestUpsertUserExchangeReentry(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+
+	useTestKeyring(t, newTestKeyring(t, 1))
+	useFakeConnector(t, &fakeConnector{permissions: &connectors.KeyPermissions{Read: true}})
+
+	exchangeStore := newInMemoryUserExchangeStore()
+	SetUserExchangeStore(exchangeStore)
+	t.Cleanup(func() {
+		SetUserExchangeStore(nil)
+	})
+
+	exchange := &model.Exchange{Name: "Binance"}
+	if err := exchangeStore.CreateExchange(exchange); err != nil {
+		t.Fatalf("failed to seed exchange: %v", err)
+	}
+	if err := exchangeStore.SaveUserExchange(&model.UserExchange{UserID: 1, ExchangeID: exchange.ID, NeedsReentry: true}); err != nil {
+		t.Fatalf("failed to seed flagged row: %v", err)
+	}
+
+	upsert := func(payload map[string]interface{}) int {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/user-exchanges/", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, &model.User{ID: 1}))
+		rec := httptest.NewRecorder()
+		UpsertUserExchangeHandler(logger).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := upsert(map[string]interface{}{"exchangeId": exchange.ID, "apiSecret": "new-secret"}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a partial re-entry, got %d", code)
+	}
+	stored, err := exchangeStore.FindUserExchange(1, exchange.ID)
+	if err != nil {
+		t.Fatalf("failed to load flagged row: %v", err)
+	}
+	if !stored.NeedsReentry || stored.DataKey != "" {
+		t.Fatalf("expected the rejected re-entry to leave the row untouched, got %+v", stored)
+	}
+
+	if code := upsert(map[string]interface{}{"exchangeId": exchange.ID, "apiKey": "new-key", "apiSecret": "new-secret"}); code != http.StatusOK {
+		t.Fatalf("expected 200 for a full re-entry, got %d", code)
+	}
+	stored, err = exchangeStore.FindUserExchange(1, exchange.ID)
+	if err != nil {
+		t.Fatalf("failed to load re-entered row: %v", err)
+	}
+	if stored.NeedsReentry {
+		t.Fatalf("expected re-entry to clear the flag")
+	}
+	creds, err := DecryptCredentials(stored)
+	if err != nil {
+		t.Fatalf("failed to decrypt re-entered credentials: %v", err)
+	}
+	if creds.APIKey != "new-key" || creds.APISecret != "new-secret" {
+		t.Fatalf("unexpected credentials after re-entry: %+v", creds)
+	}
+}
+
+func TestNewConnector(t *testing.T) {
+	exchangeStore := newInMemoryUserExchangeStore()
+	SetUserExchangeStore(exchangeStore)
+	t.Cleanup(func() {
+		SetUserExchangeStore(nil)
+	})
+	useTestKeyring(t, newTestKeyring(t, 1))
+
+	exchange := &model.Exchange{Name: "Fake Exchange"}
+	if err := exchangeStore.CreateExchange(exchange); err != nil {
+		t.Fatalf("failed to seed exchange: %v", err)
+	}
+
+	var received credentials.ExchangeCredentials
+	connectors.Register("fakeexchange", model.ExchangeCapabilities{Spot: true}, func(creds credentials.ExchangeCredentials) (connectors.ExchangeConnector, error) {
+		received = creds
+		return connectors.NewKucoinConnector(creds.APIKey, creds.APISecret, creds.APIPassphrase), nil
+	})
+	t.Cleanup(func() {
+		connectors.Unregister("fakeexchange")
+	})
+
+	ue := &model.UserExchange{ID: 1, UserID: 1, ExchangeID: exchange.ID}
+	if err := encryptCredentials(ue, credentials.ExchangeCredentials{APIKey: "key", APISecret: "secret"}); err != nil {
+		t.Fatalf("failed to encrypt credentials: %v", err)
+	}
+
+	if _, err := NewConnector(ue); err != nil {
+		t.Fatalf("expected connector, got %v", err)
+	}
+	if received.APIKey != "key" || received.APISecret != "secret" {
+		t.Fatalf("expected decrypted credentials to reach the factory, got %+v", received)
+	}
+
+	ue.Exchange = &model.Exchange{Name: "Nowhere"}
+	if _, err := NewConnector(ue); !errors.Is(err, connectors.ErrUnsupportedExchange) {
+		t.Fatalf("expected ErrUnsupportedExchange, got %v", err)
+	}
+}
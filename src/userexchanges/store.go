@@ -6,6 +6,7 @@ import (
 
 	"vsC1Y2025V01/src/db"
 	"vsC1Y2025V01/src/model"
+	"vsC1Y2025V01/src/query"
 
 	"gorm.io/gorm"
 )
@@ -20,8 +21,11 @@ type UserExchangeStore interface {
 	GetExchangeByID(id uint) (*model.Exchange, error)
 	FindUserExchange(userID, exchangeID uint) (*model.UserExchange, error)
 	SaveUserExchange(ue *model.UserExchange) error
-	ListFormUserExchanges(userID uint) ([]model.UserExchange, error)
+	// ListFormUserExchanges returns one page of the user's exchanges shown in
+	// forms that match params, and the number of matches across all pages.
+	ListFormUserExchanges(userID uint, params query.Params) ([]model.UserExchange, int64, error)
 	DeleteUserExchange(userID, exchangeID uint) (bool, error)
+	ListAllUserExchanges() ([]model.UserExchange, error)
 }
 
 var (
@@ -112,17 +116,24 @@ func (s *gormUserExchangeStore) SaveUserExchange(ue *model.UserExchange) error {
 	return db.DB.Save(ue).Error
 }
 
-func (s *gormUserExchangeStore) ListFormUserExchanges(userID uint) ([]model.UserExchange, error) {
+func (s *gormUserExchangeStore) ListFormUserExchanges(userID uint, params query.Params) ([]model.UserExchange, int64, error) {
 	if db.DB == nil {
-		return nil, errors.New("database connection is not initialized")
+		return nil, 0, errors.New("database connection is not initialized")
+	}
+
+	matching := params.Where(db.DB.Model(&model.UserExchange{}).Where("user_id = ? AND show_in_forms = ?", userID, true)).Session(&gorm.Session{})
+
+	var total int64
+	if err := matching.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
 	var exchanges []model.UserExchange
-	if err := db.DB.Preload("Exchange").Where("user_id = ? AND show_in_forms = ?", userID, true).Find(&exchanges).Error; err != nil {
-		return nil, err
+	if err := params.Page(params.Order(matching.Preload("Exchange"))).Find(&exchanges).Error; err != nil {
+		return nil, 0, err
 	}
 
-	return exchanges, nil
+	return exchanges, total, nil
 }
 
 func (s *gormUserExchangeStore) DeleteUserExchange(userID, exchangeID uint) (bool, error) {
@@ -137,3 +148,16 @@ func (s *gormUserExchangeStore) DeleteUserExchange(userID, exchangeID uint) (boo
 
 	return res.RowsAffected > 0, nil
 }
+
+func (s *gormUserExchangeStore) ListAllUserExchanges() ([]model.UserExchange, error) {
+	if db.DB == nil {
+		return nil, errors.New("database connection is not initialized")
+	}
+
+	var exchanges []model.UserExchange
+	if err := db.DB.Order("id ASC").Find(&exchanges).Error; err != nil {
+		return nil, err
+	}
+
+	return exchanges, nil
+}
@@ -0,0 +1,73 @@
+package userexchanges
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"vsC1Y2025V01/internal/connectors"
+	"vsC1Y2025V01/src/model"
+)
+
+// verifyTimeout bounds a full verification, which makes up to three calls to
+// the exchange.
+const verifyTimeout = 15 * time.Second
+
+var ErrMissingCredentials = errors.New("user exchange has no API key and secret")
+
+// VerifyCredentials checks ue's stored credentials against its exchange and
+// records the outcome on ue: LastVerifiedAt, LastError (empty on success) and,
+// where the exchange reports them, the key's trade and withdrawal
+// permissions. A failed check is not an error; it is stored in LastError.
+// The returned error means no check could be made at all, e.g. because the
+// exchange has no connector (connectors.ErrUnsupportedExchange) or the
+// credentials must be re-entered. The caller saves ue.
+func VerifyCredentials(ctx context.Context, ue *model.UserExchange) error {
+	if ue.APIKeyEnc == "" || ue.APISecretEnc == "" {
+		return ErrMissingCredentials
+	}
+
+	connector, err := NewConnector(ue)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	permissions, err := checkConnector(ctx, connector)
+
+	now := time.Now()
+	ue.LastVerifiedAt = &now
+	ue.LastError = ""
+	ue.CanTrade = nil
+	ue.CanWithdraw = nil
+
+	if err != nil {
+		ue.LastError = err.Error()
+		return nil
+	}
+	if permissions != nil {
+		ue.CanTrade = &permissions.Trade
+		ue.CanWithdraw = &permissions.Withdraw
+	}
+	return nil
+}
+
+// checkConnector makes an authenticated round trip and, if the connector
+// supports it, reads the key permissions. TestConnection alone does not
+// prove the key works for every connector, so balances are read as well.
+func checkConnector(ctx context.Context, connector connectors.ExchangeConnector) (*connectors.KeyPermissions, error) {
+	if err := connector.TestConnection(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := connector.GetBalances(ctx); err != nil {
+		return nil, err
+	}
+
+	reporter, ok := connector.(connectors.PermissionsReporter)
+	if !ok {
+		return nil, nil
+	}
+	return reporter.GetKeyPermissions(ctx)
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"encoding/json"
 	"net/http"
+	"net/mail"
 	"strings"
 	"time"
 
@@ -32,7 +33,18 @@ func UpdateUserHandler(logger *logrus.Entry) http.HandlerFunc {
 		}
 
 		if payload.Email != nil {
-			user.Email = strings.TrimSpace(*payload.Email)
+			email := strings.TrimSpace(*payload.Email)
+			if email != "" {
+				if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+					http.Error(w, "Invalid email address", http.StatusBadRequest)
+					return
+				}
+			}
+			// A new address has to be verified again.
+			if !strings.EqualFold(email, user.Email) {
+				user.EmailVerifiedAt = nil
+			}
+			user.Email = email
 		}
 		if payload.FirstName != nil {
 			user.FirstName = strings.TrimSpace(*payload.FirstName)